@@ -1,34 +1,208 @@
 package api
 
 import (
+	"context"
+	"crypto/sha256"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
+	"cloud.google.com/go/firestore"
+	"github.com/seans3/nhd/backend/apierror"
 	"github.com/seans3/nhd/backend/interfaces"
+	"github.com/seans3/nhd/backend/logging"
+	"github.com/seans3/nhd/backend/mailer"
+	"github.com/seans3/nhd/backend/metrics"
 	"github.com/seans3/nhd/backend/middleware"
+	"github.com/seans3/nhd/backend/money"
+	"github.com/seans3/nhd/backend/postal"
+	"github.com/seans3/nhd/backend/pricing"
 	"github.com/seans3/nhd/backend/proto/gen/go"
+	"github.com/seans3/nhd/backend/validation"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type API struct {
-	DS interfaces.Datastore
-	PS interfaces.Publisher
+	DS     interfaces.Datastore
+	PS     interfaces.Publisher
+	Mailer interfaces.Mailer
+	// Pricing assigns the initial cost of each new report run.
+	Pricing *pricing.Engine
+	// ReportBaseURL is prepended to a report's final_pdf_storage_path to
+	// build the download link included in report emails.
+	ReportBaseURL string
+	// RequestTimeout is the server's per-request timeout, or zero if there
+	// is none. Long polls are capped so that they answer before it expires.
+	RequestTimeout time.Duration
+}
+
+// Organizations
+
+// organizationScope is the set of organizations a request may read and
+// write: a single organization, or every organization for an admin who asked
+// for the cross-tenant view.
+type organizationScope struct {
+	// OrganizationID is empty if All is set.
+	OrganizationID string
+	All            bool
+}
+
+// allows reports whether a document belonging to organizationID is visible
+// in the scope.
+func (s organizationScope) allows(organizationID string) bool {
+	return s.All || organizationID == s.OrganizationID
+}
+
+// requestScope returns the organizations a request may act on. Requests are
+// scoped to the caller's own organization. Admins may instead pass an
+// organization_id query parameter to act on another organization, or
+// all_organizations=true to read across every organization. If the scope
+// cannot be resolved, requestScope writes the error response and returns
+// false.
+func requestScope(w http.ResponseWriter, r *http.Request) (organizationScope, bool) {
+	user := middleware.CurrentUser(r.Context())
+	if user == nil {
+		apierror.Write(w, r, apierror.New(apierror.PermissionDenied, "No user profile found; ask an administrator to register you"))
+		return organizationScope{}, false
+	}
+
+	query := r.URL.Query()
+	organizationID := query.Get("organization_id")
+	all := false
+	if s := query.Get("all_organizations"); s != "" {
+		var err error
+		if all, err = strconv.ParseBool(s); err != nil {
+			apierror.Write(w, r, apierror.New(apierror.ValidationFailed, "Invalid all_organizations"))
+			return organizationScope{}, false
+		}
+	}
+	if all && organizationID != "" {
+		apierror.Write(w, r, apierror.New(apierror.ValidationFailed, "Specify either organization_id or all_organizations, not both"))
+		return organizationScope{}, false
+	}
+	crossTenant := all || (organizationID != "" && organizationID != user.OrganizationId)
+	if crossTenant && !user.GetPermissions().GetIsAdmin() {
+		apierror.Write(w, r, apierror.New(apierror.PermissionDenied, "Only admins may access other organizations"))
+		return organizationScope{}, false
+	}
+	if all {
+		return organizationScope{All: true}, true
+	}
+
+	if organizationID == "" {
+		organizationID = user.OrganizationId
+	}
+	if organizationID == "" {
+		apierror.Write(w, r, apierror.New(apierror.PermissionDenied, "User is not assigned to an organization"))
+		return organizationScope{}, false
+	}
+	return organizationScope{OrganizationID: organizationID}, true
+}
+
+// requestOrganization returns the single organization that documents created
+// by the request belong to, as requestScope resolves it.
+func requestOrganization(w http.ResponseWriter, r *http.Request) (string, bool) {
+	scope, ok := requestScope(w, r)
+	if !ok {
+		return "", false
+	}
+	if scope.All {
+		apierror.Write(w, r, apierror.New(apierror.ValidationFailed, "all_organizations cannot be used when creating; specify organization_id"))
+		return "", false
+	}
+	return scope.OrganizationID, true
+}
+
+// CreateOrganization adds an organization. Its ID is assigned by the
+// datastore.
+func (a *API) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	var org nhd_report.Organization
+	if err := validation.Decode(r.Body, &org); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	var v validation.Validator
+	v.Required("name", org.Name)
+	if err := v.Err(); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		apierror.Write(w, r, apierror.New(apierror.Unauthenticated, "User ID not found in context"))
+		return
+	}
+	org.OrganizationId = ""
+	org.CreatedByUserId = userID
+	org.CreatedAt = timestamppb.Now()
+
+	docRef, _, err := a.DS.CreateOrganization(r.Context(), &org)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{"organization_id": docRef.ID})
+}
+
+func (a *API) GetOrganizations(w http.ResponseWriter, r *http.Request) {
+	orgs, err := a.DS.GetOrganizations(r.Context())
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	if orgs == nil {
+		orgs = []*nhd_report.Organization{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(orgs)
 }
 
 // Users
 // RegisterUserRequest defines the shape of the request body for creating a new user.
 type RegisterUserRequest struct {
-	Email       string `json:"email"`
-	Password    string `json:"password"`
-	FullName    string `json:"full_name"`
-	IsAdmin     bool   `json:"is_admin"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	FullName string `json:"full_name"`
+	// OrganizationID is the organization the user works for. It is required
+	// and must already exist.
+	OrganizationID     string `json:"organization_id"`
+	IsAdmin            bool   `json:"is_admin"`
+	CanCreateCustomers bool   `json:"can_create_customers"`
+	CanGenerateReports bool   `json:"can_generate_reports"`
 }
 
 func (a *API) RegisterUser(w http.ResponseWriter, r *http.Request) {
 	var req RegisterUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if err := validation.Decode(r.Body, &req); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	var v validation.Validator
+	v.Required("email", req.Email)
+	v.Email("email", req.Email)
+	v.Required("organization_id", req.OrganizationID)
+	if req.OrganizationID != "" {
+		_, err := a.DS.GetOrganization(r.Context(), req.OrganizationID)
+		if err != nil && !errors.Is(err, interfaces.ErrNotFound) {
+			apierror.Write(w, r, err)
+			return
+		}
+		v.Check(err == nil, "organization_id", "does not exist")
+	}
+	if err := v.Err(); err != nil {
+		apierror.Write(w, r, err)
 		return
 	}
 
@@ -43,28 +217,30 @@ func (a *API) RegisterUser(w http.ResponseWriter, r *http.Request) {
 		
 		firebaseUser, err := Firebase.CreateUser(r.Context(), params)
 		if err != nil {
-			http.Error(w, "Failed to create user in Firebase", http.StatusInternalServerError)
+			apierror.Write(w, r, apierror.Wrap(apierror.UpstreamUnavailable, err, "Failed to create user in Firebase"))
 			return
 		}
 	*/
-	log.Printf("TODO: Implement Firebase user creation for email: %s", req.Email)
+	logging.FromContext(r.Context()).Warn("TODO: Implement Firebase user creation; creating a placeholder UID")
 	// Let's pretend a user was created and we have an ID.
 	firebaseUser := struct{ UID string }{UID: "fake-firebase-uid-" + req.Email}
 
 	// Now, create the user profile in Firestore.
 	user := &nhd_report.User{
-		UserId:   firebaseUser.UID,
-		FullName: req.FullName,
-		Email:    req.Email,
+		UserId:         firebaseUser.UID,
+		FullName:       req.FullName,
+		Email:          req.Email,
+		OrganizationId: req.OrganizationID,
 		Permissions: &nhd_report.Permissions{
-			IsAdmin: req.IsAdmin,
-			// Set other default permissions as needed
+			IsAdmin:            req.IsAdmin,
+			CanCreateCustomers: req.CanCreateCustomers,
+			CanGenerateReports: req.CanGenerateReports,
 		},
 		CreatedAt: timestamppb.Now(),
 	}
 
 	if err := a.DS.CreateUser(r.Context(), user); err != nil {
-		http.Error(w, "Failed to create user profile in Firestore", http.StatusInternalServerError)
+		apierror.Write(w, r, err)
 		return
 	}
 
@@ -75,22 +251,41 @@ func (a *API) RegisterUser(w http.ResponseWriter, r *http.Request) {
 // Customers
 func (a *API) CreateCustomer(w http.ResponseWriter, r *http.Request) {
 	var customer nhd_report.Customer
-	if err := json.NewDecoder(r.Body).Decode(&customer); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if err := validation.Decode(r.Body, &customer); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	var v validation.Validator
+	v.Required("full_name", customer.FullName)
+	v.Email("email", customer.Email)
+	if err := v.Err(); err != nil {
+		apierror.Write(w, r, err)
 		return
 	}
+	// IDs are assigned by the datastore; archiving has its own endpoint.
+	customer.CustomerId = ""
+	customer.Archived = false
+	customer.ArchivedAt = nil
+	customer.SearchPrefixes = nil
+	customer.CreatedAt = timestamppb.Now()
+	customer.UpdatedAt = customer.CreatedAt
 
 	// Get the user ID from the context (set by the auth middleware)
 	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
 	if !ok {
-		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		apierror.Write(w, r, apierror.New(apierror.Unauthenticated, "User ID not found in context"))
 		return
 	}
 	customer.CreatedByUserId = userID
+	organizationID, ok := requestOrganization(w, r)
+	if !ok {
+		return
+	}
+	customer.OrganizationId = organizationID
 
 	docRef, _, err := a.DS.CreateCustomer(r.Context(), &customer)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierror.Write(w, r, err)
 		return
 	}
 
@@ -98,114 +293,1255 @@ func (a *API) CreateCustomer(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"customer_id": docRef.ID})
 }
 
+// GetCustomers lists a page of the customers of the caller's organization,
+// or of the organizations selected by an admin (see requestScope), ordered by
+// full_name. Archived customers are left out unless include_archived=true.
 func (a *API) GetCustomers(w http.ResponseWriter, r *http.Request) {
-	customers, err := a.DS.GetCustomers(r.Context())
+	scope, ok := requestScope(w, r)
+	if !ok {
+		return
+	}
+	query, err := parseCustomerQuery(r.URL.Query())
+	if err != nil {
+		apierror.Write(w, r, apierror.Wrap(apierror.ValidationFailed, err, "%v", err))
+		return
+	}
+	query.OrganizationID = scope.OrganizationID
+
+	page, err := a.DS.GetCustomers(r.Context(), query)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(page)
+}
+
+func parseCustomerQuery(values url.Values) (interfaces.CustomerQuery, error) {
+	query := interfaces.CustomerQuery{
+		Search:    values.Get("q"),
+		PageToken: values.Get("page_token"),
+	}
+	var err error
+	if s := values.Get("include_archived"); s != "" {
+		if query.IncludeArchived, err = strconv.ParseBool(s); err != nil {
+			return query, fmt.Errorf("invalid include_archived %q", s)
+		}
+	}
+	if s := values.Get("page_size"); s != "" {
+		if query.PageSize, err = strconv.Atoi(s); err != nil || query.PageSize < 0 {
+			return query, fmt.Errorf("invalid page_size %q", s)
+		}
+	}
+	if err := query.Normalize(); err != nil {
+		return query, err
+	}
+	return query, nil
+}
+
+// customerInScope loads the customer named by the request path. Customers of
+// organizations outside the request's scope are reported as not found. If
+// the customer cannot be loaded, customerInScope writes the error response
+// and returns false.
+func (a *API) customerInScope(w http.ResponseWriter, r *http.Request) (*nhd_report.Customer, bool) {
+	scope, ok := requestScope(w, r)
+	if !ok {
+		return nil, false
+	}
+	customer, err := a.DS.GetCustomer(r.Context(), r.PathValue("id"))
+	if err == nil && !scope.allows(customer.OrganizationId) {
+		err = interfaces.ErrNotFound
+	}
+	if errors.Is(err, interfaces.ErrNotFound) {
+		apierror.Write(w, r, apierror.New(apierror.NotFound, "Customer not found"))
+		return nil, false
+	}
+	if err != nil {
+		apierror.Write(w, r, err)
+		return nil, false
+	}
+	return customer, true
+}
+
+// GetCustomer returns a single customer, archived or not.
+func (a *API) GetCustomer(w http.ResponseWriter, r *http.Request) {
+	customer, ok := a.customerInScope(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(customer)
+}
+
+// UpdateCustomer applies a partial update to a customer's full_name, email
+// and company_name. Fields omitted from the request body are left
+// unchanged.
+func (a *API) UpdateCustomer(w http.ResponseWriter, r *http.Request) {
+	var patch struct {
+		FullName    *string `json:"full_name"`
+		Email       *string `json:"email"`
+		CompanyName *string `json:"company_name"`
+	}
+	if err := validation.Decode(r.Body, &patch); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	var v validation.Validator
+	if patch.FullName != nil {
+		v.Required("full_name", *patch.FullName)
+	}
+	if patch.Email != nil {
+		v.Email("email", *patch.Email)
+	}
+	if err := v.Err(); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	customer, ok := a.customerInScope(w, r)
+	if !ok {
+		return
+	}
+	updated := proto.Clone(customer).(*nhd_report.Customer)
+	if patch.FullName != nil {
+		updated.FullName = *patch.FullName
+	}
+	if patch.Email != nil {
+		updated.Email = *patch.Email
+	}
+	if patch.CompanyName != nil {
+		updated.CompanyName = *patch.CompanyName
+	}
+	updated.UpdatedAt = timestamppb.Now()
+
+	if err := a.DS.UpdateCustomer(r.Context(), updated); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	logging.FromContext(r.Context()).Info("Customer updated", "customer_id", updated.CustomerId)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(updated)
+}
+
+// ArchiveCustomer soft-deletes a customer: it is hidden from listings and
+// cannot be used for new report runs, but it and its report runs are kept.
+func (a *API) ArchiveCustomer(w http.ResponseWriter, r *http.Request) {
+	a.setCustomerArchived(w, r, true)
+}
+
+// RestoreCustomer reverses ArchiveCustomer.
+func (a *API) RestoreCustomer(w http.ResponseWriter, r *http.Request) {
+	a.setCustomerArchived(w, r, false)
+}
+
+func (a *API) setCustomerArchived(w http.ResponseWriter, r *http.Request, archived bool) {
+	customer, ok := a.customerInScope(w, r)
+	if !ok {
+		return
+	}
+	updated := proto.Clone(customer).(*nhd_report.Customer)
+	if updated.Archived != archived {
+		updated.Archived = archived
+		updated.UpdatedAt = timestamppb.Now()
+		updated.ArchivedAt = nil
+		if archived {
+			updated.ArchivedAt = updated.UpdatedAt
+		}
+		if err := a.DS.UpdateCustomer(r.Context(), updated); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+		logging.FromContext(r.Context()).Info("Customer archive state changed", "customer_id", updated.CustomerId, "archived", archived)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(updated)
+}
+
+// Property Addresses
+func (a *API) CreatePropertyAddress(w http.ResponseWriter, r *http.Request) {
+	var address nhd_report.PropertyAddress
+	if err := validation.Decode(r.Body, &address); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	var v validation.Validator
+	validateAddressDetails(&v, "address_details", address.AddressDetails)
+	if err := v.Err(); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	organizationID, ok := requestOrganization(w, r)
+	if !ok {
+		return
+	}
+	address.PropertyAddressId = ""
+	address.OrganizationId = organizationID
+	address.NormalizedKey = postal.Key(address.AddressDetails)
+
+	docRef, _, err := a.DS.CreatePropertyAddress(r.Context(), &address)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{"property_address_id": docRef.ID})
+}
+
+func (a *API) GetPropertyAddresses(w http.ResponseWriter, r *http.Request) {
+	scope, ok := requestScope(w, r)
+	if !ok {
+		return
+	}
+	addresses, err := a.DS.GetPropertyAddresses(r.Context(), scope.OrganizationID)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(addresses)
+}
+
+func (a *API) GetPropertyAddress(w http.ResponseWriter, r *http.Request) {
+	propertyAddressID := r.PathValue("id")
+	scope, ok := requestScope(w, r)
+	if !ok {
+		return
+	}
+
+	address, err := a.DS.GetPropertyAddress(r.Context(), propertyAddressID)
+	if err == nil && !scope.allows(address.OrganizationId) {
+		err = interfaces.ErrNotFound
+	}
+	if errors.Is(err, interfaces.ErrNotFound) {
+		apierror.Write(w, r, apierror.New(apierror.NotFound, "Property address not found"))
+		return
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierror.Write(w, r, err)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(customers)
+	json.NewEncoder(w).Encode(address)
+}
+
+// UpdatePropertyAddress applies a partial update to a property address. Any
+// fields present in the request body overwrite the stored values; omitted
+// fields are left unchanged.
+func (a *API) UpdatePropertyAddress(w http.ResponseWriter, r *http.Request) {
+	propertyAddressID := r.PathValue("id")
+
+	var patch nhd_report.PropertyAddress
+	if err := validation.Decode(r.Body, &patch); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	// The ID in the path is authoritative, and an address cannot be moved
+	// to another organization.
+	patch.PropertyAddressId = ""
+	patch.OrganizationId = ""
+	scope, ok := requestScope(w, r)
+	if !ok {
+		return
+	}
+
+	address, err := a.DS.GetPropertyAddress(r.Context(), propertyAddressID)
+	if err == nil && !scope.allows(address.OrganizationId) {
+		err = interfaces.ErrNotFound
+	}
+	if errors.Is(err, interfaces.ErrNotFound) {
+		apierror.Write(w, r, apierror.New(apierror.NotFound, "Property address not found"))
+		return
+	}
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	updated := proto.Clone(address).(*nhd_report.PropertyAddress)
+	proto.Merge(updated, &patch)
+	updated.NormalizedKey = postal.Key(updated.AddressDetails)
+
+	if err := a.DS.UpdatePropertyAddress(r.Context(), updated); err != nil {
+		if errors.Is(err, interfaces.ErrNotFound) {
+			apierror.Write(w, r, apierror.New(apierror.NotFound, "Property address not found"))
+			return
+		}
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(updated)
+}
+
+// validateAddressDetails checks that details, the field named field, has the
+// parts needed to locate the property.
+func validateAddressDetails(v *validation.Validator, field string, details *nhd_report.PropertyAddress_AddressDetails) {
+	if details == nil {
+		v.Add(field, "is required")
+		return
+	}
+	v.Required(field+".street_address", details.StreetAddress)
+	v.Required(field+".city", details.City)
+	v.Required(field+".zip_code", details.ZipCode)
 }
 
 // Report Runs
+// CreateReportRunRequest defines the shape of the request body for creating a
+// new report run. The property can either be referenced by an existing
+// property_address_id or supplied inline as property_address, in which case
+// it is matched against existing records and only created if it is new.
+type CreateReportRunRequest struct {
+	*nhd_report.ReportRun
+	PropertyAddress *nhd_report.PropertyAddress `json:"property_address,omitempty"`
+}
+
 func (a *API) CreateReportRun(w http.ResponseWriter, r *http.Request) {
-	var reportRun nhd_report.ReportRun
-	if err := json.NewDecoder(r.Body).Decode(&reportRun); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	req := CreateReportRunRequest{ReportRun: &nhd_report.ReportRun{}}
+	if err := validation.Decode(r.Body, &req); err != nil {
+		apierror.Write(w, r, err)
 		return
 	}
+	reportRun := req.ReportRun
 
 	// Get the user ID from the context
 	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
 	if !ok {
-		http.Error(w, "User ID not found in context", http.StatusUnauthorized)
+		apierror.Write(w, r, apierror.New(apierror.Unauthenticated, "User ID not found in context"))
+		return
+	}
+	organizationID, ok := requestOrganization(w, r)
+	if !ok {
+		return
+	}
+
+	var v validation.Validator
+	v.Required("customer_id", reportRun.CustomerId)
+	var customer *nhd_report.Customer
+	if reportRun.CustomerId != "" {
+		var err error
+		customer, err = a.DS.GetCustomer(r.Context(), reportRun.CustomerId)
+		if err != nil && !errors.Is(err, interfaces.ErrNotFound) {
+			apierror.Write(w, r, err)
+			return
+		}
+		// Customers of other organizations are reported as missing.
+		switch {
+		case err != nil || customer.OrganizationId != organizationID:
+			v.Add("customer_id", "does not exist")
+		case customer.Archived:
+			v.Add("customer_id", "is archived")
+		}
+	}
+	address := req.PropertyAddress
+	switch {
+	case address != nil && reportRun.PropertyAddressId != "":
+		v.Add("property_address", "cannot be set with property_address_id")
+	case address != nil:
+		validateAddressDetails(&v, "property_address.address_details", address.AddressDetails)
+	case reportRun.PropertyAddressId == "":
+		v.Add("property_address_id", "is required unless property_address is set")
+	default:
+		existing, err := a.DS.GetPropertyAddress(r.Context(), reportRun.PropertyAddressId)
+		if err != nil && !errors.Is(err, interfaces.ErrNotFound) {
+			apierror.Write(w, r, err)
+			return
+		}
+		// Addresses of other organizations are reported as missing.
+		v.Check(err == nil && existing.OrganizationId == organizationID, "property_address_id", "does not exist")
+	}
+	if err := v.Err(); err != nil {
+		apierror.Write(w, r, err)
 		return
 	}
+
+	if address != nil {
+		details := address.AddressDetails
+		address.PropertyAddressId = ""
+		address.OrganizationId = organizationID
+		if details.State == "" {
+			details.State = postal.DefaultState
+		}
+		address.NormalizedKey = postal.Key(details)
+	}
 	reportRun.CreatedByUserId = userID
+	reportRun.OrganizationId = organizationID
 	reportRun.Status = nhd_report.ReportRun_PENDING
 	reportRun.CreatedAt = timestamppb.Now()
+	reportRun.UpdatedAt = reportRun.CreatedAt
+	reportRun.FailureReason = ""
+	reportRun.Results = nil
+	reportRun.Attempt = 1
+	reportRun.StatusHistory = []*nhd_report.ReportRun_StatusChange{{
+		ToStatus:  nhd_report.ReportRun_PENDING,
+		ChangedAt: reportRun.CreatedAt,
+		Actor:     userID,
+		Reason:    "Report run created",
+	}}
 
-	docRef, _, err := a.DS.CreateReportRun(r.Context(), &reportRun)
+	cost, err := a.Pricing.Quote(r.Context(), customer, reportRun.CreatedAt.AsTime())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierror.Write(w, r, err)
 		return
 	}
+	cost.SetByUserId = userID
+	reportRun.CostHistory = nil
+	reportRun.Ledger = nil
+	err = interfaces.AppendLedgerEntry(reportRun, &nhd_report.ReportRun_LedgerEntry{
+		Type:            nhd_report.ReportRun_LedgerEntry_CHARGE,
+		Amount:          money.New(cost.Amount.GetCurrency(), cost.Amount.GetMinorUnits()),
+		CreatedAt:       reportRun.CreatedAt,
+		CreatedByUserId: userID,
+		Reason:          "Report run created",
+	})
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	reportRun.CostHistory = []*nhd_report.ReportRun_ReportCost{cost}
 
-	_, err = a.PS.Publish(r.Context(), "nhd-report-requests", []byte(docRef.ID))
+	var docRef *firestore.DocumentRef
+	if address != nil {
+		docRef, err = a.DS.CreateReportRunForAddress(r.Context(), reportRun, address)
+	} else {
+		docRef, _, err = a.DS.CreateReportRun(r.Context(), reportRun)
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierror.Write(w, r, err)
+		return
+	}
+
+	// If publishing fails, mark the run FAILED, as RetryReportRun does, so
+	// that it can be retried rather than staying PENDING and never
+	// processed.
+	ctx := logging.With(r.Context(), "report_run_id", docRef.ID)
+	if err := a.queueReportRun(ctx, docRef.ID); err != nil {
+		if rollbackErr := a.DS.UpdateReportRunStatus(context.WithoutCancel(ctx), docRef.ID, nhd_report.ReportRun_FAILED, userID, "could not be queued"); rollbackErr != nil {
+			logging.FromContext(ctx).Error("Failed to mark unqueued report run FAILED", "error", rollbackErr)
+		}
+		apierror.Write(w, r.WithContext(ctx), err)
 		return
 	}
+	metrics.ReportRunsCreated.Inc()
+	logging.FromContext(ctx).Info("Report run created", "customer_id", reportRun.CustomerId, "property_address_id", reportRun.PropertyAddressId)
 
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]string{"report_run_id": docRef.ID})
+	json.NewEncoder(w).Encode(map[string]string{
+		"report_run_id":       docRef.ID,
+		"property_address_id": reportRun.PropertyAddressId,
+	})
+}
+
+// queueReportRun publishes a report run to nhd-report-requests for the
+// report generators. The message attributes carry the request's trace ID,
+// so that the worker's log lines for the run can be correlated with the
+// request.
+func (a *API) queueReportRun(ctx context.Context, reportRunID string) error {
+	_, err := a.PS.Publish(ctx, "nhd-report-requests", []byte(reportRunID), logging.Attributes(ctx))
+	if err != nil {
+		metrics.PublishFailures.Inc("nhd-report-requests")
+		return apierror.Wrap(apierror.UpstreamUnavailable, err, "Failed to queue report run %s; try again later", reportRunID)
+	}
+	return nil
+}
+
+// ReportRunDetail is the response body for a single report run, with the
+// referenced customer and property address resolved.
+type ReportRunDetail struct {
+	*nhd_report.ReportRun
+	Customer        *nhd_report.Customer        `json:"customer,omitempty"`
+	PropertyAddress *nhd_report.PropertyAddress `json:"property_address,omitempty"`
+}
+
+const (
+	defaultReportRunWaitTimeout = 30 * time.Second
+	maxReportRunWaitTimeout     = 60 * time.Second
+	// reportRunWaitMargin is the time left, after a long poll ends, to
+	// build and write the response before the request times out.
+	reportRunWaitMargin = time.Second
+)
+
+// reportRunPollInterval is how often a long-polling GetReportRun re-reads the
+// report run from the datastore.
+var reportRunPollInterval = 500 * time.Millisecond
+
+// GetReportRun returns a single report run. If the wait_for_status query
+// parameter is set, the request is held open until the run reaches that
+// status, reaches a terminal status, or the timeout (default 30s) expires,
+// whichever comes first. The wait never runs past the server's request
+// timeout. The current state of the run is returned either way.
+func (a *API) GetReportRun(w http.ResponseWriter, r *http.Request) {
+	reportRunID := r.PathValue("id")
+	query := r.URL.Query()
+	scope, ok := requestScope(w, r)
+	if !ok {
+		return
+	}
+
+	var waitFor nhd_report.ReportRun_Status
+	if s := query.Get("wait_for_status"); s != "" {
+		v, ok := nhd_report.ReportRun_Status_value[s]
+		if !ok || v == int32(nhd_report.ReportRun_STATUS_UNSPECIFIED) {
+			apierror.Write(w, r, apierror.New(apierror.ValidationFailed, "Invalid wait_for_status"))
+			return
+		}
+		waitFor = nhd_report.ReportRun_Status(v)
+	}
+	timeout := defaultReportRunWaitTimeout
+	if s := query.Get("timeout"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			apierror.Write(w, r, apierror.New(apierror.ValidationFailed, "Invalid timeout"))
+			return
+		}
+		timeout = min(d, maxReportRunWaitTimeout)
+	}
+	if a.RequestTimeout > 0 {
+		timeout = max(min(timeout, a.RequestTimeout-reportRunWaitMargin), 0)
+	}
+
+	var reportRun *nhd_report.ReportRun
+	var err error
+	if waitFor != nhd_report.ReportRun_STATUS_UNSPECIFIED {
+		reportRun, err = a.waitForReportRunStatus(r.Context(), reportRunID, waitFor, timeout)
+	} else {
+		reportRun, err = a.DS.GetReportRun(r.Context(), reportRunID)
+	}
+	if err == nil && !scope.allows(reportRun.OrganizationId) {
+		err = interfaces.ErrNotFound
+	}
+	if errors.Is(err, interfaces.ErrNotFound) {
+		apierror.Write(w, r, apierror.New(apierror.NotFound, "Report run not found"))
+		return
+	}
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	detail := &ReportRunDetail{ReportRun: reportRun}
+	if reportRun.CustomerId != "" {
+		detail.Customer, err = a.DS.GetCustomer(r.Context(), reportRun.CustomerId)
+		if err != nil && !errors.Is(err, interfaces.ErrNotFound) {
+			apierror.Write(w, r, err)
+			return
+		}
+	}
+	if reportRun.PropertyAddressId != "" {
+		detail.PropertyAddress, err = a.DS.GetPropertyAddress(r.Context(), reportRun.PropertyAddressId)
+		if err != nil && !errors.Is(err, interfaces.ErrNotFound) {
+			apierror.Write(w, r, err)
+			return
+		}
+	}
+
+	body, err := json.Marshal(detail)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	sum := sha256.Sum256(body)
+	etag := fmt.Sprintf(`"%x"`, sum[:16])
+
+	w.Header().Set("ETag", etag)
+	lastModified := reportRun.UpdatedAt
+	if lastModified == nil {
+		lastModified = reportRun.CreatedAt
+	}
+	if lastModified != nil {
+		w.Header().Set("Last-Modified", lastModified.AsTime().UTC().Format(http.TimeFormat))
+	}
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// waitForReportRunStatus polls the datastore until the report run reaches the
+// wanted status or a terminal status, or until the timeout expires. The wait
+// is cut short so that a response can still be written before the request's
+// own deadline.
+func (a *API) waitForReportRunStatus(ctx context.Context, reportRunID string, want nhd_report.ReportRun_Status, timeout time.Duration) (*nhd_report.ReportRun, error) {
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Add(-reportRunWaitMargin).Before(deadline) {
+		deadline = d.Add(-reportRunWaitMargin)
+	}
+
+	for {
+		reportRun, err := a.DS.GetReportRun(ctx, reportRunID)
+		if err != nil {
+			return nil, err
+		}
+		if reportRun.Status == want || isTerminalStatus(reportRun.Status) {
+			return reportRun, nil
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return reportRun, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(min(reportRunPollInterval, remaining)):
+		}
+	}
+}
+
+func isTerminalStatus(s nhd_report.ReportRun_Status) bool {
+	return s == nhd_report.ReportRun_COMPLETED || s == nhd_report.ReportRun_FAILED || s == nhd_report.ReportRun_CANCELLED
 }
 
+// GetReportRuns lists report runs. It supports filtering by customer_id,
+// created_by_user_id, status, payment_status and a created_after /
+// created_before date range, ordering with order_by, and cursor pagination
+// with page_size and page_token. Only the caller's organization is listed
+// unless an admin selects others (see requestScope).
 func (a *API) GetReportRuns(w http.ResponseWriter, r *http.Request) {
-	paymentStatus := r.URL.Query().Get("payment_status")
-	
-	reportRuns, err := a.DS.GetReportRuns(r.Context(), paymentStatus)
+	scope, ok := requestScope(w, r)
+	if !ok {
+		return
+	}
+	query, err := parseReportRunQuery(r.URL.Query())
+	if err != nil {
+		apierror.Write(w, r, apierror.Wrap(apierror.ValidationFailed, err, "%v", err))
+		return
+	}
+	query.OrganizationID = scope.OrganizationID
+
+	page, err := a.DS.GetReportRuns(r.Context(), query)
+	if errors.Is(err, interfaces.ErrInvalidPageToken) {
+		apierror.Write(w, r, apierror.Wrap(apierror.ValidationFailed, err, "%v", err))
+		return
+	}
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierror.Write(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(reportRuns)
+	json.NewEncoder(w).Encode(page)
+}
+
+func parseReportRunQuery(values url.Values) (interfaces.ReportRunQuery, error) {
+	query := interfaces.ReportRunQuery{
+		CustomerID:      values.Get("customer_id"),
+		CreatedByUserID: values.Get("created_by_user_id"),
+		OrderBy:         values.Get("order_by"),
+		PageToken:       values.Get("page_token"),
+	}
+	if s := values.Get("status"); s != "" {
+		v, ok := nhd_report.ReportRun_Status_value[s]
+		if !ok {
+			return query, fmt.Errorf("invalid status %q", s)
+		}
+		query.Status = nhd_report.ReportRun_Status(v)
+	}
+	if s := values.Get("payment_status"); s != "" {
+		v, ok := nhd_report.ReportRun_Payment_PaymentStatus_value[s]
+		if !ok {
+			return query, fmt.Errorf("invalid payment_status %q", s)
+		}
+		query.PaymentStatus = nhd_report.ReportRun_Payment_PaymentStatus(v)
+	}
+	var err error
+	if query.CreatedAfter, err = parseTimeParam(values, "created_after"); err != nil {
+		return query, err
+	}
+	if query.CreatedBefore, err = parseTimeParam(values, "created_before"); err != nil {
+		return query, err
+	}
+	if s := values.Get("page_size"); s != "" {
+		if query.PageSize, err = strconv.Atoi(s); err != nil || query.PageSize < 0 {
+			return query, fmt.Errorf("invalid page_size %q", s)
+		}
+	}
+	if err := query.Normalize(); err != nil {
+		return query, err
+	}
+	return query, nil
+}
+
+// parseTimeParam parses an RFC 3339 timestamp or a YYYY-MM-DD date (taken as
+// midnight UTC) from the named query parameter. A missing parameter yields
+// the zero time.
+func parseTimeParam(values url.Values, name string) (time.Time, error) {
+	s := values.Get(name)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid %s %q: use RFC 3339 or YYYY-MM-DD", name, s)
+}
+
+// ResendReportEmailRequest defines the shape of the (optional) request body
+// for resending a report email.
+type ResendReportEmailRequest struct {
+	// EmailTemplateReference overrides the template used for this delivery.
+	// Defaults to the template of the most recent delivery, or
+	// mailer.DefaultTemplate if the report has never been emailed.
+	EmailTemplateReference string `json:"email_template_reference"`
 }
 
+// ResendReportEmail emails a completed report to its customer and records the
+// outcome in the report run's email_deliveries.
 func (a *API) ResendReportEmail(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusNotImplemented)
+	reportRunID := r.PathValue("id")
+
+	var req ResendReportEmailRequest
+	if err := validation.Decode(r.Body, &req); err != nil && !errors.Is(err, io.EOF) {
+		apierror.Write(w, r, err)
+		return
+	}
+	scope, ok := requestScope(w, r)
+	if !ok {
+		return
+	}
+
+	reportRun, err := a.DS.GetReportRun(r.Context(), reportRunID)
+	if err == nil && !scope.allows(reportRun.OrganizationId) {
+		err = interfaces.ErrNotFound
+	}
+	if errors.Is(err, interfaces.ErrNotFound) {
+		apierror.Write(w, r, apierror.New(apierror.NotFound, "Report run not found"))
+		return
+	}
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	if reportRun.Status != nhd_report.ReportRun_COMPLETED {
+		apierror.Write(w, r, apierror.New(apierror.Conflict, "Only COMPLETED report runs can be emailed"))
+		return
+	}
+
+	customer, err := a.DS.GetCustomer(r.Context(), reportRun.CustomerId)
+	if err != nil && !errors.Is(err, interfaces.ErrNotFound) {
+		apierror.Write(w, r, err)
+		return
+	}
+	if customer.GetEmail() == "" {
+		apierror.Write(w, r, &apierror.Error{Code: apierror.ValidationFailed, Message: "Customer has no email address", Status: http.StatusUnprocessableEntity})
+		return
+	}
+
+	data := &mailer.TemplateData{
+		ReportRunID:  reportRun.ReportRunId,
+		CustomerName: customer.FullName,
+	}
+	if reportRun.PropertyAddressId != "" {
+		address, err := a.DS.GetPropertyAddress(r.Context(), reportRun.PropertyAddressId)
+		if err != nil && !errors.Is(err, interfaces.ErrNotFound) {
+			apierror.Write(w, r, err)
+			return
+		}
+		data.PropertyAddress = postal.Format(address.GetAddressDetails())
+	}
+	if a.ReportBaseURL != "" && reportRun.FinalPdfStoragePath != "" {
+		data.ReportURL = strings.TrimSuffix(a.ReportBaseURL, "/") + "/" + strings.TrimPrefix(reportRun.FinalPdfStoragePath, "/")
+	}
+
+	reference := req.EmailTemplateReference
+	if reference == "" && len(reportRun.EmailDeliveries) > 0 {
+		reference = reportRun.EmailDeliveries[len(reportRun.EmailDeliveries)-1].EmailTemplateReference
+	}
+	if reference == "" {
+		reference = mailer.DefaultTemplate
+	}
+	subject, body, err := mailer.Render(reference, data)
+	if err != nil {
+		apierror.Write(w, r, apierror.Wrap(apierror.ValidationFailed, err, "%v", err))
+		return
+	}
+
+	delivery := &nhd_report.ReportRun_EmailDelivery{
+		Status:                 nhd_report.ReportRun_EmailDelivery_SENT,
+		EmailTemplateReference: reference,
+		Recipient:              customer.Email,
+	}
+	sendErr := a.Mailer.Send(r.Context(), &interfaces.Email{
+		To:       []string{customer.Email},
+		Subject:  subject,
+		TextBody: body,
+	})
+	delivery.SentAt = timestamppb.Now()
+	status := http.StatusOK
+	switch {
+	case errors.Is(sendErr, interfaces.ErrDeliveryUnknown):
+		logging.FromContext(r.Context()).Warn("Report email may not have been sent", "report_run_id", reportRunID, "error", sendErr)
+		delivery.Status = nhd_report.ReportRun_EmailDelivery_UNKNOWN
+		delivery.FailureReason = sendErr.Error()
+		status = http.StatusGatewayTimeout
+	case sendErr != nil:
+		logging.FromContext(r.Context()).Error("Failed to send report email", "report_run_id", reportRunID, "error", sendErr)
+		delivery.Status = nhd_report.ReportRun_EmailDelivery_FAILED
+		delivery.FailureReason = sendErr.Error()
+		status = http.StatusBadGateway
+	}
+
+	// The attempt is recorded even if the send used up the request's time.
+	if err := a.DS.AppendEmailDelivery(context.WithoutCancel(r.Context()), reportRunID, delivery); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(delivery)
+}
+
+// ReportRunActionRequest is the optional request body for retrying or
+// cancelling a report run.
+type ReportRunActionRequest struct {
+	// Reason is recorded in the run's status_history.
+	Reason string `json:"reason,omitempty"`
+}
+
+// RetryReportRun re-queues a FAILED report run. The run returns to PENDING,
+// its attempt is incremented and it is published to nhd-report-requests
+// again; it keeps its ID and its cost and payment history. The updated run
+// is returned with 202 Accepted.
+func (a *API) RetryReportRun(w http.ResponseWriter, r *http.Request) {
+	reportRun, req, userID, ok := a.reportRunForAction(w, r)
+	if !ok {
+		return
+	}
+	if reportRun.Status != nhd_report.ReportRun_FAILED {
+		apierror.Write(w, r, apierror.New(apierror.Conflict, "Only FAILED report runs can be retried"))
+		return
+	}
+	reason := req.Reason
+	if reason == "" {
+		reason = "Retry requested"
+	}
+	previousFailureReason := reportRun.FailureReason
+	if err := a.DS.UpdateReportRunStatus(r.Context(), reportRun.ReportRunId, nhd_report.ReportRun_PENDING, userID, reason); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	// The run must be PENDING before it is published, or the worker would
+	// drop the message. If publishing fails, put the run back to FAILED so
+	// that the client can retry it again.
+	ctx := logging.With(r.Context(), "report_run_id", reportRun.ReportRunId)
+	if err := a.queueReportRun(ctx, reportRun.ReportRunId); err != nil {
+		if rollbackErr := a.DS.UpdateReportRunStatus(context.WithoutCancel(ctx), reportRun.ReportRunId, nhd_report.ReportRun_FAILED, userID, previousFailureReason); rollbackErr != nil {
+			logging.FromContext(ctx).Error("Failed to return unqueued report run to FAILED", "error", rollbackErr)
+		}
+		apierror.Write(w, r.WithContext(ctx), err)
+		return
+	}
+	logging.FromContext(ctx).Info("Report run retried", "previous_failure_reason", previousFailureReason)
+	a.writeReportRun(w, r, reportRun.ReportRunId, http.StatusAccepted)
+}
+
+// CancelReportRun cancels a PENDING report run, so that it is never
+// processed. If the run has not been paid for, its cost is voided: a zero
+// cost is appended to its cost_history and its payment status becomes VOID.
+func (a *API) CancelReportRun(w http.ResponseWriter, r *http.Request) {
+	reportRun, req, userID, ok := a.reportRunForAction(w, r)
+	if !ok {
+		return
+	}
+	if reportRun.Status != nhd_report.ReportRun_PENDING {
+		apierror.Write(w, r, apierror.New(apierror.Conflict, "Only PENDING report runs can be cancelled"))
+		return
+	}
+	reason := req.Reason
+	if reason == "" {
+		reason = "Cancelled"
+	}
+	// The worker may have started the run since it was read; the datastore
+	// rejects the change if so.
+	if err := a.DS.UpdateReportRunStatus(r.Context(), reportRun.ReportRunId, nhd_report.ReportRun_CANCELLED, userID, reason); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	logging.FromContext(r.Context()).Info("Report run cancelled", "report_run_id", reportRun.ReportRunId)
+	a.writeReportRun(w, r, reportRun.ReportRunId, http.StatusOK)
+}
+
+// reportRunForAction decodes the optional ReportRunActionRequest body and
+// reads the report run named in the path, checking that it is in the
+// caller's scope. It writes an error response and returns false on failure.
+func (a *API) reportRunForAction(w http.ResponseWriter, r *http.Request) (*nhd_report.ReportRun, ReportRunActionRequest, string, bool) {
+	var req ReportRunActionRequest
+	if err := validation.Decode(r.Body, &req); err != nil && !errors.Is(err, io.EOF) {
+		apierror.Write(w, r, err)
+		return nil, req, "", false
+	}
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		apierror.Write(w, r, apierror.New(apierror.Unauthenticated, "User ID not found in context"))
+		return nil, req, "", false
+	}
+	scope, ok := requestScope(w, r)
+	if !ok {
+		return nil, req, "", false
+	}
+	reportRun, err := a.DS.GetReportRun(r.Context(), r.PathValue("id"))
+	if err == nil && !scope.allows(reportRun.OrganizationId) {
+		err = interfaces.ErrNotFound
+	}
+	if errors.Is(err, interfaces.ErrNotFound) {
+		apierror.Write(w, r, apierror.New(apierror.NotFound, "Report run not found"))
+		return nil, req, "", false
+	}
+	if err != nil {
+		apierror.Write(w, r, err)
+		return nil, req, "", false
+	}
+	return reportRun, req, userID, true
+}
+
+// writeReportRun writes the current state of a report run with the given
+// status code.
+func (a *API) writeReportRun(w http.ResponseWriter, r *http.Request, reportRunID string, statusCode int) {
+	reportRun, err := a.DS.GetReportRun(r.Context(), reportRunID)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(reportRun)
 }
 
 func (a *API) UpdateReportCost(w http.ResponseWriter, r *http.Request) {
 	reportRunID := r.PathValue("id") // Requires Go 1.22+ and http.ServeMux
 
 	var newCost nhd_report.ReportRun_ReportCost
-	if err := json.NewDecoder(r.Body).Decode(&newCost); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if err := validation.Decode(r.Body, &newCost); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	var v validation.Validator
+	v.Money("amount", newCost.Amount)
+	v.NonNegative("amount.minor_units", newCost.Amount.GetMinorUnits())
+	if err := v.Err(); err != nil {
+		apierror.Write(w, r, err)
 		return
 	}
 	newCost.SetAt = timestamppb.Now()
+	if userID, ok := r.Context().Value(middleware.UserIDKey).(string); ok {
+		newCost.SetByUserId = userID
+	}
 
 	if err := a.DS.UpdateReportCost(r.Context(), reportRunID, &newCost); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierror.Write(w, r, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 }
 
+// LedgerEntryRequest is the request body for recording a payment or refund
+// against a report run.
+type LedgerEntryRequest struct {
+	// Amount must be positive and in the currency the run is billed in.
+	Amount        *nhd_report.Money `json:"amount"`
+	PaymentMethod string            `json:"payment_method,omitempty"`
+	TransactionID string            `json:"transaction_id,omitempty"`
+	// Reason is required for refunds.
+	Reason string `json:"reason,omitempty"`
+}
+
+// RecordReportPayment appends a payment to a report run's ledger and
+// responds with the run's updated payment_details. Payments of more than is
+// due, and payments that repeat the transaction_id of an earlier one, are
+// rejected with 409.
 func (a *API) RecordReportPayment(w http.ResponseWriter, r *http.Request) {
+	payment, ok := a.appendLedgerEntry(w, r, nhd_report.ReportRun_LedgerEntry_PAYMENT)
+	if !ok {
+		return
+	}
+	metrics.PaymentsRecorded.Inc(payment.Status.String())
+	logging.FromContext(r.Context()).Info("Payment recorded", "report_run_id", r.PathValue("id"), "payment_status", payment.Status.String())
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(payment)
+}
+
+// RefundReportRun appends a refund of part or all of what has been paid to
+// a report run's ledger and responds with the run's updated
+// payment_details. Refunds of more than has been paid and not already
+// refunded are rejected with 409.
+func (a *API) RefundReportRun(w http.ResponseWriter, r *http.Request) {
+	payment, ok := a.appendLedgerEntry(w, r, nhd_report.ReportRun_LedgerEntry_REFUND)
+	if !ok {
+		return
+	}
+	metrics.RefundsRecorded.Inc(payment.Status.String())
+	logging.FromContext(r.Context()).Info("Refund recorded", "report_run_id", r.PathValue("id"), "payment_status", payment.Status.String())
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(payment)
+}
+
+// appendLedgerEntry decodes a LedgerEntryRequest, appends it to the ledger
+// of the report run named in the path as an entry of the given type and
+// returns the run's updated payment_details. On failure it writes the error
+// and returns false.
+func (a *API) appendLedgerEntry(w http.ResponseWriter, r *http.Request, entryType nhd_report.ReportRun_LedgerEntry_Type) (*nhd_report.ReportRun_Payment, bool) {
 	reportRunID := r.PathValue("id")
 
-	var payment nhd_report.ReportRun_Payment
-	if err := json.NewDecoder(r.Body).Decode(&payment); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	var req LedgerEntryRequest
+	if err := validation.Decode(r.Body, &req); err != nil {
+		apierror.Write(w, r, err)
+		return nil, false
+	}
+	var v validation.Validator
+	v.Money("amount", req.Amount)
+	v.Check(req.Amount.GetMinorUnits() > 0, "amount.minor_units", "must be positive")
+	if entryType == nhd_report.ReportRun_LedgerEntry_REFUND {
+		v.Required("reason", req.Reason)
+	}
+	if err := v.Err(); err != nil {
+		apierror.Write(w, r, err)
+		return nil, false
+	}
+	userID, _ := r.Context().Value(middleware.UserIDKey).(string)
+
+	entry := &nhd_report.ReportRun_LedgerEntry{
+		Type:            entryType,
+		Amount:          req.Amount,
+		CreatedAt:       timestamppb.Now(),
+		CreatedByUserId: userID,
+		PaymentMethod:   req.PaymentMethod,
+		TransactionId:   req.TransactionID,
+		Reason:          req.Reason,
+	}
+	if err := a.DS.AppendLedgerEntry(r.Context(), reportRunID, entry); err != nil {
+		apierror.Write(w, r, err)
+		return nil, false
+	}
+	reportRun, err := a.DS.GetReportRun(r.Context(), reportRunID)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return nil, false
+	}
+	return reportRun.GetPaymentDetails(), true
+}
+
+// Pricing
+func (a *API) GetPriceRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := a.DS.GetPriceRules(r.Context())
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	if rules == nil {
+		rules = []*nhd_report.PriceRule{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+}
+
+// CreatePriceRule adds a price rule. Existing rules are never modified; to
+// change a price, create a new rule with a later effective_from.
+func (a *API) CreatePriceRule(w http.ResponseWriter, r *http.Request) {
+	var rule nhd_report.PriceRule
+	if err := validation.Decode(r.Body, &rule); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	if err := pricing.Validate(&rule); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		apierror.Write(w, r, apierror.New(apierror.Unauthenticated, "User ID not found in context"))
 		return
 	}
-	payment.PaidAt = timestamppb.Now()
+	rule.CreatedByUserId = userID
+	rule.CreatedAt = timestamppb.Now()
+	if rule.EffectiveFrom == nil {
+		rule.EffectiveFrom = rule.CreatedAt
+	}
 
-	if err := a.DS.RecordReportPayment(r.Context(), reportRunID, &payment); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	docRef, _, err := a.DS.CreatePriceRule(r.Context(), &rule)
+	if err != nil {
+		apierror.Write(w, r, err)
 		return
 	}
+	if a.Pricing != nil {
+		a.Pricing.Invalidate()
+	}
 
-	w.WriteHeader(http.StatusOK)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{"price_rule_id": docRef.ID})
+}
+
+// QuotePrice previews the cost a new report run would be assigned for the
+// customer_id query parameter (optional) at the time given by "at" (default
+// now).
+func (a *API) QuotePrice(w http.ResponseWriter, r *http.Request) {
+	at, err := parseTimeParam(r.URL.Query(), "at")
+	if err != nil {
+		apierror.Write(w, r, apierror.Wrap(apierror.ValidationFailed, err, "%v", err))
+		return
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	var customer *nhd_report.Customer
+	if customerID := r.URL.Query().Get("customer_id"); customerID != "" {
+		customer, err = a.DS.GetCustomer(r.Context(), customerID)
+		if errors.Is(err, interfaces.ErrNotFound) {
+			var v validation.Validator
+			v.Add("customer_id", "does not exist")
+			apierror.Write(w, r, v.Err())
+			return
+		}
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+	}
+
+	cost, err := a.Pricing.Quote(r.Context(), customer, at)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cost)
+}
+
+// Exchange rates
+func (a *API) GetExchangeRates(w http.ResponseWriter, r *http.Request) {
+	rates, err := a.DS.GetExchangeRates(r.Context())
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	if rates == nil {
+		rates = []*nhd_report.ExchangeRate{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rates)
+}
+
+// CreateExchangeRate adds an exchange rate. Existing rates are never
+// modified; to change a rate, create a new one with a later effective_from.
+func (a *API) CreateExchangeRate(w http.ResponseWriter, r *http.Request) {
+	var rate nhd_report.ExchangeRate
+	if err := validation.Decode(r.Body, &rate); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+	var v validation.Validator
+	v.Currency("base_currency", rate.BaseCurrency)
+	v.Currency("quote_currency", rate.QuoteCurrency)
+	v.Check(rate.BaseCurrency == "" || rate.BaseCurrency != rate.QuoteCurrency, "quote_currency", "must differ from base_currency")
+	if _, err := money.ParseRate(rate.Rate); err != nil {
+		v.Add("rate", "must be a positive decimal number, e.g. 0.7312")
+	}
+	if err := v.Err(); err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	userID, ok := r.Context().Value(middleware.UserIDKey).(string)
+	if !ok {
+		apierror.Write(w, r, apierror.New(apierror.Unauthenticated, "User ID not found in context"))
+		return
+	}
+	rate.ExchangeRateId = ""
+	rate.CreatedByUserId = userID
+	rate.CreatedAt = timestamppb.Now()
+	if rate.EffectiveFrom == nil {
+		rate.EffectiveFrom = rate.CreatedAt
+	}
+
+	docRef, _, err := a.DS.CreateExchangeRate(r.Context(), &rate)
+	if err != nil {
+		apierror.Write(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{"exchange_rate_id": docRef.ID})
 }
 
 // Financials
+
+// GetFinancialsSummary returns revenue from reports paid between the "from"
+// and "to" query parameters, optionally grouped by day, month or customer.
+// Both bounds are optional. A date-only "to" includes the whole of that day.
+// Totals are given per currency and converted to the "currency" query
+// parameter (default USD); a transaction without an exchange rate in effect
+// is reported as 422.
+// Only the caller's organization's revenue is included unless an admin
+// selects others (see requestScope).
 func (a *API) GetFinancialsSummary(w http.ResponseWriter, r *http.Request) {
-	summary, err := a.DS.GetPaidReportsSummary(r.Context())
+	scope, ok := requestScope(w, r)
+	if !ok {
+		return
+	}
+	query, err := parseFinancialsQuery(r.URL.Query())
+	if err != nil {
+		apierror.Write(w, r, apierror.Wrap(apierror.ValidationFailed, err, "%v", err))
+		return
+	}
+	query.OrganizationID = scope.OrganizationID
+
+	summary, err := a.DS.GetPaidReportsSummary(r.Context(), query)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierror.Write(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(summary)
-}
\ No newline at end of file
+}
+
+func parseFinancialsQuery(values url.Values) (interfaces.FinancialsQuery, error) {
+	query := interfaces.FinancialsQuery{GroupBy: values.Get("group_by"), Currency: values.Get("currency")}
+	if query.Currency == "" {
+		query.Currency = pricing.DefaultCurrency
+	}
+	if !validation.IsCurrency(query.Currency) {
+		return query, fmt.Errorf("currency must be an ISO 4217 currency code, e.g. USD")
+	}
+	var err error
+	if query.PaidFrom, err = parseTimeParam(values, "from"); err != nil {
+		return query, err
+	}
+	if query.PaidTo, err = parseTimeParam(values, "to"); err != nil {
+		return query, err
+	}
+	if len(values.Get("to")) == len(time.DateOnly) {
+		query.PaidTo = query.PaidTo.AddDate(0, 0, 1)
+	}
+	return query, query.Validate()
+}
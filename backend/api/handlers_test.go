@@ -3,33 +3,56 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"cloud.google.com/go/firestore"
+	"github.com/seans3/nhd/backend/apierror"
+	"github.com/seans3/nhd/backend/interfaces"
+	"github.com/seans3/nhd/backend/mailer"
+	"github.com/seans3/nhd/backend/memstore"
+	"github.com/seans3/nhd/backend/metrics"
 	"github.com/seans3/nhd/backend/middleware"
 	"github.com/seans3/nhd/backend/mocks"
+	"github.com/seans3/nhd/backend/money"
+	"github.com/seans3/nhd/backend/pricing"
 	"github.com/seans3/nhd/backend/proto/gen/go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// testUser is the caller in handler tests: a user in organization "org1".
+var testUser = &nhd_report.User{UserId: "test-user-id", OrganizationId: "org1"}
+
+// withUser returns req as VerifyAuthToken passes it on for user.
+func withUser(req *http.Request, user *nhd_report.User) *http.Request {
+	ctx := context.WithValue(req.Context(), middleware.UserIDKey, user.UserId)
+	ctx = context.WithValue(ctx, middleware.UserKey, user)
+	return req.WithContext(ctx)
+}
+
 func TestAPI_CreateCustomer(t *testing.T) {
 	mockDS := new(mocks.MockDatastoreClient)
 	apiHandler := &API{DS: mockDS}
 
-	customerJSON := `{"full_name":"Test User","email":"test@example.com"}`
+	// An organization_id in the body is ignored in favor of the caller's.
+	customerJSON := `{"full_name":"Test User","email":"test@example.com","organization_id":"org2"}`
 	req, err := http.NewRequest("POST", "/customers", strings.NewReader(customerJSON))
 	assert.NoError(t, err)
 
-	// Add the user ID to the request context to simulate an authenticated user
-	ctx := context.WithValue(req.Context(), middleware.UserIDKey, "test-user-id")
-	req = req.WithContext(ctx)
+	// Add the user to the request context to simulate an authenticated user
+	req = withUser(req, testUser)
 
 	mockDocRef := &firestore.DocumentRef{ID: "test-id"}
-	mockDS.On("CreateCustomer", mock.Anything, mock.AnythingOfType("*nhd_report.Customer")).Return(mockDocRef, (*firestore.WriteResult)(nil), nil)
+	mockDS.On("CreateCustomer", mock.Anything, mock.MatchedBy(func(c *nhd_report.Customer) bool {
+		return c.CreatedByUserId == "test-user-id" && c.OrganizationId == "org1"
+	})).Return(mockDocRef, (*firestore.WriteResult)(nil), nil)
 
 	rr := httptest.NewRecorder()
 	handler := http.HandlerFunc(apiHandler.CreateCustomer)
@@ -49,28 +72,89 @@ func TestAPI_GetReportRuns(t *testing.T) {
 		{ReportRunId: "run2"},
 	}
 
-	mockDS.On("GetReportRuns", mock.Anything, "").Return(expectedReports, nil)
+	expectedQuery := interfaces.ReportRunQuery{
+		OrganizationID: "org1",
+		CustomerID:     "cust1",
+		PaymentStatus:  nhd_report.ReportRun_Payment_OUTSTANDING,
+		CreatedAfter:   time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		OrderBy:        "-created_at",
+		PageSize:       2,
+	}
+	mockDS.On("GetReportRuns", mock.Anything, expectedQuery).Return(&interfaces.ReportRunPage{ReportRuns: expectedReports, NextPageToken: "next"}, nil)
 
-	req, err := http.NewRequest("GET", "/report-runs", nil)
+	req, err := http.NewRequest("GET", "/report-runs?customer_id=cust1&payment_status=OUTSTANDING&created_after=2025-01-01&page_size=2", nil)
 	assert.NoError(t, err)
+	req = withUser(req, testUser)
 
 	rr := httptest.NewRecorder()
 	handler := http.HandlerFunc(apiHandler.GetReportRuns)
 	handler.ServeHTTP(rr, req)
 
 	assert.Equal(t, http.StatusOK, rr.Code)
-	var actualReports []*nhd_report.ReportRun
-	err = json.Unmarshal(rr.Body.Bytes(), &actualReports)
+	var actualPage interfaces.ReportRunPage
+	err = json.Unmarshal(rr.Body.Bytes(), &actualPage)
 	assert.NoError(t, err)
-	assert.Equal(t, len(expectedReports), len(actualReports))
+	assert.Equal(t, len(expectedReports), len(actualPage.ReportRuns))
+	assert.Equal(t, "next", actualPage.NextPageToken)
 	mockDS.AssertExpectations(t)
 }
 
+func TestAPI_GetReportRuns_InvalidParameters(t *testing.T) {
+	apiHandler := &API{DS: new(mocks.MockDatastoreClient)}
+
+	for _, query := range []string{"status=DONE", "order_by=customer_id", "created_before=yesterday", "page_size=-1", "page_token=garbage", "order_by=updated_at&created_after=2025-01-01"} {
+		req, err := http.NewRequest("GET", "/report-runs?"+query, nil)
+		assert.NoError(t, err)
+		req = withUser(req, testUser)
+
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(apiHandler.GetReportRuns).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+	}
+}
+
+func TestAPI_GetFinancialsSummary(t *testing.T) {
+	mockDS := new(mocks.MockDatastoreClient)
+	apiHandler := &API{DS: mockDS}
+
+	// A date-only "to" covers the whole day.
+	want := interfaces.FinancialsQuery{
+		OrganizationID: "org1",
+		PaidFrom:       time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC),
+		PaidTo:         time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC),
+		GroupBy:        interfaces.FinancialsGroupByMonth,
+		Currency:       "CAD",
+	}
+	mockDS.On("GetPaidReportsSummary", mock.Anything, want).Return(&interfaces.FinancialsSummary{Currency: "CAD", TotalRevenue: money.New("CAD", 1000)}, nil)
+
+	req, err := http.NewRequest("GET", "/financials/summary?from=2025-04-01&to=2025-06-30&group_by=month&currency=CAD", nil)
+	assert.NoError(t, err)
+	req = withUser(req, testUser)
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(apiHandler.GetFinancialsSummary).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockDS.AssertExpectations(t)
+
+	for _, query := range []string{"group_by=week", "from=last-month", "from=2025-06-01&to=2025-05-01", "currency=bucks"} {
+		req, err := http.NewRequest("GET", "/financials/summary?"+query, nil)
+		assert.NoError(t, err)
+		req = withUser(req, testUser)
+
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(apiHandler.GetFinancialsSummary).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+	}
+}
+
 func TestAPI_UpdateReportCost(t *testing.T) {
 	mockDS := new(mocks.MockDatastoreClient)
 	apiHandler := &API{DS: mockDS}
 
-	costJSON := `{"amount":99.99,"currency":"USD"}`
+	costJSON := `{"amount":{"currency":"USD","minor_units":9999}}`
 	req, err := http.NewRequest("PUT", "/report-runs/run123/cost", strings.NewReader(costJSON))
 	assert.NoError(t, err)
 	req.SetPathValue("id", "run123") // Set path value for Go 1.22+ mux
@@ -89,19 +173,566 @@ func TestAPI_RecordReportPayment(t *testing.T) {
 	mockDS := new(mocks.MockDatastoreClient)
 	apiHandler := &API{DS: mockDS}
 
-	paymentJSON := `{"amount_paid":99.99,"currency":"USD"}`
+	paymentJSON := `{"amount":{"currency":"USD","minor_units":9999},"payment_method":"Stripe","transaction_id":"ch_1"}`
 	req, err := http.NewRequest("POST", "/report-runs/run123/payment", strings.NewReader(paymentJSON))
 	assert.NoError(t, err)
 	req.SetPathValue("id", "run123")
 
-	mockDS.On("RecordReportPayment", mock.Anything, "run123", mock.AnythingOfType("*nhd_report.ReportRun_Payment")).Return(nil)
+	mockDS.On("AppendLedgerEntry", mock.Anything, "run123", mock.MatchedBy(func(entry *nhd_report.ReportRun_LedgerEntry) bool {
+		return entry.Type == nhd_report.ReportRun_LedgerEntry_PAYMENT && entry.Amount.GetMinorUnits() == 9999 && entry.TransactionId == "ch_1"
+	})).Return(nil)
+	mockDS.On("GetReportRun", mock.Anything, "run123").Return(&nhd_report.ReportRun{
+		PaymentDetails: &nhd_report.ReportRun_Payment{Status: nhd_report.ReportRun_Payment_PAID, AmountPaid: money.New("USD", 9999)},
+	}, nil)
 
+	recorded := metrics.PaymentsRecorded.Value("PAID")
 	rr := httptest.NewRecorder()
 	handler := http.HandlerFunc(apiHandler.RecordReportPayment)
 	handler.ServeHTTP(rr, req)
 
 	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, recorded+1, metrics.PaymentsRecorded.Value("PAID"))
+	mockDS.AssertExpectations(t)
+}
+
+func TestAPI_RecordReportPayment_RejectsOverpaymentsAndRepeats(t *testing.T) {
+	memDS := memstore.NewClient()
+	apiHandler := &API{DS: memDS}
+	docRef, _, err := memDS.CreateReportRun(context.Background(), &nhd_report.ReportRun{OrganizationId: "org1"})
+	assert.NoError(t, err)
+	assert.NoError(t, memDS.AppendLedgerEntry(context.Background(), docRef.ID, &nhd_report.ReportRun_LedgerEntry{
+		Type: nhd_report.ReportRun_LedgerEntry_CHARGE, Amount: money.New("USD", 4000),
+	}))
+
+	pay := func(body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", "/report-runs/"+docRef.ID+"/payment", strings.NewReader(body))
+		assert.NoError(t, err)
+		req.SetPathValue("id", docRef.ID)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(apiHandler.RecordReportPayment).ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := pay(`{"amount":{"currency":"USD","minor_units":4001},"transaction_id":"ch_1"}`)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Contains(t, rr.Body.String(), "cannot be paid 40.01 USD; 40.00 USD is due")
+
+	assert.Equal(t, http.StatusOK, pay(`{"amount":{"currency":"USD","minor_units":1000},"transaction_id":"ch_1"}`).Code)
+	// A retry of the same payment is not recorded twice.
+	rr = pay(`{"amount":{"currency":"USD","minor_units":1000},"transaction_id":"ch_1"}`)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Contains(t, rr.Body.String(), "already has a PAYMENT with transaction_id ch_1")
+
+	rr = pay(`{"amount":{"currency":"USD","minor_units":3000},"transaction_id":"ch_2"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var payment nhd_report.ReportRun_Payment
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &payment))
+	assert.Equal(t, nhd_report.ReportRun_Payment_PAID, payment.Status)
+	assert.Zero(t, payment.BalanceDue.GetMinorUnits())
+}
+
+func TestAPI_RefundReportRun(t *testing.T) {
+	memDS := memstore.NewClient()
+	apiHandler := &API{DS: memDS}
+	docRef, _, err := memDS.CreateReportRun(context.Background(), &nhd_report.ReportRun{OrganizationId: "org1"})
+	assert.NoError(t, err)
+	for _, entryType := range []nhd_report.ReportRun_LedgerEntry_Type{nhd_report.ReportRun_LedgerEntry_CHARGE, nhd_report.ReportRun_LedgerEntry_PAYMENT} {
+		assert.NoError(t, memDS.AppendLedgerEntry(context.Background(), docRef.ID, &nhd_report.ReportRun_LedgerEntry{
+			Type: entryType, Amount: money.New("USD", 4000),
+		}))
+	}
+
+	refund := func(body string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", "/report-runs/"+docRef.ID+"/refund", strings.NewReader(body))
+		assert.NoError(t, err)
+		req.SetPathValue("id", docRef.ID)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(apiHandler.RefundReportRun).ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Refunds need a positive amount and a reason, and must be in the
+	// run's currency.
+	assert.Equal(t, http.StatusBadRequest, refund(`{"amount":{"currency":"USD"}}`).Code)
+	assert.Equal(t, http.StatusConflict, refund(`{"amount":{"currency":"EUR","minor_units":1000},"reason":"Duplicate"}`).Code)
+
+	// Partial refunds may not add up to more than was paid.
+	rr := refund(`{"amount":{"currency":"USD","minor_units":1500},"reason":"Duplicate"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	rr = refund(`{"amount":{"currency":"USD","minor_units":2501},"reason":"Duplicate"}`)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Contains(t, rr.Body.String(), "cannot refund 25.01 USD; 25.00 USD has been paid and not refunded")
+
+	rr = refund(`{"amount":{"currency":"USD","minor_units":2500},"reason":"Duplicate"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var payment nhd_report.ReportRun_Payment
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &payment))
+	assert.Equal(t, nhd_report.ReportRun_Payment_REFUNDED, payment.Status)
+	assert.Equal(t, int64(4000), payment.AmountRefunded.GetMinorUnits())
+	assert.Zero(t, payment.AmountPaid.GetMinorUnits())
+}
+
+func TestAPI_CreateReportRun_ReportsAllFieldErrors(t *testing.T) {
+	memDS := memstore.NewClient()
+	apiHandler := &API{DS: memDS}
+	memDS.CreateCustomer(context.Background(), &nhd_report.Customer{CustomerId: "cust2", OrganizationId: "org2"})
+
+	// The customer belongs to another organization and the inline address
+	// is incomplete; both are reported, and nothing is created.
+	body := `{"customer_id":"cust2","property_address":{"address_details":{"street_address":"1 Main St"}}}`
+	req, err := http.NewRequest("POST", "/report-runs", strings.NewReader(body))
+	assert.NoError(t, err)
+	req = withUser(req, testUser)
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(apiHandler.CreateReportRun).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	var problem apierror.Problem
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+	assert.Equal(t, apierror.ValidationFailed, problem.Code)
+	assert.Equal(t, []apierror.FieldError{
+		{Field: "customer_id", Message: "does not exist"},
+		{Field: "property_address.address_details.city", Message: "is required"},
+		{Field: "property_address.address_details.zip_code", Message: "is required"},
+	}, problem.Errors)
+	page, err := memDS.GetReportRuns(context.Background(), interfaces.ReportRunQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, page.ReportRuns)
+}
+
+func TestAPI_UpdateReportCost_Invalid(t *testing.T) {
+	apiHandler := &API{DS: new(mocks.MockDatastoreClient)}
+
+	for body, fields := range map[string][]string{
+		`{}`: {"amount"},
+		`{"amount":{"minor_units":-500,"currency":""}}`:     {"amount.currency", "amount.minor_units"},
+		`{"amount":{"minor_units":500,"currency":"bucks"}}`: {"amount.currency"},
+		`{"amount":{"minor_units":500},"note":"x"}`:         {"note"},
+	} {
+		req, err := http.NewRequest("PUT", "/report-runs/run123/cost", strings.NewReader(body))
+		assert.NoError(t, err)
+		req.SetPathValue("id", "run123")
+
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(apiHandler.UpdateReportCost).ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code, body)
+		var problem apierror.Problem
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+		var got []string
+		for _, f := range problem.Errors {
+			got = append(got, f.Field)
+		}
+		assert.Equal(t, fields, got, body)
+	}
+}
+
+func TestAPI_CreateReportRun_CountsPublishFailures(t *testing.T) {
+	memDS := memstore.NewClient()
+	mockPS := new(mocks.MockPublisherClient)
+	apiHandler := &API{DS: memDS, PS: mockPS, Pricing: pricing.NewEngine(memDS)}
+	mockPS.On("Publish", mock.Anything, "nhd-report-requests", mock.Anything, mock.Anything).Return("", errors.New("pubsub unavailable"))
+	memDS.CreateCustomer(context.Background(), &nhd_report.Customer{CustomerId: "cust1", OrganizationId: "org1"})
+	memDS.CreatePropertyAddress(context.Background(), &nhd_report.PropertyAddress{PropertyAddressId: "addr1", OrganizationId: "org1"})
+
+	req, err := http.NewRequest("POST", "/report-runs", strings.NewReader(`{"customer_id":"cust1","property_address_id":"addr1"}`))
+	assert.NoError(t, err)
+	req = withUser(req, testUser)
+
+	created, failures := metrics.ReportRunsCreated.Value(), metrics.PublishFailures.Value("nhd-report-requests")
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(apiHandler.CreateReportRun).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Equal(t, apierror.ContentType, rr.Header().Get("Content-Type"))
+	var problem apierror.Problem
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+	assert.Equal(t, apierror.UpstreamUnavailable, problem.Code)
+	assert.NotContains(t, problem.Detail, "pubsub unavailable")
+	assert.Equal(t, created, metrics.ReportRunsCreated.Value())
+	assert.Equal(t, failures+1, metrics.PublishFailures.Value("nhd-report-requests"))
+	mockPS.AssertExpectations(t)
+}
+
+func TestAPI_CreateReportRun_PublishFailureLeavesRunRetryable(t *testing.T) {
+	ctx := context.Background()
+	memDS := memstore.NewClient()
+	mockPS := new(mocks.MockPublisherClient)
+	apiHandler := &API{DS: memDS, PS: mockPS, Pricing: pricing.NewEngine(memDS)}
+	mockPS.On("Publish", mock.Anything, "nhd-report-requests", mock.Anything, mock.Anything).Return("", errors.New("pubsub unavailable")).Once()
+	mockPS.On("Publish", mock.Anything, "nhd-report-requests", mock.Anything, mock.Anything).Return("msg1", nil).Once()
+	memDS.CreateCustomer(ctx, &nhd_report.Customer{CustomerId: "cust1", OrganizationId: "org1"})
+	memDS.CreatePropertyAddress(ctx, &nhd_report.PropertyAddress{PropertyAddressId: "addr1", OrganizationId: "org1"})
+
+	req := httptest.NewRequest("POST", "/report-runs", strings.NewReader(`{"customer_id":"cust1","property_address_id":"addr1"}`))
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(apiHandler.CreateReportRun).ServeHTTP(rr, withUser(req, testUser))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+
+	// The run that could not be queued is FAILED rather than left PENDING...
+	page, err := memDS.GetReportRuns(ctx, interfaces.ReportRunQuery{})
+	assert.NoError(t, err)
+	if !assert.Len(t, page.ReportRuns, 1) {
+		return
+	}
+	run := page.ReportRuns[0]
+	assert.Equal(t, nhd_report.ReportRun_FAILED, run.Status)
+	assert.Equal(t, "could not be queued", run.FailureReason)
+
+	// ...so the client can retry it.
+	req = httptest.NewRequest("POST", "/report-runs/"+run.ReportRunId+"/retry", nil)
+	req.SetPathValue("id", run.ReportRunId)
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(apiHandler.RetryReportRun).ServeHTTP(rr, withUser(req, testUser))
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	mockPS.AssertExpectations(t)
+}
+
+func TestAPI_QuotePrice_UnknownCustomer(t *testing.T) {
+	memDS := memstore.NewClient()
+	apiHandler := &API{DS: memDS, Pricing: pricing.NewEngine(memDS)}
+
+	req := httptest.NewRequest("GET", "/pricing/quote?customer_id=missing", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(apiHandler.QuotePrice).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	var problem apierror.Problem
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+	assert.Equal(t, apierror.ValidationFailed, problem.Code)
+	assert.Equal(t, []apierror.FieldError{{Field: "customer_id", Message: "does not exist"}}, problem.Errors)
+}
+
+func TestAPI_RetryReportRun_PublishFailureKeepsRunRetryable(t *testing.T) {
+	ctx := context.Background()
+	memDS := memstore.NewClient()
+	mockPS := new(mocks.MockPublisherClient)
+	apiHandler := &API{DS: memDS, PS: mockPS}
+	mockPS.On("Publish", mock.Anything, "nhd-report-requests", mock.Anything, mock.Anything).Return("", errors.New("pubsub unavailable")).Once()
+	mockPS.On("Publish", mock.Anything, "nhd-report-requests", mock.Anything, mock.Anything).Return("msg1", nil).Once()
+	docRef, _, err := memDS.CreateReportRun(ctx, &nhd_report.ReportRun{OrganizationId: "org1", Status: nhd_report.ReportRun_PENDING})
+	assert.NoError(t, err)
+	runID := docRef.ID
+	assert.NoError(t, memDS.UpdateReportRunStatus(ctx, runID, nhd_report.ReportRun_PROCESSING, "worker", ""))
+	assert.NoError(t, memDS.UpdateReportRunStatus(ctx, runID, nhd_report.ReportRun_FAILED, "worker", "no coordinates"))
+
+	retry := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/report-runs/"+runID+"/retry", nil)
+		req.SetPathValue("id", runID)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(apiHandler.RetryReportRun).ServeHTTP(rr, withUser(req, testUser))
+		return rr
+	}
+
+	// The run goes back to FAILED, with its failure reason, when the retry
+	// cannot be queued...
+	assert.Equal(t, http.StatusServiceUnavailable, retry().Code)
+	run, err := memDS.GetReportRun(ctx, runID)
+	assert.NoError(t, err)
+	assert.Equal(t, nhd_report.ReportRun_FAILED, run.Status)
+	assert.Equal(t, "no coordinates", run.FailureReason)
+
+	// ...so the client can retry it again.
+	assert.Equal(t, http.StatusAccepted, retry().Code)
+	run, err = memDS.GetReportRun(ctx, runID)
+	assert.NoError(t, err)
+	assert.Equal(t, nhd_report.ReportRun_PENDING, run.Status)
+	mockPS.AssertExpectations(t)
+}
+
+func TestAPI_GetPropertyAddress_NotFound(t *testing.T) {
+	mockDS := new(mocks.MockDatastoreClient)
+	apiHandler := &API{DS: mockDS}
+
+	req, err := http.NewRequest("GET", "/property-addresses/missing", nil)
+	assert.NoError(t, err)
+	req.SetPathValue("id", "missing")
+	req = withUser(req, testUser)
+
+	mockDS.On("GetPropertyAddress", mock.Anything, "missing").Return(nil, fmt.Errorf("property address missing: %w", interfaces.ErrNotFound))
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(apiHandler.GetPropertyAddress)
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, apierror.ContentType, rr.Header().Get("Content-Type"))
+	var problem apierror.Problem
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &problem))
+	assert.Equal(t, apierror.Problem{Type: "about:blank", Title: "Not Found", Status: http.StatusNotFound, Detail: "Property address not found", Code: apierror.NotFound}, problem)
+	mockDS.AssertExpectations(t)
+}
+
+func TestAPI_UpdatePropertyAddress(t *testing.T) {
+	mockDS := new(mocks.MockDatastoreClient)
+	apiHandler := &API{DS: mockDS}
+
+	existing := &nhd_report.PropertyAddress{
+		PropertyAddressId: "addr123",
+		OrganizationId:    "org1",
+		AddressDetails: &nhd_report.PropertyAddress_AddressDetails{
+			StreetAddress: "1 Main St",
+			City:          "Sacramento",
+			State:         "CA",
+			ZipCode:       "95814",
+		},
+	}
+	mockDS.On("GetPropertyAddress", mock.Anything, "addr123").Return(existing, nil)
+	mockDS.On("UpdatePropertyAddress", mock.Anything, mock.MatchedBy(func(a *nhd_report.PropertyAddress) bool {
+		return a.PropertyAddressId == "addr123" &&
+			a.OrganizationId == "org1" &&
+			a.AddressDetails.StreetAddress == "1 Main St" &&
+			a.AddressDetails.ZipCode == "95815" &&
+			a.Coordinates.GetLatitude() == 38.58
+	})).Return(nil)
+
+	patchJSON := `{"address_details":{"zip_code":"95815"},"coordinates":{"latitude":38.58,"longitude":-121.49},"organization_id":"org2"}`
+	req, err := http.NewRequest("PATCH", "/property-addresses/addr123", strings.NewReader(patchJSON))
+	assert.NoError(t, err)
+	req.SetPathValue("id", "addr123")
+	req = withUser(req, testUser)
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(apiHandler.UpdatePropertyAddress)
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	// The stored record must not be mutated in place.
+	assert.Equal(t, "95814", existing.AddressDetails.ZipCode)
+	mockDS.AssertExpectations(t)
+}
+
+func TestAPI_GetReportRun(t *testing.T) {
+	mockDS := new(mocks.MockDatastoreClient)
+	apiHandler := &API{DS: mockDS}
+
+	run := &nhd_report.ReportRun{
+		ReportRunId:       "run123",
+		OrganizationId:    "org1",
+		CustomerId:        "cust1",
+		PropertyAddressId: "addr1",
+		Status:            nhd_report.ReportRun_PENDING,
+		UpdatedAt:         timestamppb.New(time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)),
+	}
+	mockDS.On("GetReportRun", mock.Anything, "run123").Return(run, nil)
+	mockDS.On("GetCustomer", mock.Anything, "cust1").Return(&nhd_report.Customer{CustomerId: "cust1", FullName: "Alice"}, nil)
+	mockDS.On("GetPropertyAddress", mock.Anything, "addr1").Return(nil, fmt.Errorf("property address addr1: %w", interfaces.ErrNotFound))
+
+	req, err := http.NewRequest("GET", "/report-runs/run123", nil)
+	assert.NoError(t, err)
+	req.SetPathValue("id", "run123")
+	req = withUser(req, testUser)
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(apiHandler.GetReportRun)
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "Sat, 01 Mar 2025 12:00:00 GMT", rr.Header().Get("Last-Modified"))
+	etag := rr.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	var detail map[string]any
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &detail))
+	assert.Equal(t, "run123", detail["report_run_id"])
+	assert.Equal(t, "Alice", detail["customer"].(map[string]any)["full_name"])
+	assert.NotContains(t, detail, "property_address")
+
+	// A conditional request with the same ETag is not modified.
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	assert.Empty(t, rr.Body.String())
+}
+
+func TestAPI_GetReportRun_WaitForStatus(t *testing.T) {
+	defer func(d time.Duration) { reportRunPollInterval = d }(reportRunPollInterval)
+	reportRunPollInterval = time.Millisecond
+
+	mockDS := new(mocks.MockDatastoreClient)
+	apiHandler := &API{DS: mockDS}
+
+	pending := &nhd_report.ReportRun{ReportRunId: "run123", OrganizationId: "org1", Status: nhd_report.ReportRun_PENDING}
+	processing := &nhd_report.ReportRun{ReportRunId: "run123", OrganizationId: "org1", Status: nhd_report.ReportRun_PROCESSING}
+	completed := &nhd_report.ReportRun{ReportRunId: "run123", OrganizationId: "org1", Status: nhd_report.ReportRun_COMPLETED}
+	mockDS.On("GetReportRun", mock.Anything, "run123").Return(pending, nil).Once()
+	mockDS.On("GetReportRun", mock.Anything, "run123").Return(processing, nil).Once()
+	mockDS.On("GetReportRun", mock.Anything, "run123").Return(completed, nil).Once()
+
+	req, err := http.NewRequest("GET", "/report-runs/run123?wait_for_status=COMPLETED&timeout=5s", nil)
+	assert.NoError(t, err)
+	req.SetPathValue("id", "run123")
+	req = withUser(req, testUser)
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(apiHandler.GetReportRun)
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"status":3`)
+	mockDS.AssertExpectations(t)
+}
+
+func TestAPI_GetReportRun_DefaultWaitEndsBeforeRequestTimeout(t *testing.T) {
+	defer func(d time.Duration) { reportRunPollInterval = d }(reportRunPollInterval)
+	reportRunPollInterval = 10 * time.Millisecond
+
+	mockDS := new(mocks.MockDatastoreClient)
+	requestTimeout := 1500 * time.Millisecond
+	apiHandler := &API{DS: mockDS, RequestTimeout: requestTimeout}
+
+	pending := &nhd_report.ReportRun{ReportRunId: "run123", OrganizationId: "org1", Status: nhd_report.ReportRun_PENDING}
+	mockDS.On("GetReportRun", mock.Anything, "run123").Return(pending, nil)
+
+	// No timeout parameter: the default wait is longer than the request
+	// timeout, so the run is returned while it is still pending instead of
+	// the server answering 503.
+	req, err := http.NewRequest("GET", "/report-runs/run123?wait_for_status=COMPLETED", nil)
+	assert.NoError(t, err)
+	req.SetPathValue("id", "run123")
+	req = withUser(req, testUser)
+
+	rr := httptest.NewRecorder()
+	start := time.Now()
+	middleware.Timeout(http.HandlerFunc(apiHandler.GetReportRun), requestTimeout).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"status":1`)
+	assert.Less(t, time.Since(start), requestTimeout)
+}
+
+func TestAPI_GetReportRun_InvalidWaitForStatus(t *testing.T) {
+	apiHandler := &API{DS: new(mocks.MockDatastoreClient)}
+
+	req, err := http.NewRequest("GET", "/report-runs/run123?wait_for_status=DONE", nil)
+	assert.NoError(t, err)
+	req.SetPathValue("id", "run123")
+	req = withUser(req, testUser)
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(apiHandler.GetReportRun).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestAPI_GetReportRun_OtherOrganization(t *testing.T) {
+	mockDS := new(mocks.MockDatastoreClient)
+	apiHandler := &API{DS: mockDS}
+	mockDS.On("GetReportRun", mock.Anything, "run123").Return(&nhd_report.ReportRun{ReportRunId: "run123", OrganizationId: "org2"}, nil)
+
+	get := func(user *nhd_report.User, query string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("GET", "/report-runs/run123"+query, nil)
+		assert.NoError(t, err)
+		req.SetPathValue("id", "run123")
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(apiHandler.GetReportRun).ServeHTTP(rr, withUser(req, user))
+		return rr
+	}
+
+	// Another organization's run is indistinguishable from a missing one.
+	assert.Equal(t, http.StatusNotFound, get(testUser, "").Code)
+	// Only admins may ask for another organization.
+	assert.Equal(t, http.StatusForbidden, get(testUser, "?organization_id=org2").Code)
+	assert.Equal(t, http.StatusForbidden, get(testUser, "?all_organizations=true").Code)
+
+	admin := &nhd_report.User{UserId: "admin", OrganizationId: "org1", Permissions: &nhd_report.Permissions{IsAdmin: true}}
+	assert.Equal(t, http.StatusNotFound, get(admin, "").Code)
+	assert.Equal(t, http.StatusOK, get(admin, "?organization_id=org2").Code)
+	assert.Equal(t, http.StatusOK, get(admin, "?all_organizations=true").Code)
+	assert.Equal(t, http.StatusBadRequest, get(admin, "?organization_id=org2&all_organizations=true").Code)
+}
+
+func TestAPI_GetReportRuns_Scope(t *testing.T) {
+	mockDS := new(mocks.MockDatastoreClient)
+	apiHandler := &API{DS: mockDS}
+	page := &interfaces.ReportRunPage{ReportRuns: []*nhd_report.ReportRun{}}
+	mockDS.On("GetReportRuns", mock.Anything, mock.MatchedBy(func(q interfaces.ReportRunQuery) bool { return q.OrganizationID == "" })).Return(page, nil).Once()
+
+	list := func(user *nhd_report.User, query string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("GET", "/report-runs"+query, nil)
+		assert.NoError(t, err)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(apiHandler.GetReportRuns).ServeHTTP(rr, withUser(req, user))
+		return rr
+	}
+
+	admin := &nhd_report.User{UserId: "admin", Permissions: &nhd_report.Permissions{IsAdmin: true}}
+	assert.Equal(t, http.StatusOK, list(admin, "?all_organizations=true").Code)
+	// Without an organization of their own, even admins must pick one.
+	assert.Equal(t, http.StatusForbidden, list(admin, "").Code)
+	assert.Equal(t, http.StatusForbidden, list(&nhd_report.User{UserId: "nobody"}, "").Code)
 	mockDS.AssertExpectations(t)
 }
 
+func TestAPI_ResendReportEmail(t *testing.T) {
+	memDS := memstore.NewClient()
+	capture := mailer.NewCapture()
+	apiHandler := &API{DS: memDS, Mailer: capture, ReportBaseURL: "https://reports.example.com/"}
+	ctx := context.Background()
 
+	customerRef, _, err := memDS.CreateCustomer(ctx, &nhd_report.Customer{FullName: "Alice Buyer", Email: "alice@example.com", OrganizationId: "org1"})
+	assert.NoError(t, err)
+	addressRef, _, err := memDS.CreatePropertyAddress(ctx, &nhd_report.PropertyAddress{
+		AddressDetails: &nhd_report.PropertyAddress_AddressDetails{StreetAddress: "1 Main St", City: "Sacramento", State: "CA", ZipCode: "95814"},
+	})
+	assert.NoError(t, err)
+	pendingRef, _, err := memDS.CreateReportRun(ctx, &nhd_report.ReportRun{CustomerId: customerRef.ID, OrganizationId: "org1", Status: nhd_report.ReportRun_PENDING})
+	assert.NoError(t, err)
+	completedRef, _, err := memDS.CreateReportRun(ctx, &nhd_report.ReportRun{
+		OrganizationId:      "org1",
+		CustomerId:          customerRef.ID,
+		PropertyAddressId:   addressRef.ID,
+		Status:              nhd_report.ReportRun_COMPLETED,
+		FinalPdfStoragePath: "reports/run.pdf",
+	})
+	assert.NoError(t, err)
+
+	resend := func(id string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("POST", "/report-runs/"+id+"/resend-email", http.NoBody)
+		assert.NoError(t, err)
+		req.SetPathValue("id", id)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(apiHandler.ResendReportEmail).ServeHTTP(rr, withUser(req, testUser))
+		return rr
+	}
+
+	// Runs that are not COMPLETED are refused.
+	rr := resend(pendingRef.ID)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Empty(t, capture.Sent())
+
+	// A completed run is emailed with a link and the delivery is recorded.
+	rr = resend(completedRef.ID)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	sent := capture.Sent()
+	assert.Len(t, sent, 1)
+	assert.Equal(t, []string{"alice@example.com"}, sent[0].To)
+	assert.Contains(t, sent[0].TextBody, "1 Main St, Sacramento, CA 95814")
+	assert.Contains(t, sent[0].TextBody, "https://reports.example.com/reports/run.pdf")
+
+	// A failed send is recorded as FAILED.
+	capture.Err = fmt.Errorf("relay unavailable")
+	rr = resend(completedRef.ID)
+	assert.Equal(t, http.StatusBadGateway, rr.Code)
+
+	run, err := memDS.GetReportRun(ctx, completedRef.ID)
+	assert.NoError(t, err)
+	assert.Len(t, run.EmailDeliveries, 2)
+	assert.Equal(t, nhd_report.ReportRun_EmailDelivery_SENT, run.EmailDeliveries[0].Status)
+	assert.Equal(t, mailer.DefaultTemplate, run.EmailDeliveries[0].EmailTemplateReference)
+	assert.NotNil(t, run.EmailDeliveries[0].SentAt)
+	assert.Equal(t, nhd_report.ReportRun_EmailDelivery_FAILED, run.EmailDeliveries[1].Status)
+	assert.Equal(t, "relay unavailable", run.EmailDeliveries[1].FailureReason)
+
+	// A send that timed out after handing over the message is UNKNOWN.
+	capture.Err = fmt.Errorf("smtp send: %w: i/o timeout", interfaces.ErrDeliveryUnknown)
+	rr = resend(completedRef.ID)
+	assert.Equal(t, http.StatusGatewayTimeout, rr.Code)
+
+	run, err = memDS.GetReportRun(ctx, completedRef.ID)
+	assert.NoError(t, err)
+	assert.Len(t, run.EmailDeliveries, 3)
+	assert.Equal(t, nhd_report.ReportRun_EmailDelivery_UNKNOWN, run.EmailDeliveries[2].Status)
+}
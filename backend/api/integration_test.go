@@ -4,30 +4,73 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"firebase.google.com/go/v4/auth"
+	"github.com/seans3/nhd/backend/hazard"
 	"github.com/seans3/nhd/backend/interfaces"
+	"github.com/seans3/nhd/backend/logging"
+	"github.com/seans3/nhd/backend/mailer"
 	"github.com/seans3/nhd/backend/memstore"
+	"github.com/seans3/nhd/backend/mempubsub"
 	"github.com/seans3/nhd/backend/mocks"
 	"github.com/seans3/nhd/backend/middleware"
+	"github.com/seans3/nhd/backend/money"
+	"github.com/seans3/nhd/backend/pricing"
 	"github.com/seans3/nhd/backend/proto/gen/go"
+	"github.com/seans3/nhd/backend/worker"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
-// setupIntegrationTestServer initializes a new test server with an in-memory datastore
-// and a mock publisher/auth client.
-func setupIntegrationTestServer() (*httptest.Server, interfaces.Datastore, *mocks.MockPublisherClient, *mocks.MockFirebaseAuth, func()) {
+// setupIntegrationTestServer initializes a new test server with an in-memory datastore,
+// an in-memory broker and a mock auth client. A report worker consumes the broker's
+// report requests in the background, so report runs progress from PENDING to
+// COMPLETED or FAILED as they would in production. The datastore holds a profile
+// for "test-user", in organization "test-org", that may create customers and
+// generate reports.
+func setupIntegrationTestServer() (*httptest.Server, interfaces.Datastore, *mempubsub.Broker, *mocks.MockFirebaseAuth, func()) {
 	memDS := memstore.NewClient()
-	mockPS := new(mocks.MockPublisherClient)
+	if _, _, err := memDS.CreateOrganization(context.Background(), &nhd_report.Organization{OrganizationId: "test-org", Name: "Test Organization"}); err != nil {
+		panic(err)
+	}
+	if err := memDS.CreateUser(context.Background(), &nhd_report.User{
+		UserId:         "test-user",
+		OrganizationId: "test-org",
+		Permissions:    &nhd_report.Permissions{CanCreateCustomers: true, CanGenerateReports: true},
+	}); err != nil {
+		panic(err)
+	}
+	broker := mempubsub.NewBroker()
 	mockAuth := new(mocks.MockFirebaseAuth)
 
+	broker.CreateTopic("nhd-report-requests")
+	if err := broker.CreateSubscription(worker.DefaultSubscription, mempubsub.SubscriptionConfig{Topic: "nhd-report-requests"}); err != nil {
+		panic(err)
+	}
+	hazards, err := hazard.Load("../../reporter/data")
+	if err != nil {
+		panic(err)
+	}
+	reportWorker := &worker.Worker{DS: memDS, Sub: broker, Hazards: hazards}
+	workerCtx, stopWorker := context.WithCancel(context.Background())
+	workerDone := make(chan struct{})
+	go func() {
+		defer close(workerDone)
+		reportWorker.Run(workerCtx)
+	}()
+
 	apiHandler := &API{
-		DS: memDS,
-		PS: mockPS,
+		DS:      memDS,
+		PS:      broker,
+		Mailer:  mailer.NewCapture(),
+		Pricing: pricing.NewEngine(memDS),
 	}
 
 	authClient := &middleware.AuthClient{
@@ -43,26 +86,117 @@ func setupIntegrationTestServer() (*httptest.Server, interfaces.Datastore, *mock
 
 	// Standard authenticated API routes
 	apiMux := http.NewServeMux()
-	apiMux.HandleFunc("POST /customers", apiHandler.CreateCustomer)
+	apiMux.Handle("POST /customers", authClient.RequirePermission(middleware.CanCreateCustomers, http.HandlerFunc(apiHandler.CreateCustomer)))
 	apiMux.HandleFunc("GET /customers", apiHandler.GetCustomers)
-	apiMux.HandleFunc("POST /report-runs", apiHandler.CreateReportRun)
+	apiMux.HandleFunc("GET /customers/{id}", apiHandler.GetCustomer)
+	apiMux.Handle("PATCH /customers/{id}", authClient.RequirePermission(middleware.CanCreateCustomers, http.HandlerFunc(apiHandler.UpdateCustomer)))
+	apiMux.Handle("DELETE /customers/{id}", authClient.RequirePermission(middleware.CanCreateCustomers, http.HandlerFunc(apiHandler.ArchiveCustomer)))
+	apiMux.Handle("POST /customers/{id}/restore", authClient.RequirePermission(middleware.CanCreateCustomers, http.HandlerFunc(apiHandler.RestoreCustomer)))
+	apiMux.Handle("POST /property-addresses", authClient.RequirePermission(middleware.CanGenerateReports, http.HandlerFunc(apiHandler.CreatePropertyAddress)))
+	apiMux.HandleFunc("GET /property-addresses", apiHandler.GetPropertyAddresses)
+	apiMux.HandleFunc("GET /property-addresses/{id}", apiHandler.GetPropertyAddress)
+	apiMux.Handle("PATCH /property-addresses/{id}", authClient.RequirePermission(middleware.CanGenerateReports, http.HandlerFunc(apiHandler.UpdatePropertyAddress)))
+	apiMux.Handle("POST /report-runs", authClient.RequirePermission(middleware.CanGenerateReports, http.HandlerFunc(apiHandler.CreateReportRun)))
 	apiMux.HandleFunc("GET /report-runs", apiHandler.GetReportRuns)
+	apiMux.HandleFunc("GET /report-runs/{id}", apiHandler.GetReportRun)
+	apiMux.Handle("POST /report-runs/{id}/resend-email", authClient.RequirePermission(middleware.CanGenerateReports, http.HandlerFunc(apiHandler.ResendReportEmail)))
+	apiMux.Handle("POST /report-runs/{id}/retry", authClient.RequirePermission(middleware.CanGenerateReports, http.HandlerFunc(apiHandler.RetryReportRun)))
+	apiMux.Handle("POST /report-runs/{id}/cancel", authClient.RequirePermission(middleware.CanGenerateReports, http.HandlerFunc(apiHandler.CancelReportRun)))
 	apiMux.HandleFunc("GET /financials/summary", apiHandler.GetFinancialsSummary)
-	mux.Handle("/api/", http.StripPrefix("/api", authClient.VerifyAuthToken(apiMux)))
+	idempotency := &middleware.Idempotency{DS: memDS}
+	mux.Handle("/api/", http.StripPrefix("/api", authClient.VerifyAuthToken(idempotency.Middleware(middleware.Route("/api", apiMux)))))
 
 	// Admin-only API routes
 	adminMux := http.NewServeMux()
 	adminMux.HandleFunc("POST /users/register", apiHandler.RegisterUser)
+	adminMux.HandleFunc("POST /organizations", apiHandler.CreateOrganization)
+	adminMux.HandleFunc("GET /organizations", apiHandler.GetOrganizations)
 	adminMux.HandleFunc("PUT /report-runs/{id}/cost", apiHandler.UpdateReportCost)
 	adminMux.HandleFunc("POST /report-runs/{id}/payment", apiHandler.RecordReportPayment)
-	mux.Handle("/admin/", http.StripPrefix("/admin", authClient.RequireAdmin(adminMux)))
+	adminMux.HandleFunc("POST /report-runs/{id}/refund", apiHandler.RefundReportRun)
+	adminMux.HandleFunc("GET /pricing", apiHandler.GetPriceRules)
+	adminMux.HandleFunc("POST /pricing", apiHandler.CreatePriceRule)
+	adminMux.HandleFunc("GET /pricing/quote", apiHandler.QuotePrice)
+	adminMux.HandleFunc("GET /exchange-rates", apiHandler.GetExchangeRates)
+	adminMux.HandleFunc("POST /exchange-rates", apiHandler.CreateExchangeRate)
+	mux.Handle("/admin/", http.StripPrefix("/admin", authClient.RequireAdmin(idempotency.Middleware(middleware.Route("/admin", adminMux)))))
 
-	server := httptest.NewServer(mux)
+	server := httptest.NewServer(middleware.Logging(middleware.Route("", mux)))
 	cleanup := func() {
 		server.Close()
+		stopWorker()
+		<-workerDone
+	}
+
+	return server, memDS, broker, mockAuth, cleanup
+}
+
+// seedCustomers adds customers with the given IDs to organization
+// "test-org", for tests that create report runs for them.
+func seedCustomers(t *testing.T, ds interfaces.Datastore, ids ...string) {
+	t.Helper()
+	for _, id := range ids {
+		_, _, err := ds.CreateCustomer(context.Background(), &nhd_report.Customer{CustomerId: id, FullName: "Customer " + id, OrganizationId: "test-org"})
+		require.NoError(t, err)
+	}
+}
+
+// seedPropertyAddresses adds property addresses with the given IDs, for tests
+// that create report runs for them.
+func seedPropertyAddresses(t *testing.T, ds interfaces.Datastore, ids ...string) {
+	t.Helper()
+	for _, id := range ids {
+		_, _, err := ds.CreatePropertyAddress(context.Background(), &nhd_report.PropertyAddress{
+			PropertyAddressId: id,
+			OrganizationId:    "test-org",
+			AddressDetails:    &nhd_report.PropertyAddress_AddressDetails{StreetAddress: id + " Test St", City: "Sacramento", State: "CA", ZipCode: "95814"},
+		})
+		require.NoError(t, err)
+	}
+}
+
+// apiClient sends requests to a test server as the user its token
+// authenticates.
+type apiClient struct {
+	t      *testing.T
+	server *httptest.Server
+	token  string
+}
+
+// do sends a request and returns the response, whose body the caller must
+// close. header holds extra header names and values, in pairs.
+func (c apiClient) do(method, path, body string, header ...string) *http.Response {
+	c.t.Helper()
+	req, err := http.NewRequest(method, c.server.URL+path, bytes.NewBufferString(body))
+	require.NoError(c.t, err)
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
 	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(c.t, err)
+	return resp
+}
 
-	return server, memDS, mockPS, mockAuth, cleanup
+// doJSON sends a request, decodes a 2xx response body into v unless v is
+// nil, and returns the response status.
+func (c apiClient) doJSON(method, path, body string, v any) int {
+	c.t.Helper()
+	resp := c.do(method, path, body)
+	defer resp.Body.Close()
+	if v != nil && resp.StatusCode < 300 {
+		assert.NoError(c.t, json.NewDecoder(resp.Body).Decode(v))
+	}
+	return resp.StatusCode
+}
+
+// readBody reads and closes the body of resp.
+func readBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
 }
 
 func TestIntegration_CreateAndGetCustomers(t *testing.T) {
@@ -101,30 +235,30 @@ func TestIntegration_CreateAndGetCustomers(t *testing.T) {
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
 	defer resp.Body.Close()
 
-	var customers []*nhd_report.Customer
+	var customers interfaces.CustomerPage
 	err = json.NewDecoder(resp.Body).Decode(&customers)
 	assert.NoError(t, err)
-	assert.Len(t, customers, 1)
-	assert.Equal(t, "Charlie Integration", customers[0].FullName)
-	assert.Equal(t, newCustomerID, customers[0].CustomerId)
+	assert.Len(t, customers.Customers, 1)
+	assert.Equal(t, "Charlie Integration", customers.Customers[0].FullName)
+	assert.Equal(t, newCustomerID, customers.Customers[0].CustomerId)
 }
 
 func TestIntegration_FullReportLifecycle(t *testing.T) {
-	server, memDS, mockPS, mockAuth, cleanup := setupIntegrationTestServer()
+	server, memDS, _, mockAuth, cleanup := setupIntegrationTestServer()
+	seedPropertyAddresses(t, memDS, "addr123")
 	defer cleanup()
 
 	// Mock the auth token verification for all API calls in this test
 	mockAuth.On("VerifyIDToken", mock.Anything, "valid-token").Return(&auth.Token{UID: "test-user"}, nil)
 
 	// 1. Create a customer first (directly in memstore for simplicity)
-	customer := &nhd_report.Customer{FullName: "Test Customer", CreatedByUserId: "test-user"}
+	customer := &nhd_report.Customer{FullName: "Test Customer", CreatedByUserId: "test-user", OrganizationId: "test-org"}
 	docRef, _, err := memDS.CreateCustomer(context.Background(), customer)
 	assert.NoError(t, err)
 	customerID := docRef.ID
 
 	// 2. Create a report run for that customer
 	reportRunJSON := `{"customer_id":"` + customerID + `","property_address_id":"addr123"}`
-	mockPS.On("Publish", mock.Anything, "nhd-report-requests", mock.Anything).Return("pub-msg-id", nil)
 
 	req, err := http.NewRequest("POST", server.URL+"/api/report-runs", bytes.NewBufferString(reportRunJSON))
 	assert.NoError(t, err)
@@ -153,11 +287,11 @@ func TestIntegration_FullReportLifecycle(t *testing.T) {
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
 	defer resp.Body.Close()
 
-	var reports []*nhd_report.ReportRun
+	var reports interfaces.ReportRunPage
 	err = json.NewDecoder(resp.Body).Decode(&reports)
 	assert.NoError(t, err)
-	assert.Len(t, reports, 1)
-	assert.Equal(t, reportID, reports[0].ReportRunId)
+	assert.Len(t, reports.ReportRuns, 1)
+	assert.Equal(t, reportID, reports.ReportRuns[0].ReportRunId)
 
 	// 5. Update the cost of the report (Admin Task)
 	mockAuth.On("VerifyIDToken", mock.Anything, "valid-admin-token").Return(&auth.Token{UID: "admin-uid"}, nil)
@@ -165,7 +299,7 @@ func TestIntegration_FullReportLifecycle(t *testing.T) {
 	err = memDS.CreateUser(context.Background(), adminUser)
 	assert.NoError(t, err)
 
-	costJSON := `{"amount":123.45, "currency": "USD"}`
+	costJSON := `{"amount":{"currency":"USD","minor_units":12345}}`
 	req, err = http.NewRequest("PUT", server.URL+"/admin/report-runs/"+reportID+"/cost", bytes.NewBufferString(costJSON))
 	assert.NoError(t, err)
 	req.Header.Set("Authorization", "Bearer valid-admin-token")
@@ -177,14 +311,14 @@ func TestIntegration_FullReportLifecycle(t *testing.T) {
 	defer resp.Body.Close()
 
 	// 6. Verify the cost was updated in the datastore
-	updatedReport, err := memDS.GetReportRuns(context.Background(), "")
+	updatedReport, err := memDS.GetReportRun(context.Background(), reportID)
 	assert.NoError(t, err)
-	assert.Len(t, updatedReport, 1)
-	assert.Len(t, updatedReport[0].CostHistory, 1)
-	assert.Equal(t, 123.45, updatedReport[0].CostHistory[0].Amount)
+	assert.Len(t, updatedReport.CostHistory, 2)
+	assert.Equal(t, int64(pricing.DefaultAmount), updatedReport.CostHistory[0].Amount.GetMinorUnits())
+	assert.Equal(t, int64(12345), updatedReport.CostHistory[1].Amount.GetMinorUnits())
 
 	// 7. Record a payment for the report (Admin Task)
-	paymentJSON := `{"amount_paid":123.45, "status": 2}` // PAID = 2
+	paymentJSON := `{"amount":{"currency":"USD","minor_units":12345}, "payment_method":"Manual"}`
 	req, err = http.NewRequest("POST", server.URL+"/admin/report-runs/"+reportID+"/payment", bytes.NewBufferString(paymentJSON))
 	assert.NoError(t, err)
 	req.Header.Set("Authorization", "Bearer valid-admin-token")
@@ -194,6 +328,50 @@ func TestIntegration_FullReportLifecycle(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
 	defer resp.Body.Close()
+	var payment nhd_report.ReportRun_Payment
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&payment))
+	assert.Equal(t, nhd_report.ReportRun_Payment_PAID, payment.Status)
+	assert.Equal(t, "123.45 USD", money.String(payment.AmountPaid))
+	assert.Zero(t, payment.BalanceDue.GetMinorUnits())
+
+	// Refund part of it; refunding more than remains is rejected.
+	refund := func(body string) *http.Response {
+		req, err := http.NewRequest("POST", server.URL+"/admin/report-runs/"+reportID+"/refund", bytes.NewBufferString(body))
+		assert.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer valid-admin-token")
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		return resp
+	}
+	resp = refund(`{"amount":{"currency":"USD","minor_units":2345},"reason":"Goodwill credit"}`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	payment = nhd_report.ReportRun_Payment{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&payment))
+	resp.Body.Close()
+	assert.Equal(t, nhd_report.ReportRun_Payment_PARTIALLY_REFUNDED, payment.Status)
+	assert.Equal(t, "100.00 USD", money.String(payment.AmountPaid))
+	assert.Equal(t, "23.45 USD", money.String(payment.AmountRefunded))
+
+	resp = refund(`{"amount":{"currency":"USD","minor_units":10001},"reason":"Too much"}`)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	resp.Body.Close()
+
+	// The ledger records every movement, oldest first.
+	updatedReport, err = memDS.GetReportRun(context.Background(), reportID)
+	assert.NoError(t, err)
+	var types []nhd_report.ReportRun_LedgerEntry_Type
+	for _, entry := range updatedReport.Ledger {
+		types = append(types, entry.Type)
+		assert.NotEmpty(t, entry.EntryId)
+		assert.Equal(t, "USD", entry.Amount.GetCurrency())
+	}
+	assert.Equal(t, []nhd_report.ReportRun_LedgerEntry_Type{
+		nhd_report.ReportRun_LedgerEntry_CHARGE,
+		nhd_report.ReportRun_LedgerEntry_ADJUSTMENT,
+		nhd_report.ReportRun_LedgerEntry_PAYMENT,
+		nhd_report.ReportRun_LedgerEntry_REFUND,
+	}, types)
+	assert.Equal(t, "admin-uid", updatedReport.Ledger[3].CreatedByUserId)
 
 	// 8. Get the financials summary and verify the payment is included
 	req, err = http.NewRequest("GET", server.URL+"/api/financials/summary", nil)
@@ -208,9 +386,11 @@ func TestIntegration_FullReportLifecycle(t *testing.T) {
 	var summary interfaces.FinancialsSummary
 	err = json.NewDecoder(resp.Body).Decode(&summary)
 	assert.NoError(t, err)
-	assert.Equal(t, 123.45, summary.TotalRevenue)
+	assert.Equal(t, "123.45 USD", money.String(summary.TotalRevenue))
+	assert.Equal(t, "23.45 USD", money.String(summary.TotalRefunds))
+	assert.Equal(t, "100.00 USD", money.String(summary.NetRevenue))
 	assert.Len(t, summary.PaidReports, 1)
-	assert.Equal(t, "Customer "+customerID, summary.PaidReports[0].CustomerName)
+	assert.Equal(t, "Test Customer", summary.PaidReports[0].CustomerName)
 }
 
 func TestIntegration_RegisterUser_FullFlow(t *testing.T) {
@@ -246,7 +426,22 @@ func TestIntegration_RegisterUser_FullFlow(t *testing.T) {
 	err = memDS.CreateUser(context.Background(), adminUser)
 	assert.NoError(t, err)
 
-	// 2. Prepare and make the request again, this time as an admin
+	// 2. The organization must be given and must exist
+	for _, body := range []string{
+		`{"email":"newuser@example.com","full_name":"New User"}`,
+		`{"email":"newuser@example.com","full_name":"New User","organization_id":"no-such-org"}`,
+	} {
+		req, err = http.NewRequest("POST", server.URL+"/admin/users/register", bytes.NewBufferString(body))
+		assert.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer valid-admin-token")
+		resp, err = client.Do(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, body)
+	}
+
+	// 3. Prepare and make the request again, this time as an admin
+	newUserJSON = `{"email":"newuser@example.com","password":"password","full_name":"New User","organization_id":"test-org","can_create_customers":true}`
 	req, err = http.NewRequest("POST", server.URL+"/admin/users/register", bytes.NewBufferString(newUserJSON))
 	assert.NoError(t, err)
 	req.Header.Set("Authorization", "Bearer valid-admin-token")
@@ -256,6 +451,789 @@ func TestIntegration_RegisterUser_FullFlow(t *testing.T) {
 	assert.NoError(t, err)
 	defer resp.Body.Close()
 
-	// 3. Assert Success
+	// 4. Assert Success, with the requested organization and permissions persisted
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	var created nhd_report.User
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	stored, err := memDS.GetUserByID(context.Background(), created.UserId)
+	assert.NoError(t, err)
+	assert.Equal(t, "test-org", stored.OrganizationId)
+	assert.True(t, stored.Permissions.CanCreateCustomers)
+	assert.False(t, stored.Permissions.CanGenerateReports)
+	assert.False(t, stored.Permissions.IsAdmin)
+}
+
+func TestIntegration_RoutesRequirePermissions(t *testing.T) {
+	server, memDS, _, mockAuth, cleanup := setupIntegrationTestServer()
+	seedCustomers(t, memDS, "cust1")
+	seedPropertyAddresses(t, memDS, "addr1")
+	defer cleanup()
+
+	mockAuth.On("VerifyIDToken", mock.Anything, "no-profile-token").Return(&auth.Token{UID: "no-profile-uid"}, nil)
+	mockAuth.On("VerifyIDToken", mock.Anything, "customers-only-token").Return(&auth.Token{UID: "customers-only-uid"}, nil)
+	assert.NoError(t, memDS.CreateUser(context.Background(), &nhd_report.User{
+		UserId:         "customers-only-uid",
+		OrganizationId: "test-org",
+		Permissions:    &nhd_report.Permissions{CanCreateCustomers: true, IsAdmin: true},
+	}))
+
+	noProfile := apiClient{t, server, "no-profile-token"}
+	customersOnly := apiClient{t, server, "customers-only-token"}
+	const reportRunJSON = `{"customer_id":"cust1","property_address_id":"addr1"}`
+
+	// Without a profile, nothing that needs a permission is allowed.
+	resp := noProfile.do("POST", "/api/customers", `{"full_name":"A"}`)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Contains(t, readBody(t, resp), "No user profile found")
+	assert.Equal(t, http.StatusForbidden, noProfile.doJSON("POST", "/api/report-runs", reportRunJSON, nil))
+
+	// A profile with only can_create_customers, even an admin's, cannot
+	// order reports.
+	assert.Equal(t, http.StatusCreated, customersOnly.doJSON("POST", "/api/customers", `{"full_name":"A"}`, nil))
+	resp = customersOnly.do("POST", "/api/report-runs", reportRunJSON)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Contains(t, readBody(t, resp), "Permission can_generate_reports required")
+	assert.Equal(t, http.StatusForbidden, customersOnly.doJSON("POST", "/api/report-runs/run1/resend-email", `{}`, nil))
+	assert.Equal(t, http.StatusForbidden, customersOnly.doJSON("POST", "/api/report-runs/run1/retry", `{}`, nil))
+	assert.Equal(t, http.StatusForbidden, customersOnly.doJSON("POST", "/api/report-runs/run1/cancel", `{}`, nil))
+	assert.Equal(t, http.StatusForbidden, customersOnly.doJSON("POST", "/api/property-addresses", `{"address_details":{"address_lines":["1 Main St"],"locality":"Sacramento","administrative_area":"CA","postal_code":"95814","region_code":"US"}}`, nil))
+}
+
+func TestIntegration_OrganizationsAreIsolated(t *testing.T) {
+	server, memDS, _, mockAuth, cleanup := setupIntegrationTestServer()
+	seedPropertyAddresses(t, memDS, "addr1")
+	defer cleanup()
+
+	mockAuth.On("VerifyIDToken", mock.Anything, "valid-token").Return(&auth.Token{UID: "test-user"}, nil)
+	mockAuth.On("VerifyIDToken", mock.Anything, "other-token").Return(&auth.Token{UID: "other-user"}, nil)
+	mockAuth.On("VerifyIDToken", mock.Anything, "valid-admin-token").Return(&auth.Token{UID: "admin-uid"}, nil)
+	assert.NoError(t, memDS.CreateUser(context.Background(), &nhd_report.User{
+		UserId:         "admin-uid",
+		OrganizationId: "test-org",
+		Permissions:    &nhd_report.Permissions{IsAdmin: true},
+	}))
+
+	user := apiClient{t, server, "valid-token"}
+	other := apiClient{t, server, "other-token"}
+	admin := apiClient{t, server, "valid-admin-token"}
+
+	// An admin creates a second organization and registers a user in it.
+	var org map[string]string
+	assert.Equal(t, http.StatusCreated, admin.doJSON("POST", "/admin/organizations", `{"name":"Other Realty"}`, &org))
+	otherOrg := org["organization_id"]
+	assert.NotEmpty(t, otherOrg)
+	var orgs []*nhd_report.Organization
+	assert.Equal(t, http.StatusOK, admin.doJSON("GET", "/admin/organizations", "", &orgs))
+	assert.Len(t, orgs, 2)
+	assert.NoError(t, memDS.CreateUser(context.Background(), &nhd_report.User{
+		UserId:         "other-user",
+		OrganizationId: otherOrg,
+		Permissions:    &nhd_report.Permissions{CanCreateCustomers: true, CanGenerateReports: true},
+	}))
+
+	// test-user's customer and report run belong to test-org.
+	var created map[string]string
+	assert.Equal(t, http.StatusCreated, user.doJSON("POST", "/api/customers", `{"full_name":"Alice"}`, &created))
+	customerID := created["customer_id"]
+	assert.Equal(t, http.StatusCreated, user.doJSON("POST", "/api/report-runs", `{"customer_id":"`+customerID+`","property_address_id":"addr1"}`, &created))
+	reportID := created["report_run_id"]
+
+	// other-user sees none of it and cannot order reports for the customer.
+	var customers interfaces.CustomerPage
+	assert.Equal(t, http.StatusOK, other.doJSON("GET", "/api/customers", "", &customers))
+	assert.Empty(t, customers.Customers)
+	assert.Equal(t, http.StatusNotFound, other.doJSON("GET", "/api/customers/"+customerID, "", nil))
+	var page interfaces.ReportRunPage
+	assert.Equal(t, http.StatusOK, other.doJSON("GET", "/api/report-runs", "", &page))
+	assert.Empty(t, page.ReportRuns)
+	assert.Equal(t, http.StatusNotFound, other.doJSON("GET", "/api/report-runs/"+reportID, "", nil))
+	assert.Equal(t, http.StatusBadRequest, other.doJSON("POST", "/api/report-runs", `{"customer_id":"`+customerID+`","property_address_id":"addr1"}`, nil))
+	assert.Equal(t, http.StatusForbidden, other.doJSON("GET", "/api/report-runs?all_organizations=true", "", nil))
+
+	// Property addresses are private too, and ordering the same address
+	// gives other-user its own record.
+	var addresses []*nhd_report.PropertyAddress
+	assert.Equal(t, http.StatusOK, other.doJSON("GET", "/api/property-addresses", "", &addresses))
+	assert.Empty(t, addresses)
+	assert.Equal(t, http.StatusNotFound, other.doJSON("GET", "/api/property-addresses/addr1", "", nil))
+	assert.Equal(t, http.StatusNotFound, other.doJSON("PATCH", "/api/property-addresses/addr1", `{"plus_code":"84CV+XX"}`, nil))
+	const inlineAddress = `"property_address":{"address_details":{"street_address":"9 Elm St","city":"Sacramento","zip_code":"95814"}}`
+	assert.Equal(t, http.StatusCreated, user.doJSON("POST", "/api/report-runs", `{"customer_id":"`+customerID+`",`+inlineAddress+`}`, &created))
+	ownAddress := created["property_address_id"]
+	assert.Equal(t, http.StatusCreated, other.doJSON("POST", "/api/customers", `{"full_name":"Carol"}`, &created))
+	assert.Equal(t, http.StatusCreated, other.doJSON("POST", "/api/report-runs", `{"customer_id":"`+created["customer_id"]+`",`+inlineAddress+`}`, &created))
+	assert.NotEqual(t, ownAddress, created["property_address_id"])
+
+	// Customers created by other-user belong to its own organization.
+	assert.Equal(t, http.StatusCreated, other.doJSON("POST", "/api/customers", `{"full_name":"Bob"}`, nil))
+	assert.Equal(t, http.StatusOK, user.doJSON("GET", "/api/customers", "", &customers))
+	assert.Len(t, customers.Customers, 1)
+	assert.Equal(t, "Alice", customers.Customers[0].FullName)
+
+	// The admin sees its own organization by default and every
+	// organization only when it asks.
+	assert.Equal(t, http.StatusOK, admin.doJSON("GET", "/api/customers", "", &customers))
+	assert.Len(t, customers.Customers, 1)
+	assert.Equal(t, http.StatusOK, admin.doJSON("GET", "/api/customers?all_organizations=true", "", &customers))
+	assert.Len(t, customers.Customers, 3)
+	assert.Equal(t, http.StatusOK, admin.doJSON("GET", "/api/customers?organization_id="+otherOrg, "", &customers))
+	assert.Len(t, customers.Customers, 2)
+	assert.Equal(t, "Bob", customers.Customers[0].FullName)
+	assert.Equal(t, http.StatusOK, admin.doJSON("GET", "/api/property-addresses?all_organizations=true", "", &addresses))
+	assert.Len(t, addresses, 3)
+}
+
+func TestIntegration_CustomerLifecycle(t *testing.T) {
+	server, memDS, _, mockAuth, cleanup := setupIntegrationTestServer()
+	defer cleanup()
+	seedPropertyAddresses(t, memDS, "addr1")
+
+	mockAuth.On("VerifyIDToken", mock.Anything, "valid-token").Return(&auth.Token{UID: "test-user"}, nil)
+	user := apiClient{t, server, "valid-token"}
+	names := func(page interfaces.CustomerPage) []string {
+		var names []string
+		for _, c := range page.Customers {
+			names = append(names, c.FullName)
+		}
+		return names
+	}
+
+	var created map[string]string
+	assert.Equal(t, http.StatusCreated, user.doJSON("POST", "/api/customers", `{"full_name":"Dana Scully","email":"dana@fbi.gov","company_name":"Bureau Realty"}`, &created))
+	danaID := created["customer_id"]
+	assert.Equal(t, http.StatusCreated, user.doJSON("POST", "/api/customers", `{"full_name":"Fox Mulder","email":"fox@fbi.gov"}`, nil))
+	assert.Equal(t, http.StatusCreated, user.doJSON("POST", "/api/customers", `{"full_name":"Walter Skinner","company_name":"Bureau Realty"}`, nil))
+
+	// Search matches a prefix of the name, a word in it, the email or the
+	// company, ignoring case.
+	var page interfaces.CustomerPage
+	assert.Equal(t, http.StatusOK, user.doJSON("GET", "/api/customers?q=scu", "", &page))
+	assert.Equal(t, []string{"Dana Scully"}, names(page))
+	assert.Equal(t, http.StatusOK, user.doJSON("GET", "/api/customers?q=FOX@", "", &page))
+	assert.Equal(t, []string{"Fox Mulder"}, names(page))
+	assert.Equal(t, http.StatusOK, user.doJSON("GET", "/api/customers?q=bureau", "", &page))
+	assert.Equal(t, []string{"Dana Scully", "Walter Skinner"}, names(page))
+
+	// Pages are ordered by name.
+	assert.Equal(t, http.StatusOK, user.doJSON("GET", "/api/customers?page_size=2", "", &page))
+	assert.Equal(t, []string{"Dana Scully", "Fox Mulder"}, names(page))
+	assert.NotEmpty(t, page.NextPageToken)
+	var next interfaces.CustomerPage
+	assert.Equal(t, http.StatusOK, user.doJSON("GET", "/api/customers?page_size=2&page_token="+page.NextPageToken, "", &next))
+	assert.Equal(t, []string{"Walter Skinner"}, names(next))
+	assert.Empty(t, next.NextPageToken)
+
+	// A typo in the email is fixed; other fields are left alone.
+	var customer nhd_report.Customer
+	assert.Equal(t, http.StatusOK, user.doJSON("PATCH", "/api/customers/"+danaID, `{"email":"dana.scully@fbi.gov"}`, &customer))
+	assert.Equal(t, "dana.scully@fbi.gov", customer.Email)
+	assert.Equal(t, "Dana Scully", customer.FullName)
+	assert.Equal(t, http.StatusBadRequest, user.doJSON("PATCH", "/api/customers/"+danaID, `{"email":"not-an-email"}`, nil))
+	assert.Equal(t, http.StatusNotFound, user.doJSON("PATCH", "/api/customers/missing", `{"email":"a@b.com"}`, nil))
+	assert.Equal(t, http.StatusOK, user.doJSON("GET", "/api/customers/"+danaID, "", &customer))
+	assert.Equal(t, "dana.scully@fbi.gov", customer.Email)
+	assert.Empty(t, customer.SearchPrefixes)
+
+	// Dana has a report run, which survives archiving.
+	assert.Equal(t, http.StatusCreated, user.doJSON("POST", "/api/report-runs", `{"customer_id":"`+danaID+`","property_address_id":"addr1"}`, &created))
+	reportID := created["report_run_id"]
+
+	assert.Equal(t, http.StatusOK, user.doJSON("DELETE", "/api/customers/"+danaID, "", &customer))
+	assert.True(t, customer.Archived)
+	assert.NotNil(t, customer.ArchivedAt)
+	assert.Equal(t, http.StatusOK, user.doJSON("GET", "/api/customers", "", &page))
+	assert.Equal(t, []string{"Fox Mulder", "Walter Skinner"}, names(page))
+	assert.Equal(t, http.StatusOK, user.doJSON("GET", "/api/customers?include_archived=true", "", &page))
+	assert.Len(t, page.Customers, 3)
+	assert.Equal(t, http.StatusOK, user.doJSON("GET", "/api/customers/"+danaID, "", &customer))
+	assert.True(t, customer.Archived)
+	assert.Equal(t, http.StatusOK, user.doJSON("GET", "/api/report-runs/"+reportID, "", nil))
+	assert.Equal(t, http.StatusBadRequest, user.doJSON("POST", "/api/report-runs", `{"customer_id":"`+danaID+`","property_address_id":"addr1"}`, nil))
+
+	var restored nhd_report.Customer
+	assert.Equal(t, http.StatusOK, user.doJSON("POST", "/api/customers/"+danaID+"/restore", "", &restored))
+	assert.False(t, restored.Archived)
+	assert.Nil(t, restored.ArchivedAt)
+	assert.Equal(t, http.StatusOK, user.doJSON("GET", "/api/customers", "", &page))
+	assert.Len(t, page.Customers, 3)
+}
+
+func TestIntegration_PropertyAddressCRUD(t *testing.T) {
+	server, _, _, mockAuth, cleanup := setupIntegrationTestServer()
+	defer cleanup()
+
+	mockAuth.On("VerifyIDToken", mock.Anything, "valid-token").Return(&auth.Token{UID: "test-user"}, nil)
+	client := &http.Client{}
+
+	// 1. Create a property address
+	addressJSON := `{"address_details":{"street_address":"1 Main St","city":"Sacramento","state":"CA","zip_code":"95814"}}`
+	req, err := http.NewRequest("POST", server.URL+"/api/property-addresses", bytes.NewBufferString(addressJSON))
+	assert.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer valid-token")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	assert.NoError(t, err)
 	assert.Equal(t, http.StatusCreated, resp.StatusCode)
-}
\ No newline at end of file
+	defer resp.Body.Close()
+
+	var createResult map[string]string
+	err = json.NewDecoder(resp.Body).Decode(&createResult)
+	assert.NoError(t, err)
+	addressID := createResult["property_address_id"]
+	assert.NotEmpty(t, addressID)
+
+	// 2. Add coordinates to it
+	patchJSON := `{"coordinates":{"latitude":38.58,"longitude":-121.49}}`
+	req, err = http.NewRequest("PATCH", server.URL+"/api/property-addresses/"+addressID, bytes.NewBufferString(patchJSON))
+	assert.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer valid-token")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err = client.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	defer resp.Body.Close()
+
+	// 3. Fetch it back and verify both the original and patched fields
+	req, err = http.NewRequest("GET", server.URL+"/api/property-addresses/"+addressID, nil)
+	assert.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer valid-token")
+
+	resp, err = client.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	defer resp.Body.Close()
+
+	var address nhd_report.PropertyAddress
+	err = json.NewDecoder(resp.Body).Decode(&address)
+	assert.NoError(t, err)
+	assert.Equal(t, addressID, address.PropertyAddressId)
+	assert.Equal(t, "1 Main St", address.AddressDetails.StreetAddress)
+	assert.Equal(t, 38.58, address.Coordinates.Latitude)
+
+	// 4. List all property addresses
+	req, err = http.NewRequest("GET", server.URL+"/api/property-addresses", nil)
+	assert.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer valid-token")
+
+	resp, err = client.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	defer resp.Body.Close()
+
+	var addresses []*nhd_report.PropertyAddress
+	err = json.NewDecoder(resp.Body).Decode(&addresses)
+	assert.NoError(t, err)
+	assert.Len(t, addresses, 1)
+
+	// 5. Unknown IDs return 404
+	req, err = http.NewRequest("GET", server.URL+"/api/property-addresses/does-not-exist", nil)
+	assert.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer valid-token")
+
+	resp, err = client.Do(req)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	defer resp.Body.Close()
+}
+
+func TestIntegration_CreateReportRun_FindsOrCreatesPropertyAddress(t *testing.T) {
+	server, memDS, _, mockAuth, cleanup := setupIntegrationTestServer()
+	seedCustomers(t, memDS, "cust1", "cust2")
+	defer cleanup()
+
+	mockAuth.On("VerifyIDToken", mock.Anything, "valid-token").Return(&auth.Token{UID: "test-user"}, nil)
+	client := &http.Client{}
+
+	createRun := func(body string) map[string]string {
+		req, err := http.NewRequest("POST", server.URL+"/api/report-runs", bytes.NewBufferString(body))
+		assert.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer valid-token")
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+		var result map[string]string
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		return result
+	}
+
+	// 1. The first order creates the property address
+	first := createRun(`{"customer_id":"cust1","property_address":{"address_details":{"street_address":"123 Main Street","city":"Sacramento","zip_code":"95814"}}}`)
+	assert.NotEmpty(t, first["property_address_id"])
+
+	// 2. A repeat order with a different spelling reuses it and backfills coordinates
+	second := createRun(`{"customer_id":"cust2","property_address":{"address_details":{"street_address":"123 MAIN ST.","city":"sacramento","state":"CA","zip_code":"95814"},"coordinates":{"latitude":38.58,"longitude":-121.49}}}`)
+	assert.Equal(t, first["property_address_id"], second["property_address_id"])
+	assert.NotEqual(t, first["report_run_id"], second["report_run_id"])
+
+	addresses, err := memDS.GetPropertyAddresses(context.Background(), "test-org")
+	assert.NoError(t, err)
+	assert.Len(t, addresses, 1)
+	assert.Equal(t, 38.58, addresses[0].Coordinates.GetLatitude())
+
+	// 3. A different house gets its own record
+	third := createRun(`{"customer_id":"cust1","property_address":{"address_details":{"street_address":"125 Main Street","city":"Sacramento","zip_code":"95814"}}}`)
+	assert.NotEqual(t, first["property_address_id"], third["property_address_id"])
+
+	// 4. Supplying both an ID and an inline address is rejected
+	req, err := http.NewRequest("POST", server.URL+"/api/report-runs", bytes.NewBufferString(`{"property_address_id":"addr1","property_address":{"address_details":{"street_address":"1 A St","city":"B","zip_code":"90000"}}}`))
+	assert.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer valid-token")
+	resp, err := client.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestIntegration_ListReportRuns_FilterAndPaginate(t *testing.T) {
+	server, memDS, _, mockAuth, cleanup := setupIntegrationTestServer()
+	defer cleanup()
+
+	mockAuth.On("VerifyIDToken", mock.Anything, "valid-token").Return(&auth.Token{UID: "test-user"}, nil)
+	client := &http.Client{}
+
+	// Seed five runs for cust1, one day apart, plus one for another customer.
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	var cust1Runs []string
+	for i := 0; i < 5; i++ {
+		run := &nhd_report.ReportRun{CustomerId: "cust1", OrganizationId: "test-org", CreatedAt: timestamppb.New(start.AddDate(0, 0, i))}
+		docRef, _, err := memDS.CreateReportRun(context.Background(), run)
+		assert.NoError(t, err)
+		cust1Runs = append(cust1Runs, docRef.ID)
+	}
+	_, _, err := memDS.CreateReportRun(context.Background(), &nhd_report.ReportRun{CustomerId: "cust2", OrganizationId: "test-org", CreatedAt: timestamppb.New(start)})
+	assert.NoError(t, err)
+
+	listPage := func(query string) interfaces.ReportRunPage {
+		req, err := http.NewRequest("GET", server.URL+"/api/report-runs?"+query, nil)
+		assert.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer valid-token")
+
+		resp, err := client.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var page interfaces.ReportRunPage
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+		return page
+	}
+
+	// Walk all of cust1's runs oldest first, two at a time.
+	var seen []string
+	query := "customer_id=cust1&order_by=created_at&page_size=2"
+	page := listPage(query)
+	for {
+		for _, run := range page.ReportRuns {
+			seen = append(seen, run.ReportRunId)
+		}
+		if page.NextPageToken == "" {
+			break
+		}
+		page = listPage(query + "&page_token=" + page.NextPageToken)
+	}
+	assert.Equal(t, cust1Runs, seen)
+
+	// The default order is newest first, and the date range is half-open.
+	page = listPage("customer_id=cust1&created_after=2025-06-02&created_before=2025-06-04")
+	assert.Len(t, page.ReportRuns, 2)
+	assert.Equal(t, cust1Runs[2], page.ReportRuns[0].ReportRunId)
+	assert.Equal(t, cust1Runs[1], page.ReportRuns[1].ReportRunId)
+	assert.Empty(t, page.NextPageToken)
+}
+
+func TestIntegration_PricingAssignsInitialCost(t *testing.T) {
+	server, memDS, _, mockAuth, cleanup := setupIntegrationTestServer()
+	seedPropertyAddresses(t, memDS, "addr123")
+	defer cleanup()
+
+	mockAuth.On("VerifyIDToken", mock.Anything, "valid-token").Return(&auth.Token{UID: "test-user"}, nil)
+	mockAuth.On("VerifyIDToken", mock.Anything, "valid-admin-token").Return(&auth.Token{UID: "admin-uid"}, nil)
+	assert.NoError(t, memDS.CreateUser(context.Background(), &nhd_report.User{UserId: "admin-uid", Permissions: &nhd_report.Permissions{IsAdmin: true}}))
+	user := apiClient{t, server, "valid-token"}
+	admin := apiClient{t, server, "valid-admin-token"}
+
+	acmeRef, _, err := memDS.CreateCustomer(context.Background(), &nhd_report.Customer{FullName: "Ann Agent", CompanyName: "Acme Realty", OrganizationId: "test-org"})
+	assert.NoError(t, err)
+	soloRef, _, err := memDS.CreateCustomer(context.Background(), &nhd_report.Customer{FullName: "Sam Solo", OrganizationId: "test-org"})
+	assert.NoError(t, err)
+
+	// A new default price and a company override. Scope 1 is DEFAULT and 2 is COMPANY.
+	resp := admin.do("POST", "/admin/pricing", `{"scope":1,"amount":{"currency":"USD","minor_units":5900}}`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	resp.Body.Close()
+	resp = admin.do("POST", "/admin/pricing", `{"scope":2,"scope_value":"Acme Realty","amount":{"currency":"USD","minor_units":3900}}`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	resp.Body.Close()
+
+	// Company rules need a company.
+	resp = admin.do("POST", "/admin/pricing", `{"scope":2,"amount":{"currency":"USD","minor_units":3900}}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp.Body.Close()
+
+	resp = admin.do("GET", "/admin/pricing", "")
+	var rules []*nhd_report.PriceRule
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&rules))
+	resp.Body.Close()
+	assert.Len(t, rules, 2)
+	assert.Equal(t, "admin-uid", rules[0].CreatedByUserId)
+
+	resp = admin.do("GET", "/admin/pricing/quote?customer_id="+acmeRef.ID, "")
+	var quote nhd_report.ReportRun_ReportCost
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&quote))
+	resp.Body.Close()
+	assert.Equal(t, "39.00 USD", money.String(quote.Amount))
+	assert.Equal(t, rules[1].PriceRuleId, quote.PriceRuleId)
+
+	createRun := func(customerID string) *nhd_report.ReportRun {
+		resp := user.do("POST", "/api/report-runs", `{"customer_id":"`+customerID+`","property_address_id":"addr123"}`)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		var result map[string]string
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		run, err := memDS.GetReportRun(context.Background(), result["report_run_id"])
+		assert.NoError(t, err)
+		return run
+	}
+
+	acmeRun := createRun(acmeRef.ID)
+	assert.Len(t, acmeRun.CostHistory, 1)
+	assert.Equal(t, "39.00 USD", money.String(acmeRun.CostHistory[0].Amount))
+	assert.Equal(t, "test-user", acmeRun.CostHistory[0].SetByUserId)
+	assert.Equal(t, nhd_report.ReportRun_Payment_OUTSTANDING, acmeRun.PaymentDetails.GetStatus())
+	assert.Equal(t, "39.00 USD", money.String(acmeRun.PaymentDetails.GetBalanceDue()))
+
+	soloRun := createRun(soloRef.ID)
+	assert.Equal(t, "59.00 USD", money.String(soloRun.CostHistory[0].Amount))
+	assert.Equal(t, rules[0].PriceRuleId, soloRun.CostHistory[0].PriceRuleId)
+
+	// Both new runs now show up as outstanding.
+	resp = user.do("GET", "/api/report-runs?payment_status=OUTSTANDING", "")
+	var page interfaces.ReportRunPage
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+	resp.Body.Close()
+	assert.Len(t, page.ReportRuns, 2)
+}
+
+func TestIntegration_FinancialsSummary_RangeAndGrouping(t *testing.T) {
+	server, memDS, _, mockAuth, cleanup := setupIntegrationTestServer()
+	defer cleanup()
+
+	mockAuth.On("VerifyIDToken", mock.Anything, "valid-token").Return(&auth.Token{UID: "test-user"}, nil)
+	mockAuth.On("VerifyIDToken", mock.Anything, "valid-admin-token").Return(&auth.Token{UID: "admin-uid"}, nil)
+	ctx := context.Background()
+	assert.NoError(t, memDS.CreateUser(ctx, &nhd_report.User{UserId: "admin-uid", Permissions: &nhd_report.Permissions{IsAdmin: true}}))
+
+	aliceRef, _, err := memDS.CreateCustomer(ctx, &nhd_report.Customer{FullName: "Alice Buyer", OrganizationId: "test-org"})
+	assert.NoError(t, err)
+	bobRef, _, err := memDS.CreateCustomer(ctx, &nhd_report.Customer{FullName: "Bob Seller", OrganizationId: "test-org"})
+	assert.NoError(t, err)
+	addrRef, _, err := memDS.CreatePropertyAddress(ctx, &nhd_report.PropertyAddress{
+		AddressDetails: &nhd_report.PropertyAddress_AddressDetails{StreetAddress: "1 Main St", City: "Sacramento", State: "CA", ZipCode: "95814"},
+	})
+	assert.NoError(t, err)
+
+	record := func(reportRunID string, entryType nhd_report.ReportRun_LedgerEntry_Type, amount *nhd_report.Money, at time.Time) {
+		assert.NoError(t, memDS.AppendLedgerEntry(ctx, reportRunID, &nhd_report.ReportRun_LedgerEntry{
+			Type: entryType, Amount: amount, CreatedAt: timestamppb.New(at),
+		}))
+	}
+	pay := func(customerID string, amount *nhd_report.Money, paidAt time.Time) string {
+		docRef, _, err := memDS.CreateReportRun(ctx, &nhd_report.ReportRun{CustomerId: customerID, OrganizationId: "test-org", PropertyAddressId: addrRef.ID})
+		assert.NoError(t, err)
+		record(docRef.ID, nhd_report.ReportRun_LedgerEntry_CHARGE, amount, paidAt)
+		record(docRef.ID, nhd_report.ReportRun_LedgerEntry_PAYMENT, amount, paidAt)
+		return docRef.ID
+	}
+	usd := func(dollars int64) *nhd_report.Money { return money.New("USD", dollars*100) }
+	mayRun := pay(aliceRef.ID, usd(50), time.Date(2025, 5, 31, 12, 0, 0, 0, time.UTC))
+	pay(aliceRef.ID, usd(40), time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+	pay(bobRef.ID, money.New("CAD", 4000), time.Date(2025, 6, 10, 12, 0, 0, 0, time.UTC))
+	pay(bobRef.ID, usd(30), time.Date(2025, 6, 30, 23, 0, 0, 0, time.UTC))
+	pay(aliceRef.ID, usd(20), time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC))
+	// A partial refund in June of a payment made in May.
+	record(mayRun, nhd_report.ReportRun_LedgerEntry_REFUND, usd(10), time.Date(2025, 6, 15, 9, 0, 0, 0, time.UTC))
+
+	// One USD is worth 1.25 CAD, so the CAD payment is worth 32.00 USD.
+	req, err := http.NewRequest("POST", server.URL+"/admin/exchange-rates", bytes.NewBufferString(
+		`{"base_currency":"USD","quote_currency":"CAD","rate":"1.25","effective_from":{"seconds":1735689600}}`))
+	assert.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer valid-admin-token")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	getSummary := func(query string) interfaces.FinancialsSummary {
+		req, err := http.NewRequest("GET", server.URL+"/api/financials/summary?"+query, nil)
+		assert.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer valid-token")
+
+		resp, err := http.DefaultClient.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode, query)
+
+		var summary interfaces.FinancialsSummary
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&summary))
+		return summary
+	}
+
+	// June only, grouped by customer. The refund counts against June even
+	// though its payment was made in May.
+	summary := getSummary("from=2025-06-01&to=2025-06-30&group_by=customer")
+	assert.Equal(t, "USD", summary.Currency)
+	assert.Equal(t, "102.00 USD", money.String(summary.TotalRevenue))
+	assert.Equal(t, "10.00 USD", money.String(summary.TotalRefunds))
+	assert.Equal(t, "92.00 USD", money.String(summary.NetRevenue))
+	assert.Len(t, summary.Totals, 2)
+	assert.Equal(t, "40.00 CAD", money.String(summary.Totals[0].NetRevenue))
+	assert.Equal(t, "60.00 USD", money.String(summary.Totals[1].NetRevenue))
+	assert.Len(t, summary.PaidReports, 4)
+	assert.Equal(t, "Alice Buyer", summary.PaidReports[0].CustomerName)
+	assert.Equal(t, "1 Main St, Sacramento, CA 95814", summary.PaidReports[0].PropertyAddress)
+	assert.Equal(t, "40.00 CAD", money.String(summary.PaidReports[1].AmountPaid))
+	assert.Equal(t, mayRun, summary.PaidReports[2].ReportRunID)
+	assert.Equal(t, "0.00 USD", money.String(summary.PaidReports[2].AmountPaid))
+	assert.Equal(t, "-10.00 USD", money.String(summary.PaidReports[2].NetAmount))
+	assert.Equal(t, "2025-06-15", summary.PaidReports[2].PaidAt)
+	assert.Equal(t, []interfaces.FinancialsGroup{
+		{Key: bobRef.ID, Label: "Bob Seller", Revenue: usd(62), Refunds: usd(0), NetRevenue: usd(62), ReportCount: 2},
+		{Key: aliceRef.ID, Label: "Alice Buyer", Revenue: usd(40), Refunds: usd(10), NetRevenue: usd(30), ReportCount: 2},
+	}, summary.Groups)
+
+	// Everything, grouped by month in date order.
+	summary = getSummary("group_by=month")
+	assert.Equal(t, "172.00 USD", money.String(summary.TotalRevenue))
+	assert.Equal(t, "162.00 USD", money.String(summary.NetRevenue))
+	assert.Equal(t, []interfaces.FinancialsGroup{
+		{Key: "2025-05", Label: "2025-05", Revenue: usd(50), Refunds: usd(0), NetRevenue: usd(50), ReportCount: 1},
+		{Key: "2025-06", Label: "2025-06", Revenue: usd(102), Refunds: usd(10), NetRevenue: usd(92), ReportCount: 4},
+		{Key: "2025-07", Label: "2025-07", Revenue: usd(20), Refunds: usd(0), NetRevenue: usd(20), ReportCount: 1},
+	}, summary.Groups)
+
+	// June again, reported in CAD.
+	summary = getSummary("from=2025-06-01&to=2025-06-30&currency=CAD")
+	assert.Equal(t, "127.50 CAD", money.String(summary.TotalRevenue))
+	assert.Equal(t, "115.00 CAD", money.String(summary.NetRevenue))
+
+	// There is no rate to convert to EUR.
+	req, err = http.NewRequest("GET", server.URL+"/api/financials/summary?currency=EUR", nil)
+	assert.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer valid-token")
+	resp, err = http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+}
+
+func TestIntegration_ReportRunIsProcessedByWorker(t *testing.T) {
+	server, memDS, _, mockAuth, cleanup := setupIntegrationTestServer()
+	seedCustomers(t, memDS, "cust1")
+	defer cleanup()
+
+	mockAuth.On("VerifyIDToken", mock.Anything, "valid-token").Return(&auth.Token{UID: "test-user"}, nil)
+	user := apiClient{t, server, "valid-token"}
+	createAndWait := func(body string) *ReportRunDetail {
+		resp := user.do("POST", "/api/report-runs", body)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		var created map[string]string
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+		resp.Body.Close()
+
+		resp = user.do("GET", "/api/report-runs/"+created["report_run_id"]+"?wait_for_status=COMPLETED&timeout=10s", "")
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var detail ReportRunDetail
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&detail))
+		return &detail
+	}
+
+	// A property inside the mock flood and fire zones.
+	detail := createAndWait(`{"customer_id":"cust1","property_address":{"address_details":{"street_address":"1 Flood Way","city":"San Francisco","zip_code":"94117"},"coordinates":{"latitude":37.785,"longitude":-122.435}}}`)
+	assert.Equal(t, nhd_report.ReportRun_COMPLETED, detail.Status)
+	assert.True(t, detail.Results.GetInSpecialFloodHazardArea())
+	assert.True(t, detail.Results.GetInVeryHighFireHazardSeverityZone())
+	assert.False(t, detail.Results.GetInEarthquakeFaultZone())
+	// Each result comes with the evidence for it.
+	require.Len(t, detail.Results.GetEvidence(), 6)
+	var fire *nhd_report.ReportRun_HazardEvidence
+	for _, evidence := range detail.Results.Evidence {
+		if evidence.Hazard == "very_high_fire_hazard_severity_zone" {
+			fire = evidence
+		}
+	}
+	require.NotNil(t, fire)
+	assert.Equal(t, "CAL FIRE", fire.Agency)
+	require.Len(t, fire.MatchedFeatures, 1)
+	assert.Equal(t, "fire-1", fire.MatchedFeatures[0].FeatureId)
+	assert.Equal(t, "Very High", fire.MatchedFeatures[0].Attributes["HAZ_CLASS"])
+	// Every step of the run's lifecycle is recorded, with who took it.
+	var steps []string
+	for _, change := range detail.StatusHistory {
+		steps = append(steps, change.ToStatus.String()+" by "+change.Actor)
+	}
+	assert.Equal(t, []string{"PENDING by test-user", "PROCESSING by worker", "COMPLETED by worker"}, steps)
+
+	// Without coordinates the run fails, and says why.
+	detail = createAndWait(`{"customer_id":"cust1","property_address":{"address_details":{"street_address":"2 Unknown Rd","city":"San Francisco","zip_code":"94117"}}}`)
+	assert.Equal(t, nhd_report.ReportRun_FAILED, detail.Status)
+	assert.Contains(t, detail.FailureReason, "has no coordinates")
+}
+
+func TestIntegration_TraceIDReachesWorkerMessage(t *testing.T) {
+	server, memDS, broker, mockAuth, cleanup := setupIntegrationTestServer()
+	seedCustomers(t, memDS, "cust1")
+	defer cleanup()
+	require.NoError(t, broker.CreateSubscription("trace-test", mempubsub.SubscriptionConfig{Topic: "nhd-report-requests"}))
+
+	mockAuth.On("VerifyIDToken", mock.Anything, "valid-token").Return(&auth.Token{UID: "test-user"}, nil)
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req, err := http.NewRequest("POST", server.URL+"/api/report-runs", bytes.NewBufferString(`{"customer_id":"cust1","property_address":{"address_details":{"street_address":"1 Trace St","city":"San Francisco","zip_code":"94117"}}}`))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer valid-token")
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.NotEmpty(t, resp.Header.Get("X-Request-ID"))
+	var created map[string]string
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var got *interfaces.Message
+	require.NoError(t, broker.Receive(ctx, "trace-test", 1, func(ctx context.Context, msg *interfaces.Message) {
+		msg.Ack()
+		got = msg
+		cancel()
+	}))
+	require.NotNil(t, got, "timed out waiting for the report request")
+	assert.Equal(t, created["report_run_id"], string(got.Data))
+	assert.Equal(t, traceID, got.Attributes[logging.TraceIDAttribute])
+}
+
+func TestIntegration_IdempotencyKeyPreventsDuplicateRuns(t *testing.T) {
+	server, memDS, _, mockAuth, cleanup := setupIntegrationTestServer()
+	seedCustomers(t, memDS, "cust1", "cust2")
+	seedPropertyAddresses(t, memDS, "addr1")
+	defer cleanup()
+
+	mockAuth.On("VerifyIDToken", mock.Anything, "valid-token").Return(&auth.Token{UID: "test-user"}, nil)
+
+	user := apiClient{t, server, "valid-token"}
+	create := func(body, key string) (*http.Response, map[string]string) {
+		resp := user.do("POST", "/api/report-runs", body, middleware.IdempotencyKeyHeader, key)
+		defer resp.Body.Close()
+		var created map[string]string
+		if resp.StatusCode == http.StatusCreated {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+		}
+		return resp, created
+	}
+	const body = `{"customer_id":"cust1","property_address_id":"addr1"}`
+
+	resp, first := create(body, "retry-1")
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	// The retry gets the original response, and no second run is created.
+	resp, retried := create(body, "retry-1")
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "true", resp.Header.Get(middleware.IdempotentReplayedHeader))
+	assert.Equal(t, first["report_run_id"], retried["report_run_id"])
+
+	page, err := memDS.GetReportRuns(context.Background(), interfaces.ReportRunQuery{})
+	require.NoError(t, err)
+	assert.Len(t, page.ReportRuns, 1)
+
+	// Reusing the key for a different request is a conflict.
+	resp, _ = create(`{"customer_id":"cust2","property_address_id":"addr1"}`, "retry-1")
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+}
+
+func TestIntegration_RetryAndCancelReportRuns(t *testing.T) {
+	server, memDS, _, mockAuth, cleanup := setupIntegrationTestServer()
+	seedCustomers(t, memDS, "cust1")
+	defer cleanup()
+
+	mockAuth.On("VerifyIDToken", mock.Anything, "valid-token").Return(&auth.Token{UID: "test-user"}, nil)
+	user := apiClient{t, server, "valid-token"}
+	decode := func(resp *http.Response, wantStatus int) *nhd_report.ReportRun {
+		defer resp.Body.Close()
+		require.Equal(t, wantStatus, resp.StatusCode)
+		var run nhd_report.ReportRun
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&run))
+		return &run
+	}
+
+	// A run for a property without coordinates fails...
+	resp := user.do("POST", "/api/report-runs", `{"customer_id":"cust1","property_address":{"address_details":{"street_address":"2 Unknown Rd","city":"San Francisco","zip_code":"94117"}}}`)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var created map[string]string
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	resp.Body.Close()
+	runPath := "/api/report-runs/" + created["report_run_id"]
+	run := decode(user.do("GET", runPath+"?wait_for_status=FAILED&timeout=10s", ""), http.StatusOK)
+	require.Equal(t, nhd_report.ReportRun_FAILED, run.Status)
+	assert.EqualValues(t, 1, run.Attempt)
+
+	// ...and, once the coordinates are known, can be retried in place.
+	resp = user.do("PATCH", "/api/property-addresses/"+created["property_address_id"], `{"coordinates":{"latitude":37.785,"longitude":-122.435}}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp.Body.Close()
+	run = decode(user.do("POST", runPath+"/retry", `{"reason":"Coordinates added"}`), http.StatusAccepted)
+	assert.EqualValues(t, 2, run.Attempt)
+	run = decode(user.do("GET", runPath+"?wait_for_status=COMPLETED&timeout=10s", ""), http.StatusOK)
+	require.Equal(t, nhd_report.ReportRun_COMPLETED, run.Status)
+	assert.Empty(t, run.FailureReason)
+	assert.True(t, run.Results.GetInSpecialFloodHazardArea())
+	var steps []string
+	for _, change := range run.StatusHistory {
+		steps = append(steps, change.ToStatus.String()+" by "+change.Actor)
+	}
+	assert.Equal(t, []string{
+		"PENDING by test-user", "PROCESSING by worker", "FAILED by worker",
+		"PENDING by test-user", "PROCESSING by worker", "COMPLETED by worker",
+	}, steps)
+	assert.Equal(t, "Coordinates added", run.StatusHistory[3].Reason)
+
+	// Only FAILED runs can be retried, and only PENDING runs cancelled.
+	resp = user.do("POST", runPath+"/retry", "")
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	resp.Body.Close()
+	resp = user.do("POST", runPath+"/cancel", "")
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	resp.Body.Close()
+
+	// Cancelling an unpaid run voids its cost. The run is never published,
+	// so the worker cannot pick it up first.
+	pendingRef, _, err := memDS.CreateReportRun(context.Background(), &nhd_report.ReportRun{
+		CustomerId:     "cust1",
+		OrganizationId: "test-org",
+		Status:         nhd_report.ReportRun_PENDING,
+		CostHistory:    []*nhd_report.ReportRun_ReportCost{{Amount: money.New("USD", 5000)}},
+		PaymentDetails: &nhd_report.ReportRun_Payment{Status: nhd_report.ReportRun_Payment_OUTSTANDING},
+	})
+	require.NoError(t, err)
+	run = decode(user.do("POST", "/api/report-runs/"+pendingRef.ID+"/cancel", `{"reason":"Ordered twice"}`), http.StatusOK)
+	assert.Equal(t, nhd_report.ReportRun_CANCELLED, run.Status)
+	assert.Equal(t, nhd_report.ReportRun_Payment_VOID, run.PaymentDetails.GetStatus())
+	require.Len(t, run.CostHistory, 2)
+	assert.Equal(t, "0.00 USD", money.String(run.CostHistory[1].Amount))
+	assert.Equal(t, "0.00 USD", money.String(run.PaymentDetails.GetBalanceDue()))
+	assert.Equal(t, "test-user", run.CostHistory[1].SetByUserId)
+	last := run.StatusHistory[len(run.StatusHistory)-1]
+	assert.Equal(t, nhd_report.ReportRun_CANCELLED, last.ToStatus)
+	assert.Equal(t, "Ordered twice", last.Reason)
+
+	resp = user.do("POST", "/api/report-runs/"+pendingRef.ID+"/retry", "")
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	resp.Body.Close()
+}
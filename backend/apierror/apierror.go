@@ -0,0 +1,202 @@
+// Package apierror defines the errors the API reports to clients. Each error
+// has a machine-readable Code and a message that is safe to show; the
+// underlying cause is logged but never sent. Errors are written as RFC 9457
+// problem details (application/problem+json).
+package apierror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/seans3/nhd/backend/interfaces"
+	"github.com/seans3/nhd/backend/logging"
+	"github.com/seans3/nhd/backend/money"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Code classifies an error for clients.
+type Code string
+
+const (
+	// NotFound means the resource does not exist or is not visible to the
+	// caller.
+	NotFound Code = "NOT_FOUND"
+	// ValidationFailed means the request was malformed or invalid.
+	ValidationFailed Code = "VALIDATION_FAILED"
+	// Conflict means the request conflicts with the resource's current
+	// state.
+	Conflict Code = "CONFLICT"
+	// Unauthenticated means the request has no valid credentials.
+	Unauthenticated Code = "UNAUTHENTICATED"
+	// PermissionDenied means the caller may not make the request.
+	PermissionDenied Code = "PERMISSION_DENIED"
+	// RateLimited means the caller has made too many requests.
+	RateLimited Code = "RATE_LIMITED"
+	// UpstreamUnavailable means a service the API depends on, such as the
+	// datastore or the message queue, failed or timed out. The request may
+	// be retried.
+	UpstreamUnavailable Code = "UPSTREAM_UNAVAILABLE"
+	// Internal means an unexpected error.
+	Internal Code = "INTERNAL"
+)
+
+// Status returns the HTTP status code for c.
+func (c Code) Status() int {
+	switch c {
+	case NotFound:
+		return http.StatusNotFound
+	case ValidationFailed:
+		return http.StatusBadRequest
+	case Conflict:
+		return http.StatusConflict
+	case Unauthenticated:
+		return http.StatusUnauthorized
+	case PermissionDenied:
+		return http.StatusForbidden
+	case RateLimited:
+		return http.StatusTooManyRequests
+	case UpstreamUnavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// Error is an error with a Code and a client-safe message.
+type Error struct {
+	Code Code
+	// Message is shown to clients.
+	Message string
+	// Status overrides Code.Status() when non-zero, for the few responses
+	// that need a more specific status, such as 422 or 502.
+	Status int
+	// Err is the underlying cause, if any. It is logged, not shown.
+	Err error
+	// Fields lists the invalid fields of a ValidationFailed request.
+	Fields []FieldError
+}
+
+// FieldError describes one invalid field of a request body.
+type FieldError struct {
+	// Field is the JSON name of the field, with the names of any enclosing
+	// objects, e.g. "property_address.address_details.city".
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// New returns an Error with a message formatted as by fmt.Sprintf.
+func New(code Code, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Wrap returns an Error caused by err, with a message formatted as by
+// fmt.Sprintf.
+func Wrap(code Code, err error, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...), Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// HTTPStatus returns the HTTP status code of the response for e.
+func (e *Error) HTTPStatus() int {
+	if e.Status != 0 {
+		return e.Status
+	}
+	return e.Code.Status()
+}
+
+// From converts err to an Error. Errors that are already Errors are returned
+// as they are. Datastore and Publisher errors are classified by the
+// interfaces sentinels and gRPC status codes they carry; anything else is
+// Internal. Only messages known to be safe are passed on to clients.
+func From(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	switch {
+	case errors.Is(err, interfaces.ErrNotFound):
+		// Datastore implementations wrap ErrNotFound with the kind and ID
+		// of the missing document, e.g. "report run abc: not found".
+		return Wrap(NotFound, err, "%s", err.Error())
+	case errors.Is(err, interfaces.ErrInvalidTransition):
+		// e.g. "report run abc cannot change from COMPLETED to CANCELLED:
+		// invalid status transition".
+		return Wrap(Conflict, err, "%s", err.Error())
+	case errors.Is(err, interfaces.ErrInvalidLedgerEntry):
+		// e.g. "report run abc cannot refund 50.00 USD; 20.00 has been paid
+		// and not refunded: invalid ledger entry".
+		return Wrap(Conflict, err, "%s", err.Error())
+	case errors.Is(err, money.ErrNoExchangeRate):
+		// e.g. "report run abc: converting CAD to USD on 2025-06-01: no
+		// exchange rate".
+		return &Error{Code: ValidationFailed, Message: err.Error(), Status: http.StatusUnprocessableEntity, Err: err}
+	case errors.Is(err, interfaces.ErrInvalidPageToken):
+		return Wrap(ValidationFailed, err, "Invalid page_token")
+	case errors.Is(err, context.DeadlineExceeded):
+		return Wrap(UpstreamUnavailable, err, "The request timed out; try again")
+	case errors.Is(err, context.Canceled):
+		return Wrap(UpstreamUnavailable, err, "The request was cancelled")
+	}
+	switch status.Code(err) {
+	case codes.NotFound:
+		return Wrap(NotFound, err, "Not found")
+	case codes.AlreadyExists, codes.Aborted, codes.FailedPrecondition:
+		return Wrap(Conflict, err, "The request conflicts with a concurrent change; try again")
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted:
+		return Wrap(UpstreamUnavailable, err, "A backing service is unavailable; try again later")
+	}
+	return Wrap(Internal, err, "Internal error")
+}
+
+// Problem is an RFC 9457 problem details object, extended with the error's
+// Code, any invalid fields and the ID of the request.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Code      Code         `json:"code"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+}
+
+// ContentType is the media type of problem details.
+const ContentType = "application/problem+json"
+
+// Write converts err with From and writes it as problem details. Errors
+// with a 5xx status and a cause are logged, using the request's logger.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr := From(err)
+	statusCode := apiErr.HTTPStatus()
+	if statusCode >= 500 && apiErr.Err != nil {
+		logging.FromContext(r.Context()).Error(apiErr.Message, "code", string(apiErr.Code), "error", apiErr.Err)
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(statusCode),
+		Status: statusCode,
+		Detail: apiErr.Message,
+		Code:   apiErr.Code,
+		Errors: apiErr.Fields,
+		// Set by middleware.Logging.
+		RequestID: w.Header().Get("X-Request-ID"),
+	})
+}
@@ -0,0 +1,71 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/seans3/nhd/backend/interfaces"
+	"github.com/seans3/nhd/backend/money"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFrom(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		code    Code
+		message string
+	}{
+		{"not found", fmt.Errorf("report run r1: %w", interfaces.ErrNotFound), NotFound, "report run r1: not found"},
+		{"transition", fmt.Errorf("report run r1 cannot change from COMPLETED to CANCELLED: %w", interfaces.ErrInvalidTransition), Conflict, "report run r1 cannot change from COMPLETED to CANCELLED: invalid status transition"},
+		{"ledger", fmt.Errorf("report run r1 cannot refund 50.00 USD; 20.00 has been paid and not refunded: %w", interfaces.ErrInvalidLedgerEntry), Conflict, "report run r1 cannot refund 50.00 USD; 20.00 has been paid and not refunded: invalid ledger entry"},
+		{"exchange rate", fmt.Errorf("report run r1: converting CAD to USD on 2025-06-01: %w", money.ErrNoExchangeRate), ValidationFailed, "report run r1: converting CAD to USD on 2025-06-01: no exchange rate"},
+		{"page token", interfaces.ErrInvalidPageToken, ValidationFailed, "Invalid page_token"},
+		{"grpc unavailable", status.Error(codes.Unavailable, "connection refused to 10.0.0.1"), UpstreamUnavailable, "A backing service is unavailable; try again later"},
+		{"grpc aborted", status.Error(codes.Aborted, "transaction aborted"), Conflict, "The request conflicts with a concurrent change; try again"},
+		{"unknown", errors.New("secret internal detail"), Internal, "Internal error"},
+		{"api error", New(PermissionDenied, "No"), PermissionDenied, "No"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := From(tt.err)
+			assert.Equal(t, tt.code, got.Code)
+			assert.Equal(t, tt.message, got.Message)
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rec.Header().Set("X-Request-ID", "req-1")
+	req := httptest.NewRequest("GET", "/report-runs/r1", nil)
+
+	Write(rec, req, &Error{Code: ValidationFailed, Message: "Customer has no email address", Status: http.StatusUnprocessableEntity})
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Equal(t, ContentType, rec.Header().Get("Content-Type"))
+	var problem Problem
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+	assert.Equal(t, Problem{
+		Type:      "about:blank",
+		Title:     "Unprocessable Entity",
+		Status:    http.StatusUnprocessableEntity,
+		Detail:    "Customer has no email address",
+		Code:      ValidationFailed,
+		RequestID: "req-1",
+	}, problem)
+}
+
+func TestWrite_HidesCause(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Write(rec, httptest.NewRequest("GET", "/", nil), errors.New("dial tcp 10.0.0.1: refused"))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "10.0.0.1")
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+	"github.com/seans3/nhd/backend/interfaces"
+	"github.com/seans3/nhd/backend/proto/gen/go"
+)
+
+// customerSearchSteps backfills the fields that customer listings filter on
+// for customers stored before archiving and search existed: Archived, which
+// listings require to be false unless archived customers are included, and
+// SearchPrefixes.
+func customerSearchSteps(ctx context.Context, c *firestore.Client, o options) ([]step, error) {
+	return []step{{collection: "customers", migrate: backfillCustomerSearch}}, nil
+}
+
+func backfillCustomerSearch(id string, data map[string]any) (bool, error) {
+	changed := false
+	if _, ok := data["Archived"]; !ok {
+		data["Archived"] = false
+		changed = true
+	}
+	if _, ok := data["SearchPrefixes"]; !ok {
+		data["SearchPrefixes"] = interfaces.CustomerSearchPrefixes(&nhd_report.Customer{
+			FullName:    stringField(data, "FullName"),
+			Email:       stringField(data, "Email"),
+			CompanyName: stringField(data, "CompanyName"),
+		})
+		changed = true
+	}
+	return changed, nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackfillCustomerSearch(t *testing.T) {
+	data := map[string]any{"FullName": "Alice Smith", "Email": "alice@example.com"}
+	changed, err := backfillCustomerSearch("cust1", data)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, false, data["Archived"])
+	assert.Contains(t, data["SearchPrefixes"], "smi")
+	assert.Contains(t, data["SearchPrefixes"], "alice@")
+
+	// A second run changes nothing, and archived customers stay archived.
+	changed, err = backfillCustomerSearch("cust1", data)
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	data = map[string]any{"FullName": "Bob", "Archived": true}
+	changed, err = backfillCustomerSearch("cust2", data)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, true, data["Archived"])
+	assert.Contains(t, data["SearchPrefixes"], "bob")
+}
@@ -0,0 +1,75 @@
+// Command migrate rewrites documents stored in Firestore by older versions of
+// the backend into the shape the current version reads.
+//
+// Each step is idempotent: documents that are already migrated are left
+// alone, so a step can be re-run after a failure. Run with -dry-run first to
+// see how many documents each step would change.
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/seans3/nhd/backend/datastore"
+	"github.com/seans3/nhd/backend/logging"
+)
+
+func main() {
+	stepNames := flag.String("steps", "", "Comma-separated steps to run, in order: "+strings.Join(stepOrder, ", "))
+	organization := flag.String("organization", "", "Organization ID given to records created before organizations existed (organizations step)")
+	dryRun := flag.Bool("dry-run", false, "Count the documents each step would change without writing them")
+	flag.Parse()
+
+	slog.SetDefault(logging.New(os.Stderr, slog.LevelInfo))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	projectID := os.Getenv("GOOGLE_CLOUD_PROJECT")
+	if projectID == "" {
+		fatal("GOOGLE_CLOUD_PROJECT environment variable must be set")
+	}
+	if *stepNames == "" {
+		fatal("Specify the steps to run with -steps", "steps", strings.Join(stepOrder, ","))
+	}
+	var names []string
+	for _, name := range strings.Split(*stepNames, ",") {
+		name = strings.TrimSpace(name)
+		if _, ok := steps[name]; !ok {
+			fatal("Unknown step", "step", name, "steps", strings.Join(stepOrder, ","))
+		}
+		names = append(names, name)
+	}
+
+	dsClient, err := datastore.NewClient(ctx, projectID)
+	if err != nil {
+		fatal("Failed to create datastore client", "error", err)
+	}
+	defer dsClient.Close()
+
+	opts := options{organization: *organization}
+	for _, name := range names {
+		collectionSteps, err := steps[name](ctx, dsClient.Client, opts)
+		if err != nil {
+			fatal("Failed to prepare step", "step", name, "error", err)
+		}
+		for _, s := range collectionSteps {
+			changed, err := runStep(ctx, dsClient.Client, s, *dryRun)
+			if err != nil {
+				fatal("Step failed", "step", name, "collection", s.collection, "changed", changed, "error", err)
+			}
+			slog.Info("Step done", "step", name, "collection", s.collection, "changed", changed, "dry_run", *dryRun)
+		}
+	}
+}
+
+// fatal logs msg and args at error level and exits.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// options are the command-line settings that steps depend on.
+type options struct {
+	// organization receives records that have no organization_id.
+	organization string
+}
+
+// A step rewrites the documents of one collection. Documents are handled as
+// the maps Firestore stores, keyed by the Go field names of the generated
+// proto structs, so that a step can read fields that the current structs no
+// longer have.
+type step struct {
+	collection string
+	// migrate updates data in place and reports whether it changed
+	// anything. It must not change a document it has already migrated.
+	migrate func(id string, data map[string]any) (bool, error)
+}
+
+// steps builds the collection steps of each named step. A builder may read
+// Firestore first, e.g. to collect what its steps need to know about other
+// collections.
+var steps = map[string]func(ctx context.Context, c *firestore.Client, o options) ([]step, error){
+	"organizations":    organizationSteps,
+	"customer-search":  customerSearchSteps,
+	"money":            moneySteps,
+	"last-transaction": lastTransactionSteps,
+}
+
+// stepOrder lists the steps in the order they should be run.
+var stepOrder = []string{"organizations", "customer-search", "money", "last-transaction"}
+
+// runStep applies s to every document of its collection and returns the
+// number of documents changed. Each change is written in a transaction that
+// re-reads the document, so a write made since it was listed is migrated
+// rather than overwritten.
+func runStep(ctx context.Context, c *firestore.Client, s step, dryRun bool) (int, error) {
+	iter := c.Collection(s.collection).Documents(ctx)
+	defer iter.Stop()
+	changed := 0
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			return changed, nil
+		}
+		if err != nil {
+			return changed, err
+		}
+		ok, err := s.migrate(doc.Ref.ID, doc.Data())
+		if err != nil {
+			return changed, fmt.Errorf("%s/%s: %w", s.collection, doc.Ref.ID, err)
+		}
+		if !ok {
+			continue
+		}
+		if !dryRun {
+			err = c.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+				current, err := tx.Get(doc.Ref)
+				if err != nil {
+					return err
+				}
+				data := current.Data()
+				if ok, err := s.migrate(current.Ref.ID, data); err != nil || !ok {
+					return err
+				}
+				return tx.Set(current.Ref, data)
+			})
+			if err != nil {
+				return changed, fmt.Errorf("%s/%s: %w", s.collection, doc.Ref.ID, err)
+			}
+		}
+		changed++
+	}
+}
+
+// stringField returns data[key] if it is a string, or "".
+func stringField(data map[string]any, key string) string {
+	s, _ := data[key].(string)
+	return s
+}
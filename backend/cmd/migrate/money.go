@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"cloud.google.com/go/firestore"
+	"github.com/seans3/nhd/backend/money"
+	"github.com/seans3/nhd/backend/pricing"
+)
+
+// moneySteps converts the amounts of report runs and price rules stored
+// before amounts were Money values, when each was a double of major units
+// beside a Currency field, into Money values. The generated structs decode
+// those doubles as Money and fail, so such documents cannot be read until
+// this step has run.
+func moneySteps(ctx context.Context, c *firestore.Client, o options) ([]step, error) {
+	return []step{
+		{collection: "report_runs", migrate: convertReportRunAmounts},
+		{collection: "price_rules", migrate: convertPriceRuleAmount},
+	}, nil
+}
+
+func convertPriceRuleAmount(id string, data map[string]any) (bool, error) {
+	return convertAmounts(data, pricing.DefaultCurrency, "Amount")
+}
+
+// convertReportRunAmounts converts the amounts of a report run's cost
+// history, ledger and payment details. Entries without a currency of their
+// own, such as the payment details of a run that was never paid, are in the
+// currency of the run's other amounts.
+func convertReportRunAmounts(id string, data map[string]any) (bool, error) {
+	currency := reportRunCurrency(data)
+	changed := false
+	for _, key := range []string{"CostHistory", "Ledger"} {
+		entries, _ := data[key].([]any)
+		for i, entry := range entries {
+			entry, ok := entry.(map[string]any)
+			if !ok {
+				continue
+			}
+			ok, err := convertAmounts(entry, currency, "Amount")
+			if err != nil {
+				return false, fmt.Errorf("%s[%d]: %w", key, i, err)
+			}
+			changed = changed || ok
+		}
+	}
+	if payment, ok := data["PaymentDetails"].(map[string]any); ok {
+		ok, err := convertAmounts(payment, currency, "AmountPaid", "AmountRefunded", "BalanceDue")
+		if err != nil {
+			return false, fmt.Errorf("PaymentDetails: %w", err)
+		}
+		changed = changed || ok
+	}
+	return changed, nil
+}
+
+// reportRunCurrency returns the first currency recorded on a stored report
+// run, whether beside a legacy amount or in a Money value, or
+// pricing.DefaultCurrency if there is none.
+func reportRunCurrency(data map[string]any) string {
+	var entries []any
+	for _, key := range []string{"CostHistory", "Ledger"} {
+		list, _ := data[key].([]any)
+		entries = append(entries, list...)
+	}
+	entries = append(entries, data["PaymentDetails"])
+	for _, entry := range entries {
+		entry, _ := entry.(map[string]any)
+		if currency := stringField(entry, "Currency"); currency != "" {
+			return currency
+		}
+		for _, key := range []string{"Amount", "AmountPaid"} {
+			amount, _ := entry[key].(map[string]any)
+			if currency := stringField(amount, "Currency"); currency != "" {
+				return currency
+			}
+		}
+	}
+	return pricing.DefaultCurrency
+}
+
+// convertAmounts replaces each of the given fields of data that holds a
+// legacy amount with a Money value in data's Currency, or in fallback if it
+// has none, and then removes the Currency field. Fields that are already
+// Money values, or missing, are left alone.
+func convertAmounts(data map[string]any, fallback string, fields ...string) (bool, error) {
+	currency := stringField(data, "Currency")
+	if currency == "" {
+		currency = fallback
+	}
+	changed := false
+	for _, field := range fields {
+		var amount float64
+		switch v := data[field].(type) {
+		case float64:
+			amount = v
+		case int64:
+			amount = float64(v)
+		default:
+			continue
+		}
+		units := math.Round(amount * math.Pow10(money.Exponent(currency)))
+		if math.IsNaN(units) || math.Abs(units) > math.MaxInt64/2 {
+			return false, fmt.Errorf("%s: %v is not an amount of %s", field, amount, currency)
+		}
+		data[field] = map[string]any{"Currency": currency, "MinorUnits": int64(units)}
+		changed = true
+	}
+	if changed {
+		delete(data, "Currency")
+	}
+	return changed, nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func usd(units int64) map[string]any {
+	return map[string]any{"Currency": "USD", "MinorUnits": units}
+}
+
+func TestConvertReportRunAmounts(t *testing.T) {
+	data := map[string]any{
+		"CostHistory": []any{map[string]any{"Amount": 49.99, "Currency": "USD"}},
+		"Ledger":      []any{map[string]any{"Amount": -10.0, "Currency": "USD"}},
+		// A run paid before payments recorded their own currency.
+		"PaymentDetails": map[string]any{"Status": int64(2), "AmountPaid": 19.99, "AmountRefunded": 0.0, "BalanceDue": 20.0},
+	}
+	changed, err := convertReportRunAmounts("run1", data)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	cost := data["CostHistory"].([]any)[0].(map[string]any)
+	assert.Equal(t, usd(4999), cost["Amount"])
+	assert.NotContains(t, cost, "Currency")
+	assert.Equal(t, usd(-1000), data["Ledger"].([]any)[0].(map[string]any)["Amount"])
+	payment := data["PaymentDetails"].(map[string]any)
+	assert.Equal(t, usd(1999), payment["AmountPaid"])
+	assert.Equal(t, usd(0), payment["AmountRefunded"])
+	assert.Equal(t, usd(2000), payment["BalanceDue"])
+
+	// A second run changes nothing.
+	changed, err = convertReportRunAmounts("run1", data)
+	require.NoError(t, err)
+	assert.False(t, changed)
+}
+
+func TestConvertAmounts_UsesCurrencyExponent(t *testing.T) {
+	data := map[string]any{"Amount": 4999.0, "Currency": "JPY"}
+	changed, err := convertPriceRuleAmount("rule1", data)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, map[string]any{"Currency": "JPY", "MinorUnits": int64(4999)}, data["Amount"])
+
+	// Rules without a currency are in the default currency.
+	data = map[string]any{"Amount": 12.5}
+	_, err = convertPriceRuleAmount("rule2", data)
+	require.NoError(t, err)
+	assert.Equal(t, usd(1250), data["Amount"])
+
+	_, err = convertPriceRuleAmount("rule3", map[string]any{"Amount": 1e300})
+	assert.Error(t, err)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// organizationSteps assigns records stored before organizations existed, which
+// have no OrganizationId, to o.organization. A property address is instead
+// given the organization of the report runs that reference it, if they all
+// belong to one.
+func organizationSteps(ctx context.Context, c *firestore.Client, o options) ([]step, error) {
+	if o.organization == "" {
+		return nil, errors.New("the organizations step requires -organization")
+	}
+	owners := addressOrganizations{}
+	iter := c.Collection("report_runs").Documents(ctx)
+	defer iter.Stop()
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		owners.add(doc.Data(), o.organization)
+	}
+	return []step{
+		{collection: "customers", migrate: setOrganization(o.organization)},
+		{collection: "report_runs", migrate: setOrganization(o.organization)},
+		{collection: "property_addresses", migrate: owners.migrate(o.organization)},
+	}, nil
+}
+
+// setOrganization returns a migration that sets a missing OrganizationId to
+// organizationID.
+func setOrganization(organizationID string) func(id string, data map[string]any) (bool, error) {
+	return func(id string, data map[string]any) (bool, error) {
+		if stringField(data, "OrganizationId") != "" {
+			return false, nil
+		}
+		data["OrganizationId"] = organizationID
+		return true, nil
+	}
+}
+
+// addressOrganizations maps each property address ID to the organizations of
+// the report runs that reference it.
+type addressOrganizations map[string]map[string]bool
+
+// add records the organization of run, a stored report run. Runs without one
+// are counted as belonging to fallback, as the organizations step assigns
+// them.
+func (a addressOrganizations) add(run map[string]any, fallback string) {
+	addressID := stringField(run, "PropertyAddressId")
+	if addressID == "" {
+		return
+	}
+	organizationID := stringField(run, "OrganizationId")
+	if organizationID == "" {
+		organizationID = fallback
+	}
+	if a[addressID] == nil {
+		a[addressID] = map[string]bool{}
+	}
+	a[addressID][organizationID] = true
+}
+
+// migrate returns a migration that gives a property address without an
+// OrganizationId the organization of its report runs. Addresses with no runs
+// go to fallback. Addresses used by several organizations, which could
+// happen while addresses were shared, also go to fallback; they are logged so
+// that the other organizations' runs can be re-linked if need be.
+func (a addressOrganizations) migrate(fallback string) func(id string, data map[string]any) (bool, error) {
+	return func(id string, data map[string]any) (bool, error) {
+		if stringField(data, "OrganizationId") != "" {
+			return false, nil
+		}
+		organizationID := fallback
+		switch orgs := a[id]; len(orgs) {
+		case 0:
+		case 1:
+			for org := range orgs {
+				organizationID = org
+			}
+		default:
+			ids := make([]string, 0, len(orgs))
+			for org := range orgs {
+				ids = append(ids, org)
+			}
+			slices.Sort(ids)
+			slog.Warn("Property address is used by several organizations; assigning it to -organization", "property_address_id", id, "organization_ids", ids)
+		}
+		data["OrganizationId"] = organizationID
+		return true, nil
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetOrganization(t *testing.T) {
+	migrate := setOrganization("legacy-org")
+
+	data := map[string]any{"FullName": "Alice"}
+	changed, err := migrate("cust1", data)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "legacy-org", data["OrganizationId"])
+
+	// Records that already have an organization are left alone.
+	data = map[string]any{"FullName": "Bob", "OrganizationId": "org1"}
+	changed, err = migrate("cust2", data)
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, "org1", data["OrganizationId"])
+}
+
+func TestAddressOrganizations(t *testing.T) {
+	owners := addressOrganizations{}
+	owners.add(map[string]any{"PropertyAddressId": "addr1", "OrganizationId": "org1"}, "legacy-org")
+	owners.add(map[string]any{"PropertyAddressId": "addr1", "OrganizationId": "org1"}, "legacy-org")
+	owners.add(map[string]any{"PropertyAddressId": "addr2"}, "legacy-org")
+	owners.add(map[string]any{"PropertyAddressId": "addr3", "OrganizationId": "org1"}, "legacy-org")
+	owners.add(map[string]any{"PropertyAddressId": "addr3", "OrganizationId": "org2"}, "legacy-org")
+	migrate := owners.migrate("legacy-org")
+
+	for id, want := range map[string]string{
+		"addr1": "org1",       // every run is in org1
+		"addr2": "legacy-org", // its run had no organization either
+		"addr3": "legacy-org", // shared by two organizations
+		"addr4": "legacy-org", // no runs
+	} {
+		data := map[string]any{"NormalizedKey": id}
+		changed, err := migrate(id, data)
+		require.NoError(t, err)
+		assert.True(t, changed, id)
+		assert.Equal(t, want, data["OrganizationId"], id)
+	}
+
+	changed, err := migrate("addr1", map[string]any{"OrganizationId": "org2"})
+	require.NoError(t, err)
+	assert.False(t, changed)
+}
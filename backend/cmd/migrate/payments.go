@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/seans3/nhd/backend/proto/gen/go"
+)
+
+// lastTransactionSteps backfills PaymentDetails.LastTransactionAt, which the
+// financials summary finds runs by, on report runs paid before it was
+// recorded. Their latest payment is the one in PaidAt.
+func lastTransactionSteps(ctx context.Context, c *firestore.Client, o options) ([]step, error) {
+	return []step{{collection: "report_runs", migrate: backfillLastTransaction}}, nil
+}
+
+func backfillLastTransaction(id string, data map[string]any) (bool, error) {
+	payment, _ := data["PaymentDetails"].(map[string]any)
+	if payment == nil || payment["LastTransactionAt"] != nil {
+		return false, nil
+	}
+	paidAt, ok := payment["PaidAt"].(time.Time)
+	if !ok {
+		return false, nil
+	}
+	status, _ := payment["Status"].(int64)
+	switch nhd_report.ReportRun_Payment_PaymentStatus(status) {
+	case nhd_report.ReportRun_Payment_PAID, nhd_report.ReportRun_Payment_PARTIALLY_REFUNDED, nhd_report.ReportRun_Payment_REFUNDED:
+		payment["LastTransactionAt"] = paidAt
+		return true, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackfillLastTransaction(t *testing.T) {
+	paidAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	data := map[string]any{"PaymentDetails": map[string]any{"Status": int64(2), "PaidAt": paidAt}}
+	changed, err := backfillLastTransaction("run1", data)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, paidAt, data["PaymentDetails"].(map[string]any)["LastTransactionAt"])
+
+	// A second run changes nothing.
+	changed, err = backfillLastTransaction("run1", data)
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	// Runs that were never paid are left alone.
+	for id, data := range map[string]map[string]any{
+		"outstanding": {"PaymentDetails": map[string]any{"Status": int64(1), "PaidAt": paidAt}},
+		"unpaid":      {"PaymentDetails": map[string]any{"Status": int64(2)}},
+		"new":         {},
+	} {
+		changed, err := backfillLastTransaction(id, data)
+		require.NoError(t, err)
+		assert.False(t, changed, id)
+	}
+}
@@ -0,0 +1,72 @@
+// Command worker consumes report requests from Pub/Sub, evaluates hazards for
+// each report run and writes the results to Firestore.
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/seans3/nhd/backend/datastore"
+	"github.com/seans3/nhd/backend/hazard"
+	"github.com/seans3/nhd/backend/logging"
+	"github.com/seans3/nhd/backend/publisher"
+	"github.com/seans3/nhd/backend/worker"
+)
+
+func main() {
+	subscriptionID := flag.String("subscription", worker.DefaultSubscription, "Pub/Sub subscription to the nhd-report-requests topic")
+	concurrency := flag.Int("concurrency", worker.DefaultConcurrency, "Maximum number of report runs processed at once")
+	hazardDataDir := flag.String("hazard.data-dir", "../reporter/data", "Directory containing the hazard zone GeoJSON layers")
+	var logLevel slog.Level
+	flag.TextVar(&logLevel, "log.level", slog.LevelInfo, "Minimum level of the JSON log lines written to stderr: DEBUG, INFO, WARN or ERROR")
+	flag.Parse()
+
+	slog.SetDefault(logging.New(os.Stderr, logLevel))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	projectID := os.Getenv("GOOGLE_CLOUD_PROJECT")
+	if projectID == "" {
+		fatal("GOOGLE_CLOUD_PROJECT environment variable must be set")
+	}
+
+	hazards, err := hazard.Load(*hazardDataDir)
+	if err != nil {
+		fatal("Failed to load hazard layers", "error", err)
+	}
+
+	dsClient, err := datastore.NewClient(ctx, projectID)
+	if err != nil {
+		fatal("Failed to create datastore client", "error", err)
+	}
+	defer dsClient.Close()
+
+	psClient, err := publisher.NewClient(ctx, projectID)
+	if err != nil {
+		fatal("Failed to create pub/sub client", "error", err)
+	}
+	defer psClient.Close()
+
+	w := &worker.Worker{
+		DS:             dsClient,
+		Sub:            psClient,
+		Hazards:        hazards,
+		SubscriptionID: *subscriptionID,
+		Concurrency:    *concurrency,
+	}
+	if err := w.Run(ctx); err != nil {
+		fatal("Worker stopped", "error", err)
+	}
+	slog.Info("Worker stopped")
+}
+
+// fatal logs msg and args at error level and exits.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
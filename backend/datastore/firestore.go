@@ -2,17 +2,31 @@ package datastore
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
+	"time"
 
 	"cloud.google.com/go/firestore"
 	"github.com/seans3/nhd/backend/interfaces"
+	"github.com/seans3/nhd/backend/logging"
+	"github.com/seans3/nhd/backend/money"
+	"github.com/seans3/nhd/backend/postal"
 	"github.com/seans3/nhd/backend/proto/gen/go"
 	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // Statically assert that our client satisfies the interface.
 var _ interfaces.Datastore = (*Client)(nil)
 
+// Documents are the generated proto structs as the Firestore client encodes
+// them. Those structs have no firestore tags, so each field is stored under
+// its Go name (PaymentDetails, not payment_details), and every field path in
+// a query or update must use the Go names too.
+
 type Client struct {
 	*firestore.Client
 }
@@ -22,17 +36,120 @@ func NewClient(ctx context.Context, projectID string) (*Client, error) {
 	if err != nil {
 		return nil, err
 	}
-	log.Println("Firestore client initialized")
+	slog.Info("Firestore client initialized")
 	return &Client{fsClient}, nil
 }
 
+func (c *Client) CreateOrganization(ctx context.Context, org *nhd_report.Organization) (*firestore.DocumentRef, *firestore.WriteResult, error) {
+	docRef := c.Collection("organizations").NewDoc()
+	if org.OrganizationId != "" {
+		docRef = c.Collection("organizations").Doc(org.OrganizationId)
+	}
+	org.OrganizationId = docRef.ID
+	wr, err := docRef.Create(ctx, org)
+	if err != nil {
+		return nil, nil, err
+	}
+	return docRef, wr, nil
+}
+
+func (c *Client) GetOrganization(ctx context.Context, organizationID string) (*nhd_report.Organization, error) {
+	doc, err := c.Collection("organizations").Doc(organizationID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, fmt.Errorf("organization %s: %w", organizationID, interfaces.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var org nhd_report.Organization
+	if err := doc.DataTo(&org); err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+func (c *Client) organizationsQuery() firestore.Query {
+	return c.Collection("organizations").OrderBy("Name", firestore.Asc)
+}
+
+func (c *Client) GetOrganizations(ctx context.Context) ([]*nhd_report.Organization, error) {
+	var orgs []*nhd_report.Organization
+	iter := c.organizationsQuery().Documents(ctx)
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var org nhd_report.Organization
+		if err := doc.DataTo(&org); err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, &org)
+	}
+	return orgs, nil
+}
+
+// Customers are stored with SearchPrefixes set, so that GetCustomers can
+// find them with an array-contains query. The prefixes are removed when
+// customers are read back.
+
 func (c *Client) CreateCustomer(ctx context.Context, customer *nhd_report.Customer) (*firestore.DocumentRef, *firestore.WriteResult, error) {
-	return c.Collection("customers").Add(ctx, customer)
+	return c.Collection("customers").Add(ctx, customerForWrite(customer))
+}
+
+// UpdateCustomer replaces the stored customer with the given one. The
+// document must already exist.
+func (c *Client) UpdateCustomer(ctx context.Context, customer *nhd_report.Customer) error {
+	docRef := c.Collection("customers").Doc(customer.CustomerId)
+	return c.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if _, err := tx.Get(docRef); err != nil {
+			if status.Code(err) == codes.NotFound {
+				return fmt.Errorf("customer %s: %w", customer.CustomerId, interfaces.ErrNotFound)
+			}
+			return err
+		}
+		return tx.Set(docRef, customerForWrite(customer))
+	})
 }
 
-func (c *Client) GetCustomers(ctx context.Context) ([]*nhd_report.Customer, error) {
-	var customers []*nhd_report.Customer
-	iter := c.Collection("customers").Documents(ctx)
+func customerForWrite(customer *nhd_report.Customer) *nhd_report.Customer {
+	stored := proto.Clone(customer).(*nhd_report.Customer)
+	stored.SearchPrefixes = interfaces.CustomerSearchPrefixes(customer)
+	return stored
+}
+
+func customerFromDoc(doc *firestore.DocumentSnapshot) (*nhd_report.Customer, error) {
+	var customer nhd_report.Customer
+	if err := doc.DataTo(&customer); err != nil {
+		return nil, err
+	}
+	customer.CustomerId = doc.Ref.ID
+	customer.SearchPrefixes = nil
+	return &customer, nil
+}
+
+// GetCustomers lists customers ordered by full_name. Customers stored before
+// archiving was introduced have no Archived field and are not listed until
+// the customer-search step of cmd/migrate has backfilled it.
+func (c *Client) GetCustomers(ctx context.Context, query interfaces.CustomerQuery) (*interfaces.CustomerPage, error) {
+	if err := query.Normalize(); err != nil {
+		return nil, err
+	}
+
+	q := c.customersQuery(query)
+	if query.PageToken != "" {
+		cursor, _ := query.Cursor()
+		q = q.StartAfter(cursor.FullName, cursor.CustomerID)
+	}
+	// Fetch one extra document to find out whether there is another page.
+	q = q.Limit(query.PageSize + 1)
+
+	page := &interfaces.CustomerPage{Customers: []*nhd_report.Customer{}}
+	iter := q.Documents(ctx)
+	defer iter.Stop()
 	for {
 		doc, err := iter.Next()
 		if err == iterator.Done {
@@ -41,31 +158,201 @@ func (c *Client) GetCustomers(ctx context.Context) ([]*nhd_report.Customer, erro
 		if err != nil {
 			return nil, err
 		}
-		var customer nhd_report.Customer
-		if err := doc.DataTo(&customer); err != nil {
+		if len(page.Customers) == query.PageSize {
+			page.NextPageToken = query.NextPageToken(page.Customers[len(page.Customers)-1])
+			break
+		}
+		customer, err := customerFromDoc(doc)
+		if err != nil {
 			return nil, err
 		}
-		customers = append(customers, &customer)
+		page.Customers = append(page.Customers, customer)
 	}
-	return customers, nil
+	return page, nil
+}
+
+// customersQuery filters and orders customers as query asks, without its
+// page bounds.
+func (c *Client) customersQuery(query interfaces.CustomerQuery) firestore.Query {
+	q := c.Collection("customers").Query
+	if query.OrganizationID != "" {
+		q = q.Where("OrganizationId", "==", query.OrganizationID)
+	}
+	if !query.IncludeArchived {
+		q = q.Where("Archived", "==", false)
+	}
+	if query.Search != "" {
+		q = q.Where("SearchPrefixes", "array-contains", query.Search)
+	}
+	return q.OrderBy("FullName", firestore.Asc).OrderBy(firestore.DocumentID, firestore.Asc)
+}
+
+func (c *Client) CreatePropertyAddress(ctx context.Context, address *nhd_report.PropertyAddress) (*firestore.DocumentRef, *firestore.WriteResult, error) {
+	docRef := c.Collection("property_addresses").NewDoc()
+	address.PropertyAddressId = docRef.ID
+	wr, err := docRef.Create(ctx, address)
+	if err != nil {
+		return nil, nil, err
+	}
+	return docRef, wr, nil
+}
+
+func (c *Client) GetPropertyAddress(ctx context.Context, propertyAddressID string) (*nhd_report.PropertyAddress, error) {
+	doc, err := c.Collection("property_addresses").Doc(propertyAddressID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, fmt.Errorf("property address %s: %w", propertyAddressID, interfaces.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var address nhd_report.PropertyAddress
+	if err := doc.DataTo(&address); err != nil {
+		return nil, err
+	}
+	return &address, nil
+}
+
+func (c *Client) propertyAddressesQuery(organizationID string) firestore.Query {
+	q := c.Collection("property_addresses").Query
+	if organizationID != "" {
+		q = q.Where("OrganizationId", "==", organizationID)
+	}
+	return q
+}
+
+func (c *Client) GetPropertyAddresses(ctx context.Context, organizationID string) ([]*nhd_report.PropertyAddress, error) {
+	var addresses []*nhd_report.PropertyAddress
+	iter := c.propertyAddressesQuery(organizationID).Documents(ctx)
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var address nhd_report.PropertyAddress
+		if err := doc.DataTo(&address); err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, &address)
+	}
+	return addresses, nil
+}
+
+// UpdatePropertyAddress replaces the stored property address with the given one.
+// The document must already exist.
+func (c *Client) UpdatePropertyAddress(ctx context.Context, address *nhd_report.PropertyAddress) error {
+	docRef := c.Collection("property_addresses").Doc(address.PropertyAddressId)
+	return c.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		if _, err := tx.Get(docRef); err != nil {
+			if status.Code(err) == codes.NotFound {
+				return fmt.Errorf("property address %s: %w", address.PropertyAddressId, interfaces.ErrNotFound)
+			}
+			return err
+		}
+		return tx.Set(docRef, address)
+	})
+}
+
+func (c *Client) GetCustomer(ctx context.Context, customerID string) (*nhd_report.Customer, error) {
+	doc, err := c.Collection("customers").Doc(customerID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, fmt.Errorf("customer %s: %w", customerID, interfaces.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return customerFromDoc(doc)
 }
 
 func (c *Client) CreateReportRun(ctx context.Context, reportRun *nhd_report.ReportRun) (*firestore.DocumentRef, *firestore.WriteResult, error) {
 	return c.Collection("report_runs").Add(ctx, reportRun)
 }
 
-func (c *Client) GetReportRuns(ctx context.Context, paymentStatusFilter string) ([]*nhd_report.ReportRun, error) {
-	var reportRuns []*nhd_report.ReportRun
-	
-	query := c.Collection("report_runs").Query
-	
-	// Apply filter if one is provided
-	if paymentStatusFilter != "" {
-		// Note: This requires a composite index in Firestore on `payment_details.status`
-		query = query.Where("payment_details.status", "==", paymentStatusFilter)
+func (c *Client) CreateReportRunForAddress(ctx context.Context, reportRun *nhd_report.ReportRun, address *nhd_report.PropertyAddress) (*firestore.DocumentRef, error) {
+	addresses := c.Collection("property_addresses")
+	runRef := c.Collection("report_runs").NewDoc()
+	err := c.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		docs, err := tx.Documents(c.matchingAddressQuery(address).Limit(1)).GetAll()
+		if err != nil {
+			return err
+		}
+		if len(docs) > 0 {
+			var existing nhd_report.PropertyAddress
+			if err := docs[0].DataTo(&existing); err != nil {
+				return err
+			}
+			// Backfill coordinates on records that were created without them.
+			if existing.Coordinates == nil && address.Coordinates != nil {
+				if err := tx.Update(docs[0].Ref, coordinatesUpdates(address.Coordinates)); err != nil {
+					return err
+				}
+			}
+			reportRun.PropertyAddressId = docs[0].Ref.ID
+		} else {
+			addressRef := addresses.NewDoc()
+			address.PropertyAddressId = addressRef.ID
+			if err := tx.Create(addressRef, address); err != nil {
+				return err
+			}
+			reportRun.PropertyAddressId = addressRef.ID
+		}
+		reportRun.ReportRunId = runRef.ID
+		return tx.Create(runRef, reportRun)
+	})
+	if err != nil {
+		return nil, err
 	}
+	return runRef, nil
+}
+
+// matchingAddressQuery finds the stored address in address's organization
+// with the same normalized key. Its equality filters are served by the
+// single-field indexes.
+func (c *Client) matchingAddressQuery(address *nhd_report.PropertyAddress) firestore.Query {
+	return c.Collection("property_addresses").Where("OrganizationId", "==", address.OrganizationId).Where("NormalizedKey", "==", address.NormalizedKey)
+}
 
-	iter := query.Documents(ctx)
+func coordinatesUpdates(coordinates *nhd_report.PropertyAddress_Coordinates) []firestore.Update {
+	return []firestore.Update{{Path: "Coordinates", Value: coordinates}}
+}
+
+func (c *Client) GetReportRun(ctx context.Context, reportRunID string) (*nhd_report.ReportRun, error) {
+	doc, err := c.Collection("report_runs").Doc(reportRunID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, fmt.Errorf("report run %s: %w", reportRunID, interfaces.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var reportRun nhd_report.ReportRun
+	if err := doc.DataTo(&reportRun); err != nil {
+		return nil, err
+	}
+	reportRun.ReportRunId = doc.Ref.ID
+	return &reportRun, nil
+}
+
+// GetReportRuns returns one page of report runs matching the query.
+// Note: Each combination of filters and ordering used by the API requires a
+// composite index in Firestore.
+func (c *Client) GetReportRuns(ctx context.Context, query interfaces.ReportRunQuery) (*interfaces.ReportRunPage, error) {
+	if err := query.Normalize(); err != nil {
+		return nil, err
+	}
+
+	q := c.reportRunsQuery(query)
+	if query.PageToken != "" {
+		cursor, _ := query.Cursor()
+		q = q.StartAfter(cursor.Value, cursor.ReportRunID)
+	}
+	// Fetch one extra document to find out whether there is another page.
+	q = q.Limit(query.PageSize + 1)
+
+	page := &interfaces.ReportRunPage{ReportRuns: []*nhd_report.ReportRun{}}
+	iter := q.Documents(ctx)
+	defer iter.Stop()
 	for {
 		doc, err := iter.Next()
 		if err == iterator.Done {
@@ -74,41 +361,210 @@ func (c *Client) GetReportRuns(ctx context.Context, paymentStatusFilter string)
 		if err != nil {
 			return nil, err
 		}
+		if len(page.ReportRuns) == query.PageSize {
+			page.NextPageToken = query.NextPageToken(page.ReportRuns[len(page.ReportRuns)-1])
+			break
+		}
 		var reportRun nhd_report.ReportRun
 		if err := doc.DataTo(&reportRun); err != nil {
-			log.Printf("Failed to unmarshal report run: %v", err)
-			continue
+			return nil, fmt.Errorf("report run %s: %w", doc.Ref.ID, err)
 		}
-		reportRuns = append(reportRuns, &reportRun)
+		reportRun.ReportRunId = doc.Ref.ID
+		page.ReportRuns = append(page.ReportRuns, &reportRun)
 	}
-	return reportRuns, nil
+	return page, nil
 }
 
-func (c *Client) UpdateReportCost(ctx context.Context, reportRunID string, newCost *nhd_report.ReportRun_ReportCost) error {
+// reportRunsQuery filters and orders report runs as query asks, without its
+// page bounds.
+func (c *Client) reportRunsQuery(query interfaces.ReportRunQuery) firestore.Query {
+	q := c.Collection("report_runs").Query
+	if query.OrganizationID != "" {
+		q = q.Where("OrganizationId", "==", query.OrganizationID)
+	}
+	if query.CustomerID != "" {
+		q = q.Where("CustomerId", "==", query.CustomerID)
+	}
+	if query.CreatedByUserID != "" {
+		q = q.Where("CreatedByUserId", "==", query.CreatedByUserID)
+	}
+	if query.Status != nhd_report.ReportRun_STATUS_UNSPECIFIED {
+		q = q.Where("Status", "==", query.Status)
+	}
+	if query.PaymentStatus != nhd_report.ReportRun_Payment_PAYMENT_STATUS_UNSPECIFIED {
+		q = q.Where("PaymentDetails.Status", "==", query.PaymentStatus)
+	}
+	if !query.CreatedAfter.IsZero() {
+		q = q.Where("CreatedAt", ">=", query.CreatedAfter)
+	}
+	if !query.CreatedBefore.IsZero() {
+		q = q.Where("CreatedAt", "<", query.CreatedBefore)
+	}
+
+	field, descending := query.OrderField()
+	direction := firestore.Asc
+	if descending {
+		direction = firestore.Desc
+	}
+	return q.OrderBy(reportRunOrderPaths[field], direction).OrderBy(firestore.DocumentID, direction)
+}
+
+// reportRunOrderPaths maps each ReportRunQuery order_by field to its path in
+// a stored report run.
+var reportRunOrderPaths = map[string]string{
+	interfaces.ReportRunOrderByCreatedAt: "CreatedAt",
+	interfaces.ReportRunOrderByUpdatedAt: "UpdatedAt",
+}
+
+func (c *Client) AppendEmailDelivery(ctx context.Context, reportRunID string, delivery *nhd_report.ReportRun_EmailDelivery) error {
 	reportRunRef := c.Collection("report_runs").Doc(reportRunID)
+	_, err := reportRunRef.Update(ctx, emailDeliveryUpdates(delivery))
+	if status.Code(err) == codes.NotFound {
+		return fmt.Errorf("report run %s: %w", reportRunID, interfaces.ErrNotFound)
+	}
+	return err
+}
+
+func emailDeliveryUpdates(delivery *nhd_report.ReportRun_EmailDelivery) []firestore.Update {
+	return []firestore.Update{
+		{Path: "EmailDeliveries", Value: firestore.ArrayUnion(delivery)},
+		{Path: "UpdatedAt", Value: timestamppb.Now()},
+	}
+}
+
+func (c *Client) UpdateReportRunStatus(ctx context.Context, reportRunID string, status nhd_report.ReportRun_Status, actor, reason string) error {
+	return c.changeReportRunStatus(ctx, reportRunID, status, actor, reason, nil)
+}
+
+func (c *Client) CompleteReportRun(ctx context.Context, reportRunID string, results *nhd_report.ReportRun_HazardResults, actor string) error {
+	return c.changeReportRunStatus(ctx, reportRunID, nhd_report.ReportRun_COMPLETED, actor, "", resultsUpdates(results))
+}
+
+func resultsUpdates(results *nhd_report.ReportRun_HazardResults) []firestore.Update {
+	return []firestore.Update{{Path: "Results", Value: results}}
+}
 
+// changeReportRunStatus checks and applies a status change, together with
+// any other updates, in a transaction, so that concurrent changes cannot
+// both succeed.
+func (c *Client) changeReportRunStatus(ctx context.Context, reportRunID string, to nhd_report.ReportRun_Status, actor, reason string, updates []firestore.Update) error {
+	docRef := c.Collection("report_runs").Doc(reportRunID)
 	return c.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
-		return tx.Update(reportRunRef, []firestore.Update{
-			{Path: "cost_history", Value: firestore.ArrayUnion(newCost)},
-		})
+		doc, err := tx.Get(docRef)
+		if status.Code(err) == codes.NotFound {
+			return fmt.Errorf("report run %s: %w", reportRunID, interfaces.ErrNotFound)
+		}
+		if err != nil {
+			return err
+		}
+		var reportRun nhd_report.ReportRun
+		if err := doc.DataTo(&reportRun); err != nil {
+			return err
+		}
+		reportRun.ReportRunId = reportRunID
+		change, err := interfaces.ChangeReportRunStatus(&reportRun, to, actor, reason, time.Now())
+		if err != nil {
+			return err
+		}
+		return tx.Update(docRef, append(updates, statusUpdates(&reportRun, change)...))
 	})
 }
 
-func (c *Client) RecordReportPayment(ctx context.Context, reportRunID string, payment *nhd_report.ReportRun_Payment) error {
-	reportRunRef := c.Collection("report_runs").Doc(reportRunID)
+// statusUpdates writes back what a status change made to reportRun changes.
+func statusUpdates(reportRun *nhd_report.ReportRun, change *nhd_report.ReportRun_StatusChange) []firestore.Update {
+	return []firestore.Update{
+		{Path: "Status", Value: reportRun.Status},
+		{Path: "FailureReason", Value: reportRun.FailureReason},
+		{Path: "StatusHistory", Value: firestore.ArrayUnion(change)},
+		{Path: "UpdatedAt", Value: reportRun.UpdatedAt},
+		// Retries and cancellations also change these.
+		{Path: "Attempt", Value: reportRun.Attempt},
+		{Path: "CostHistory", Value: reportRun.CostHistory},
+		{Path: "Ledger", Value: reportRun.Ledger},
+		{Path: "PaymentDetails", Value: reportRun.PaymentDetails},
+	}
+}
+
+func (c *Client) UpdateReportCost(ctx context.Context, reportRunID string, newCost *nhd_report.ReportRun_ReportCost) error {
+	return c.changeLedger(ctx, reportRunID, func(reportRun *nhd_report.ReportRun) error {
+		return interfaces.ChangeReportCost(reportRun, newCost)
+	})
+}
+
+func (c *Client) AppendLedgerEntry(ctx context.Context, reportRunID string, entry *nhd_report.ReportRun_LedgerEntry) error {
+	return c.changeLedger(ctx, reportRunID, func(reportRun *nhd_report.ReportRun) error {
+		return interfaces.AppendLedgerEntry(reportRun, entry)
+	})
+}
+
+// changeLedger applies change to a report run in a transaction and writes
+// back its cost history, ledger and payment details, so that concurrent
+// payments and refunds are each checked against the balance the other
+// leaves.
+func (c *Client) changeLedger(ctx context.Context, reportRunID string, change func(*nhd_report.ReportRun) error) error {
+	docRef := c.Collection("report_runs").Doc(reportRunID)
 	return c.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
-		return tx.Update(reportRunRef, []firestore.Update{
-			{Path: "payment_details", Value: payment},
-		})
+		doc, err := tx.Get(docRef)
+		if status.Code(err) == codes.NotFound {
+			return fmt.Errorf("report run %s: %w", reportRunID, interfaces.ErrNotFound)
+		}
+		if err != nil {
+			return err
+		}
+		var reportRun nhd_report.ReportRun
+		if err := doc.DataTo(&reportRun); err != nil {
+			return err
+		}
+		reportRun.ReportRunId = reportRunID
+		if err := change(&reportRun); err != nil {
+			return err
+		}
+		return tx.Update(docRef, ledgerUpdates(&reportRun))
 	})
 }
 
-func (c *Client) GetPaidReportsSummary(ctx context.Context) (*interfaces.FinancialsSummary, error) {
-	summary := &interfaces.FinancialsSummary{}
-	var totalRevenue float64
+// ledgerUpdates writes back what a ledger change made to reportRun changes.
+func ledgerUpdates(reportRun *nhd_report.ReportRun) []firestore.Update {
+	return []firestore.Update{
+		{Path: "CostHistory", Value: reportRun.CostHistory},
+		{Path: "Ledger", Value: reportRun.Ledger},
+		{Path: "PaymentDetails", Value: reportRun.PaymentDetails},
+		{Path: "UpdatedAt", Value: reportRun.UpdatedAt},
+	}
+}
+
+// paidReportsQuery finds report runs whose latest payment or refund is in or
+// after query's range. Without a start, the lower bound still skips runs
+// that have never been paid, whose LastTransactionAt is null. Runs paid
+// before LastTransactionAt was recorded are found once the last-transaction
+// step of cmd/migrate has backfilled it.
+func (c *Client) paidReportsQuery(query interfaces.FinancialsQuery) firestore.Query {
+	q := c.Collection("report_runs").Query
+	if query.OrganizationID != "" {
+		q = q.Where("OrganizationId", "==", query.OrganizationID)
+	}
+	if !query.PaidFrom.IsZero() {
+		q = q.Where("PaymentDetails.LastTransactionAt", ">=", query.PaidFrom)
+	} else {
+		q = q.Where("PaymentDetails.LastTransactionAt", ">", time.Unix(0, 0))
+	}
+	return q.OrderBy("PaymentDetails.LastTransactionAt", firestore.Asc)
+}
+
+func (c *Client) GetPaidReportsSummary(ctx context.Context, query interfaces.FinancialsQuery) (*interfaces.FinancialsSummary, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	// A later refund can move a report's latest transaction past the
+	// range, so the end of the range and the individual transactions are
+	// checked below.
+	q := c.paidReportsQuery(query)
 
-	// Query for paid reports
-	iter := c.Collection("report_runs").Where("payment_details.status", "==", nhd_report.ReportRun_Payment_PAID).Documents(ctx)
+	var reportRuns []*nhd_report.ReportRun
+	var paidReports []interfaces.PaidReportInfo
+	iter := q.Documents(ctx)
+	defer iter.Stop()
 	for {
 		doc, err := iter.Next()
 		if err == iterator.Done {
@@ -120,33 +576,179 @@ func (c *Client) GetPaidReportsSummary(ctx context.Context) (*interfaces.Financi
 
 		var reportRun nhd_report.ReportRun
 		if err := doc.DataTo(&reportRun); err != nil {
-			// Log the error but continue if possible
-			log.Printf("Failed to unmarshal report run: %v", err)
+			// Skipping the run would silently understate the totals.
+			return nil, fmt.Errorf("report run %s: %w", doc.Ref.ID, err)
+		}
+		reportRun.ReportRunId = doc.Ref.ID
+		paidReport, ok := query.NewPaidReportInfo(&reportRun)
+		if !ok {
 			continue
 		}
+		reportRuns = append(reportRuns, &reportRun)
+		paidReports = append(paidReports, paidReport)
+	}
 
-		if reportRun.PaymentDetails != nil {
-			totalRevenue += reportRun.PaymentDetails.AmountPaid
+	// Resolve customer and address names with one batched read per collection.
+	customerNames, err := c.customerNames(ctx, reportRuns)
+	if err != nil {
+		return nil, err
+	}
+	addresses, err := c.formattedAddresses(ctx, reportRuns)
+	if err != nil {
+		return nil, err
+	}
 
-			// For simplicity, we are not fetching customer and address details in this example.
-			// In a real application, you would fetch the customer and property address documents
-			// using the IDs from the reportRun to get the full name and address string.
-			paidReport := interfaces.PaidReportInfo{
-				CustomerName:      "Customer " + reportRun.CustomerId, // Placeholder
-				PropertyAddress:   "Address for " + reportRun.PropertyAddressId, // Placeholder
-				AmountPaid:        reportRun.PaymentDetails.AmountPaid,
-				PaidAt:            reportRun.PaymentDetails.PaidAt.AsTime().Format("2006-01-02"),
-			}
-			summary.PaidReports = append(summary.PaidReports, paidReport)
+	for i := range paidReports {
+		paidReports[i].CustomerName = customerNames[paidReports[i].CustomerID]
+		paidReports[i].PropertyAddress = addresses[paidReports[i].PropertyAddressID]
+	}
+	rates, err := c.GetExchangeRates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return query.NewFinancialsSummary(paidReports, money.NewConverter(rates))
+}
+
+// customerNames returns the full name of each customer referenced by
+// reportRuns, keyed by customer ID. Missing customers are omitted.
+func (c *Client) customerNames(ctx context.Context, reportRuns []*nhd_report.ReportRun) (map[string]string, error) {
+	refs := uniqueRefs(c.Collection("customers"), reportRuns, (*nhd_report.ReportRun).GetCustomerId)
+	if len(refs) == 0 {
+		return map[string]string{}, nil
+	}
+	docs, err := c.GetAll(ctx, refs)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(docs))
+	for _, doc := range docs {
+		if !doc.Exists() {
+			continue
+		}
+		var customer nhd_report.Customer
+		if err := doc.DataTo(&customer); err != nil {
+			logging.FromContext(ctx).Error("Failed to unmarshal customer", "customer_id", doc.Ref.ID, "error", err)
+			continue
+		}
+		names[doc.Ref.ID] = customer.FullName
+	}
+	return names, nil
+}
+
+// formattedAddresses returns the display form of each property address
+// referenced by reportRuns, keyed by property address ID. Missing addresses
+// are omitted.
+func (c *Client) formattedAddresses(ctx context.Context, reportRuns []*nhd_report.ReportRun) (map[string]string, error) {
+	refs := uniqueRefs(c.Collection("property_addresses"), reportRuns, (*nhd_report.ReportRun).GetPropertyAddressId)
+	if len(refs) == 0 {
+		return map[string]string{}, nil
+	}
+	docs, err := c.GetAll(ctx, refs)
+	if err != nil {
+		return nil, err
+	}
+	addresses := make(map[string]string, len(docs))
+	for _, doc := range docs {
+		if !doc.Exists() {
+			continue
+		}
+		var address nhd_report.PropertyAddress
+		if err := doc.DataTo(&address); err != nil {
+			logging.FromContext(ctx).Error("Failed to unmarshal property address", "property_address_id", doc.Ref.ID, "error", err)
+			continue
 		}
+		addresses[doc.Ref.ID] = postal.Format(address.GetAddressDetails())
 	}
+	return addresses, nil
+}
+
+// uniqueRefs returns a reference into collection for each distinct, non-empty
+// ID that id extracts from reportRuns.
+func uniqueRefs(collection *firestore.CollectionRef, reportRuns []*nhd_report.ReportRun, id func(*nhd_report.ReportRun) string) []*firestore.DocumentRef {
+	seen := map[string]bool{}
+	var refs []*firestore.DocumentRef
+	for _, reportRun := range reportRuns {
+		if v := id(reportRun); v != "" && !seen[v] {
+			seen[v] = true
+			refs = append(refs, collection.Doc(v))
+		}
+	}
+	return refs
+}
+
+func (c *Client) CreatePriceRule(ctx context.Context, rule *nhd_report.PriceRule) (*firestore.DocumentRef, *firestore.WriteResult, error) {
+	docRef := c.Collection("price_rules").NewDoc()
+	rule.PriceRuleId = docRef.ID
+	wr, err := docRef.Create(ctx, rule)
+	if err != nil {
+		return nil, nil, err
+	}
+	return docRef, wr, nil
+}
 
-	summary.TotalRevenue = totalRevenue
-	return summary, nil
+func (c *Client) priceRulesQuery() firestore.Query {
+	return c.Collection("price_rules").OrderBy("EffectiveFrom", firestore.Asc)
+}
+
+func (c *Client) GetPriceRules(ctx context.Context) ([]*nhd_report.PriceRule, error) {
+	var rules []*nhd_report.PriceRule
+	iter := c.priceRulesQuery().Documents(ctx)
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var rule nhd_report.PriceRule
+		if err := doc.DataTo(&rule); err != nil {
+			return nil, err
+		}
+		rules = append(rules, &rule)
+	}
+	return rules, nil
+}
+
+func (c *Client) CreateExchangeRate(ctx context.Context, rate *nhd_report.ExchangeRate) (*firestore.DocumentRef, *firestore.WriteResult, error) {
+	docRef := c.Collection("exchange_rates").NewDoc()
+	rate.ExchangeRateId = docRef.ID
+	wr, err := docRef.Create(ctx, rate)
+	if err != nil {
+		return nil, nil, err
+	}
+	return docRef, wr, nil
+}
+
+func (c *Client) exchangeRatesQuery() firestore.Query {
+	return c.Collection("exchange_rates").OrderBy("EffectiveFrom", firestore.Asc)
+}
+
+func (c *Client) GetExchangeRates(ctx context.Context) ([]*nhd_report.ExchangeRate, error) {
+	var rates []*nhd_report.ExchangeRate
+	iter := c.exchangeRatesQuery().Documents(ctx)
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var rate nhd_report.ExchangeRate
+		if err := doc.DataTo(&rate); err != nil {
+			return nil, err
+		}
+		rates = append(rates, &rate)
+	}
+	return rates, nil
 }
 
 func (c *Client) GetUserByID(ctx context.Context, uid string) (*nhd_report.User, error) {
 	doc, err := c.Collection("users").Doc(uid).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, fmt.Errorf("user %s: %w", uid, interfaces.ErrNotFound)
+	}
 	if err != nil {
 		return nil, err
 	}
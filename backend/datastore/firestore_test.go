@@ -0,0 +1,355 @@
+package datastore
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	"github.com/google/uuid"
+	"github.com/seans3/nhd/backend/interfaces"
+	"github.com/seans3/nhd/backend/money"
+	"github.com/seans3/nhd/backend/proto/gen/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// newEmulatorClient connects to the Firestore emulator named by
+// FIRESTORE_EMULATOR_HOST, e.g. one started with
+// "gcloud emulators firestore start --host-port=localhost:8086". The test is
+// skipped if no emulator is configured.
+func newEmulatorClient(t *testing.T) *Client {
+	t.Helper()
+	if os.Getenv("FIRESTORE_EMULATOR_HOST") == "" {
+		t.Skip("FIRESTORE_EMULATOR_HOST is not set")
+	}
+	c, err := NewClient(context.Background(), "nhd-test")
+	require.NoError(t, err)
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// newReportRun returns a PENDING report run in organizationID, charged
+// amount, as CreateReportRun builds it.
+func newReportRun(t *testing.T, organizationID, customerID string, amount *nhd_report.Money) *nhd_report.ReportRun {
+	t.Helper()
+	now := timestamppb.Now()
+	reportRun := &nhd_report.ReportRun{
+		OrganizationId: organizationID,
+		CustomerId:     customerID,
+		Status:         nhd_report.ReportRun_PENDING,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		Attempt:        1,
+		StatusHistory:  []*nhd_report.ReportRun_StatusChange{{ToStatus: nhd_report.ReportRun_PENDING, ChangedAt: now, Actor: "test"}},
+	}
+	require.NoError(t, interfaces.AppendLedgerEntry(reportRun, &nhd_report.ReportRun_LedgerEntry{
+		Type:      nhd_report.ReportRun_LedgerEntry_CHARGE,
+		Amount:    amount,
+		CreatedAt: now,
+	}))
+	reportRun.CostHistory = []*nhd_report.ReportRun_ReportCost{{Amount: amount, SetAt: now}}
+	return reportRun
+}
+
+// The tests below write each field that is later updated or queried by
+// path, and read it back, so that a path that does not match the stored
+// field name fails.
+
+func TestFirestore_StatusChangesAreStored(t *testing.T) {
+	c := newEmulatorClient(t)
+	ctx := context.Background()
+	org := uuid.NewString()
+
+	ref, _, err := c.CreateReportRun(ctx, newReportRun(t, org, "cust", money.New("USD", 4900)))
+	require.NoError(t, err)
+	require.NoError(t, c.UpdateReportRunStatus(ctx, ref.ID, nhd_report.ReportRun_PROCESSING, "worker", ""))
+	require.NoError(t, c.UpdateReportRunStatus(ctx, ref.ID, nhd_report.ReportRun_FAILED, "worker", "no coordinates"))
+
+	run, err := c.GetReportRun(ctx, ref.ID)
+	require.NoError(t, err)
+	assert.Equal(t, nhd_report.ReportRun_FAILED, run.Status)
+	assert.Equal(t, "no coordinates", run.FailureReason)
+	require.Len(t, run.StatusHistory, 3)
+	assert.Equal(t, nhd_report.ReportRun_FAILED, run.StatusHistory[2].ToStatus)
+
+	// A retry reads the stored history and attempt.
+	require.NoError(t, c.UpdateReportRunStatus(ctx, ref.ID, nhd_report.ReportRun_PENDING, "user", "retry"))
+	run, err = c.GetReportRun(ctx, ref.ID)
+	require.NoError(t, err)
+	assert.Empty(t, run.FailureReason)
+	assert.Equal(t, int32(2), run.Attempt)
+	assert.Len(t, run.StatusHistory, 4)
+
+	// Cancelling an unpaid run voids its cost.
+	require.NoError(t, c.UpdateReportRunStatus(ctx, ref.ID, nhd_report.ReportRun_CANCELLED, "user", "not needed"))
+	run, err = c.GetReportRun(ctx, ref.ID)
+	require.NoError(t, err)
+	require.Len(t, run.CostHistory, 2)
+	assert.Zero(t, run.CostHistory[1].Amount.GetMinorUnits())
+	assert.Len(t, run.Ledger, 2)
+	assert.Equal(t, nhd_report.ReportRun_Payment_VOID, run.PaymentDetails.GetStatus())
+}
+
+func TestFirestore_ResultsAreStored(t *testing.T) {
+	c := newEmulatorClient(t)
+	ctx := context.Background()
+
+	ref, _, err := c.CreateReportRun(ctx, &nhd_report.ReportRun{OrganizationId: uuid.NewString(), Status: nhd_report.ReportRun_PROCESSING})
+	require.NoError(t, err)
+	require.NoError(t, c.CompleteReportRun(ctx, ref.ID, &nhd_report.ReportRun_HazardResults{
+		InSeismicHazardZone: true,
+		Evidence:            []*nhd_report.ReportRun_HazardEvidence{{Hazard: "seismic_hazard_zone", InZone: true}},
+	}, "worker"))
+	require.NoError(t, c.AppendEmailDelivery(ctx, ref.ID, &nhd_report.ReportRun_EmailDelivery{Recipient: "a@example.com"}))
+
+	run, err := c.GetReportRun(ctx, ref.ID)
+	require.NoError(t, err)
+	assert.Equal(t, nhd_report.ReportRun_COMPLETED, run.Status)
+	assert.True(t, run.GetResults().GetInSeismicHazardZone())
+	assert.Len(t, run.GetResults().GetEvidence(), 1)
+	assert.Len(t, run.EmailDeliveries, 1)
+}
+
+func TestFirestore_LedgerIsStored(t *testing.T) {
+	c := newEmulatorClient(t)
+	ctx := context.Background()
+	org := uuid.NewString()
+
+	ref, _, err := c.CreateReportRun(ctx, newReportRun(t, org, "cust", money.New("USD", 4900)))
+	require.NoError(t, err)
+	require.NoError(t, c.AppendLedgerEntry(ctx, ref.ID, &nhd_report.ReportRun_LedgerEntry{
+		Type: nhd_report.ReportRun_LedgerEntry_PAYMENT, Amount: money.New("USD", 4900), CreatedAt: timestamppb.Now(),
+	}))
+	require.NoError(t, c.AppendLedgerEntry(ctx, ref.ID, &nhd_report.ReportRun_LedgerEntry{
+		Type: nhd_report.ReportRun_LedgerEntry_REFUND, Amount: money.New("USD", 900), CreatedAt: timestamppb.Now(), Reason: "goodwill",
+	}))
+	// The second refund is checked against the stored ledger, which has
+	// only 40.00 USD left to refund.
+	err = c.AppendLedgerEntry(ctx, ref.ID, &nhd_report.ReportRun_LedgerEntry{
+		Type: nhd_report.ReportRun_LedgerEntry_REFUND, Amount: money.New("USD", 4001), CreatedAt: timestamppb.Now(), Reason: "too much",
+	})
+	assert.ErrorIs(t, err, interfaces.ErrInvalidLedgerEntry)
+
+	run, err := c.GetReportRun(ctx, ref.ID)
+	require.NoError(t, err)
+	assert.Len(t, run.Ledger, 3)
+	assert.Equal(t, nhd_report.ReportRun_Payment_PARTIALLY_REFUNDED, run.PaymentDetails.GetStatus())
+	assert.Equal(t, "40.00 USD", money.String(run.PaymentDetails.GetAmountPaid()))
+	assert.NotNil(t, run.PaymentDetails.GetLastTransactionAt())
+
+	require.NoError(t, c.UpdateReportCost(ctx, ref.ID, &nhd_report.ReportRun_ReportCost{Amount: money.New("USD", 5900), SetAt: timestamppb.Now()}))
+	run, err = c.GetReportRun(ctx, ref.ID)
+	require.NoError(t, err)
+	assert.Len(t, run.CostHistory, 2)
+	assert.Equal(t, "10.00 USD", money.String(run.PaymentDetails.GetBalanceDue()))
+
+	// The summary finds the run by its last transaction.
+	_, _, err = c.CreateExchangeRate(ctx, &nhd_report.ExchangeRate{
+		BaseCurrency: "USD", QuoteCurrency: "CAD", Rate: "1.25", EffectiveFrom: timestamppb.New(time.Now().Add(-time.Hour)),
+	})
+	require.NoError(t, err)
+	summary, err := c.GetPaidReportsSummary(ctx, interfaces.FinancialsQuery{OrganizationID: org, Currency: "CAD"})
+	require.NoError(t, err)
+	require.Len(t, summary.PaidReports, 1)
+	assert.Equal(t, "50.00 CAD", money.String(summary.NetRevenue))
+}
+
+func TestFirestore_QueriesMatchStoredFields(t *testing.T) {
+	c := newEmulatorClient(t)
+	ctx := context.Background()
+	org := uuid.NewString()
+
+	_, _, err := c.CreateOrganization(ctx, &nhd_report.Organization{OrganizationId: org, Name: "Zeta " + org})
+	require.NoError(t, err)
+	orgs, err := c.GetOrganizations(ctx)
+	require.NoError(t, err)
+	var names []string
+	for _, o := range orgs {
+		names = append(names, o.Name)
+	}
+	assert.Contains(t, names, "Zeta "+org)
+
+	for _, name := range []string{"Bob Seller", "Alice Buyer"} {
+		_, _, err := c.CreateCustomer(ctx, &nhd_report.Customer{FullName: name, OrganizationId: org})
+		require.NoError(t, err)
+	}
+	page, err := c.GetCustomers(ctx, interfaces.CustomerQuery{OrganizationID: org})
+	require.NoError(t, err)
+	require.Len(t, page.Customers, 2)
+	assert.Equal(t, "Alice Buyer", page.Customers[0].FullName)
+	page, err = c.GetCustomers(ctx, interfaces.CustomerQuery{OrganizationID: org, Search: "sel"})
+	require.NoError(t, err)
+	require.Len(t, page.Customers, 1)
+	assert.Equal(t, "Bob Seller", page.Customers[0].FullName)
+
+	// The second run finds the address the first one created, but another
+	// organization gets its own.
+	address := func(organizationID string) *nhd_report.PropertyAddress {
+		return &nhd_report.PropertyAddress{OrganizationId: organizationID, NormalizedKey: "1 main st|sacramento|ca|95814"}
+	}
+	first := newReportRun(t, org, "cust-1", money.New("USD", 4900))
+	_, err = c.CreateReportRunForAddress(ctx, first, address(org))
+	require.NoError(t, err)
+	second := newReportRun(t, org, "cust-2", money.New("USD", 4900))
+	_, err = c.CreateReportRunForAddress(ctx, second, address(org))
+	require.NoError(t, err)
+	assert.Equal(t, first.PropertyAddressId, second.PropertyAddressId)
+	otherOrg := uuid.NewString()
+	other := newReportRun(t, otherOrg, "cust-3", money.New("USD", 4900))
+	_, err = c.CreateReportRunForAddress(ctx, other, address(otherOrg))
+	require.NoError(t, err)
+	assert.NotEqual(t, first.PropertyAddressId, other.PropertyAddressId)
+	addresses, err := c.GetPropertyAddresses(ctx, org)
+	require.NoError(t, err)
+	require.Len(t, addresses, 1)
+	assert.Equal(t, first.PropertyAddressId, addresses[0].PropertyAddressId)
+
+	runs, err := c.GetReportRuns(ctx, interfaces.ReportRunQuery{OrganizationID: org, CustomerID: "cust-2"})
+	require.NoError(t, err)
+	require.Len(t, runs.ReportRuns, 1)
+	runs, err = c.GetReportRuns(ctx, interfaces.ReportRunQuery{
+		OrganizationID: org,
+		Status:         nhd_report.ReportRun_PENDING,
+		PaymentStatus:  nhd_report.ReportRun_Payment_OUTSTANDING,
+		CreatedAfter:   time.Now().Add(-time.Hour),
+		OrderBy:        "created_at",
+	})
+	require.NoError(t, err)
+	require.Len(t, runs.ReportRuns, 2)
+	assert.Equal(t, first.ReportRunId, runs.ReportRuns[0].ReportRunId)
+}
+
+// The tests below need no emulator. They check that every field path the
+// client queries or updates names a field of the generated struct that the
+// collection stores, under the Go name that the Firestore client stores it
+// by.
+
+// newOfflineClient returns a client that builds queries but is never used to
+// send them.
+func newOfflineClient(t *testing.T) *Client {
+	t.Helper()
+	c, err := firestore.NewClient(context.Background(), "nhd-test", option.WithoutAuthentication(), option.WithEndpoint("localhost:0"))
+	require.NoError(t, err)
+	t.Cleanup(func() { c.Close() })
+	return &Client{Client: c}
+}
+
+// queryPaths returns the field paths that q filters and orders on, other
+// than the document ID.
+func queryPaths(t *testing.T, q firestore.Query) []string {
+	t.Helper()
+	b, err := q.Serialize()
+	require.NoError(t, err)
+	var req firestorepb.RunQueryRequest
+	require.NoError(t, proto.Unmarshal(b, &req))
+	structured := req.GetStructuredQuery()
+
+	var paths []string
+	add := func(field *firestorepb.StructuredQuery_FieldReference) {
+		if path := field.GetFieldPath(); path != firestore.DocumentID {
+			paths = append(paths, path)
+		}
+	}
+	var addFilter func(*firestorepb.StructuredQuery_Filter)
+	addFilter = func(filter *firestorepb.StructuredQuery_Filter) {
+		switch f := filter.GetFilterType().(type) {
+		case *firestorepb.StructuredQuery_Filter_CompositeFilter:
+			for _, sub := range f.CompositeFilter.GetFilters() {
+				addFilter(sub)
+			}
+		case *firestorepb.StructuredQuery_Filter_FieldFilter:
+			add(f.FieldFilter.GetField())
+		case *firestorepb.StructuredQuery_Filter_UnaryFilter:
+			add(f.UnaryFilter.GetField())
+		}
+	}
+	addFilter(structured.GetWhere())
+	for _, order := range structured.GetOrderBy() {
+		add(order.GetField())
+	}
+	return paths
+}
+
+func updatePaths(updates []firestore.Update) []string {
+	var paths []string
+	for _, update := range updates {
+		paths = append(paths, update.Path)
+	}
+	return paths
+}
+
+// assertStoredPaths asserts that each of paths, a dot-separated field path,
+// names a field of stored, a pointer to the struct a collection stores.
+func assertStoredPaths(t *testing.T, stored any, paths []string) {
+	t.Helper()
+	require.NotEmpty(t, paths)
+	for _, path := range paths {
+		typ := reflect.TypeOf(stored)
+		for _, name := range strings.Split(path, ".") {
+			if typ.Kind() == reflect.Pointer {
+				typ = typ.Elem()
+			}
+			if typ.Kind() != reflect.Struct {
+				typ = nil
+				break
+			}
+			field, ok := typ.FieldByName(name)
+			if !ok || !field.IsExported() {
+				typ = nil
+				break
+			}
+			typ = field.Type
+		}
+		assert.NotNil(t, typ, "%s is not a field of %T", path, stored)
+	}
+}
+
+func TestFirestore_QueryPathsAreStoredFields(t *testing.T) {
+	c := newOfflineClient(t)
+	for _, tc := range []struct {
+		name   string
+		query  firestore.Query
+		stored any
+	}{
+		{"organizations", c.organizationsQuery(), &nhd_report.Organization{}},
+		{"customers", c.customersQuery(interfaces.CustomerQuery{OrganizationID: "org1", Search: "ali"}), &nhd_report.Customer{}},
+		{"property addresses", c.propertyAddressesQuery("org1"), &nhd_report.PropertyAddress{}},
+		{"matching address", c.matchingAddressQuery(&nhd_report.PropertyAddress{OrganizationId: "org1", NormalizedKey: "1 main st"}), &nhd_report.PropertyAddress{}},
+		{"report runs by created_at", c.reportRunsQuery(interfaces.ReportRunQuery{
+			OrganizationID:  "org1",
+			CustomerID:      "cust1",
+			CreatedByUserID: "user1",
+			Status:          nhd_report.ReportRun_PENDING,
+			PaymentStatus:   nhd_report.ReportRun_Payment_OUTSTANDING,
+			CreatedAfter:    time.Unix(1, 0),
+			CreatedBefore:   time.Unix(2, 0),
+			OrderBy:         interfaces.ReportRunOrderByCreatedAt,
+		}), &nhd_report.ReportRun{}},
+		{"report runs by updated_at", c.reportRunsQuery(interfaces.ReportRunQuery{OrderBy: "-" + interfaces.ReportRunOrderByUpdatedAt}), &nhd_report.ReportRun{}},
+		{"paid reports", c.paidReportsQuery(interfaces.FinancialsQuery{OrganizationID: "org1"}), &nhd_report.ReportRun{}},
+		{"paid reports from", c.paidReportsQuery(interfaces.FinancialsQuery{PaidFrom: time.Unix(1, 0)}), &nhd_report.ReportRun{}},
+		{"price rules", c.priceRulesQuery(), &nhd_report.PriceRule{}},
+		{"exchange rates", c.exchangeRatesQuery(), &nhd_report.ExchangeRate{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assertStoredPaths(t, tc.stored, queryPaths(t, tc.query))
+		})
+	}
+}
+
+func TestFirestore_UpdatePathsAreStoredFields(t *testing.T) {
+	run := &nhd_report.ReportRun{}
+	assertStoredPaths(t, run, updatePaths(statusUpdates(run, &nhd_report.ReportRun_StatusChange{})))
+	assertStoredPaths(t, run, updatePaths(ledgerUpdates(run)))
+	assertStoredPaths(t, run, updatePaths(emailDeliveryUpdates(&nhd_report.ReportRun_EmailDelivery{})))
+	assertStoredPaths(t, run, updatePaths(resultsUpdates(&nhd_report.ReportRun_HazardResults{})))
+	assertStoredPaths(t, &nhd_report.PropertyAddress{}, updatePaths(coordinatesUpdates(&nhd_report.PropertyAddress_Coordinates{})))
+}
@@ -0,0 +1,57 @@
+package datastore
+
+import (
+	"context"
+	"net/url"
+
+	"cloud.google.com/go/firestore"
+	"github.com/seans3/nhd/backend/interfaces"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Idempotency records are kept in the idempotency_keys collection. A
+// Firestore TTL policy on expires_at deletes them once they expire; until
+// then ClaimIdempotencyKey treats expired records as absent.
+
+func (c *Client) idempotencyDoc(key string) *firestore.DocumentRef {
+	// Keys contain characters, such as "/", that are not allowed in IDs.
+	return c.Collection("idempotency_keys").Doc(url.PathEscape(key))
+}
+
+func (c *Client) ClaimIdempotencyKey(ctx context.Context, record *interfaces.IdempotencyRecord) (*interfaces.IdempotencyRecord, error) {
+	docRef := c.idempotencyDoc(record.Key)
+	var existing *interfaces.IdempotencyRecord
+	err := c.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		existing = nil
+		doc, err := tx.Get(docRef)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if err == nil {
+			var stored interfaces.IdempotencyRecord
+			if err := doc.DataTo(&stored); err != nil {
+				return err
+			}
+			if !stored.Expired(record.CreatedAt) {
+				existing = &stored
+				return nil
+			}
+		}
+		return tx.Set(docRef, record)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+func (c *Client) SaveIdempotencyRecord(ctx context.Context, record *interfaces.IdempotencyRecord) error {
+	_, err := c.idempotencyDoc(record.Key).Set(ctx, record)
+	return err
+}
+
+func (c *Client) DeleteIdempotencyKey(ctx context.Context, key string) error {
+	_, err := c.idempotencyDoc(key).Delete(ctx)
+	return err
+}
@@ -0,0 +1,57 @@
+package datastore
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/seans3/nhd/backend/ratelimit"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Statically assert that our store satisfies the interface.
+var _ ratelimit.Store = (*RateLimitStore)(nil)
+
+// RateLimitStore keeps rate limit buckets in the rate_limits collection, so
+// that every server instance draws on the same budgets. Each bucket document
+// has an expires_at field set to when the bucket will be full again; a
+// Firestore TTL policy on that field deletes idle buckets.
+type RateLimitStore struct {
+	Client *Client
+}
+
+type rateLimitDoc struct {
+	Tokens    float64   `firestore:"tokens"`
+	UpdatedAt time.Time `firestore:"updated_at"`
+	ExpiresAt time.Time `firestore:"expires_at"`
+}
+
+// Take implements ratelimit.Store.
+func (s *RateLimitStore) Take(ctx context.Context, key string, limit ratelimit.Limit, now time.Time) (ratelimit.Result, error) {
+	// Keys contain characters, such as "/", that are not allowed in IDs.
+	docRef := s.Client.Collection("rate_limits").Doc(url.PathEscape(key))
+	var result ratelimit.Result
+	err := s.Client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		var bucket ratelimit.Bucket
+		doc, err := tx.Get(docRef)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if err == nil {
+			var stored rateLimitDoc
+			if err := doc.DataTo(&stored); err != nil {
+				return err
+			}
+			bucket = ratelimit.Bucket{Tokens: stored.Tokens, Updated: stored.UpdatedAt}
+		}
+		bucket, result = limit.Take(bucket, now)
+		return tx.Set(docRef, rateLimitDoc{
+			Tokens:    bucket.Tokens,
+			UpdatedAt: bucket.Updated,
+			ExpiresAt: now.Add(result.ResetAfter),
+		})
+	})
+	return result, err
+}
@@ -0,0 +1,88 @@
+// Package devauth is a stand-in for Firebase Authentication that lets the
+// backend run offline. It trusts any token of the form "dev:<uid>" and
+// treats the caller as <uid>, so it must never be used in production.
+package devauth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"firebase.google.com/go/v4/auth"
+	"github.com/seans3/nhd/backend/interfaces"
+	"github.com/seans3/nhd/backend/proto/gen/go"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Statically assert that our verifier satisfies the interface.
+var _ interfaces.FirebaseAuth = (*Verifier)(nil)
+
+const (
+	// TokenPrefix starts every development token.
+	TokenPrefix = "dev:"
+	// Issuer is set as the issuer of every verified token.
+	Issuer = "nhd-dev"
+	// DefaultAdminUID is the UID of the admin user created by SeedAdmin.
+	DefaultAdminUID = "dev-admin"
+	// DefaultOrganizationID is the ID of the organization created by
+	// SeedAdmin, which the admin belongs to.
+	DefaultOrganizationID = "dev-org"
+)
+
+// Verifier verifies development tokens.
+type Verifier struct{}
+
+// NewVerifier returns a Verifier.
+func NewVerifier() *Verifier {
+	return &Verifier{}
+}
+
+// Token returns the development token for uid.
+func Token(uid string) string {
+	return TokenPrefix + uid
+}
+
+// VerifyIDToken accepts "dev:<uid>" for any non-empty uid.
+func (v *Verifier) VerifyIDToken(ctx context.Context, idToken string) (*auth.Token, error) {
+	uid, ok := strings.CutPrefix(idToken, TokenPrefix)
+	if !ok || uid == "" {
+		return nil, fmt.Errorf("not a development token: want %q followed by a user ID", TokenPrefix)
+	}
+	now := time.Now()
+	return &auth.Token{
+		Issuer:   Issuer,
+		Audience: Issuer,
+		Subject:  uid,
+		UID:      uid,
+		AuthTime: now.Unix(),
+		IssuedAt: now.Unix(),
+		Expires:  now.Add(time.Hour).Unix(),
+	}, nil
+}
+
+// SeedAdmin creates an organization and an admin user in it with every
+// permission, so that a fresh in-memory datastore can be used straight away
+// with Token(uid).
+func SeedAdmin(ctx context.Context, ds interfaces.Datastore, uid string) error {
+	now := timestamppb.Now()
+	if _, _, err := ds.CreateOrganization(ctx, &nhd_report.Organization{
+		OrganizationId: DefaultOrganizationID,
+		Name:           "Development Organization",
+		CreatedAt:      now,
+	}); err != nil {
+		return err
+	}
+	return ds.CreateUser(ctx, &nhd_report.User{
+		UserId:         uid,
+		FullName:       "Development Admin",
+		Email:          uid + "@localhost",
+		OrganizationId: DefaultOrganizationID,
+		Permissions: &nhd_report.Permissions{
+			CanCreateCustomers: true,
+			CanGenerateReports: true,
+			IsAdmin:            true,
+		},
+		CreatedAt: now,
+	})
+}
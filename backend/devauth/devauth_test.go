@@ -0,0 +1,38 @@
+package devauth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/seans3/nhd/backend/memstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyIDToken(t *testing.T) {
+	v := NewVerifier()
+
+	token, err := v.VerifyIDToken(context.Background(), Token("alice"))
+	require.NoError(t, err)
+	assert.Equal(t, "alice", token.UID)
+	assert.Equal(t, Issuer, token.Issuer)
+
+	for _, bad := range []string{"", "alice", "dev:", "Bearer dev:alice"} {
+		_, err := v.VerifyIDToken(context.Background(), bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestSeedAdmin(t *testing.T) {
+	ds := memstore.NewClient()
+	require.NoError(t, SeedAdmin(context.Background(), ds, DefaultAdminUID))
+
+	user, err := ds.GetUserByID(context.Background(), DefaultAdminUID)
+	require.NoError(t, err)
+	assert.True(t, user.Permissions.IsAdmin)
+	assert.True(t, user.Permissions.CanGenerateReports)
+	assert.Equal(t, DefaultOrganizationID, user.OrganizationId)
+
+	_, err = ds.GetOrganization(context.Background(), DefaultOrganizationID)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,176 @@
+package hazard
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// A Feature is one zone polygon from a hazard layer.
+type Feature struct {
+	// ID is the GeoJSON feature id, or "<index>" within its layer if the
+	// feature has none.
+	ID string
+	// Properties are the feature's GeoJSON properties, e.g. HAZ_CLASS.
+	Properties map[string]any
+	// Polygons holds the feature's geometry. A GeoJSON Polygon becomes a
+	// single entry; a MultiPolygon becomes one entry per member.
+	Polygons []Polygon
+}
+
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+	// Metadata is a foreign member describing the layer's source.
+	Metadata *geoJSONMetadata `json:"metadata"`
+}
+
+// geoJSONMetadata describes the dataset a layer file was exported from, e.g.
+//
+//	"metadata": {"dataset": "Fire Hazard Severity Zones", "agency": "CAL FIRE", "version": "2024.1", "effective_date": "2024-04-01"}
+type geoJSONMetadata struct {
+	Dataset       string `json:"dataset"`
+	Agency        string `json:"agency"`
+	Version       string `json:"version"`
+	EffectiveDate string `json:"effective_date"` // YYYY-MM-DD
+}
+
+type geoJSONFeature struct {
+	ID         any              `json:"id"`
+	Properties map[string]any   `json:"properties"`
+	Geometry   *geoJSONGeometry `json:"geometry"`
+}
+
+type geoJSONGeometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// ParseFeatures decodes a GeoJSON FeatureCollection of Polygon and
+// MultiPolygon features. Features without a geometry are skipped; any other
+// geometry type is an error.
+func ParseFeatures(data []byte) ([]*Feature, error) {
+	fc, err := parseFeatureCollection(data)
+	if err != nil {
+		return nil, err
+	}
+	return fc.features()
+}
+
+func parseFeatureCollection(data []byte) (*geoJSONFeatureCollection, error) {
+	var fc geoJSONFeatureCollection
+	if err := json.Unmarshal(data, &fc); err != nil {
+		return nil, err
+	}
+	if fc.Type != "FeatureCollection" {
+		return nil, fmt.Errorf("expected a FeatureCollection, got %q", fc.Type)
+	}
+	return &fc, nil
+}
+
+// source returns the layer source described by the collection's metadata.
+func (fc *geoJSONFeatureCollection) source() (Source, error) {
+	if fc.Metadata == nil {
+		return Source{}, nil
+	}
+	source := Source{
+		Dataset: fc.Metadata.Dataset,
+		Agency:  fc.Metadata.Agency,
+		Version: fc.Metadata.Version,
+	}
+	if fc.Metadata.EffectiveDate != "" {
+		date, err := time.Parse(time.DateOnly, fc.Metadata.EffectiveDate)
+		if err != nil {
+			return Source{}, fmt.Errorf("metadata effective_date: %w", err)
+		}
+		source.EffectiveDate = date
+	}
+	return source, nil
+}
+
+func (fc *geoJSONFeatureCollection) features() ([]*Feature, error) {
+
+	var features []*Feature
+	for i, f := range fc.Features {
+		if f.Geometry == nil {
+			continue
+		}
+		feature := &Feature{ID: fmt.Sprint(i), Properties: f.Properties}
+		if f.ID != nil {
+			feature.ID = fmt.Sprint(f.ID)
+		}
+
+		switch f.Geometry.Type {
+		case "Polygon":
+			var coords [][][]float64
+			if err := json.Unmarshal(f.Geometry.Coordinates, &coords); err != nil {
+				return nil, fmt.Errorf("feature %s: %w", feature.ID, err)
+			}
+			polygon, err := toPolygon(coords)
+			if err != nil {
+				return nil, fmt.Errorf("feature %s: %w", feature.ID, err)
+			}
+			feature.Polygons = []Polygon{polygon}
+		case "MultiPolygon":
+			var coords [][][][]float64
+			if err := json.Unmarshal(f.Geometry.Coordinates, &coords); err != nil {
+				return nil, fmt.Errorf("feature %s: %w", feature.ID, err)
+			}
+			for _, c := range coords {
+				polygon, err := toPolygon(c)
+				if err != nil {
+					return nil, fmt.Errorf("feature %s: %w", feature.ID, err)
+				}
+				feature.Polygons = append(feature.Polygons, polygon)
+			}
+		default:
+			return nil, fmt.Errorf("feature %s: unsupported geometry type %q", feature.ID, f.Geometry.Type)
+		}
+		features = append(features, feature)
+	}
+	return features, nil
+}
+
+func toPolygon(coords [][][]float64) (Polygon, error) {
+	if len(coords) == 0 {
+		return nil, fmt.Errorf("polygon has no rings")
+	}
+	polygon := make(Polygon, 0, len(coords))
+	for _, c := range coords {
+		if len(c) < 3 {
+			return nil, fmt.Errorf("ring has %d positions, need at least 3", len(c))
+		}
+		ring := make(Ring, 0, len(c))
+		for _, pos := range c {
+			if len(pos) < 2 {
+				return nil, fmt.Errorf("position has %d coordinates, need 2", len(pos))
+			}
+			ring = append(ring, Point{X: pos[0], Y: pos[1]})
+		}
+		polygon = append(polygon, ring)
+	}
+	return polygon, nil
+}
+
+// bounds returns the bounding box of the feature's outer rings.
+func (f *Feature) bounds() rect {
+	r := emptyRect()
+	for _, polygon := range f.Polygons {
+		for _, p := range polygon[0] {
+			r = r.extend(p)
+		}
+	}
+	return r
+}
+
+// Contains reports whether p lies inside the feature or on its boundary.
+// Points inside a hole are outside the feature, but points on a hole's edge
+// are on the boundary and so are inside.
+func (f *Feature) Contains(p Point) bool {
+	for _, polygon := range f.Polygons {
+		if polygon.Contains(p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+package hazard
+
+import "math"
+
+// Point is a longitude/latitude pair, in GeoJSON order.
+type Point struct {
+	X, Y float64
+}
+
+// A Ring is a closed line of points. The last point may or may not repeat the
+// first; either way the ring is treated as closed.
+type Ring []Point
+
+// A Polygon is an outer ring followed by zero or more holes.
+type Polygon []Ring
+
+// Contains reports whether p lies inside the polygon or on its boundary.
+func (pg Polygon) Contains(p Point) bool {
+	for _, ring := range pg {
+		if ring.onBoundary(p) {
+			return true
+		}
+	}
+	if !pg[0].encloses(p) {
+		return false
+	}
+	for _, hole := range pg[1:] {
+		if hole.encloses(p) {
+			return false
+		}
+	}
+	return true
+}
+
+// boundaryTolerance is how far, in degrees, a point may be from an edge and
+// still count as on it. It absorbs floating point error for points that are
+// meant to lie exactly on a diagonal edge; 1e-9 degrees is about 0.1 mm.
+const boundaryTolerance = 1e-9
+
+// onBoundary reports whether p lies on one of the ring's edges.
+func (r Ring) onBoundary(p Point) bool {
+	for i, j := 0, len(r)-1; i < len(r); j, i = i, i+1 {
+		if distanceToSegment(p, r[j], r[i]) <= boundaryTolerance {
+			return true
+		}
+	}
+	return false
+}
+
+// encloses reports whether p is strictly inside the ring, using the even-odd
+// rule. The result for points on the boundary is unspecified; callers check
+// onBoundary first.
+func (r Ring) encloses(p Point) bool {
+	inside := false
+	for i, j := 0, len(r)-1; i < len(r); j, i = i, i+1 {
+		a, b := r[j], r[i]
+		if (a.Y > p.Y) != (b.Y > p.Y) {
+			x := a.X + (p.Y-a.Y)*(b.X-a.X)/(b.Y-a.Y)
+			if p.X < x {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+// rect is an axis-aligned bounding box.
+type rect struct {
+	minX, minY, maxX, maxY float64
+}
+
+// emptyRect returns a rect that contains nothing and extends to exactly the
+// first point or rect added to it.
+func emptyRect() rect {
+	return rect{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+}
+
+func (r rect) extend(p Point) rect {
+	return rect{math.Min(r.minX, p.X), math.Min(r.minY, p.Y), math.Max(r.maxX, p.X), math.Max(r.maxY, p.Y)}
+}
+
+func (r rect) union(o rect) rect {
+	return rect{math.Min(r.minX, o.minX), math.Min(r.minY, o.minY), math.Max(r.maxX, o.maxX), math.Max(r.maxY, o.maxY)}
+}
+
+// contains reports whether p is inside r, allowing for boundaryTolerance so
+// that points on a feature's edge are never filtered out by the index.
+func (r rect) contains(p Point) bool {
+	return p.X >= r.minX-boundaryTolerance && p.X <= r.maxX+boundaryTolerance &&
+		p.Y >= r.minY-boundaryTolerance && p.Y <= r.maxY+boundaryTolerance
+}
+
+func (r rect) center() Point {
+	return Point{(r.minX + r.maxX) / 2, (r.minY + r.maxY) / 2}
+}
+
+// distanceToSegment returns the planar distance from p to the segment ab.
+func distanceToSegment(p, a, b Point) float64 {
+	dx, dy := b.X-a.X, b.Y-a.Y
+	if dx == 0 && dy == 0 {
+		return math.Hypot(p.X-a.X, p.Y-a.Y)
+	}
+	t := ((p.X-a.X)*dx + (p.Y-a.Y)*dy) / (dx*dx + dy*dy)
+	t = math.Max(0, math.Min(1, t))
+	return math.Hypot(p.X-(a.X+t*dx), p.Y-(a.Y+t*dy))
+}
+
+// earthRadiusMeters is the mean radius of the Earth.
+const earthRadiusMeters = 6371008.8
+
+// localProjection measures distances in meters from an origin, using an
+// equirectangular projection centred on it. Over the few kilometres that
+// matter for the distance to a hazard boundary its error is well under 1%.
+type localProjection struct {
+	origin Point
+	// kx and ky are meters per degree of longitude and of latitude at the
+	// origin.
+	kx, ky float64
+}
+
+func newLocalProjection(origin Point) localProjection {
+	ky := earthRadiusMeters * math.Pi / 180
+	return localProjection{origin: origin, kx: ky * math.Cos(origin.Y*math.Pi/180), ky: ky}
+}
+
+func (lp localProjection) project(p Point) Point {
+	return Point{(p.X - lp.origin.X) * lp.kx, (p.Y - lp.origin.Y) * lp.ky}
+}
+
+// distanceToRect returns the distance from the origin to r, or 0 if r
+// contains the origin.
+func (lp localProjection) distanceToRect(r rect) float64 {
+	dx := max(0, r.minX-lp.origin.X, lp.origin.X-r.maxX)
+	dy := max(0, r.minY-lp.origin.Y, lp.origin.Y-r.maxY)
+	return math.Hypot(dx*lp.kx, dy*lp.ky)
+}
+
+// distanceToBoundary returns the distance from the origin to the nearest
+// edge of f, including the edges of holes.
+func (lp localProjection) distanceToBoundary(f *Feature) float64 {
+	distance := math.Inf(1)
+	for _, polygon := range f.Polygons {
+		for _, ring := range polygon {
+			for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+				distance = min(distance, distanceToSegment(Point{}, lp.project(ring[j]), lp.project(ring[i])))
+			}
+		}
+	}
+	return distance
+}
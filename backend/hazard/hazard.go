@@ -0,0 +1,242 @@
+// Package hazard determines which of the six statutory Natural Hazard
+// Disclosure zones a property lies in.
+//
+// Each zone is described by a GeoJSON layer of Polygon and MultiPolygon
+// features, the same files the Python reporter reads from reporter/data.
+// An Index loads all six layers into one R-tree so that evaluating a point
+// only tests the polygons whose bounding boxes contain it.
+//
+// A layer file may describe its source in a "metadata" foreign member, e.g.
+//
+//	"metadata": {"dataset": "Fire Hazard Severity Zones", "agency": "CAL FIRE", "version": "2024.1", "effective_date": "2024-04-01"}
+//
+// which is reported, with the matched features, as the evidence for each
+// result.
+package hazard
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/seans3/nhd/backend/proto/gen/go"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Zone identifies one of the statutory hazard zones. Its value is the name of
+// the corresponding ReportRun_HazardResults field without the "in_" prefix.
+type Zone string
+
+const (
+	SpecialFloodHazardArea         Zone = "special_flood_hazard_area"
+	DamInundationArea              Zone = "dam_inundation_area"
+	VeryHighFireHazardSeverityZone Zone = "very_high_fire_hazard_severity_zone"
+	WildlandFireArea               Zone = "wildland_fire_area"
+	EarthquakeFaultZone            Zone = "earthquake_fault_zone"
+	SeismicHazardZone              Zone = "seismic_hazard_zone"
+)
+
+// Zones lists every zone in the order they appear on the disclosure.
+var Zones = []Zone{
+	SpecialFloodHazardArea,
+	DamInundationArea,
+	VeryHighFireHazardSeverityZone,
+	WildlandFireArea,
+	EarthquakeFaultZone,
+	SeismicHazardZone,
+}
+
+// LayerFiles maps each zone to its GeoJSON file name within a data directory.
+var LayerFiles = map[Zone]string{
+	SpecialFloodHazardArea:         "mock_flood_hazard_zones.geojson",
+	DamInundationArea:              "mock_dam_inundation_areas.geojson",
+	VeryHighFireHazardSeverityZone: "mock_fire_hazard_zones.geojson",
+	WildlandFireArea:               "mock_wildland_fire_areas.geojson",
+	EarthquakeFaultZone:            "mock_earthquake_fault_zones.geojson",
+	SeismicHazardZone:              "mock_seismic_hazard_zones.geojson",
+}
+
+// A Layer holds the features that make up one zone.
+type Layer struct {
+	Zone     Zone
+	Source   Source
+	Features []*Feature
+}
+
+// Source describes the dataset a layer was built from.
+type Source struct {
+	Dataset string // e.g. "Fire Hazard Severity Zones"
+	Agency  string // e.g. "CAL FIRE"
+	Version string
+	// EffectiveDate is the date the layer took effect, or zero if unknown.
+	EffectiveDate time.Time
+}
+
+// ParseLayer decodes a GeoJSON FeatureCollection as the layer for zone.
+//
+// Fire hazard severity layers classify every feature as Moderate, High or
+// Very High in a HAZ_CLASS property; only Very High features are kept for
+// VeryHighFireHazardSeverityZone. Features without HAZ_CLASS are kept.
+func ParseLayer(zone Zone, data []byte) (*Layer, error) {
+	fc, err := parseFeatureCollection(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s layer: %w", zone, err)
+	}
+	source, err := fc.source()
+	if err != nil {
+		return nil, fmt.Errorf("%s layer: %w", zone, err)
+	}
+	features, err := fc.features()
+	if err != nil {
+		return nil, fmt.Errorf("%s layer: %w", zone, err)
+	}
+	layer := &Layer{Zone: zone, Source: source}
+	for _, f := range features {
+		if zone == VeryHighFireHazardSeverityZone {
+			if class, ok := f.Properties["HAZ_CLASS"].(string); ok && !strings.EqualFold(class, "Very High") {
+				continue
+			}
+		}
+		layer.Features = append(layer.Features, f)
+	}
+	return layer, nil
+}
+
+// Index answers point queries against a set of layers.
+type Index struct {
+	entries []indexEntry
+	boxes   []rect
+	tree    *rtree
+	// sources holds the source of every layer in the index.
+	sources map[Zone]Source
+}
+
+type indexEntry struct {
+	zone    Zone
+	feature *Feature
+}
+
+// NewIndex builds an Index over layers.
+func NewIndex(layers ...*Layer) *Index {
+	ix := &Index{sources: map[Zone]Source{}}
+	for _, layer := range layers {
+		ix.sources[layer.Zone] = layer.Source
+		for _, f := range layer.Features {
+			ix.entries = append(ix.entries, indexEntry{zone: layer.Zone, feature: f})
+			ix.boxes = append(ix.boxes, f.bounds())
+		}
+	}
+	ix.tree = newRTree(ix.boxes)
+	return ix
+}
+
+// Load reads the layer for every zone from dir, using the file names in
+// LayerFiles.
+func Load(dir string) (*Index, error) {
+	var layers []*Layer
+	for _, zone := range Zones {
+		data, err := os.ReadFile(filepath.Join(dir, LayerFiles[zone]))
+		if err != nil {
+			return nil, err
+		}
+		layer, err := ParseLayer(zone, data)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, layer)
+	}
+	return NewIndex(layers...), nil
+}
+
+// Matches returns the features containing the point at latitude, longitude,
+// grouped by zone. Points on a feature's boundary are inside it.
+func (ix *Index) Matches(latitude, longitude float64) map[Zone][]*Feature {
+	p := Point{X: longitude, Y: latitude}
+	matches := map[Zone][]*Feature{}
+	ix.tree.search(p, ix.boxes, func(i int) {
+		entry := ix.entries[i]
+		if entry.feature.Contains(p) {
+			matches[entry.zone] = append(matches[entry.zone], entry.feature)
+		}
+	})
+	return matches
+}
+
+// Evaluate reports which zones contain the point at latitude, longitude,
+// with evidence for the result of every zone that has a layer in the index.
+func (ix *Index) Evaluate(latitude, longitude float64) *nhd_report.ReportRun_HazardResults {
+	matches := ix.Matches(latitude, longitude)
+	results := &nhd_report.ReportRun_HazardResults{
+		InSpecialFloodHazardArea:         len(matches[SpecialFloodHazardArea]) > 0,
+		InDamInundationArea:              len(matches[DamInundationArea]) > 0,
+		InVeryHighFireHazardSeverityZone: len(matches[VeryHighFireHazardSeverityZone]) > 0,
+		InWildlandFireArea:               len(matches[WildlandFireArea]) > 0,
+		InEarthquakeFaultZone:            len(matches[EarthquakeFaultZone]) > 0,
+		InSeismicHazardZone:              len(matches[SeismicHazardZone]) > 0,
+	}
+	p := Point{X: longitude, Y: latitude}
+	for _, zone := range Zones {
+		if _, ok := ix.sources[zone]; ok {
+			results.Evidence = append(results.Evidence, ix.evidence(zone, p, matches[zone]))
+		}
+	}
+	return results
+}
+
+// evidence describes the result for zone at p, given the zone's features
+// that contain p.
+func (ix *Index) evidence(zone Zone, p Point, matched []*Feature) *nhd_report.ReportRun_HazardEvidence {
+	source := ix.sources[zone]
+	evidence := &nhd_report.ReportRun_HazardEvidence{
+		Hazard:       string(zone),
+		InZone:       len(matched) > 0,
+		Dataset:      source.Dataset,
+		Agency:       source.Agency,
+		LayerVersion: source.Version,
+	}
+	if !source.EffectiveDate.IsZero() {
+		evidence.LayerEffectiveDate = timestamppb.New(source.EffectiveDate)
+	}
+
+	proj := newLocalProjection(p)
+	distance := math.Inf(1)
+	if len(matched) > 0 {
+		for _, f := range matched {
+			evidence.MatchedFeatures = append(evidence.MatchedFeatures, &nhd_report.ReportRun_HazardEvidence_MatchedFeature{
+				FeatureId:  f.ID,
+				Attributes: attributes(f.Properties),
+			})
+			distance = min(distance, proj.distanceToBoundary(f))
+		}
+	} else {
+		distance = ix.tree.nearest(ix.boxes, proj.distanceToRect, func(i int) float64 {
+			if ix.entries[i].zone != zone {
+				return math.Inf(1)
+			}
+			return proj.distanceToBoundary(ix.entries[i].feature)
+		})
+	}
+	if !math.IsInf(distance, 1) {
+		// Centimetres are well beyond the precision of any hazard layer.
+		evidence.DistanceToBoundaryMeters = proto.Float64(math.Round(distance*10) / 10)
+	}
+	return evidence
+}
+
+// attributes converts GeoJSON properties to strings, dropping nulls.
+func attributes(properties map[string]any) map[string]string {
+	if len(properties) == 0 {
+		return nil
+	}
+	attrs := make(map[string]string, len(properties))
+	for k, v := range properties {
+		if v != nil {
+			attrs[k] = fmt.Sprint(v)
+		}
+	}
+	return attrs
+}
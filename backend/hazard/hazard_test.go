@@ -0,0 +1,247 @@
+package hazard
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/seans3/nhd/backend/proto/gen/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockDataDir holds the layers shipped with the Python reporter.
+const mockDataDir = "../../reporter/data"
+
+func TestLoad_MockLayers(t *testing.T) {
+	ix, err := Load(mockDataDir)
+	require.NoError(t, err)
+
+	// The flood and fire mock zones overlap between -122.44 and -122.43.
+	assert.Equal(t, &nhd_report.ReportRun_HazardResults{
+		InSpecialFloodHazardArea:         true,
+		InVeryHighFireHazardSeverityZone: true,
+	}, withoutEvidence(ix.Evaluate(37.785, -122.435)))
+
+	assert.Equal(t, &nhd_report.ReportRun_HazardResults{
+		InEarthquakeFaultZone: true,
+	}, withoutEvidence(ix.Evaluate(37.77, -122.50)))
+
+	assert.Equal(t, &nhd_report.ReportRun_HazardResults{}, withoutEvidence(ix.Evaluate(34.05, -118.24)))
+}
+
+// withoutEvidence returns r with only its boolean results.
+func withoutEvidence(r *nhd_report.ReportRun_HazardResults) *nhd_report.ReportRun_HazardResults {
+	r.Evidence = nil
+	return r
+}
+
+func TestEvaluate_Evidence(t *testing.T) {
+	ix, err := Load(mockDataDir)
+	require.NoError(t, err)
+
+	results := ix.Evaluate(37.785, -122.435)
+	require.Len(t, results.Evidence, len(Zones))
+	for i, zone := range Zones {
+		assert.Equal(t, string(zone), results.Evidence[i].Hazard)
+	}
+
+	fire := evidenceFor(t, results, VeryHighFireHazardSeverityZone)
+	assert.True(t, fire.InZone)
+	assert.Equal(t, "Fire Hazard Severity Zones (mock)", fire.Dataset)
+	assert.Equal(t, "CAL FIRE", fire.Agency)
+	assert.Equal(t, "mock-2024.1", fire.LayerVersion)
+	assert.Equal(t, "2024-04-01", fire.LayerEffectiveDate.AsTime().Format(time.DateOnly))
+	require.Len(t, fire.MatchedFeatures, 1)
+	assert.Equal(t, "fire-1", fire.MatchedFeatures[0].FeatureId)
+	assert.Equal(t, map[string]string{"HAZ_CLASS": "Very High"}, fire.MatchedFeatures[0].Attributes)
+	// The zone's west edge, at -122.44, is 0.005 degrees of longitude away.
+	require.NotNil(t, fire.DistanceToBoundaryMeters)
+	assert.InDelta(t, 0.005*earthRadiusMeters*math.Pi/180*math.Cos(37.785*math.Pi/180), *fire.DistanceToBoundaryMeters, 0.1)
+
+	// Outside a zone, the distance is to its nearest feature.
+	seismic := evidenceFor(t, results, SeismicHazardZone)
+	assert.False(t, seismic.InZone)
+	assert.Empty(t, seismic.MatchedFeatures)
+	require.NotNil(t, seismic.DistanceToBoundaryMeters)
+	assert.Positive(t, *seismic.DistanceToBoundaryMeters)
+}
+
+func TestEvaluate_EvidenceForLoadedLayersOnly(t *testing.T) {
+	flood, err := ParseLayer(SpecialFloodHazardArea, []byte(layerWithHoleAndMultiPolygon))
+	require.NoError(t, err)
+	fire, err := ParseLayer(VeryHighFireHazardSeverityZone, []byte(`{"type": "FeatureCollection", "features": []}`))
+	require.NoError(t, err)
+
+	results := NewIndex(flood, fire).Evaluate(5, 1)
+	// Hazards without a layer have no evidence, so the fire evidence is not
+	// at its position in Zones.
+	require.Len(t, results.Evidence, 2)
+	assert.True(t, evidenceFor(t, results, SpecialFloodHazardArea).InZone)
+	assert.NotNil(t, evidenceFor(t, results, SpecialFloodHazardArea).DistanceToBoundaryMeters)
+	// A layer without features has no boundary to measure to.
+	assert.False(t, evidenceFor(t, results, VeryHighFireHazardSeverityZone).InZone)
+	assert.Nil(t, evidenceFor(t, results, VeryHighFireHazardSeverityZone).DistanceToBoundaryMeters)
+}
+
+// evidenceFor returns the evidence for zone in results.
+func evidenceFor(t *testing.T, results *nhd_report.ReportRun_HazardResults, zone Zone) *nhd_report.ReportRun_HazardEvidence {
+	t.Helper()
+	for _, evidence := range results.Evidence {
+		if evidence.Hazard == string(zone) {
+			return evidence
+		}
+	}
+	require.FailNow(t, "no evidence", "hazard %s", zone)
+	return nil
+}
+
+func TestEvaluate_DistanceToBoundary(t *testing.T) {
+	layer, err := ParseLayer(SpecialFloodHazardArea, []byte(layerWithHoleAndMultiPolygon))
+	require.NoError(t, err)
+	ix := NewIndex(layer)
+	metersPerDegree := earthRadiusMeters * math.Pi / 180
+
+	tests := []struct {
+		name   string
+		x, y   float64
+		inside bool
+		want   float64
+	}{
+		// The hole's edges at x=4 and x=6 are a degree of longitude away.
+		{"inside hole", 5, 5, false, metersPerDegree * math.Cos(5*math.Pi/180)},
+		{"near outer edge", 1, 5, true, metersPerDegree * math.Cos(5*math.Pi/180)},
+		{"on edge", 10, 5, true, 0},
+		// The nearest feature is the square member of the multipolygon.
+		{"beyond multipolygon", 51, 5, false, metersPerDegree * math.Cos(5*math.Pi/180)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := ix.Evaluate(tt.y, tt.x)
+			evidence := evidenceFor(t, results, SpecialFloodHazardArea)
+			assert.Equal(t, tt.inside, evidence.InZone)
+			require.NotNil(t, evidence.DistanceToBoundaryMeters)
+			assert.InEpsilon(t, tt.want+1, *evidence.DistanceToBoundaryMeters+1, 1e-6)
+		})
+	}
+}
+
+func TestParseLayer_RejectsInvalidEffectiveDate(t *testing.T) {
+	_, err := ParseLayer(SeismicHazardZone, []byte(`{"type":"FeatureCollection","metadata":{"effective_date":"April 2024"},"features":[]}`))
+	assert.ErrorContains(t, err, "effective_date")
+}
+
+const layerWithHoleAndMultiPolygon = `{
+  "type": "FeatureCollection",
+  "features": [
+    {
+      "type": "Feature",
+      "id": "donut",
+      "properties": {},
+      "geometry": {
+        "type": "Polygon",
+        "coordinates": [
+          [[0, 0], [10, 0], [10, 10], [0, 10], [0, 0]],
+          [[4, 4], [6, 4], [6, 6], [4, 6], [4, 4]]
+        ]
+      }
+    },
+    {
+      "type": "Feature",
+      "properties": {},
+      "geometry": {
+        "type": "MultiPolygon",
+        "coordinates": [
+          [[[20, 0], [30, 0], [25, 10], [20, 0]]],
+          [[[40, 0], [50, 0], [50, 10], [40, 10], [40, 0]]]
+        ]
+      }
+    }
+  ]
+}`
+
+func TestIndex_Geometry(t *testing.T) {
+	layer, err := ParseLayer(SpecialFloodHazardArea, []byte(layerWithHoleAndMultiPolygon))
+	require.NoError(t, err)
+	assert.Equal(t, "donut", layer.Features[0].ID)
+	assert.Equal(t, "1", layer.Features[1].ID)
+	ix := NewIndex(layer)
+
+	tests := []struct {
+		name   string
+		x, y   float64
+		inside bool
+	}{
+		{"interior", 2, 2, true},
+		{"inside hole", 5, 5, false},
+		{"on hole edge", 4, 5, true},
+		{"on outer edge", 10, 5, true},
+		{"on outer vertex", 0, 0, true},
+		{"just outside", 10.0001, 5, false},
+		{"triangle interior", 25, 5, true},
+		{"on diagonal triangle edge", 22.5, 5, true},
+		{"beside triangle apex", 21, 9, false},
+		{"second member of multipolygon", 45, 5, true},
+		{"between multipolygon members", 35, 5, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ix.Evaluate(tt.y, tt.x).InSpecialFloodHazardArea
+			assert.Equal(t, tt.inside, got)
+		})
+	}
+}
+
+func TestParseLayer_KeepsOnlyVeryHighFireClass(t *testing.T) {
+	var features []string
+	for i, class := range []string{"Moderate", "High", "Very High"} {
+		features = append(features, fmt.Sprintf(`{"type":"Feature","properties":{"HAZ_CLASS":%q},"geometry":{"type":"Polygon","coordinates":[[[%d,0],[%d,0],[%d,1],[%d,0]]]}}`, class, i, i+1, i, i))
+	}
+	data := `{"type":"FeatureCollection","features":[` + strings.Join(features, ",") + `]}`
+
+	layer, err := ParseLayer(VeryHighFireHazardSeverityZone, []byte(data))
+	require.NoError(t, err)
+	require.Len(t, layer.Features, 1)
+	assert.Equal(t, "Very High", layer.Features[0].Properties["HAZ_CLASS"])
+}
+
+func TestParseLayer_RejectsUnsupportedGeometry(t *testing.T) {
+	_, err := ParseLayer(SeismicHazardZone, []byte(`{"type":"FeatureCollection","features":[{"type":"Feature","geometry":{"type":"Point","coordinates":[0,0]}}]}`))
+	assert.ErrorContains(t, err, `unsupported geometry type "Point"`)
+}
+
+func TestRTree_MatchesLinearScan(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	boxes := make([]rect, 1000)
+	for i := range boxes {
+		x, y := rng.Float64()*100, rng.Float64()*100
+		boxes[i] = rect{x, y, x + rng.Float64()*5, y + rng.Float64()*5}
+	}
+	tree := newRTree(boxes)
+
+	for q := 0; q < 200; q++ {
+		p := Point{rng.Float64() * 105, rng.Float64() * 105}
+		proj := newLocalProjection(p)
+		want := math.Inf(1)
+		for _, b := range boxes {
+			want = min(want, proj.distanceToRect(b))
+		}
+		got := tree.nearest(boxes, proj.distanceToRect, func(i int) float64 { return proj.distanceToRect(boxes[i]) })
+		assert.Equal(t, want, got)
+	}
+
+	for q := 0; q < 200; q++ {
+		p := Point{rng.Float64() * 105, rng.Float64() * 105}
+		var want, got []int
+		for i, b := range boxes {
+			if b.contains(p) {
+				want = append(want, i)
+			}
+		}
+		tree.search(p, boxes, func(i int) { got = append(got, i) })
+		assert.ElementsMatch(t, want, got)
+	}
+}
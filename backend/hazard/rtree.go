@@ -0,0 +1,122 @@
+package hazard
+
+import (
+	"math"
+	"slices"
+	"sort"
+)
+
+// rtreeNodeCapacity is the maximum number of entries in an R-tree node.
+const rtreeNodeCapacity = 16
+
+// rtree is a static R-tree over a fixed set of bounding boxes, bulk-loaded
+// with the Sort-Tile-Recursive algorithm. Hazard layers are loaded once at
+// startup and never modified, so the tree does not support insertion.
+type rtree struct {
+	root *rnode
+}
+
+type rnode struct {
+	bounds   rect
+	children []*rnode // nil for leaves
+	items    []int    // indices of the boxes in a leaf
+}
+
+// newRTree indexes boxes; search reports matches by their index in boxes.
+func newRTree(boxes []rect) *rtree {
+	if len(boxes) == 0 {
+		return &rtree{}
+	}
+	nodes := make([]*rnode, len(boxes))
+	for i, b := range boxes {
+		nodes[i] = &rnode{bounds: b, items: []int{i}}
+	}
+	// The first pass packs the single-item nodes into leaves.
+	nodes = packNodes(nodes, true)
+	for len(nodes) > 1 {
+		nodes = packNodes(nodes, false)
+	}
+	return &rtree{root: nodes[0]}
+}
+
+// packNodes groups nodes into parents of at most rtreeNodeCapacity entries,
+// tiling them first by x and then by y so that each parent covers a compact
+// area. If leaves is set, the items of the grouped nodes are merged into a
+// leaf rather than kept as children.
+func packNodes(nodes []*rnode, leaves bool) []*rnode {
+	parentCount := (len(nodes) + rtreeNodeCapacity - 1) / rtreeNodeCapacity
+	sliceCount := int(math.Ceil(math.Sqrt(float64(parentCount))))
+	sliceSize := sliceCount * rtreeNodeCapacity
+
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].bounds.center().X < nodes[j].bounds.center().X })
+
+	var parents []*rnode
+	for start := 0; start < len(nodes); start += sliceSize {
+		slice := nodes[start:min(start+sliceSize, len(nodes))]
+		sort.Slice(slice, func(i, j int) bool { return slice[i].bounds.center().Y < slice[j].bounds.center().Y })
+		for i := 0; i < len(slice); i += rtreeNodeCapacity {
+			group := slice[i:min(i+rtreeNodeCapacity, len(slice))]
+			parent := &rnode{bounds: emptyRect()}
+			for _, n := range group {
+				parent.bounds = parent.bounds.union(n.bounds)
+				if leaves {
+					parent.items = append(parent.items, n.items...)
+				} else {
+					parent.children = append(parent.children, n)
+				}
+			}
+			parents = append(parents, parent)
+		}
+	}
+	return parents
+}
+
+// search calls fn with the index of every box that contains p.
+func (t *rtree) search(p Point, boxes []rect, fn func(i int)) {
+	if t.root != nil {
+		t.root.search(p, boxes, fn)
+	}
+}
+
+func (n *rnode) search(p Point, boxes []rect, fn func(i int)) {
+	if !n.bounds.contains(p) {
+		return
+	}
+	for _, child := range n.children {
+		child.search(p, boxes, fn)
+	}
+	for _, i := range n.items {
+		if boxes[i].contains(p) {
+			fn(i)
+		}
+	}
+}
+
+// nearest returns the smallest dist(i) over all boxes, or +Inf if there are
+// none. lowerBound(r) must not exceed dist(i) for any box i within r; nodes
+// and boxes whose lower bound is no better than the best distance found so
+// far are skipped.
+func (t *rtree) nearest(boxes []rect, lowerBound func(rect) float64, dist func(i int) float64) float64 {
+	best := math.Inf(1)
+	if t.root != nil {
+		t.root.nearest(boxes, lowerBound, dist, &best)
+	}
+	return best
+}
+
+func (n *rnode) nearest(boxes []rect, lowerBound func(rect) float64, dist func(i int) float64, best *float64) {
+	if lowerBound(n.bounds) >= *best {
+		return
+	}
+	// Visiting the closest children first tightens best sooner.
+	children := slices.Clone(n.children)
+	sort.Slice(children, func(i, j int) bool { return lowerBound(children[i].bounds) < lowerBound(children[j].bounds) })
+	for _, child := range children {
+		child.nearest(boxes, lowerBound, dist, best)
+	}
+	for _, i := range n.items {
+		if lowerBound(boxes[i]) < *best {
+			*best = min(*best, dist(i))
+		}
+	}
+}
@@ -3,6 +3,7 @@ package health
 import (
 	"net/http"
 
+	"github.com/seans3/nhd/backend/apierror"
 	"github.com/seans3/nhd/backend/interfaces"
 )
 
@@ -23,7 +24,7 @@ func (h *ReadyzHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// In a real application, you might perform a more thorough check,
 	// like pinging the database. For now, we'll just check if the client exists.
 	if h.DS == nil {
-		http.Error(w, "datastore is not available", http.StatusServiceUnavailable)
+		apierror.Write(w, r, apierror.New(apierror.UpstreamUnavailable, "Datastore is not available"))
 		return
 	}
 
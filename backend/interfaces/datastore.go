@@ -2,6 +2,8 @@ package interfaces
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"cloud.google.com/go/firestore"
 	"github.com/seans3/nhd/backend/proto/gen/go"
@@ -9,27 +11,138 @@ import (
 
 // FinancialsSummary holds the aggregated financial data.
 type FinancialsSummary struct {
-	TotalRevenue float64        `json:"total_revenue"`
-	PaidReports  []PaidReportInfo `json:"paid_reports"`
+	// Currency is the reporting currency. TotalRevenue (payments),
+	// TotalRefunds and NetRevenue, and the amounts of each group, are
+	// converted to it, each transaction at the exchange rate in effect when
+	// it was made.
+	Currency     string            `json:"currency"`
+	TotalRevenue *nhd_report.Money `json:"total_revenue"`
+	TotalRefunds *nhd_report.Money `json:"total_refunds"`
+	NetRevenue   *nhd_report.Money `json:"net_revenue"`
+	// Totals has the unconverted totals for each currency that payments or
+	// refunds were made in, in currency order.
+	Totals      []CurrencyTotals `json:"totals"`
+	PaidReports []PaidReportInfo `json:"paid_reports"`
+	// Groups breaks the total down by the query's GroupBy. It is omitted
+	// when no grouping was requested.
+	Groups []FinancialsGroup `json:"groups,omitempty"`
 }
 
-// PaidReportInfo holds data for a single paid report.
+// CurrencyTotals are the payments and refunds made in one currency.
+type CurrencyTotals struct {
+	Currency   string            `json:"currency"`
+	Revenue    *nhd_report.Money `json:"revenue"`
+	Refunds    *nhd_report.Money `json:"refunds"`
+	NetRevenue *nhd_report.Money `json:"net_revenue"`
+}
+
+// PaidReportInfo holds data for a single report with payments or refunds in
+// the summary's range.
 type PaidReportInfo struct {
-	CustomerName      string  `json:"customer_name"`
-	PropertyAddress   string  `json:"property_address"`
-	AmountPaid        float64 `json:"amount_paid"`
-	PaidAt            string  `json:"paid_at"`
+	ReportRunID       string `json:"report_run_id"`
+	CustomerID        string `json:"customer_id"`
+	CustomerName      string `json:"customer_name"`
+	PropertyAddressID string `json:"property_address_id"`
+	PropertyAddress   string `json:"property_address"`
+	// AmountPaid and AmountRefunded total the report's payments and refunds
+	// in the range, in the currency it is billed in; NetAmount is the
+	// difference.
+	AmountPaid     *nhd_report.Money `json:"amount_paid"`
+	AmountRefunded *nhd_report.Money `json:"amount_refunded"`
+	NetAmount      *nhd_report.Money `json:"net_amount"`
+	// PaidAt is the date of the report's first payment or refund in the
+	// range.
+	PaidAt       string        `json:"paid_at"`
+	Transactions []Transaction `json:"transactions"`
+}
+
+// Transaction is a payment or refund in a FinancialsSummary.
+type Transaction struct {
+	// Type is "PAYMENT" or "REFUND".
+	Type   string            `json:"type"`
+	Amount *nhd_report.Money `json:"amount"`
+	// Date is the day (YYYY-MM-DD) of the transaction.
+	Date string `json:"date"`
+
+	at time.Time
+}
+
+// FinancialsGroup is the revenue for one day, month or customer, in the
+// summary's reporting currency.
+type FinancialsGroup struct {
+	// Key is the day (YYYY-MM-DD), month (YYYY-MM) or customer ID.
+	Key string `json:"key"`
+	// Label is a display name for the group: the customer's name when
+	// grouping by customer, otherwise the same as Key.
+	Label      string            `json:"label"`
+	Revenue    *nhd_report.Money `json:"revenue"`
+	Refunds    *nhd_report.Money `json:"refunds"`
+	NetRevenue *nhd_report.Money `json:"net_revenue"`
+	// ReportCount is the number of reports with payments or refunds in the
+	// group.
+	ReportCount int `json:"report_count"`
 }
 
+// ErrNotFound is returned (possibly wrapped) by Datastore implementations when
+// a requested document does not exist.
+var ErrNotFound = errors.New("not found")
+
 // Datastore is an interface for the datastore client to allow for mocking.
 type Datastore interface {
-	GetCustomers(ctx context.Context) ([]*nhd_report.Customer, error)
+	CreateOrganization(ctx context.Context, org *nhd_report.Organization) (*firestore.DocumentRef, *firestore.WriteResult, error)
+	GetOrganization(ctx context.Context, organizationID string) (*nhd_report.Organization, error)
+	GetOrganizations(ctx context.Context) ([]*nhd_report.Organization, error)
+	GetCustomers(ctx context.Context, query CustomerQuery) (*CustomerPage, error)
+	GetCustomer(ctx context.Context, customerID string) (*nhd_report.Customer, error)
 	CreateCustomer(ctx context.Context, customer *nhd_report.Customer) (*firestore.DocumentRef, *firestore.WriteResult, error)
+	// UpdateCustomer replaces the stored customer with the given one. The
+	// customer must already exist.
+	UpdateCustomer(ctx context.Context, customer *nhd_report.Customer) error
+	CreatePropertyAddress(ctx context.Context, address *nhd_report.PropertyAddress) (*firestore.DocumentRef, *firestore.WriteResult, error)
+	GetPropertyAddress(ctx context.Context, propertyAddressID string) (*nhd_report.PropertyAddress, error)
+	// GetPropertyAddresses lists the property addresses of an organization,
+	// or of every organization if organizationID is empty.
+	GetPropertyAddresses(ctx context.Context, organizationID string) ([]*nhd_report.PropertyAddress, error)
+	UpdatePropertyAddress(ctx context.Context, address *nhd_report.PropertyAddress) error
 	CreateReportRun(ctx context.Context, reportRun *nhd_report.ReportRun) (*firestore.DocumentRef, *firestore.WriteResult, error)
-	GetReportRuns(ctx context.Context, paymentStatusFilter string) ([]*nhd_report.ReportRun, error)
+	// CreateReportRunForAddress atomically finds the property address of
+	// address.OrganizationId whose normalized_key matches
+	// address.NormalizedKey (creating it if there is none), links reportRun
+	// to it and creates reportRun.
+	CreateReportRunForAddress(ctx context.Context, reportRun *nhd_report.ReportRun, address *nhd_report.PropertyAddress) (*firestore.DocumentRef, error)
+	GetReportRun(ctx context.Context, reportRunID string) (*nhd_report.ReportRun, error)
+	GetReportRuns(ctx context.Context, query ReportRunQuery) (*ReportRunPage, error)
+	AppendEmailDelivery(ctx context.Context, reportRunID string, delivery *nhd_report.ReportRun_EmailDelivery) error
+	// UpdateReportRunStatus changes a report run's status on behalf of
+	// actor, as ChangeReportRunStatus describes. Changes the lifecycle does
+	// not allow fail with ErrInvalidTransition.
+	UpdateReportRunStatus(ctx context.Context, reportRunID string, status nhd_report.ReportRun_Status, actor, reason string) error
+	// CompleteReportRun stores a report run's hazard results and marks it
+	// COMPLETED on behalf of actor. The run must be PROCESSING.
+	CompleteReportRun(ctx context.Context, reportRunID string, results *nhd_report.ReportRun_HazardResults, actor string) error
+	// UpdateReportCost appends newCost to a report run's cost_history and
+	// records the change in its ledger, as ChangeReportCost describes.
 	UpdateReportCost(ctx context.Context, reportRunID string, newCost *nhd_report.ReportRun_ReportCost) error
-	RecordReportPayment(ctx context.Context, reportRunID string, payment *nhd_report.ReportRun_Payment) error
-	GetPaidReportsSummary(ctx context.Context) (*FinancialsSummary, error)
+	// AppendLedgerEntry appends entry to a report run's ledger and
+	// recomputes its payment_details, as AppendLedgerEntry describes.
+	// Entries the ledger does not allow fail with ErrInvalidLedgerEntry.
+	AppendLedgerEntry(ctx context.Context, reportRunID string, entry *nhd_report.ReportRun_LedgerEntry) error
+	GetPaidReportsSummary(ctx context.Context, query FinancialsQuery) (*FinancialsSummary, error)
+	CreatePriceRule(ctx context.Context, rule *nhd_report.PriceRule) (*firestore.DocumentRef, *firestore.WriteResult, error)
+	GetPriceRules(ctx context.Context) ([]*nhd_report.PriceRule, error)
+	CreateExchangeRate(ctx context.Context, rate *nhd_report.ExchangeRate) (*firestore.DocumentRef, *firestore.WriteResult, error)
+	// GetExchangeRates returns every exchange rate, oldest effective_from
+	// first.
+	GetExchangeRates(ctx context.Context) ([]*nhd_report.ExchangeRate, error)
 	GetUserByID(ctx context.Context, uid string) (*nhd_report.User, error)
 	CreateUser(ctx context.Context, user *nhd_report.User) error
+	// ClaimIdempotencyKey atomically stores record unless a record with the
+	// same key exists that has not expired by record.CreatedAt. It returns
+	// nil if record was stored, and otherwise the existing record.
+	ClaimIdempotencyKey(ctx context.Context, record *IdempotencyRecord) (*IdempotencyRecord, error)
+	// SaveIdempotencyRecord replaces the record with the same key, e.g. to
+	// store the response once the request has been served.
+	SaveIdempotencyRecord(ctx context.Context, record *IdempotencyRecord) error
+	// DeleteIdempotencyKey removes the record for key, if there is one.
+	DeleteIdempotencyKey(ctx context.Context, key string) error
 }
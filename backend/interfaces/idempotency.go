@@ -0,0 +1,30 @@
+package interfaces
+
+import "time"
+
+// IdempotencyRecord is what is stored for a request sent with an
+// Idempotency-Key header: a fingerprint of the request and, once it has been
+// served, the response, which is replayed to retries of the request.
+type IdempotencyRecord struct {
+	// Key identifies the record. It combines the caller and the header value,
+	// so that different callers' keys never collide.
+	Key string `firestore:"key"`
+	// Fingerprint is a hash of the request's method, URL and body.
+	Fingerprint string `firestore:"fingerprint"`
+	// Completed is false while the first request with the key is still being
+	// served.
+	Completed   bool      `firestore:"completed"`
+	StatusCode  int       `firestore:"status_code"`
+	ContentType string    `firestore:"content_type"`
+	Body        []byte    `firestore:"body"`
+	CreatedAt   time.Time `firestore:"created_at"`
+	// ExpiresAt is when the record may be discarded. After that the key can
+	// be reused. Until the record is completed it is the end of the claim's
+	// lease, so that a key whose request never finished can be taken over.
+	ExpiresAt time.Time `firestore:"expires_at"`
+}
+
+// Expired reports whether the record has expired at now.
+func (r *IdempotencyRecord) Expired(now time.Time) bool {
+	return !now.Before(r.ExpiresAt)
+}
@@ -0,0 +1,225 @@
+package interfaces
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/seans3/nhd/backend/money"
+	"github.com/seans3/nhd/backend/proto/gen/go"
+)
+
+// ErrInvalidLedgerEntry is returned (possibly wrapped) by Datastore
+// implementations when a ledger entry cannot be appended to a report run,
+// e.g. because it refunds more than has been paid.
+var ErrInvalidLedgerEntry = errors.New("invalid ledger entry")
+
+// LedgerBalance totals a report run's ledger, in minor units of its
+// currency.
+type LedgerBalance struct {
+	// Currency is the currency of the ledger's first entry. A ledger only
+	// ever holds one currency.
+	Currency string
+	// Charged is the total of CHARGE and ADJUSTMENT entries.
+	Charged  int64
+	Paid     int64
+	Refunded int64
+}
+
+// NewLedgerBalance totals ledger.
+func NewLedgerBalance(ledger []*nhd_report.ReportRun_LedgerEntry) LedgerBalance {
+	var b LedgerBalance
+	for _, entry := range ledger {
+		if b.Currency == "" {
+			b.Currency = entry.GetAmount().GetCurrency()
+		}
+		switch entry.Type {
+		case nhd_report.ReportRun_LedgerEntry_CHARGE, nhd_report.ReportRun_LedgerEntry_ADJUSTMENT:
+			b.Charged += entry.GetAmount().GetMinorUnits()
+		case nhd_report.ReportRun_LedgerEntry_PAYMENT:
+			b.Paid += entry.GetAmount().GetMinorUnits()
+		case nhd_report.ReportRun_LedgerEntry_REFUND:
+			b.Refunded += entry.GetAmount().GetMinorUnits()
+		}
+	}
+	return b
+}
+
+// NetPaid is what has been paid and not refunded.
+func (b LedgerBalance) NetPaid() int64 {
+	return b.Paid - b.Refunded
+}
+
+// Due is what is still owed. A refund is credited against the charge as
+// well as the payment, so it does not change what is due.
+func (b LedgerBalance) Due() int64 {
+	return b.Charged - b.Paid
+}
+
+// Money returns minorUnits of the ledger's currency.
+func (b LedgerBalance) Money(minorUnits int64) *nhd_report.Money {
+	return money.New(b.Currency, minorUnits)
+}
+
+// ReportRunLedger returns reportRun's ledger. Runs created before ledgers
+// were kept have none; for them an opening ledger is derived from their
+// latest cost and recorded payment.
+func ReportRunLedger(reportRun *nhd_report.ReportRun) []*nhd_report.ReportRun_LedgerEntry {
+	if len(reportRun.Ledger) > 0 {
+		return reportRun.Ledger
+	}
+	var ledger []*nhd_report.ReportRun_LedgerEntry
+	if n := len(reportRun.CostHistory); n > 0 {
+		cost := reportRun.CostHistory[n-1]
+		ledger = append(ledger, &nhd_report.ReportRun_LedgerEntry{
+			EntryId:         "opening-charge",
+			Type:            nhd_report.ReportRun_LedgerEntry_CHARGE,
+			Amount:          cost.Amount,
+			CreatedAt:       reportRun.CreatedAt,
+			CreatedByUserId: cost.SetByUserId,
+			Reason:          "Opening balance",
+		})
+	}
+	payment := reportRun.PaymentDetails
+	if payment.GetAmountPaid().GetMinorUnits() <= 0 {
+		return ledger
+	}
+	switch payment.Status {
+	case nhd_report.ReportRun_Payment_PAID, nhd_report.ReportRun_Payment_REFUNDED:
+		ledger = append(ledger, &nhd_report.ReportRun_LedgerEntry{
+			EntryId:       "opening-payment",
+			Type:          nhd_report.ReportRun_LedgerEntry_PAYMENT,
+			Amount:        payment.AmountPaid,
+			CreatedAt:     payment.PaidAt,
+			PaymentMethod: payment.PaymentMethod,
+			TransactionId: payment.TransactionId,
+			Reason:        "Opening balance",
+		})
+	}
+	if payment.Status == nhd_report.ReportRun_Payment_REFUNDED {
+		ledger = append(ledger, &nhd_report.ReportRun_LedgerEntry{
+			EntryId:   "opening-refund",
+			Type:      nhd_report.ReportRun_LedgerEntry_REFUND,
+			Amount:    payment.AmountPaid,
+			CreatedAt: payment.PaidAt,
+			Reason:    "Opening balance",
+		})
+	}
+	return ledger
+}
+
+// SummarizeLedger computes a report run's payment_details from its ledger.
+func SummarizeLedger(ledger []*nhd_report.ReportRun_LedgerEntry) *nhd_report.ReportRun_Payment {
+	balance := NewLedgerBalance(ledger)
+	payment := &nhd_report.ReportRun_Payment{
+		AmountPaid:     balance.Money(balance.NetPaid()),
+		AmountRefunded: balance.Money(balance.Refunded),
+		BalanceDue:     balance.Money(balance.Due()),
+	}
+	for _, entry := range ledger {
+		switch entry.Type {
+		case nhd_report.ReportRun_LedgerEntry_PAYMENT:
+			payment.PaidAt = entry.CreatedAt
+			payment.PaymentMethod = entry.PaymentMethod
+			payment.TransactionId = entry.TransactionId
+			payment.LastTransactionAt = entry.CreatedAt
+		case nhd_report.ReportRun_LedgerEntry_REFUND:
+			payment.LastTransactionAt = entry.CreatedAt
+		}
+	}
+	switch {
+	case balance.Due() > 0:
+		payment.Status = nhd_report.ReportRun_Payment_OUTSTANDING
+	case balance.Refunded > 0 && balance.NetPaid() <= 0:
+		payment.Status = nhd_report.ReportRun_Payment_REFUNDED
+	case balance.Paid == 0:
+		payment.Status = nhd_report.ReportRun_Payment_VOID
+	case balance.Refunded > 0:
+		payment.Status = nhd_report.ReportRun_Payment_PARTIALLY_REFUNDED
+	default:
+		payment.Status = nhd_report.ReportRun_Payment_PAID
+	}
+	return payment
+}
+
+// AppendLedgerEntry appends entry to reportRun's ledger, recomputes its
+// payment_details and sets its updated_at to the entry's created_at. An
+// entry without an ID, or whose amount has no currency, is given a new ID
+// and the ledger's currency.
+//
+// Datastore implementations call it to record payments and refunds, so that
+// every implementation keeps ledgers the same way. Entries in a different
+// currency from the ledger, payments of more than is due, refunds of more
+// than has been paid and not already refunded, and payments or refunds that
+// repeat the transaction_id of an earlier one return an error wrapping
+// ErrInvalidLedgerEntry and leave reportRun unchanged.
+func AppendLedgerEntry(reportRun *nhd_report.ReportRun, entry *nhd_report.ReportRun_LedgerEntry) error {
+	ledger := ReportRunLedger(reportRun)
+	balance := NewLedgerBalance(ledger)
+	amount := entry.GetAmount()
+	if amount.GetCurrency() == "" {
+		amount = balance.Money(amount.GetMinorUnits())
+	}
+	if balance.Currency != "" && amount.Currency != balance.Currency {
+		return fmt.Errorf("report run %s is billed in %s, not %s: %w", reportRun.ReportRunId, balance.Currency, amount.Currency, ErrInvalidLedgerEntry)
+	}
+	if entry.Type == nhd_report.ReportRun_LedgerEntry_PAYMENT && amount.MinorUnits > balance.Due() {
+		return fmt.Errorf("report run %s cannot be paid %s; %s is due: %w", reportRun.ReportRunId, money.String(amount), money.String(money.New(amount.Currency, balance.Due())), ErrInvalidLedgerEntry)
+	}
+	if entry.Type == nhd_report.ReportRun_LedgerEntry_REFUND && amount.MinorUnits > balance.NetPaid() {
+		return fmt.Errorf("report run %s cannot refund %s; %s has been paid and not refunded: %w", reportRun.ReportRunId, money.String(amount), money.String(balance.Money(balance.NetPaid())), ErrInvalidLedgerEntry)
+	}
+	if entry.TransactionId != "" {
+		// A client that retries a payment without an Idempotency-Key must
+		// not record it twice.
+		for _, existing := range ledger {
+			if existing.Type == entry.Type && existing.TransactionId == entry.TransactionId {
+				return fmt.Errorf("report run %s already has a %s with transaction_id %s: %w", reportRun.ReportRunId, entry.Type, entry.TransactionId, ErrInvalidLedgerEntry)
+			}
+		}
+	}
+	entry.Amount = amount
+	if entry.EntryId == "" {
+		entry.EntryId = uuid.NewString()
+	}
+	reportRun.Ledger = append(ledger, entry)
+	reportRun.PaymentDetails = SummarizeLedger(reportRun.Ledger)
+	reportRun.UpdatedAt = entry.CreatedAt
+	return nil
+}
+
+// ChangeReportCost appends cost to reportRun's cost_history and records the
+// difference from its previous cost in its ledger as an ADJUSTMENT. Like
+// AppendLedgerEntry, it returns an error wrapping ErrInvalidLedgerEntry and
+// leaves reportRun unchanged if cost is in a different currency from the
+// ledger.
+func ChangeReportCost(reportRun *nhd_report.ReportRun, cost *nhd_report.ReportRun_ReportCost) error {
+	previous := money.New(cost.GetAmount().GetCurrency(), 0)
+	if n := len(reportRun.CostHistory); n > 0 {
+		previous = reportRun.CostHistory[n-1].GetAmount()
+	}
+	reason := cost.Reason
+	if reason == "" {
+		reason = fmt.Sprintf("Cost changed from %s to %s", money.String(previous), money.String(cost.GetAmount()))
+	}
+	if difference := cost.GetAmount().GetMinorUnits() - previous.GetMinorUnits(); difference != 0 {
+		err := AppendLedgerEntry(reportRun, &nhd_report.ReportRun_LedgerEntry{
+			Type:            nhd_report.ReportRun_LedgerEntry_ADJUSTMENT,
+			Amount:          money.New(cost.GetAmount().GetCurrency(), difference),
+			CreatedAt:       cost.SetAt,
+			CreatedByUserId: cost.SetByUserId,
+			Reason:          reason,
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		// An older run's opening ledger is derived from its latest cost, so
+		// it must be kept before that cost is superseded.
+		reportRun.Ledger = ReportRunLedger(reportRun)
+		reportRun.PaymentDetails = SummarizeLedger(reportRun.Ledger)
+	}
+	reportRun.CostHistory = append(reportRun.CostHistory, cost)
+	reportRun.UpdatedAt = cost.SetAt
+	return nil
+}
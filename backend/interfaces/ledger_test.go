@@ -0,0 +1,37 @@
+package interfaces
+
+import (
+	"testing"
+
+	"github.com/seans3/nhd/backend/money"
+	"github.com/seans3/nhd/backend/proto/gen/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarizeLedger_Status(t *testing.T) {
+	entry := func(entryType nhd_report.ReportRun_LedgerEntry_Type, units int64) *nhd_report.ReportRun_LedgerEntry {
+		return &nhd_report.ReportRun_LedgerEntry{Type: entryType, Amount: money.New("USD", units)}
+	}
+	charge := entry(nhd_report.ReportRun_LedgerEntry_CHARGE, 10000)
+	payment := entry(nhd_report.ReportRun_LedgerEntry_PAYMENT, 10000)
+
+	for name, tc := range map[string]struct {
+		ledger []*nhd_report.ReportRun_LedgerEntry
+		want   nhd_report.ReportRun_Payment_PaymentStatus
+	}{
+		"charged":       {[]*nhd_report.ReportRun_LedgerEntry{charge}, nhd_report.ReportRun_Payment_OUTSTANDING},
+		"part paid":     {[]*nhd_report.ReportRun_LedgerEntry{charge, entry(nhd_report.ReportRun_LedgerEntry_PAYMENT, 4000)}, nhd_report.ReportRun_Payment_OUTSTANDING},
+		"paid":          {[]*nhd_report.ReportRun_LedgerEntry{charge, payment}, nhd_report.ReportRun_Payment_PAID},
+		"part refunded": {[]*nhd_report.ReportRun_LedgerEntry{charge, payment, entry(nhd_report.ReportRun_LedgerEntry_REFUND, 4000)}, nhd_report.ReportRun_Payment_PARTIALLY_REFUNDED},
+		"refunded":      {[]*nhd_report.ReportRun_LedgerEntry{charge, payment, entry(nhd_report.ReportRun_LedgerEntry_REFUND, 4000), entry(nhd_report.ReportRun_LedgerEntry_REFUND, 6000)}, nhd_report.ReportRun_Payment_REFUNDED},
+		"voided":        {[]*nhd_report.ReportRun_LedgerEntry{charge, entry(nhd_report.ReportRun_LedgerEntry_ADJUSTMENT, -10000)}, nhd_report.ReportRun_Payment_VOID},
+	} {
+		summary := SummarizeLedger(tc.ledger)
+		assert.Equal(t, tc.want, summary.Status, name)
+		if tc.want == nhd_report.ReportRun_Payment_PARTIALLY_REFUNDED {
+			assert.Equal(t, "60.00 USD", money.String(summary.AmountPaid))
+			assert.Equal(t, "40.00 USD", money.String(summary.AmountRefunded))
+			assert.Zero(t, summary.BalanceDue.GetMinorUnits())
+		}
+	}
+}
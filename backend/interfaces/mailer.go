@@ -0,0 +1,31 @@
+package interfaces
+
+import (
+	"context"
+	"errors"
+)
+
+// Email is a single outgoing message.
+type Email struct {
+	To          []string
+	Subject     string
+	TextBody    string
+	Attachments []Attachment
+}
+
+// Attachment is a file attached to an Email.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// ErrDeliveryUnknown is wrapped by Send errors returned after the message was
+// handed over for delivery but before delivery was confirmed, such as a
+// timeout waiting for the relay's reply. The message may have been sent.
+var ErrDeliveryUnknown = errors.New("email delivery outcome unknown")
+
+// Mailer is an interface for sending email to allow for mocking and local capture.
+type Mailer interface {
+	Send(ctx context.Context, email *Email) error
+}
@@ -6,5 +6,36 @@ import (
 
 // Publisher is an interface for the pub/sub client to allow for mocking.
 type Publisher interface {
-	Publish(ctx context.Context, topicID string, data []byte) (string, error)
+	// Publish sends data to topicID with the given attributes, which may be
+	// nil, and returns the message ID.
+	Publish(ctx context.Context, topicID string, data []byte, attributes map[string]string) (string, error)
+}
+
+// Message is a message delivered to a Subscriber's handler.
+type Message struct {
+	ID   string
+	Data []byte
+	// Attributes are the attributes the message was published with, such
+	// as its trace ID. It may be nil.
+	Attributes map[string]string
+	// DeliveryAttempt is 1 on the first delivery and counts up on each
+	// redelivery. It is 0 if the subscription does not track attempts.
+	DeliveryAttempt int
+	// Ack acknowledges the message so that it is not redelivered. Nack asks
+	// for it to be redelivered. A message that is neither acked nor nacked
+	// is redelivered once its ack deadline expires.
+	Ack  func()
+	Nack func()
+}
+
+// MessageHandler processes one message. It must call msg.Ack or msg.Nack.
+type MessageHandler func(ctx context.Context, msg *Message)
+
+// Subscriber is the receiving counterpart to Publisher.
+type Subscriber interface {
+	// Receive calls handler for each message on the subscription, running
+	// at most maxConcurrent handlers at once, until ctx is done or an
+	// unrecoverable error occurs. Delivery is at-least-once: handlers must
+	// tolerate seeing the same message more than once.
+	Receive(ctx context.Context, subscriptionID string, maxConcurrent int, handler MessageHandler) error
 }
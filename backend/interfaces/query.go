@@ -0,0 +1,445 @@
+package interfaces
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/seans3/nhd/backend/money"
+	"github.com/seans3/nhd/backend/proto/gen/go"
+)
+
+const (
+	// DefaultPageSize is used when a query does not specify a page size.
+	DefaultPageSize = 50
+	// MaxPageSize is the largest page a query may request.
+	MaxPageSize = 500
+)
+
+// ErrInvalidPageToken is returned when a page token cannot be decoded or was
+// issued for a query with a different ordering.
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// CustomerQuery describes a filtered, paginated listing of customers,
+// ordered by full_name. Zero values mean "no filter".
+type CustomerQuery struct {
+	// OrganizationID restricts the listing to one organization. Handlers
+	// always set it unless an admin asked for every organization.
+	OrganizationID string
+	// Search matches customers whose full_name, email or company_name, or a
+	// word in them, starts with it, ignoring case.
+	Search string
+	// IncludeArchived includes archived customers, which are otherwise
+	// left out.
+	IncludeArchived bool
+	PageSize        int
+	PageToken       string
+}
+
+// CustomerPage is one page of results from a CustomerQuery.
+type CustomerPage struct {
+	Customers     []*nhd_report.Customer `json:"customers"`
+	NextPageToken string                 `json:"next_page_token,omitempty"`
+}
+
+// CustomerCursor is the decoded form of a customer page token. It identifies
+// the last customer returned on the previous page.
+type CustomerCursor struct {
+	FullName   string `json:"n"`
+	CustomerID string `json:"i"`
+}
+
+// Normalize fills in defaults and validates the query. Datastore
+// implementations call it before executing the query.
+func (q *CustomerQuery) Normalize() error {
+	q.Search = strings.ToLower(strings.TrimSpace(q.Search))
+	if q.PageSize <= 0 {
+		q.PageSize = DefaultPageSize
+	}
+	if q.PageSize > MaxPageSize {
+		q.PageSize = MaxPageSize
+	}
+	if q.PageToken != "" {
+		if _, err := q.Cursor(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Cursor decodes the query's page token.
+func (q *CustomerQuery) Cursor() (*CustomerCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(q.PageToken)
+	if err != nil {
+		return nil, ErrInvalidPageToken
+	}
+	var cursor CustomerCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil || cursor.CustomerID == "" {
+		return nil, ErrInvalidPageToken
+	}
+	return &cursor, nil
+}
+
+// NextPageToken returns the token for the page that follows customer.
+func (q *CustomerQuery) NextPageToken(customer *nhd_report.Customer) string {
+	raw, _ := json.Marshal(CustomerCursor{FullName: customer.FullName, CustomerID: customer.CustomerId})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// Matches reports whether customer satisfies the query's filters. The query
+// must have been normalized.
+func (q *CustomerQuery) Matches(customer *nhd_report.Customer) bool {
+	if q.OrganizationID != "" && customer.OrganizationId != q.OrganizationID {
+		return false
+	}
+	if customer.Archived && !q.IncludeArchived {
+		return false
+	}
+	if q.Search != "" && !slices.Contains(CustomerSearchPrefixes(customer), q.Search) {
+		return false
+	}
+	return true
+}
+
+// CustomerSearchPrefixes returns the search_prefixes of customer: every
+// prefix of its lower-cased full_name, email and company_name, and of each
+// word in them.
+func CustomerSearchPrefixes(customer *nhd_report.Customer) []string {
+	seen := map[string]bool{}
+	var prefixes []string
+	add := func(s string) {
+		runes := []rune(s)
+		for i := 1; i <= len(runes); i++ {
+			if prefix := string(runes[:i]); !seen[prefix] {
+				seen[prefix] = true
+				prefixes = append(prefixes, prefix)
+			}
+		}
+	}
+	for _, value := range []string{customer.FullName, customer.Email, customer.CompanyName} {
+		value = strings.ToLower(strings.TrimSpace(value))
+		add(value)
+		for _, word := range strings.Fields(value) {
+			add(word)
+		}
+	}
+	return prefixes
+}
+
+// ReportRunQuery describes a filtered, ordered and paginated listing of
+// report runs. Zero values mean "no filter".
+type ReportRunQuery struct {
+	// OrganizationID restricts the listing to one organization. Handlers
+	// always set it unless an admin asked for every organization.
+	OrganizationID  string
+	CustomerID      string
+	CreatedByUserID string
+	Status          nhd_report.ReportRun_Status
+	PaymentStatus   nhd_report.ReportRun_Payment_PaymentStatus
+	// CreatedAfter and CreatedBefore bound created_at. CreatedAfter is
+	// inclusive and CreatedBefore is exclusive.
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	// OrderBy is one of the ReportRunOrderBy* values, optionally prefixed
+	// with "-" for descending order. Defaults to "-created_at".
+	OrderBy   string
+	PageSize  int
+	PageToken string
+}
+
+// ReportRunPage is one page of results from a ReportRunQuery.
+type ReportRunPage struct {
+	ReportRuns    []*nhd_report.ReportRun `json:"report_runs"`
+	NextPageToken string                  `json:"next_page_token,omitempty"`
+}
+
+const (
+	ReportRunOrderByCreatedAt = "created_at"
+	ReportRunOrderByUpdatedAt = "updated_at"
+	defaultReportRunOrderBy   = "-" + ReportRunOrderByCreatedAt
+)
+
+// Normalize fills in defaults and validates the query. Datastore
+// implementations call it before executing the query.
+func (q *ReportRunQuery) Normalize() error {
+	if q.OrderBy == "" {
+		q.OrderBy = defaultReportRunOrderBy
+	}
+	field := strings.TrimPrefix(q.OrderBy, "-")
+	if field != ReportRunOrderByCreatedAt && field != ReportRunOrderByUpdatedAt {
+		return fmt.Errorf("unsupported order_by %q", q.OrderBy)
+	}
+	// Firestore must order first on the field a range filter applies to, so
+	// a created_at range can only be listed in created_at order.
+	if field == ReportRunOrderByUpdatedAt && (!q.CreatedAfter.IsZero() || !q.CreatedBefore.IsZero()) {
+		return fmt.Errorf("order_by %q cannot be combined with created_after or created_before", q.OrderBy)
+	}
+	if q.PageSize <= 0 {
+		q.PageSize = DefaultPageSize
+	}
+	if q.PageSize > MaxPageSize {
+		q.PageSize = MaxPageSize
+	}
+	if q.PageToken != "" {
+		if _, err := q.Cursor(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OrderField returns the field being ordered on and whether the order is
+// descending.
+func (q *ReportRunQuery) OrderField() (field string, descending bool) {
+	return strings.TrimPrefix(q.OrderBy, "-"), strings.HasPrefix(q.OrderBy, "-")
+}
+
+// PageCursor is the decoded form of a page token. It identifies the last
+// report run returned on the previous page.
+type PageCursor struct {
+	OrderBy     string    `json:"o"`
+	Value       time.Time `json:"v"`
+	ReportRunID string    `json:"i"`
+}
+
+// Cursor decodes the query's page token.
+func (q *ReportRunQuery) Cursor() (*PageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(q.PageToken)
+	if err != nil {
+		return nil, ErrInvalidPageToken
+	}
+	var cursor PageCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, ErrInvalidPageToken
+	}
+	if cursor.OrderBy != q.OrderBy {
+		return nil, ErrInvalidPageToken
+	}
+	return &cursor, nil
+}
+
+// NextPageToken returns the token for the page that follows reportRun.
+func (q *ReportRunQuery) NextPageToken(reportRun *nhd_report.ReportRun) string {
+	raw, _ := json.Marshal(PageCursor{
+		OrderBy:     q.OrderBy,
+		Value:       q.SortValue(reportRun),
+		ReportRunID: reportRun.ReportRunId,
+	})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// SortValue returns the value of the report run's order_by field.
+func (q *ReportRunQuery) SortValue(reportRun *nhd_report.ReportRun) time.Time {
+	field, _ := q.OrderField()
+	if field == ReportRunOrderByUpdatedAt {
+		return reportRun.GetUpdatedAt().AsTime()
+	}
+	return reportRun.GetCreatedAt().AsTime()
+}
+
+// Values for FinancialsQuery.GroupBy.
+const (
+	FinancialsGroupByDay      = "day"
+	FinancialsGroupByMonth    = "month"
+	FinancialsGroupByCustomer = "customer"
+)
+
+// FinancialsQuery selects the payments and refunds included in a
+// FinancialsSummary.
+type FinancialsQuery struct {
+	// OrganizationID restricts the summary to one organization's reports.
+	// Empty means every organization.
+	OrganizationID string
+	// PaidFrom and PaidTo bound the created_at of ledger payments and
+	// refunds. PaidFrom is inclusive and PaidTo is exclusive; zero values
+	// leave the range open.
+	PaidFrom time.Time
+	PaidTo   time.Time
+	// GroupBy is empty or one of the FinancialsGroupBy* values.
+	GroupBy string
+	// Currency is the reporting currency that totals are converted to.
+	Currency string
+}
+
+// Validate checks the query's range, grouping and reporting currency.
+func (q *FinancialsQuery) Validate() error {
+	switch q.GroupBy {
+	case "", FinancialsGroupByDay, FinancialsGroupByMonth, FinancialsGroupByCustomer:
+	default:
+		return fmt.Errorf("unsupported group_by %q", q.GroupBy)
+	}
+	if !q.PaidFrom.IsZero() && !q.PaidTo.IsZero() && !q.PaidFrom.Before(q.PaidTo) {
+		return fmt.Errorf("from must be before to")
+	}
+	if q.Currency == "" {
+		return fmt.Errorf("a reporting currency is required")
+	}
+	return nil
+}
+
+// Matches reports whether a payment made at paidAt falls in the query's range.
+func (q *FinancialsQuery) Matches(paidAt time.Time) bool {
+	if !q.PaidFrom.IsZero() && paidAt.Before(q.PaidFrom) {
+		return false
+	}
+	if !q.PaidTo.IsZero() && !paidAt.Before(q.PaidTo) {
+		return false
+	}
+	return true
+}
+
+// NewPaidReportInfo totals the payments and refunds in reportRun's ledger
+// that fall in the query's range; ok is false if there are none. The
+// customer and address names are left for the caller to resolve.
+func (q *FinancialsQuery) NewPaidReportInfo(reportRun *nhd_report.ReportRun) (info PaidReportInfo, ok bool) {
+	ledger := ReportRunLedger(reportRun)
+	balance := NewLedgerBalance(ledger)
+	info = PaidReportInfo{
+		ReportRunID:       reportRun.ReportRunId,
+		CustomerID:        reportRun.CustomerId,
+		PropertyAddressID: reportRun.PropertyAddressId,
+		AmountPaid:        balance.Money(0),
+		AmountRefunded:    balance.Money(0),
+	}
+	for _, entry := range ledger {
+		at := entry.GetCreatedAt().AsTime()
+		switch {
+		case entry.Type != nhd_report.ReportRun_LedgerEntry_PAYMENT && entry.Type != nhd_report.ReportRun_LedgerEntry_REFUND:
+			continue
+		case !q.Matches(at):
+			continue
+		case entry.Type == nhd_report.ReportRun_LedgerEntry_PAYMENT:
+			info.AmountPaid.MinorUnits += entry.GetAmount().GetMinorUnits()
+		default:
+			info.AmountRefunded.MinorUnits += entry.GetAmount().GetMinorUnits()
+		}
+		if len(info.Transactions) == 0 {
+			info.PaidAt = at.Format("2006-01-02")
+		}
+		info.Transactions = append(info.Transactions, Transaction{
+			Type:   entry.Type.String(),
+			Amount: entry.GetAmount(),
+			Date:   at.Format("2006-01-02"),
+			at:     at,
+		})
+	}
+	info.NetAmount = balance.Money(info.AmountPaid.MinorUnits - info.AmountRefunded.MinorUnits)
+	return info, len(info.Transactions) > 0
+}
+
+// NewFinancialsSummary totals paidReports, which must come from
+// NewPaidReportInfo, and groups them as the query asks, converting amounts
+// to the query's reporting currency with converter. Reports are listed in
+// the order of their first transaction in the range. It returns an error
+// wrapping money.ErrNoExchangeRate if a transaction cannot be converted.
+// Datastore implementations call it once the paid reports have been loaded
+// and their customer and address names resolved.
+func (q *FinancialsQuery) NewFinancialsSummary(paidReports []PaidReportInfo, converter *money.Converter) (*FinancialsSummary, error) {
+	sort.SliceStable(paidReports, func(i, j int) bool {
+		ti, tj := paidReports[i].Transactions[0].at, paidReports[j].Transactions[0].at
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		return paidReports[i].ReportRunID < paidReports[j].ReportRunID
+	})
+
+	summary := &FinancialsSummary{
+		Currency:     q.Currency,
+		TotalRevenue: money.New(q.Currency, 0),
+		TotalRefunds: money.New(q.Currency, 0),
+		PaidReports:  paidReports,
+	}
+	totals := map[string]*CurrencyTotals{}
+	groups := map[string]int{}
+	// lastReport is the last report counted in each group.
+	lastReport := map[string]string{}
+	group := func(key, label, reportRunID string) *FinancialsGroup {
+		i, ok := groups[key]
+		if !ok {
+			i = len(summary.Groups)
+			groups[key] = i
+			summary.Groups = append(summary.Groups, FinancialsGroup{
+				Key: key, Label: label, Revenue: money.New(q.Currency, 0), Refunds: money.New(q.Currency, 0),
+			})
+		}
+		if lastReport[key] != reportRunID {
+			lastReport[key] = reportRunID
+			summary.Groups[i].ReportCount++
+		}
+		return &summary.Groups[i]
+	}
+	for _, report := range paidReports {
+		for _, transaction := range report.Transactions {
+			converted, err := converter.Convert(transaction.Amount, q.Currency, transaction.at)
+			if err != nil {
+				return nil, fmt.Errorf("report run %s: %w", report.ReportRunID, err)
+			}
+			refund := transaction.Type == nhd_report.ReportRun_LedgerEntry_REFUND.String()
+
+			currency := transaction.Amount.GetCurrency()
+			total, ok := totals[currency]
+			if !ok {
+				total = &CurrencyTotals{Currency: currency, Revenue: money.New(currency, 0), Refunds: money.New(currency, 0)}
+				totals[currency] = total
+			}
+			credit(total.Revenue, total.Refunds, refund, transaction.Amount.GetMinorUnits())
+			credit(summary.TotalRevenue, summary.TotalRefunds, refund, converted.MinorUnits)
+
+			switch q.GroupBy {
+			case FinancialsGroupByCustomer:
+				g := group(report.CustomerID, report.CustomerName, report.ReportRunID)
+				credit(g.Revenue, g.Refunds, refund, converted.MinorUnits)
+			case FinancialsGroupByDay, FinancialsGroupByMonth:
+				// A report's payment and its refund may fall in different
+				// periods.
+				key := transaction.Date
+				if q.GroupBy == FinancialsGroupByMonth {
+					key = key[:len("2006-01")]
+				}
+				g := group(key, key, report.ReportRunID)
+				credit(g.Revenue, g.Refunds, refund, converted.MinorUnits)
+			}
+		}
+	}
+	summary.NetRevenue = money.New(q.Currency, summary.TotalRevenue.MinorUnits-summary.TotalRefunds.MinorUnits)
+	summary.Totals = []CurrencyTotals{}
+	for _, total := range totals {
+		total.NetRevenue = money.New(total.Currency, total.Revenue.MinorUnits-total.Refunds.MinorUnits)
+		summary.Totals = append(summary.Totals, *total)
+	}
+	sort.Slice(summary.Totals, func(i, j int) bool {
+		return summary.Totals[i].Currency < summary.Totals[j].Currency
+	})
+	for i := range summary.Groups {
+		g := &summary.Groups[i]
+		g.NetRevenue = money.New(q.Currency, g.Revenue.MinorUnits-g.Refunds.MinorUnits)
+	}
+	if q.GroupBy == FinancialsGroupByCustomer {
+		sort.SliceStable(summary.Groups, func(i, j int) bool {
+			return summary.Groups[i].NetRevenue.MinorUnits > summary.Groups[j].NetRevenue.MinorUnits
+		})
+	} else {
+		sort.SliceStable(summary.Groups, func(i, j int) bool {
+			return summary.Groups[i].Key < summary.Groups[j].Key
+		})
+	}
+	if summary.PaidReports == nil {
+		summary.PaidReports = []PaidReportInfo{}
+	}
+	return summary, nil
+}
+
+// credit adds minorUnits to refunds if refund is set, and otherwise to
+// revenue.
+func credit(revenue, refunds *nhd_report.Money, refund bool, minorUnits int64) {
+	if refund {
+		refunds.MinorUnits += minorUnits
+	} else {
+		revenue.MinorUnits += minorUnits
+	}
+}
@@ -0,0 +1,96 @@
+package interfaces
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/seans3/nhd/backend/money"
+	"github.com/seans3/nhd/backend/proto/gen/go"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ErrInvalidTransition is returned (possibly wrapped) by Datastore
+// implementations when a status change is not allowed by the report run
+// lifecycle.
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// reportRunTransitions lists the statuses a report run may move to from each
+// status. COMPLETED and CANCELLED are final; a FAILED run may be retried. A
+// PENDING run fails without being processed if it cannot be queued.
+var reportRunTransitions = map[nhd_report.ReportRun_Status][]nhd_report.ReportRun_Status{
+	nhd_report.ReportRun_PENDING:    {nhd_report.ReportRun_PROCESSING, nhd_report.ReportRun_CANCELLED, nhd_report.ReportRun_FAILED},
+	nhd_report.ReportRun_PROCESSING: {nhd_report.ReportRun_COMPLETED, nhd_report.ReportRun_FAILED},
+	nhd_report.ReportRun_FAILED:     {nhd_report.ReportRun_PENDING},
+}
+
+// CanTransition reports whether a report run may move from one status to
+// another.
+func CanTransition(from, to nhd_report.ReportRun_Status) bool {
+	return slices.Contains(reportRunTransitions[from], to)
+}
+
+// ChangeReportRunStatus moves reportRun to status at the given time,
+// recording the change in its status_history, and returns the new history
+// entry. reason is also stored as failure_reason when status is FAILED.
+//
+// Some changes have further effects. Retrying a FAILED run (moving it back
+// to PENDING) increments its attempt. Cancelling a run that has not been
+// paid for voids it: a zero cost set by actor is appended to its
+// cost_history, its charge is cancelled by an adjustment in its ledger and
+// its payment status becomes VOID.
+//
+// Datastore implementations call it to apply status changes, so that every
+// implementation enforces the same lifecycle; illegal changes return an
+// error wrapping ErrInvalidTransition and leave reportRun unchanged.
+// Cancellations whose adjustment the ledger rejects likewise fail, with
+// ErrInvalidLedgerEntry.
+func ChangeReportRunStatus(reportRun *nhd_report.ReportRun, status nhd_report.ReportRun_Status, actor, reason string, at time.Time) (*nhd_report.ReportRun_StatusChange, error) {
+	if !CanTransition(reportRun.Status, status) {
+		return nil, fmt.Errorf("report run %s cannot change from %s to %s: %w", reportRun.ReportRunId, reportRun.Status, status, ErrInvalidTransition)
+	}
+	change := &nhd_report.ReportRun_StatusChange{
+		FromStatus: reportRun.Status,
+		ToStatus:   status,
+		ChangedAt:  timestamppb.New(at),
+		Actor:      actor,
+		Reason:     reason,
+	}
+	if status == nhd_report.ReportRun_CANCELLED {
+		if err := voidOutstandingCost(reportRun, actor, change.ChangedAt); err != nil {
+			return nil, err
+		}
+	}
+	if change.FromStatus == nhd_report.ReportRun_FAILED && status == nhd_report.ReportRun_PENDING {
+		// Runs created before attempts were counted have made one.
+		reportRun.Attempt = max(reportRun.Attempt, 1) + 1
+	}
+	reportRun.Status = status
+	reportRun.FailureReason = ""
+	if status == nhd_report.ReportRun_FAILED {
+		reportRun.FailureReason = reason
+	}
+	reportRun.StatusHistory = append(reportRun.StatusHistory, change)
+	reportRun.UpdatedAt = change.ChangedAt
+	return change, nil
+}
+
+// voidOutstandingCost sets the cost of a run that has not been paid for to
+// zero, which cancels its charge in the ledger.
+func voidOutstandingCost(reportRun *nhd_report.ReportRun, actor string, at *timestamppb.Timestamp) error {
+	if NewLedgerBalance(ReportRunLedger(reportRun)).Paid > 0 {
+		// Money has changed hands; undoing that is a refund, not a void.
+		return nil
+	}
+	currency := reportRun.GetPaymentDetails().GetBalanceDue().GetCurrency()
+	if n := len(reportRun.CostHistory); n > 0 {
+		currency = reportRun.CostHistory[n-1].GetAmount().GetCurrency()
+	}
+	return ChangeReportCost(reportRun, &nhd_report.ReportRun_ReportCost{
+		Amount:      money.New(currency, 0),
+		SetAt:       at,
+		SetByUserId: actor,
+		Reason:      "Report run cancelled",
+	})
+}
@@ -0,0 +1,106 @@
+// Package logging provides structured JSON logging with a logger carried in
+// the context, so that every line written while serving a request or
+// processing a report run is tagged with the same correlation fields.
+//
+// A trace ID identifies the work started by one API call. The API sends it
+// to the worker in the TraceIDAttribute attribute of each Pub/Sub message, so
+// that the worker's log lines for a report run share the trace_id of the
+// request that created it.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+)
+
+// TraceIDAttribute is the Pub/Sub message attribute that carries the trace
+// ID. It is also the key of the trace ID in log lines.
+const TraceIDAttribute = "trace_id"
+
+type contextKey int
+
+const (
+	loggerKey contextKey = iota
+	traceIDKey
+)
+
+// New returns a logger that writes JSON lines at or above level to w. The
+// level and message are written as "severity" and "message", and WARN as
+// WARNING, so that Cloud Logging recognizes them.
+func New(w io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) > 0 {
+				return a
+			}
+			switch a.Key {
+			case slog.LevelKey:
+				a.Key = "severity"
+				if level, _ := a.Value.Any().(slog.Level); level == slog.LevelWarn {
+					a.Value = slog.StringValue("WARNING")
+				}
+			case slog.MessageKey:
+				a.Key = "message"
+			}
+			return a
+		},
+	}))
+}
+
+// FromContext returns the logger stored in ctx, or slog.Default if there is
+// none.
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+// NewContext returns a copy of ctx that carries l.
+func NewContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, l)
+}
+
+// With returns a copy of ctx whose logger adds args to every line, as
+// slog.Logger.With does.
+func With(ctx context.Context, args ...any) context.Context {
+	return NewContext(ctx, FromContext(ctx).With(args...))
+}
+
+// WithTraceID returns a copy of ctx that carries traceID and whose logger
+// tags every line with it. An empty traceID returns ctx unchanged.
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	if traceID == "" {
+		return ctx
+	}
+	ctx = context.WithValue(ctx, traceIDKey, traceID)
+	return With(ctx, TraceIDAttribute, traceID)
+}
+
+// TraceID returns the trace ID stored in ctx, or "" if there is none.
+func TraceID(ctx context.Context) string {
+	traceID, _ := ctx.Value(traceIDKey).(string)
+	return traceID
+}
+
+// Attributes returns the Pub/Sub message attributes that carry ctx's
+// correlation fields to a subscriber. It returns nil if ctx has none.
+func Attributes(ctx context.Context) map[string]string {
+	traceID := TraceID(ctx)
+	if traceID == "" {
+		return nil
+	}
+	return map[string]string{TraceIDAttribute: traceID}
+}
+
+// NewTraceID returns a random trace ID: 32 lowercase hex digits, the format
+// used by W3C Trace Context and Cloud Trace.
+func NewTraceID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
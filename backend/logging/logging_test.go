@@ -0,0 +1,51 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContextLogger_CarriesTraceIDAndFields(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := NewContext(context.Background(), New(&buf, slog.LevelInfo))
+	ctx = WithTraceID(ctx, "4bf92f3577b34da6a3ce929d0e0e4736")
+	ctx = With(ctx, "report_run_id", "run1")
+
+	FromContext(ctx).Debug("not written")
+	FromContext(ctx).Info("Report run completed")
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, "INFO", line["severity"])
+	assert.Equal(t, "Report run completed", line["message"])
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", line["trace_id"])
+	assert.Equal(t, "run1", line["report_run_id"])
+
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", TraceID(ctx))
+	assert.Equal(t, map[string]string{TraceIDAttribute: "4bf92f3577b34da6a3ce929d0e0e4736"}, Attributes(ctx))
+}
+
+func TestContextLogger_Defaults(t *testing.T) {
+	ctx := context.Background()
+	assert.Same(t, slog.Default(), FromContext(ctx))
+	assert.Equal(t, ctx, WithTraceID(ctx, ""))
+	assert.Empty(t, TraceID(ctx))
+	assert.Nil(t, Attributes(ctx))
+	assert.Regexp(t, "^[0-9a-f]{32}$", NewTraceID())
+}
+
+func TestNew_UsesCloudLoggingSeverity(t *testing.T) {
+	var buf bytes.Buffer
+	New(&buf, slog.LevelInfo).Warn("Report run failed")
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, "WARNING", line["severity"])
+	assert.Equal(t, "Report run failed", line["message"])
+}
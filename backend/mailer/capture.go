@@ -0,0 +1,44 @@
+package mailer
+
+import (
+	"context"
+	"sync"
+
+	"github.com/seans3/nhd/backend/interfaces"
+	"github.com/seans3/nhd/backend/logging"
+)
+
+// Statically assert that our client satisfies the interface.
+var _ interfaces.Mailer = (*Capture)(nil)
+
+// Capture is a Mailer that records messages in memory instead of sending
+// them. It is used in tests and local development.
+type Capture struct {
+	mu   sync.Mutex
+	sent []*interfaces.Email
+	// Err, if set, is returned from Send instead of recording the message.
+	Err error
+}
+
+// NewCapture creates a new capturing mailer.
+func NewCapture() *Capture {
+	return &Capture{}
+}
+
+func (c *Capture) Send(ctx context.Context, email *interfaces.Email) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Err != nil {
+		return c.Err
+	}
+	c.sent = append(c.sent, email)
+	logging.FromContext(ctx).Info("Captured email", "subject", email.Subject, "recipients", len(email.To))
+	return nil
+}
+
+// Sent returns the messages captured so far.
+func (c *Capture) Sent() []*interfaces.Email {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*interfaces.Email(nil), c.sent...)
+}
@@ -0,0 +1,112 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/seans3/nhd/backend/interfaces"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	subject, body, err := Render(DefaultTemplate, &TemplateData{
+		ReportRunID:     "run123",
+		CustomerName:    "Alice",
+		PropertyAddress: "1 Main St, Sacramento, CA 95814",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Your Natural Hazard Disclosure report is ready", subject)
+	assert.True(t, strings.HasPrefix(body, "Hello Alice,"))
+	assert.NotContains(t, body, "download")
+
+	_, _, err = Render("no_such_template", &TemplateData{})
+	assert.Error(t, err)
+}
+
+func TestBuildMessage_WithAttachment(t *testing.T) {
+	msg, err := buildMessage("reports@example.com", &interfaces.Email{
+		To:       []string{"alice@example.com"},
+		Subject:  "Report",
+		TextBody: "See attached.",
+		Attachments: []interfaces.Attachment{
+			{Filename: "report.pdf", ContentType: "application/pdf", Data: []byte("%PDF-1.7")},
+		},
+	}, time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC))
+	assert.NoError(t, err)
+
+	s := string(msg)
+	assert.Contains(t, s, "To: alice@example.com\r\n")
+	assert.Contains(t, s, "Content-Type: multipart/mixed; boundary=")
+	assert.Contains(t, s, `attachment; filename=report.pdf`)
+	assert.Contains(t, s, "JVBERi0xLjc=") // base64("%PDF-1.7")
+}
+
+// stallingRelay starts an SMTP server that stops answering at the named
+// point of the exchange: "greeting" or "data" (after the message is sent).
+func stallingRelay(t *testing.T, stallAt string) *SMTP {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if stallAt == "greeting" {
+			time.Sleep(time.Second)
+			return
+		}
+		conn.Write([]byte("220 localhost\r\n"))
+		r := bufio.NewReader(conn)
+		inData := false
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch {
+			case inData && line == ".\r\n":
+				time.Sleep(time.Second)
+				return
+			case inData:
+			case strings.HasPrefix(line, "DATA"):
+				inData = true
+				conn.Write([]byte("354 go ahead\r\n"))
+			default:
+				conn.Write([]byte("250 OK\r\n"))
+			}
+		}
+	}()
+	host, port, _ := net.SplitHostPort(l.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return &SMTP{Host: host, Port: p, From: "reports@example.com"}
+}
+
+func TestSMTP_Send_Timeouts(t *testing.T) {
+	email := &interfaces.Email{To: []string{"alice@example.com"}, Subject: "Report", TextBody: "Hello"}
+
+	// A relay that never greets fails the send outright.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := stallingRelay(t, "greeting").Send(ctx, email)
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, interfaces.ErrDeliveryUnknown))
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+
+	// A relay that takes the message but never confirms it may have sent it.
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	err = stallingRelay(t, "data").Send(ctx, email)
+	assert.ErrorIs(t, err, interfaces.ErrDeliveryUnknown)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
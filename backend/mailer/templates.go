@@ -0,0 +1,60 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"text/template"
+)
+
+// DefaultTemplate is the template used when a report run does not specify
+// an email template reference.
+const DefaultTemplate = "report_ready"
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+// templates maps a template reference (the file name in templates/ without
+// its extension) to its parsed template. Each file defines a "subject" and a
+// "body" template.
+var templates = mustParseTemplates()
+
+func mustParseTemplates() map[string]*template.Template {
+	files, err := fs.Glob(templateFS, "templates/*.tmpl")
+	if err != nil {
+		panic(err)
+	}
+	parsed := make(map[string]*template.Template, len(files))
+	for _, file := range files {
+		name := strings.TrimSuffix(path.Base(file), ".tmpl")
+		parsed[name] = template.Must(template.ParseFS(templateFS, file))
+	}
+	return parsed
+}
+
+// TemplateData is the data available to email templates.
+type TemplateData struct {
+	ReportRunID     string
+	CustomerName    string
+	PropertyAddress string
+	ReportURL       string
+}
+
+// Render renders the referenced template and returns the subject and body.
+func Render(reference string, data *TemplateData) (subject, body string, err error) {
+	tmpl, ok := templates[reference]
+	if !ok {
+		return "", "", fmt.Errorf("unknown email template %q", reference)
+	}
+	var subj, text bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subj, "subject", data); err != nil {
+		return "", "", err
+	}
+	if err := tmpl.ExecuteTemplate(&text, "body", data); err != nil {
+		return "", "", err
+	}
+	return strings.TrimSpace(subj.String()), strings.TrimLeft(text.String(), "\n"), nil
+}
@@ -3,7 +3,7 @@ package main
 import (
 	"context"
 	"flag"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"time"
@@ -11,57 +11,169 @@ import (
 	firebase "firebase.google.com/go/v4"
 	"github.com/seans3/nhd/backend/api"
 	"github.com/seans3/nhd/backend/datastore"
+	"github.com/seans3/nhd/backend/devauth"
+	"github.com/seans3/nhd/backend/hazard"
 	"github.com/seans3/nhd/backend/health"
+	"github.com/seans3/nhd/backend/interfaces"
+	"github.com/seans3/nhd/backend/logging"
+	"github.com/seans3/nhd/backend/mailer"
+	"github.com/seans3/nhd/backend/mempubsub"
+	"github.com/seans3/nhd/backend/memstore"
 	"github.com/seans3/nhd/backend/metrics"
 	"github.com/seans3/nhd/backend/middleware"
+	"github.com/seans3/nhd/backend/pricing"
 	"github.com/seans3/nhd/backend/publisher"
+	"github.com/seans3/nhd/backend/ratelimit"
+	"github.com/seans3/nhd/backend/worker"
 )
 
-// Define constants for the rate limiter and timeout.
+// Define constants for the rate limiter and timeout. Each route class has
+// its own budget per user; report creation is the most expensive.
 const (
-	DefaultRateLimitRPS   = 10.0
-	DefaultRateLimitBurst = 20
-	DefaultRequestTimeout = 30 * time.Second
+	DefaultRateLimitRPS         = 10.0
+	DefaultRateLimitBurst       = 20
+	DefaultRateLimitWriteRPS    = 5.0
+	DefaultRateLimitWriteBurst  = 10
+	DefaultRateLimitReportRPS   = 0.5
+	DefaultRateLimitReportBurst = 5
+	// The per-IP budget is checked before authentication, so it must leave
+	// room for every user behind a shared address.
+	DefaultRateLimitIPRPS   = 50.0
+	DefaultRateLimitIPBurst = 100
+	DefaultRequestTimeout   = 30 * time.Second
+	// An Idempotency-Key is held this long beyond the request timeout
+	// before a retry may take over a request that never finished.
+	idempotencyLeaseMargin = 30 * time.Second
 )
 
 func main() {
 	// Define command-line flags for configuration.
-	rps := flag.Float64("ratelimit.rps", DefaultRateLimitRPS, "Requests per second for the rate limiter")
-	burst := flag.Int("ratelimit.burst", DefaultRateLimitBurst, "Burst size for the rate limiter")
+	rps := flag.Float64("ratelimit.rps", DefaultRateLimitRPS, "Read requests per second allowed per user (or client IP if unauthenticated)")
+	burst := flag.Int("ratelimit.burst", DefaultRateLimitBurst, "Burst size for read requests")
+	writeRPS := flag.Float64("ratelimit.write.rps", DefaultRateLimitWriteRPS, "Write requests per second allowed per user")
+	writeBurst := flag.Int("ratelimit.write.burst", DefaultRateLimitWriteBurst, "Burst size for write requests")
+	reportRPS := flag.Float64("ratelimit.report.rps", DefaultRateLimitReportRPS, "Report runs per second each user may create")
+	reportBurst := flag.Int("ratelimit.report.burst", DefaultRateLimitReportBurst, "Burst size for report run creation")
+	ipRPS := flag.Float64("ratelimit.ip.rps", DefaultRateLimitIPRPS, "Requests per second allowed per client IP before authentication")
+	ipBurst := flag.Int("ratelimit.ip.burst", DefaultRateLimitIPBurst, "Burst size for requests per client IP before authentication")
+	rateLimitStore := flag.String("ratelimit.store", "memory", "Where rate limit buckets are kept: memory (per instance), or firestore to share them between instances")
+	trustForwardedFor := flag.Bool("ratelimit.trust-forwarded-for", false, "Take the client IP from the last X-Forwarded-For entry; enable only behind a proxy such as Cloud Run's")
+	idempotencyTTL := flag.Duration("idempotency.ttl", middleware.DefaultIdempotencyTTL, "How long Idempotency-Key responses are kept for replay")
 	timeout := flag.Duration("server.timeout", DefaultRequestTimeout, "Request timeout duration")
+	smtpHost := flag.String("smtp.host", "", "SMTP relay host for report emails; if empty, emails are captured in memory and logged")
+	smtpPort := flag.Int("smtp.port", 587, "SMTP relay port")
+	smtpUsername := flag.String("smtp.username", "", "SMTP username (the password is read from SMTP_PASSWORD)")
+	smtpFrom := flag.String("smtp.from", "reports@nhd.example.com", "From address for report emails")
+	reportBaseURL := flag.String("report.base-url", "", "Base URL prepended to report storage paths in email links")
+	datastoreKind := flag.String("datastore", "firestore", "Datastore: firestore, or memory for an in-process store that is lost on exit")
+	authKind := flag.String("auth", "firebase", "Token verification: firebase, or dev to accept \"dev:<uid>\" tokens (never use in production)")
+	devAdminUID := flag.String("dev.admin-uid", devauth.DefaultAdminUID, "UID of the admin user seeded into the in-memory datastore")
+	queue := flag.String("queue", "pubsub", "Queue for report requests: pubsub, or memory to process reports in-process with the Go worker")
+	hazardDataDir := flag.String("hazard.data-dir", "../reporter/data", "Directory containing the hazard zone GeoJSON layers (used with -queue=memory)")
+	workerConcurrency := flag.Int("worker.concurrency", worker.DefaultConcurrency, "Maximum number of report runs processed at once (used with -queue=memory)")
+	var logLevel slog.Level
+	flag.TextVar(&logLevel, "log.level", slog.LevelInfo, "Minimum level of the JSON log lines written to stderr: DEBUG, INFO, WARN or ERROR")
 	flag.Parse()
 
+	slog.SetDefault(logging.New(os.Stderr, logLevel))
+
 	ctx := context.Background()
 	projectID := os.Getenv("GOOGLE_CLOUD_PROJECT")
-	if projectID == "" {
-		log.Fatal("GOOGLE_CLOUD_PROJECT environment variable must be set")
+	if projectID == "" && (*datastoreKind == "firestore" || *queue == "pubsub" || *authKind == "firebase") {
+		fatal("GOOGLE_CLOUD_PROJECT environment variable must be set unless -datastore=memory, -queue=memory and -auth=dev")
 	}
 
-	// Initialize Firebase Admin SDK
-	firebaseApp, err := firebase.NewApp(ctx, nil)
-	if err != nil {
-		log.Fatalf("error initializing Firebase app: %v\n", err)
+	var firebaseAuth interfaces.FirebaseAuth
+	switch *authKind {
+	case "firebase":
+		// Initialize Firebase Admin SDK
+		firebaseApp, err := firebase.NewApp(ctx, nil)
+		if err != nil {
+			fatal("error initializing Firebase app", "error", err)
+		}
+		firebaseAuth, err = firebaseApp.Auth(ctx)
+		if err != nil {
+			fatal("error getting Firebase Auth client", "error", err)
+		}
+	case "dev":
+		slog.Warn("Using development auth; send the admin token as \"Authorization: Bearer <token>\" to act as the admin user", "token", devauth.Token(*devAdminUID))
+		firebaseAuth = devauth.NewVerifier()
+	default:
+		fatal("Unknown -auth: use firebase or dev", "auth", *authKind)
 	}
-	firebaseAuth, err := firebaseApp.Auth(ctx)
-	if err != nil {
-		log.Fatalf("error getting Firebase Auth client: %v\n", err)
+
+	var dsClient interfaces.Datastore
+	var firestoreClient *datastore.Client
+	switch *datastoreKind {
+	case "firestore":
+		var err error
+		firestoreClient, err = datastore.NewClient(ctx, projectID)
+		if err != nil {
+			fatal("Failed to create datastore client", "error", err)
+		}
+		defer firestoreClient.Close()
+		dsClient = firestoreClient
+	case "memory":
+		memClient := memstore.NewClient()
+		if err := devauth.SeedAdmin(ctx, memClient, *devAdminUID); err != nil {
+			fatal("Failed to seed admin user", "error", err)
+		}
+		slog.Warn("Using the in-memory datastore; all data is lost on exit", "admin_uid", *devAdminUID)
+		dsClient = memClient
+	default:
+		fatal("Unknown -datastore: use firestore or memory", "datastore", *datastoreKind)
 	}
 
-	dsClient, err := datastore.NewClient(ctx, projectID)
-	if err != nil {
-		log.Fatalf("Failed to create datastore client: %v", err)
+	var psClient interfaces.Publisher
+	switch *queue {
+	case "pubsub":
+		pubsubClient, err := publisher.NewClient(ctx, projectID)
+		if err != nil {
+			fatal("Failed to create publisher client", "error", err)
+		}
+		defer pubsubClient.Close()
+		psClient = pubsubClient
+	case "memory":
+		broker, err := newMemoryBroker()
+		if err != nil {
+			fatal("Failed to create in-memory broker", "error", err)
+		}
+		hazards, err := hazard.Load(*hazardDataDir)
+		if err != nil {
+			fatal("Failed to load hazard layers", "error", err)
+		}
+		reportWorker := &worker.Worker{
+			DS:          dsClient,
+			Sub:         broker,
+			Hazards:     hazards,
+			Concurrency: *workerConcurrency,
+		}
+		go func() {
+			if err := reportWorker.Run(ctx); err != nil {
+				fatal("Report worker stopped", "error", err)
+			}
+		}()
+		slog.Info("Using the in-memory queue; report runs are processed in-process")
+		psClient = broker
+	default:
+		fatal("Unknown -queue: use pubsub or memory", "queue", *queue)
 	}
-	defer dsClient.Close()
 
-	psClient, err := publisher.NewClient(ctx, projectID)
-	if err != nil {
-		log.Fatalf("Failed to create publisher client: %v", err)
+	var mailClient interfaces.Mailer
+	if *smtpHost != "" {
+		mailClient = mailer.NewSMTP(*smtpHost, *smtpPort, *smtpUsername, os.Getenv("SMTP_PASSWORD"), *smtpFrom)
+	} else {
+		slog.Info("No SMTP host configured; report emails will be captured, not sent")
+		mailClient = mailer.NewCapture()
 	}
-	defer psClient.Close()
 
 	apiHandler := &api.API{
-		DS: dsClient,
-		PS: psClient,
+		DS:             dsClient,
+		PS:             psClient,
+		Mailer:         mailClient,
+		Pricing:        pricing.NewEngine(dsClient),
+		ReportBaseURL:  *reportBaseURL,
+		RequestTimeout: *timeout,
 	}
 
 	authClient := &middleware.AuthClient{
@@ -69,52 +181,128 @@ func main() {
 		DS:       dsClient,
 	}
 
+	var limitStore ratelimit.Store
+	switch *rateLimitStore {
+	case "memory":
+		limitStore = ratelimit.NewMemoryStore()
+	case "firestore":
+		if firestoreClient == nil {
+			fatal("-ratelimit.store=firestore requires -datastore=firestore")
+		}
+		limitStore = &datastore.RateLimitStore{Client: firestoreClient}
+	default:
+		fatal("Unknown -ratelimit.store: use memory or firestore", "ratelimit_store", *rateLimitStore)
+	}
+	rateLimiter := &middleware.RateLimiter{
+		Store: limitStore,
+		Limits: map[string]ratelimit.Limit{
+			middleware.RateLimitClassRead:   {Rate: *rps, Burst: *burst},
+			middleware.RateLimitClassWrite:  {Rate: *writeRPS, Burst: *writeBurst},
+			middleware.RateLimitClassReport: {Rate: *reportRPS, Burst: *reportBurst},
+		},
+		TrustForwardedFor: *trustForwardedFor,
+		OnLimited:         func(class string) { metrics.RateLimited.Inc(class) },
+	}
+	// ipLimiter runs before VerifyAuthToken, so that requests with bad or
+	// missing tokens are limited too and cannot make the server verify
+	// tokens and look up users without bound.
+	ipLimiter := &middleware.RateLimiter{
+		Store:             limitStore,
+		Limits:            map[string]ratelimit.Limit{middleware.RateLimitClassAuth: {Rate: *ipRPS, Burst: *ipBurst}},
+		Classify:          func(*http.Request) string { return middleware.RateLimitClassAuth },
+		TrustForwardedFor: *trustForwardedFor,
+		OnLimited:         func(class string) { metrics.RateLimited.Inc(class) },
+	}
+
+	idempotency := &middleware.Idempotency{DS: dsClient, TTL: *idempotencyTTL, Lease: *timeout + idempotencyLeaseMargin}
+
 	metricsHandler := metrics.NewMetricsHandler()
 	readyzHandler := &health.ReadyzHandler{DS: dsClient}
 
 	// --- Create protected sub-routers ---
 	apiMux := http.NewServeMux()
 	// User
-	apiMux.HandleFunc("POST /customers", apiHandler.CreateCustomer)
+	apiMux.Handle("POST /customers", authClient.RequirePermission(middleware.CanCreateCustomers, http.HandlerFunc(apiHandler.CreateCustomer)))
 	apiMux.HandleFunc("GET /customers", apiHandler.GetCustomers)
+	apiMux.HandleFunc("GET /customers/{id}", apiHandler.GetCustomer)
+	apiMux.Handle("PATCH /customers/{id}", authClient.RequirePermission(middleware.CanCreateCustomers, http.HandlerFunc(apiHandler.UpdateCustomer)))
+	apiMux.Handle("DELETE /customers/{id}", authClient.RequirePermission(middleware.CanCreateCustomers, http.HandlerFunc(apiHandler.ArchiveCustomer)))
+	apiMux.Handle("POST /customers/{id}/restore", authClient.RequirePermission(middleware.CanCreateCustomers, http.HandlerFunc(apiHandler.RestoreCustomer)))
+	// Property Addresses
+	apiMux.Handle("POST /property-addresses", authClient.RequirePermission(middleware.CanGenerateReports, http.HandlerFunc(apiHandler.CreatePropertyAddress)))
+	apiMux.HandleFunc("GET /property-addresses", apiHandler.GetPropertyAddresses)
+	apiMux.HandleFunc("GET /property-addresses/{id}", apiHandler.GetPropertyAddress)
+	apiMux.Handle("PATCH /property-addresses/{id}", authClient.RequirePermission(middleware.CanGenerateReports, http.HandlerFunc(apiHandler.UpdatePropertyAddress)))
 	// Report Runs
-	apiMux.HandleFunc("POST /report-runs", apiHandler.CreateReportRun)
+	apiMux.Handle("POST /report-runs", authClient.RequirePermission(middleware.CanGenerateReports, http.HandlerFunc(apiHandler.CreateReportRun)))
 	apiMux.HandleFunc("GET /report-runs", apiHandler.GetReportRuns)
-	apiMux.HandleFunc("POST /report-runs/{id}/resend-email", apiHandler.ResendReportEmail)
+	apiMux.HandleFunc("GET /report-runs/{id}", apiHandler.GetReportRun)
+	apiMux.Handle("POST /report-runs/{id}/resend-email", authClient.RequirePermission(middleware.CanGenerateReports, http.HandlerFunc(apiHandler.ResendReportEmail)))
+	apiMux.Handle("POST /report-runs/{id}/retry", authClient.RequirePermission(middleware.CanGenerateReports, http.HandlerFunc(apiHandler.RetryReportRun)))
+	apiMux.Handle("POST /report-runs/{id}/cancel", authClient.RequirePermission(middleware.CanGenerateReports, http.HandlerFunc(apiHandler.CancelReportRun)))
 	// Financials
 	apiMux.HandleFunc("GET /financials/summary", apiHandler.GetFinancialsSummary)
 
 	adminMux := http.NewServeMux()
 	// User Management
 	adminMux.HandleFunc("POST /users/register", apiHandler.RegisterUser)
+	adminMux.HandleFunc("POST /organizations", apiHandler.CreateOrganization)
+	adminMux.HandleFunc("GET /organizations", apiHandler.GetOrganizations)
 	// Financial Management
 	adminMux.HandleFunc("PUT /report-runs/{id}/cost", apiHandler.UpdateReportCost)
 	adminMux.HandleFunc("POST /report-runs/{id}/payment", apiHandler.RecordReportPayment)
+	adminMux.HandleFunc("POST /report-runs/{id}/refund", apiHandler.RefundReportRun)
+	adminMux.HandleFunc("GET /pricing", apiHandler.GetPriceRules)
+	adminMux.HandleFunc("POST /pricing", apiHandler.CreatePriceRule)
+	adminMux.HandleFunc("GET /pricing/quote", apiHandler.QuotePrice)
+	adminMux.HandleFunc("GET /exchange-rates", apiHandler.GetExchangeRates)
+	adminMux.HandleFunc("POST /exchange-rates", apiHandler.CreateExchangeRate)
 
 	// --- Register all routes ---
 	mux := http.NewServeMux()
-	// Health and Metrics Probes (public)
+	// Health and Metrics Probes (public). Only readyz, which queries the
+	// datastore, is rate limited, by client IP.
 	mux.HandleFunc("GET /healthz", health.HealthzHandler)
-	mux.Handle("GET /readyz", readyzHandler)
+	mux.Handle("GET /readyz", rateLimiter.Middleware(readyzHandler))
 	mux.HandleFunc("GET /metrics", metricsHandler.Handler)
-	// Standard authenticated API routes
-	mux.Handle("/api/", http.StripPrefix("/api", authClient.VerifyAuthToken(apiMux)))
+	// Standard authenticated API routes, rate limited per client IP before
+	// authentication and per user after it. POST and PUT requests may carry
+	// an Idempotency-Key.
+	mux.Handle("/api/", http.StripPrefix("/api", ipLimiter.Middleware(authClient.VerifyAuthToken(rateLimiter.Middleware(idempotency.Middleware(middleware.Route("/api", apiMux)))))))
 	// Admin-only API routes
-	mux.Handle("/admin/", http.StripPrefix("/admin", authClient.RequireAdmin(adminMux)))
-
-	// Create the rate limiting middleware with the configured values.
-	rateLimitMiddleware := middleware.RateLimit(*rps, *burst)
+	mux.Handle("/admin/", http.StripPrefix("/admin", ipLimiter.Middleware(authClient.RequireAdmin(rateLimiter.Middleware(idempotency.Middleware(middleware.Route("/admin", adminMux)))))))
 
 	// Wrap the entire mux with all middleware
-	var finalMux http.Handler = mux
+	var finalMux http.Handler = middleware.Route("", mux)
 	finalMux = middleware.Timeout(finalMux, *timeout)
 	finalMux = middleware.Recover(finalMux) // Recover from panics
-	finalMux = rateLimitMiddleware(finalMux)
 	finalMux = metricsHandler.Middleware(finalMux)
 	finalMux = middleware.Logging(finalMux)
 
-	log.Printf("Starting server on :8080 with rate limit of %.2f rps and a burst of %d", *rps, *burst)
+	slog.Info("Starting server", "addr", ":8080", "ratelimit_store", *rateLimitStore)
 	if err := http.ListenAndServe(":8080", finalMux); err != nil {
-		log.Fatal(err)
+		fatal("Server stopped", "error", err)
 	}
 }
+
+// fatal logs msg and args at error level and exits.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// newMemoryBroker returns an in-memory broker with the report request topic,
+// its dead-letter topic and the worker's subscription.
+func newMemoryBroker() (*mempubsub.Broker, error) {
+	broker := mempubsub.NewBroker()
+	broker.CreateTopic("nhd-report-requests")
+	broker.CreateTopic("nhd-report-requests-dead-letter")
+	err := broker.CreateSubscription(worker.DefaultSubscription, mempubsub.SubscriptionConfig{
+		Topic:               "nhd-report-requests",
+		AckDeadline:         time.Minute,
+		RetryDelay:          time.Second,
+		MaxDeliveryAttempts: 5,
+		DeadLetterTopic:     "nhd-report-requests-dead-letter",
+	})
+	return broker, err
+}
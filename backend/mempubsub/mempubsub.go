@@ -0,0 +1,266 @@
+// Package mempubsub is an in-process implementation of the Publisher and
+// Subscriber interfaces for local development and tests.
+//
+// Like Cloud Pub/Sub, a Broker delivers every message published to a topic
+// to each of the topic's subscriptions, at least once. A delivered message
+// that is not acked before its subscription's ack deadline, or that is
+// nacked, is redelivered. Once a message has been delivered
+// MaxDeliveryAttempts times it is forwarded to the subscription's dead-letter
+// topic instead.
+package mempubsub
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/seans3/nhd/backend/interfaces"
+)
+
+// Statically assert that our broker satisfies the interfaces.
+var (
+	_ interfaces.Publisher  = (*Broker)(nil)
+	_ interfaces.Subscriber = (*Broker)(nil)
+)
+
+const (
+	// DefaultAckDeadline is used when a subscription does not set one.
+	DefaultAckDeadline = 10 * time.Second
+	// DefaultRetryDelay is used when a subscription does not set one.
+	DefaultRetryDelay = 100 * time.Millisecond
+)
+
+// SubscriptionConfig configures a subscription.
+type SubscriptionConfig struct {
+	// Topic is the topic the subscription receives messages from.
+	Topic string
+	// AckDeadline is how long a handler has to ack a message before it is
+	// redelivered. Defaults to DefaultAckDeadline.
+	AckDeadline time.Duration
+	// RetryDelay is how long a nacked or expired message waits before it is
+	// redelivered. Defaults to DefaultRetryDelay.
+	RetryDelay time.Duration
+	// MaxDeliveryAttempts limits how often a message is delivered. Zero
+	// means no limit.
+	MaxDeliveryAttempts int
+	// DeadLetterTopic receives messages that have used up their delivery
+	// attempts. If empty, such messages are dropped.
+	DeadLetterTopic string
+}
+
+// Broker holds a set of topics and subscriptions.
+type Broker struct {
+	mu            sync.Mutex
+	topics        map[string][]*subscription
+	subscriptions map[string]*subscription
+}
+
+type subscription struct {
+	id      string
+	config  SubscriptionConfig
+	pending []*message
+	// ready has a value whenever pending may be non-empty.
+	ready chan struct{}
+}
+
+type message struct {
+	id         string
+	data       []byte
+	attributes map[string]string
+	attempts   int
+}
+
+// lease is one delivery of a message. It ends when the message is acked or
+// nacked, or when its ack deadline expires.
+type lease struct {
+	msg   *message
+	timer *time.Timer
+	ended bool
+}
+
+// NewBroker returns a Broker with no topics.
+func NewBroker() *Broker {
+	return &Broker{
+		topics:        map[string][]*subscription{},
+		subscriptions: map[string]*subscription{},
+	}
+}
+
+// CreateTopic creates a topic. Creating a topic that already exists is a
+// no-op.
+func (b *Broker) CreateTopic(topicID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.topics[topicID]; !ok {
+		b.topics[topicID] = nil
+	}
+}
+
+// CreateSubscription creates a subscription to an existing topic. Only
+// messages published after the subscription is created are delivered to it.
+func (b *Broker) CreateSubscription(subscriptionID string, config SubscriptionConfig) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subscriptions[subscriptionID]; ok {
+		return fmt.Errorf("subscription %q already exists", subscriptionID)
+	}
+	if _, ok := b.topics[config.Topic]; !ok {
+		return fmt.Errorf("topic %q does not exist", config.Topic)
+	}
+	if config.DeadLetterTopic != "" {
+		if _, ok := b.topics[config.DeadLetterTopic]; !ok {
+			return fmt.Errorf("dead-letter topic %q does not exist", config.DeadLetterTopic)
+		}
+	}
+	if config.AckDeadline <= 0 {
+		config.AckDeadline = DefaultAckDeadline
+	}
+	if config.RetryDelay <= 0 {
+		config.RetryDelay = DefaultRetryDelay
+	}
+	sub := &subscription{id: subscriptionID, config: config, ready: make(chan struct{}, 1)}
+	b.subscriptions[subscriptionID] = sub
+	b.topics[config.Topic] = append(b.topics[config.Topic], sub)
+	return nil
+}
+
+// Publish delivers data and attributes to every subscription of topicID and
+// returns the message ID. Publishing to a topic without subscriptions
+// succeeds, and the message is discarded.
+func (b *Broker) Publish(ctx context.Context, topicID string, data []byte, attributes map[string]string) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.publishLocked(topicID, data, attributes)
+}
+
+func (b *Broker) publishLocked(topicID string, data []byte, attributes map[string]string) (string, error) {
+	subs, ok := b.topics[topicID]
+	if !ok {
+		return "", fmt.Errorf("topic %q does not exist", topicID)
+	}
+	id := uuid.New().String()
+	for _, sub := range subs {
+		sub.push(&message{id: id, data: append([]byte(nil), data...), attributes: maps.Clone(attributes)})
+	}
+	return id, nil
+}
+
+func (s *subscription) push(msg *message) {
+	s.pending = append(s.pending, msg)
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Receive delivers the subscription's messages to handler until ctx is done,
+// running at most maxConcurrent handlers at once. It waits for running
+// handlers to return before returning itself.
+func (b *Broker) Receive(ctx context.Context, subscriptionID string, maxConcurrent int, handler interfaces.MessageHandler) error {
+	b.mu.Lock()
+	sub, ok := b.subscriptions[subscriptionID]
+	b.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("subscription %q does not exist", subscriptionID)
+	}
+	if maxConcurrent <= 0 {
+		maxConcurrent = 1
+	}
+
+	slots := make(chan struct{}, maxConcurrent)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil
+		}
+		msg, ok := b.next(ctx, sub)
+		if !ok {
+			return nil
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			handler(ctx, msg)
+		}()
+	}
+}
+
+// next waits for a pending message on sub and leases it.
+func (b *Broker) next(ctx context.Context, sub *subscription) (*interfaces.Message, bool) {
+	for {
+		b.mu.Lock()
+		if len(sub.pending) > 0 {
+			msg := sub.pending[0]
+			sub.pending = sub.pending[1:]
+			if len(sub.pending) > 0 {
+				// Let other receivers know there is more to do.
+				select {
+				case sub.ready <- struct{}{}:
+				default:
+				}
+			}
+			msg.attempts++
+			l := &lease{msg: msg}
+			l.timer = time.AfterFunc(sub.config.AckDeadline, func() { b.end(sub, l, false) })
+			b.mu.Unlock()
+			return &interfaces.Message{
+				ID:              msg.id,
+				Data:            msg.data,
+				Attributes:      msg.attributes,
+				DeliveryAttempt: msg.attempts,
+				Ack:             func() { b.end(sub, l, true) },
+				Nack:            func() { b.end(sub, l, false) },
+			}, true
+		}
+		b.mu.Unlock()
+
+		select {
+		case <-sub.ready:
+		case <-ctx.Done():
+			return nil, false
+		}
+	}
+}
+
+// end finishes a lease. Unless the message was acked it is scheduled for
+// redelivery, or dead-lettered if it has no delivery attempts left. Only the
+// first call for a lease has any effect, so an ack that arrives after the
+// deadline has expired does not stop the redelivery.
+func (b *Broker) end(sub *subscription, l *lease, acked bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if l.ended {
+		return
+	}
+	l.ended = true
+	l.timer.Stop()
+	if acked {
+		return
+	}
+
+	msg := l.msg
+	if max := sub.config.MaxDeliveryAttempts; max > 0 && msg.attempts >= max {
+		if sub.config.DeadLetterTopic == "" {
+			slog.Warn("Dropping message after its last delivery attempt", "message_id", msg.id, "subscription", sub.id, "delivery_attempts", msg.attempts)
+			return
+		}
+		slog.Warn("Forwarding message to its dead-letter topic", "message_id", msg.id, "subscription", sub.id, "dead_letter_topic", sub.config.DeadLetterTopic, "delivery_attempts", msg.attempts)
+		if _, err := b.publishLocked(sub.config.DeadLetterTopic, msg.data, msg.attributes); err != nil {
+			slog.Error("Failed to dead-letter message", "message_id", msg.id, "error", err)
+		}
+		return
+	}
+	time.AfterFunc(sub.config.RetryDelay, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		sub.push(msg)
+	})
+}
@@ -0,0 +1,142 @@
+package mempubsub
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/seans3/nhd/backend/interfaces"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receiveN runs Receive until n messages have been handled and returns them
+// in order.
+func receiveN(t *testing.T, b *Broker, subscriptionID string, n int, handle func(*interfaces.Message)) []*interfaces.Message {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var mu sync.Mutex
+	var got []*interfaces.Message
+	err := b.Receive(ctx, subscriptionID, 1, func(ctx context.Context, msg *interfaces.Message) {
+		mu.Lock()
+		got = append(got, msg)
+		done := len(got) == n
+		mu.Unlock()
+		handle(msg)
+		if done {
+			cancel()
+		}
+	})
+	require.NoError(t, err)
+	require.Len(t, got, n, "timed out waiting for messages")
+	return got
+}
+
+func TestBroker_FanOutAndAck(t *testing.T) {
+	b := NewBroker()
+	b.CreateTopic("orders")
+	require.NoError(t, b.CreateSubscription("billing", SubscriptionConfig{Topic: "orders"}))
+	require.NoError(t, b.CreateSubscription("shipping", SubscriptionConfig{Topic: "orders"}))
+
+	id, err := b.Publish(context.Background(), "orders", []byte("order-1"), map[string]string{"trace_id": "abc"})
+	require.NoError(t, err)
+
+	for _, sub := range []string{"billing", "shipping"} {
+		got := receiveN(t, b, sub, 1, func(msg *interfaces.Message) { msg.Ack() })
+		assert.Equal(t, id, got[0].ID)
+		assert.Equal(t, "order-1", string(got[0].Data))
+		assert.Equal(t, map[string]string{"trace_id": "abc"}, got[0].Attributes)
+		assert.Equal(t, 1, got[0].DeliveryAttempt)
+	}
+
+	_, err = b.Publish(context.Background(), "no-such-topic", nil, nil)
+	assert.Error(t, err)
+}
+
+func TestBroker_RedeliversNackedAndExpiredMessages(t *testing.T) {
+	b := NewBroker()
+	b.CreateTopic("orders")
+	require.NoError(t, b.CreateSubscription("billing", SubscriptionConfig{
+		Topic:       "orders",
+		AckDeadline: 20 * time.Millisecond,
+		RetryDelay:  time.Millisecond,
+	}))
+	_, err := b.Publish(context.Background(), "orders", []byte("order-1"), nil)
+	require.NoError(t, err)
+
+	// Nack the first delivery, let the second expire and ack the third.
+	got := receiveN(t, b, "billing", 3, func(msg *interfaces.Message) {
+		switch msg.DeliveryAttempt {
+		case 1:
+			msg.Nack()
+		case 3:
+			msg.Ack()
+		}
+	})
+	for i, msg := range got {
+		assert.Equal(t, i+1, msg.DeliveryAttempt)
+		assert.Equal(t, "order-1", string(msg.Data))
+	}
+
+	// Once acked, the message is not redelivered.
+	b.mu.Lock()
+	assert.Empty(t, b.subscriptions["billing"].pending)
+	b.mu.Unlock()
+}
+
+func TestBroker_DeadLetter(t *testing.T) {
+	b := NewBroker()
+	b.CreateTopic("orders")
+	b.CreateTopic("orders-dead-letter")
+	require.NoError(t, b.CreateSubscription("billing", SubscriptionConfig{
+		Topic:               "orders",
+		RetryDelay:          time.Millisecond,
+		MaxDeliveryAttempts: 2,
+		DeadLetterTopic:     "orders-dead-letter",
+	}))
+	require.NoError(t, b.CreateSubscription("dead-letters", SubscriptionConfig{Topic: "orders-dead-letter"}))
+
+	_, err := b.Publish(context.Background(), "orders", []byte("poison"), nil)
+	require.NoError(t, err)
+
+	receiveN(t, b, "billing", 2, func(msg *interfaces.Message) { msg.Nack() })
+	got := receiveN(t, b, "dead-letters", 1, func(msg *interfaces.Message) { msg.Ack() })
+	assert.Equal(t, "poison", string(got[0].Data))
+}
+
+func TestBroker_LimitsConcurrency(t *testing.T) {
+	b := NewBroker()
+	b.CreateTopic("orders")
+	require.NoError(t, b.CreateSubscription("billing", SubscriptionConfig{Topic: "orders"}))
+	for i := 0; i < 20; i++ {
+		_, err := b.Publish(context.Background(), "orders", []byte("order"), nil)
+		require.NoError(t, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var running, peak, handled int32
+	err := b.Receive(ctx, "billing", 3, func(ctx context.Context, msg *interfaces.Message) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		msg.Ack()
+		if atomic.AddInt32(&handled, 1) == 20 {
+			cancel()
+		}
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int32(20), handled)
+	assert.LessOrEqual(t, peak, int32(3))
+	assert.Greater(t, peak, int32(1))
+}
@@ -3,34 +3,97 @@ package memstore
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 
 	"cloud.google.com/go/firestore"
 	"github.com/google/uuid"
 	"github.com/seans3/nhd/backend/interfaces"
+	"github.com/seans3/nhd/backend/money"
+	"github.com/seans3/nhd/backend/postal"
 	"github.com/seans3/nhd/backend/proto/gen/go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // Statically assert that our client satisfies the interface.
 var _ interfaces.Datastore = (*Client)(nil)
 
 // Client is a thread-safe, in-memory implementation of the Datastore interface.
+//
+// Like a real datastore, it stores copies of the documents it is given and
+// returns copies of what it stores, so callers never share a document with
+// the store or with each other. Creating a document whose ID is taken fails
+// with codes.AlreadyExists, as it does in Firestore.
 type Client struct {
 	mu        sync.RWMutex
+	orgs      map[string]*nhd_report.Organization
 	users     map[string]*nhd_report.User
 	customers map[string]*nhd_report.Customer
+	addresses map[string]*nhd_report.PropertyAddress
 	reports   map[string]*nhd_report.ReportRun
+	prices    []*nhd_report.PriceRule
+	rates     []*nhd_report.ExchangeRate
+	// idempotency is keyed by IdempotencyRecord.Key.
+	idempotency map[string]*interfaces.IdempotencyRecord
 }
 
 // NewClient creates a new in-memory datastore client.
 func NewClient() *Client {
 	return &Client{
-		users:     make(map[string]*nhd_report.User),
-		customers: make(map[string]*nhd_report.Customer),
-		reports:   make(map[string]*nhd_report.ReportRun),
+		orgs:        make(map[string]*nhd_report.Organization),
+		users:       make(map[string]*nhd_report.User),
+		customers:   make(map[string]*nhd_report.Customer),
+		addresses:   make(map[string]*nhd_report.PropertyAddress),
+		reports:     make(map[string]*nhd_report.ReportRun),
+		idempotency: make(map[string]*interfaces.IdempotencyRecord),
 	}
 }
 
+// clone returns a deep copy of m.
+func clone[M proto.Message](m M) M {
+	return proto.Clone(m).(M)
+}
+
+// --- Organization Methods ---
+
+func (c *Client) CreateOrganization(ctx context.Context, org *nhd_report.Organization) (*firestore.DocumentRef, *firestore.WriteResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if org.OrganizationId == "" {
+		org.OrganizationId = uuid.New().String()
+	}
+	if _, ok := c.orgs[org.OrganizationId]; ok {
+		return nil, nil, status.Errorf(codes.AlreadyExists, "organization %s already exists", org.OrganizationId)
+	}
+	c.orgs[org.OrganizationId] = clone(org)
+	return &firestore.DocumentRef{ID: org.OrganizationId}, nil, nil
+}
+
+func (c *Client) GetOrganization(ctx context.Context, organizationID string) (*nhd_report.Organization, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	org, ok := c.orgs[organizationID]
+	if !ok {
+		return nil, fmt.Errorf("organization %s: %w", organizationID, interfaces.ErrNotFound)
+	}
+	return clone(org), nil
+}
+
+func (c *Client) GetOrganizations(ctx context.Context) ([]*nhd_report.Organization, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	orgs := make([]*nhd_report.Organization, 0, len(c.orgs))
+	for _, org := range c.orgs {
+		orgs = append(orgs, clone(org))
+	}
+	sort.Slice(orgs, func(i, j int) bool { return orgs[i].Name < orgs[j].Name })
+	return orgs, nil
+}
+
 // --- User Methods ---
 
 func (c *Client) GetUserByID(ctx context.Context, uid string) (*nhd_report.User, error) {
@@ -38,9 +101,9 @@ func (c *Client) GetUserByID(ctx context.Context, uid string) (*nhd_report.User,
 	defer c.mu.RUnlock()
 	user, ok := c.users[uid]
 	if !ok {
-		return nil, fmt.Errorf("user not found")
+		return nil, fmt.Errorf("user %s: %w", uid, interfaces.ErrNotFound)
 	}
-	return user, nil
+	return clone(user), nil
 }
 
 func (c *Client) CreateUser(ctx context.Context, user *nhd_report.User) error {
@@ -49,29 +112,133 @@ func (c *Client) CreateUser(ctx context.Context, user *nhd_report.User) error {
 	if user.UserId == "" {
 		return fmt.Errorf("user id cannot be empty")
 	}
-	c.users[user.UserId] = user
+	c.users[user.UserId] = clone(user)
 	return nil
 }
 
 // --- Customer Methods ---
 
-func (c *Client) GetCustomers(ctx context.Context) ([]*nhd_report.Customer, error) {
+func (c *Client) GetCustomers(ctx context.Context, query interfaces.CustomerQuery) (*interfaces.CustomerPage, error) {
+	if err := query.Normalize(); err != nil {
+		return nil, err
+	}
+	var cursor *interfaces.CustomerCursor
+	if query.PageToken != "" {
+		cursor, _ = query.Cursor()
+	}
+
+	// less reports whether a sorts before b: by full name, breaking ties on
+	// the customer ID.
+	less := func(aName, aID, bName, bID string) bool {
+		if aName != bName {
+			return aName < bName
+		}
+		return aID < bID
+	}
+
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	customers := make([]*nhd_report.Customer, 0, len(c.customers))
 	for _, customer := range c.customers {
-		customers = append(customers, customer)
+		if !query.Matches(customer) {
+			continue
+		}
+		if cursor != nil && !less(cursor.FullName, cursor.CustomerID, customer.FullName, customer.CustomerId) {
+			continue
+		}
+		customers = append(customers, clone(customer))
+	}
+	sort.Slice(customers, func(i, j int) bool {
+		return less(customers[i].FullName, customers[i].CustomerId, customers[j].FullName, customers[j].CustomerId)
+	})
+
+	page := &interfaces.CustomerPage{Customers: customers}
+	if len(customers) > query.PageSize {
+		page.Customers = customers[:query.PageSize]
+		page.NextPageToken = query.NextPageToken(page.Customers[query.PageSize-1])
+	}
+	return page, nil
+}
+
+func (c *Client) GetCustomer(ctx context.Context, customerID string) (*nhd_report.Customer, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	customer, ok := c.customers[customerID]
+	if !ok {
+		return nil, fmt.Errorf("customer %s: %w", customerID, interfaces.ErrNotFound)
 	}
-	return customers, nil
+	return clone(customer), nil
 }
 
 func (c *Client) CreateCustomer(ctx context.Context, customer *nhd_report.Customer) (*firestore.DocumentRef, *firestore.WriteResult, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	newID := uuid.New().String()
-	customer.CustomerId = newID
-	c.customers[newID] = customer
-	return &firestore.DocumentRef{ID: newID}, nil, nil
+	if customer.CustomerId == "" {
+		customer.CustomerId = uuid.New().String()
+	}
+	if _, ok := c.customers[customer.CustomerId]; ok {
+		return nil, nil, status.Errorf(codes.AlreadyExists, "customer %s already exists", customer.CustomerId)
+	}
+	c.customers[customer.CustomerId] = clone(customer)
+	return &firestore.DocumentRef{ID: customer.CustomerId}, nil, nil
+}
+
+func (c *Client) UpdateCustomer(ctx context.Context, customer *nhd_report.Customer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.customers[customer.CustomerId]; !ok {
+		return fmt.Errorf("customer %s: %w", customer.CustomerId, interfaces.ErrNotFound)
+	}
+	c.customers[customer.CustomerId] = clone(customer)
+	return nil
+}
+
+// --- Property Address Methods ---
+
+func (c *Client) CreatePropertyAddress(ctx context.Context, address *nhd_report.PropertyAddress) (*firestore.DocumentRef, *firestore.WriteResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if address.PropertyAddressId == "" {
+		address.PropertyAddressId = uuid.New().String()
+	}
+	if _, ok := c.addresses[address.PropertyAddressId]; ok {
+		return nil, nil, status.Errorf(codes.AlreadyExists, "property address %s already exists", address.PropertyAddressId)
+	}
+	c.addresses[address.PropertyAddressId] = clone(address)
+	return &firestore.DocumentRef{ID: address.PropertyAddressId}, nil, nil
+}
+
+func (c *Client) GetPropertyAddress(ctx context.Context, propertyAddressID string) (*nhd_report.PropertyAddress, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	address, ok := c.addresses[propertyAddressID]
+	if !ok {
+		return nil, fmt.Errorf("property address %s: %w", propertyAddressID, interfaces.ErrNotFound)
+	}
+	return clone(address), nil
+}
+
+func (c *Client) GetPropertyAddresses(ctx context.Context, organizationID string) ([]*nhd_report.PropertyAddress, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	addresses := make([]*nhd_report.PropertyAddress, 0, len(c.addresses))
+	for _, address := range c.addresses {
+		if organizationID != "" && address.OrganizationId != organizationID {
+			continue
+		}
+		addresses = append(addresses, clone(address))
+	}
+	return addresses, nil
+}
+
+func (c *Client) UpdatePropertyAddress(ctx context.Context, address *nhd_report.PropertyAddress) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.addresses[address.PropertyAddressId]; !ok {
+		return fmt.Errorf("property address %s: %w", address.PropertyAddressId, interfaces.ErrNotFound)
+	}
+	c.addresses[address.PropertyAddressId] = clone(address)
+	return nil
 }
 
 // --- Report Run Methods ---
@@ -81,67 +248,278 @@ func (c *Client) CreateReportRun(ctx context.Context, reportRun *nhd_report.Repo
 	defer c.mu.Unlock()
 	newID := uuid.New().String()
 	reportRun.ReportRunId = newID
-	c.reports[newID] = reportRun
+	c.reports[newID] = clone(reportRun)
 	return &firestore.DocumentRef{ID: newID}, nil, nil
 }
 
-func (c *Client) GetReportRuns(ctx context.Context, paymentStatusFilter string) ([]*nhd_report.ReportRun, error) {
+func (c *Client) CreateReportRunForAddress(ctx context.Context, reportRun *nhd_report.ReportRun, address *nhd_report.PropertyAddress) (*firestore.DocumentRef, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var match *nhd_report.PropertyAddress
+	for _, existing := range c.addresses {
+		if existing.OrganizationId == address.OrganizationId && existing.NormalizedKey == address.NormalizedKey {
+			match = existing
+			break
+		}
+	}
+	if match == nil {
+		address.PropertyAddressId = uuid.New().String()
+		match = clone(address)
+		c.addresses[match.PropertyAddressId] = match
+	} else if match.Coordinates == nil && address.Coordinates != nil {
+		match.Coordinates = clone(address.Coordinates)
+	}
+	newID := uuid.New().String()
+	reportRun.ReportRunId = newID
+	reportRun.PropertyAddressId = match.PropertyAddressId
+	c.reports[newID] = clone(reportRun)
+	return &firestore.DocumentRef{ID: newID}, nil
+}
+
+func (c *Client) GetReportRun(ctx context.Context, reportRunID string) (*nhd_report.ReportRun, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	report, ok := c.reports[reportRunID]
+	if !ok {
+		return nil, fmt.Errorf("report run %s: %w", reportRunID, interfaces.ErrNotFound)
+	}
+	return clone(report), nil
+}
+
+func (c *Client) GetReportRuns(ctx context.Context, query interfaces.ReportRunQuery) (*interfaces.ReportRunPage, error) {
+	if err := query.Normalize(); err != nil {
+		return nil, err
+	}
+	var cursor *interfaces.PageCursor
+	if query.PageToken != "" {
+		cursor, _ = query.Cursor()
+	}
+	_, descending := query.OrderField()
+
+	// less reports whether a sorts before b in the requested order, breaking
+	// ties on the report run ID.
+	less := func(aValue time.Time, aID string, bValue time.Time, bID string) bool {
+		if !aValue.Equal(bValue) {
+			return aValue.Before(bValue) != descending
+		}
+		return (aID < bID) != descending
+	}
+
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	reports := make([]*nhd_report.ReportRun, 0, len(c.reports))
 	for _, report := range c.reports {
-		if paymentStatusFilter != "" {
-			if report.PaymentDetails != nil && report.PaymentDetails.Status.String() == paymentStatusFilter {
-				reports = append(reports, report)
-			}
-		} else {
-			reports = append(reports, report)
+		if !matchesReportRunQuery(report, &query) {
+			continue
+		}
+		if cursor != nil && !less(cursor.Value, cursor.ReportRunID, query.SortValue(report), report.ReportRunId) {
+			continue
 		}
+		reports = append(reports, clone(report))
 	}
-	return reports, nil
+	sort.Slice(reports, func(i, j int) bool {
+		return less(query.SortValue(reports[i]), reports[i].ReportRunId, query.SortValue(reports[j]), reports[j].ReportRunId)
+	})
+
+	page := &interfaces.ReportRunPage{ReportRuns: reports}
+	if len(reports) > query.PageSize {
+		page.ReportRuns = reports[:query.PageSize]
+		page.NextPageToken = query.NextPageToken(page.ReportRuns[query.PageSize-1])
+	}
+	return page, nil
 }
 
-func (c *Client) UpdateReportCost(ctx context.Context, reportRunID string, newCost *nhd_report.ReportRun_ReportCost) error {
+func matchesReportRunQuery(report *nhd_report.ReportRun, query *interfaces.ReportRunQuery) bool {
+	if query.OrganizationID != "" && report.OrganizationId != query.OrganizationID {
+		return false
+	}
+	if query.CustomerID != "" && report.CustomerId != query.CustomerID {
+		return false
+	}
+	if query.CreatedByUserID != "" && report.CreatedByUserId != query.CreatedByUserID {
+		return false
+	}
+	if query.Status != nhd_report.ReportRun_STATUS_UNSPECIFIED && report.Status != query.Status {
+		return false
+	}
+	if query.PaymentStatus != nhd_report.ReportRun_Payment_PAYMENT_STATUS_UNSPECIFIED && report.GetPaymentDetails().GetStatus() != query.PaymentStatus {
+		return false
+	}
+	createdAt := report.GetCreatedAt().AsTime()
+	if !query.CreatedAfter.IsZero() && createdAt.Before(query.CreatedAfter) {
+		return false
+	}
+	if !query.CreatedBefore.IsZero() && !createdAt.Before(query.CreatedBefore) {
+		return false
+	}
+	return true
+}
+
+func (c *Client) AppendEmailDelivery(ctx context.Context, reportRunID string, delivery *nhd_report.ReportRun_EmailDelivery) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	report, ok := c.reports[reportRunID]
 	if !ok {
-		return fmt.Errorf("report not found")
+		return fmt.Errorf("report run %s: %w", reportRunID, interfaces.ErrNotFound)
 	}
-	report.CostHistory = append(report.CostHistory, newCost)
+	report.EmailDeliveries = append(report.EmailDeliveries, clone(delivery))
+	report.UpdatedAt = timestamppb.Now()
 	return nil
 }
 
-func (c *Client) RecordReportPayment(ctx context.Context, reportRunID string, payment *nhd_report.ReportRun_Payment) error {
+func (c *Client) UpdateReportRunStatus(ctx context.Context, reportRunID string, status nhd_report.ReportRun_Status, actor, reason string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	report, ok := c.reports[reportRunID]
+	if !ok {
+		return fmt.Errorf("report run %s: %w", reportRunID, interfaces.ErrNotFound)
+	}
+	_, err := interfaces.ChangeReportRunStatus(report, status, actor, reason, time.Now())
+	return err
+}
+
+func (c *Client) CompleteReportRun(ctx context.Context, reportRunID string, results *nhd_report.ReportRun_HazardResults, actor string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	report, ok := c.reports[reportRunID]
 	if !ok {
-		return fmt.Errorf("report not found")
+		return fmt.Errorf("report run %s: %w", reportRunID, interfaces.ErrNotFound)
+	}
+	if _, err := interfaces.ChangeReportRunStatus(report, nhd_report.ReportRun_COMPLETED, actor, "", time.Now()); err != nil {
+		return err
 	}
-	report.PaymentDetails = payment
+	report.Results = clone(results)
 	return nil
 }
 
-func (c *Client) GetPaidReportsSummary(ctx context.Context) (*interfaces.FinancialsSummary, error) {
+func (c *Client) UpdateReportCost(ctx context.Context, reportRunID string, newCost *nhd_report.ReportRun_ReportCost) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	report, ok := c.reports[reportRunID]
+	if !ok {
+		return fmt.Errorf("report run %s: %w", reportRunID, interfaces.ErrNotFound)
+	}
+	return interfaces.ChangeReportCost(report, clone(newCost))
+}
+
+func (c *Client) AppendLedgerEntry(ctx context.Context, reportRunID string, entry *nhd_report.ReportRun_LedgerEntry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	report, ok := c.reports[reportRunID]
+	if !ok {
+		return fmt.Errorf("report run %s: %w", reportRunID, interfaces.ErrNotFound)
+	}
+	return interfaces.AppendLedgerEntry(report, clone(entry))
+}
+
+func (c *Client) GetPaidReportsSummary(ctx context.Context, query interfaces.FinancialsQuery) (*interfaces.FinancialsSummary, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	summary := &interfaces.FinancialsSummary{}
-	var totalRevenue float64
-
+	var paidReports []interfaces.PaidReportInfo
 	for _, report := range c.reports {
-		if report.PaymentDetails != nil && report.PaymentDetails.Status == nhd_report.ReportRun_Payment_PAID {
-			totalRevenue += report.PaymentDetails.AmountPaid
-			paidReport := interfaces.PaidReportInfo{
-				CustomerName:    "Customer " + report.CustomerId, // Placeholder
-				PropertyAddress: "Address for " + report.PropertyAddressId, // Placeholder
-				AmountPaid:      report.PaymentDetails.AmountPaid,
-				PaidAt:          report.PaymentDetails.PaidAt.AsTime().Format("2006-01-02"),
-			}
-			summary.PaidReports = append(summary.PaidReports, paidReport)
+		if query.OrganizationID != "" && report.OrganizationId != query.OrganizationID {
+			continue
+		}
+		paidReport, ok := query.NewPaidReportInfo(clone(report))
+		if !ok {
+			continue
+		}
+		if customer, ok := c.customers[report.CustomerId]; ok {
+			paidReport.CustomerName = customer.FullName
+		}
+		if address, ok := c.addresses[report.PropertyAddressId]; ok {
+			paidReport.PropertyAddress = postal.Format(address.GetAddressDetails())
+		}
+		paidReports = append(paidReports, paidReport)
+	}
+	return query.NewFinancialsSummary(paidReports, money.NewConverter(c.rates))
+}
+
+// --- Pricing Methods ---
+
+func (c *Client) CreatePriceRule(ctx context.Context, rule *nhd_report.PriceRule) (*firestore.DocumentRef, *firestore.WriteResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	newID := uuid.New().String()
+	rule.PriceRuleId = newID
+	c.prices = append(c.prices, clone(rule))
+	return &firestore.DocumentRef{ID: newID}, nil, nil
+}
+
+func (c *Client) GetPriceRules(ctx context.Context) ([]*nhd_report.PriceRule, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	rules := make([]*nhd_report.PriceRule, 0, len(c.prices))
+	for _, rule := range c.prices {
+		rules = append(rules, clone(rule))
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].GetEffectiveFrom().AsTime().Before(rules[j].GetEffectiveFrom().AsTime())
+	})
+	return rules, nil
+}
+
+// --- Exchange Rate Methods ---
+
+func (c *Client) CreateExchangeRate(ctx context.Context, rate *nhd_report.ExchangeRate) (*firestore.DocumentRef, *firestore.WriteResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	newID := uuid.New().String()
+	rate.ExchangeRateId = newID
+	c.rates = append(c.rates, clone(rate))
+	return &firestore.DocumentRef{ID: newID}, nil, nil
+}
+
+func (c *Client) GetExchangeRates(ctx context.Context) ([]*nhd_report.ExchangeRate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	rates := make([]*nhd_report.ExchangeRate, 0, len(c.rates))
+	for _, rate := range c.rates {
+		rates = append(rates, clone(rate))
+	}
+	sort.SliceStable(rates, func(i, j int) bool {
+		return rates[i].GetEffectiveFrom().AsTime().Before(rates[j].GetEffectiveFrom().AsTime())
+	})
+	return rates, nil
+}
+
+// --- Idempotency Methods ---
+
+// ClaimIdempotencyKey also discards every expired record, which stands in for
+// the TTL policy of the Firestore collection.
+func (c *Client) ClaimIdempotencyKey(ctx context.Context, record *interfaces.IdempotencyRecord) (*interfaces.IdempotencyRecord, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, existing := range c.idempotency {
+		if existing.Expired(record.CreatedAt) {
+			delete(c.idempotency, key)
 		}
 	}
-	summary.TotalRevenue = totalRevenue
-	return summary, nil
+	if existing, ok := c.idempotency[record.Key]; ok {
+		copied := *existing
+		return &copied, nil
+	}
+	copied := *record
+	c.idempotency[record.Key] = &copied
+	return nil, nil
+}
+
+func (c *Client) SaveIdempotencyRecord(ctx context.Context, record *interfaces.IdempotencyRecord) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	copied := *record
+	c.idempotency[record.Key] = &copied
+	return nil
+}
+
+func (c *Client) DeleteIdempotencyKey(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.idempotency, key)
+	return nil
 }
@@ -0,0 +1,78 @@
+package memstore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/seans3/nhd/backend/proto/gen/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestClient_ReturnsCopies(t *testing.T) {
+	ctx := context.Background()
+	c := NewClient()
+
+	customer := &nhd_report.Customer{FullName: "Alice", OrganizationId: "org1"}
+	ref, _, err := c.CreateCustomer(ctx, customer)
+	require.NoError(t, err)
+
+	// Changing the created document or a fetched copy does not change the
+	// stored one.
+	customer.FullName = "Mallory"
+	got, err := c.GetCustomer(ctx, ref.ID)
+	require.NoError(t, err)
+	got.Email = "mallory@example.com"
+
+	got, err = c.GetCustomer(ctx, ref.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", got.FullName)
+	assert.Empty(t, got.Email)
+
+	runRef, _, err := c.CreateReportRun(ctx, &nhd_report.ReportRun{OrganizationId: "org1", Status: nhd_report.ReportRun_PENDING})
+	require.NoError(t, err)
+	run, err := c.GetReportRun(ctx, runRef.ID)
+	require.NoError(t, err)
+	run.Status = nhd_report.ReportRun_COMPLETED
+
+	run, err = c.GetReportRun(ctx, runRef.ID)
+	require.NoError(t, err)
+	assert.Equal(t, nhd_report.ReportRun_PENDING, run.Status)
+}
+
+func TestClient_CreateOrganizationConflict(t *testing.T) {
+	ctx := context.Background()
+	c := NewClient()
+
+	_, _, err := c.CreateOrganization(ctx, &nhd_report.Organization{OrganizationId: "org1", Name: "Acme"})
+	require.NoError(t, err)
+	_, _, err = c.CreateOrganization(ctx, &nhd_report.Organization{OrganizationId: "org1", Name: "Mallory"})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	org, err := c.GetOrganization(ctx, "org1")
+	require.NoError(t, err)
+	assert.Equal(t, "Acme", org.Name)
+}
+
+func TestClient_CreateWithExistingIDConflicts(t *testing.T) {
+	ctx := context.Background()
+	c := NewClient()
+
+	_, _, err := c.CreateCustomer(ctx, &nhd_report.Customer{CustomerId: "cust1", FullName: "Alice"})
+	require.NoError(t, err)
+	_, _, err = c.CreateCustomer(ctx, &nhd_report.Customer{CustomerId: "cust1", FullName: "Mallory"})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	customer, err := c.GetCustomer(ctx, "cust1")
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", customer.FullName)
+
+	_, _, err = c.CreatePropertyAddress(ctx, &nhd_report.PropertyAddress{PropertyAddressId: "addr1", NormalizedKey: "1 main st"})
+	require.NoError(t, err)
+	_, _, err = c.CreatePropertyAddress(ctx, &nhd_report.PropertyAddress{PropertyAddressId: "addr1", NormalizedKey: "2 main st"})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	address, err := c.GetPropertyAddress(ctx, "addr1")
+	require.NoError(t, err)
+	assert.Equal(t, "1 main st", address.NormalizedKey)
+}
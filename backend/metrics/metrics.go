@@ -1,9 +1,33 @@
+// Package metrics records request and business metrics and serves them in the
+// Prometheus text exposition format, which both Prometheus and Cloud
+// Monitoring's managed collection can scrape.
 package metrics
 
 import (
-	"encoding/json"
+	"fmt"
 	"net/http"
-	"sync"
+	"time"
+
+	"github.com/seans3/nhd/backend/middleware"
+)
+
+// Business metrics, recorded where the events happen and served by every
+// MetricsHandler.
+var (
+	ReportRunsCreated = Default.NewCounterVec("nhd_report_runs_created_total",
+		"Report runs created through the API.")
+	PaymentsRecorded = Default.NewCounterVec("nhd_payments_recorded_total",
+		"Payments recorded against report runs, by resulting payment status.", "status")
+	RefundsRecorded = Default.NewCounterVec("nhd_refunds_recorded_total",
+		"Refunds recorded against report runs, by resulting payment status.", "status")
+	PublishFailures = Default.NewCounterVec("nhd_publish_failures_total",
+		"Messages that could not be published, by topic.", "topic")
+	ReportRunsProcessed = Default.NewCounterVec("nhd_worker_report_runs_processed_total",
+		"Report runs finished by the worker, by outcome (completed or failed).", "outcome")
+	ReportRunsInFlight = Default.NewGaugeVec("nhd_worker_report_runs_in_flight",
+		"Report requests the worker is currently processing.")
+	RateLimited = Default.NewCounterVec("nhd_rate_limited_total",
+		"Requests rejected by the rate limiter, by route class.", "class")
 )
 
 // statusCodeRecorder is a wrapper around http.ResponseWriter to capture the status code.
@@ -18,20 +42,32 @@ func (rec *statusCodeRecorder) WriteHeader(statusCode int) {
 	rec.ResponseWriter.WriteHeader(statusCode)
 }
 
-// MetricsHandler holds the metrics data and provides the middleware and handler.
+// MetricsHandler holds the HTTP metrics and provides the middleware and handler.
 type MetricsHandler struct {
-	mu          sync.RWMutex
-	statusCodes map[int]int
+	registry *Registry
+	requests *CounterVec
+	duration *HistogramVec
+	inFlight *GaugeVec
 }
 
 // NewMetricsHandler creates a new MetricsHandler.
 func NewMetricsHandler() *MetricsHandler {
+	registry := NewRegistry()
 	return &MetricsHandler{
-		statusCodes: make(map[int]int),
+		registry: registry,
+		requests: registry.NewCounterVec("nhd_http_requests_total",
+			"HTTP requests served, by route pattern, method and status class.", "route", "method", "status_class"),
+		duration: registry.NewHistogramVec("nhd_http_request_duration_seconds",
+			"HTTP request latency, by route pattern, method and status class.", DefaultBuckets, "route", "method", "status_class"),
+		inFlight: registry.NewGaugeVec("nhd_http_requests_in_flight",
+			"HTTP requests currently being served."),
 	}
 }
 
-// Middleware is the middleware function to record metrics.
+// Middleware is the middleware function to record metrics. Requests are
+// labeled with the route pattern recorded by middleware.Route, so it must run
+// inside middleware.Logging; requests that match no route are labeled
+// "unmatched".
 func (mh *MetricsHandler) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Don't record metrics for the metrics endpoint itself.
@@ -40,22 +76,27 @@ func (mh *MetricsHandler) Middleware(next http.Handler) http.Handler {
 			return
 		}
 
+		start := time.Now()
+		mh.inFlight.Inc()
+		defer mh.inFlight.Dec()
+
 		recorder := &statusCodeRecorder{ResponseWriter: w, statusCode: http.StatusOK}
 		next.ServeHTTP(recorder, r)
 
-		mh.mu.Lock()
-		defer mh.mu.Unlock()
-		mh.statusCodes[recorder.statusCode]++
+		route := "unmatched"
+		if info := middleware.Info(r.Context()); info != nil && info.Route != "" {
+			route = info.Route
+		}
+		statusClass := fmt.Sprintf("%dxx", recorder.statusCode/100)
+		mh.requests.Inc(route, r.Method, statusClass)
+		mh.duration.Observe(time.Since(start).Seconds(), route, r.Method, statusClass)
 	})
 }
 
-// Handler is the HTTP handler to serve the metrics.
+// Handler is the HTTP handler to serve the metrics: the HTTP metrics followed
+// by the business metrics in Default.
 func (mh *MetricsHandler) Handler(w http.ResponseWriter, r *http.Request) {
-	mh.mu.RLock()
-	defer mh.mu.RUnlock()
-
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(mh.statusCodes); err != nil {
-		http.Error(w, "Failed to encode metrics", http.StatusInternalServerError)
-	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	mh.registry.WriteTo(w)
+	Default.WriteTo(w)
 }
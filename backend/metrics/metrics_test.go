@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/seans3/nhd/backend/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry_WritesTextFormat(t *testing.T) {
+	r := NewRegistry()
+	runs := r.NewCounterVec("runs_total", "Runs.")
+	payments := r.NewCounterVec("payments_total", "Payments.", "status")
+	latency := r.NewHistogramVec("latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+
+	runs.Inc()
+	payments.Inc("PAID")
+	payments.Inc("PAID")
+	payments.Inc(`a"b`)
+	latency.Observe(0.05, "/x")
+	latency.Observe(0.1, "/x")
+	latency.Observe(3, "/x")
+	// Reading a series that was never written does not export it.
+	assert.Zero(t, payments.Value("REFUNDED"))
+	assert.Equal(t, float64(2), payments.Value("PAID"))
+
+	var b strings.Builder
+	_, err := r.WriteTo(&b)
+	assert.NoError(t, err)
+	assert.Equal(t, `# HELP runs_total Runs.
+# TYPE runs_total counter
+runs_total 1
+# HELP payments_total Payments.
+# TYPE payments_total counter
+payments_total{status="PAID"} 2
+payments_total{status="a\"b"} 1
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/x",le="0.1"} 2
+latency_seconds_bucket{route="/x",le="1"} 2
+latency_seconds_bucket{route="/x",le="+Inf"} 3
+latency_seconds_sum{route="/x"} 3.15
+latency_seconds_count{route="/x"} 3
+`, b.String())
+}
+
+func TestMiddleware_LabelsByRouteMethodAndStatusClass(t *testing.T) {
+	mh := NewMetricsHandler()
+	apiMux := http.NewServeMux()
+	apiMux.HandleFunc("GET /report-runs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") == "missing" {
+			http.NotFound(w, r)
+		}
+	})
+	mux := http.NewServeMux()
+	mux.Handle("/api/", http.StripPrefix("/api", middleware.Route("/api", apiMux)))
+	mux.HandleFunc("GET /metrics", mh.Handler)
+	handler := middleware.Logging(mh.Middleware(middleware.Route("", mux)))
+
+	for _, path := range []string{"/api/report-runs/a", "/api/report-runs/b", "/api/report-runs/missing", "/nowhere"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	assert.Equal(t, float64(2), mh.requests.Value("/api/report-runs/{id}", "GET", "2xx"))
+	assert.Equal(t, float64(1), mh.requests.Value("/api/report-runs/{id}", "GET", "4xx"))
+	assert.Equal(t, float64(1), mh.requests.Value("unmatched", "GET", "4xx"))
+	assert.Equal(t, float64(0), mh.inFlight.Value())
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rec.Header().Get("Content-Type"))
+	body := rec.Body.String()
+	assert.Contains(t, body, `nhd_http_requests_total{route="/api/report-runs/{id}",method="GET",status_class="2xx"} 2`)
+	assert.Contains(t, body, `nhd_http_request_duration_seconds_count{route="/api/report-runs/{id}",method="GET",status_class="4xx"} 1`)
+	assert.Contains(t, body, "nhd_http_requests_in_flight 0")
+	assert.Contains(t, body, "# TYPE nhd_report_runs_created_total counter")
+}
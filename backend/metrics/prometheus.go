@@ -0,0 +1,238 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of the latency histogram
+// buckets. They match the Prometheus client libraries' defaults.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// A Registry holds a set of metrics and writes them in the Prometheus text
+// exposition format.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []*family
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Default holds the business metrics recorded by the API and the worker.
+var Default = NewRegistry()
+
+// family is a metric name with one series per combination of label values.
+type family struct {
+	name, help, kind string
+	labels           []string
+	buckets          []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	// value is the counter or gauge value. For histograms, counts holds the
+	// number of observations in each bucket (not cumulative), and value the
+	// sum of all observations.
+	value  float64
+	counts []uint64
+}
+
+func (r *Registry) register(kind, name, help string, labels []string, buckets []float64) *family {
+	f := &family{name: name, help: help, kind: kind, labels: labels, buckets: buckets, series: map[string]*series{}}
+	if len(labels) == 0 {
+		// Series without labels are reported from the start, as zero.
+		f.get()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, m := range r.metrics {
+		if m.name == name {
+			panic(fmt.Sprintf("metrics: %s registered twice", name))
+		}
+	}
+	r.metrics = append(r.metrics, f)
+	return f
+}
+
+// get returns the series for labelValues, creating it if needed. The caller
+// must not hold f.mu.
+func (f *family) get(labelValues ...string) *series {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s has %d labels, got %d values", f.name, len(f.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		if f.buckets != nil {
+			s.counts = make([]uint64, len(f.buckets)+1)
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+func (f *family) add(delta float64, labelValues []string) {
+	s := f.get(labelValues...)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s.value += delta
+}
+
+// A CounterVec is a counter partitioned by labels.
+type CounterVec struct{ f *family }
+
+// NewCounterVec registers a counter. Counter names should end in _total.
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{r.register("counter", name, help, labels, nil)}
+}
+
+// Inc adds one to the series for labelValues.
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.f.add(1, labelValues)
+}
+
+// Value returns the current value of the series for labelValues.
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	return c.f.value(labelValues)
+}
+
+// A GaugeVec is a gauge partitioned by labels.
+type GaugeVec struct{ f *family }
+
+// NewGaugeVec registers a gauge.
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{r.register("gauge", name, help, labels, nil)}
+}
+
+// Inc adds one to the series for labelValues.
+func (g *GaugeVec) Inc(labelValues ...string) {
+	g.f.add(1, labelValues)
+}
+
+// Dec subtracts one from the series for labelValues.
+func (g *GaugeVec) Dec(labelValues ...string) {
+	g.f.add(-1, labelValues)
+}
+
+// Value returns the current value of the series for labelValues.
+func (g *GaugeVec) Value(labelValues ...string) float64 {
+	return g.f.value(labelValues)
+}
+
+// value returns the value of the series for labelValues, or zero if it does
+// not exist. Unlike get, it never creates the series, so reading a metric
+// does not change what is exported.
+func (f *family) value(labelValues []string) float64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if s, ok := f.series[strings.Join(labelValues, "\xff")]; ok {
+		return s.value
+	}
+	return 0
+}
+
+// A HistogramVec is a histogram partitioned by labels.
+type HistogramVec struct{ f *family }
+
+// NewHistogramVec registers a histogram with the given bucket upper bounds,
+// which must be sorted in increasing order.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	return &HistogramVec{r.register("histogram", name, help, labels, buckets)}
+}
+
+// Observe records v in the series for labelValues.
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	s := h.f.get(labelValues...)
+	i := sort.SearchFloat64s(h.f.buckets, v)
+	h.f.mu.Lock()
+	defer h.f.mu.Unlock()
+	s.counts[i]++
+	s.value += v
+}
+
+// WriteTo writes every metric in r in the Prometheus text exposition format.
+// Series are sorted by their label values so that the output is stable.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	metrics := append([]*family(nil), r.metrics...)
+	r.mu.Unlock()
+
+	var b strings.Builder
+	for _, f := range metrics {
+		f.write(&b)
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func (f *family) write(b *strings.Builder) {
+	fmt.Fprintf(b, "# HELP %s %s\n", f.name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(f.help))
+	fmt.Fprintf(b, "# TYPE %s %s\n", f.name, f.kind)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s := f.series[key]
+		if f.buckets == nil {
+			fmt.Fprintf(b, "%s%s %s\n", f.name, f.labelPairs(s.labelValues, ""), formatFloat(s.value))
+			continue
+		}
+		var cumulative uint64
+		for i, upper := range f.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(b, "%s_bucket%s %d\n", f.name, f.labelPairs(s.labelValues, formatFloat(upper)), cumulative)
+		}
+		cumulative += s.counts[len(f.buckets)]
+		fmt.Fprintf(b, "%s_bucket%s %d\n", f.name, f.labelPairs(s.labelValues, "+Inf"), cumulative)
+		fmt.Fprintf(b, "%s_sum%s %s\n", f.name, f.labelPairs(s.labelValues, ""), formatFloat(s.value))
+		fmt.Fprintf(b, "%s_count%s %d\n", f.name, f.labelPairs(s.labelValues, ""), cumulative)
+	}
+}
+
+// labelPairs formats labelValues as {name="value",...}, adding an le label
+// when le is not empty.
+func (f *family) labelPairs(labelValues []string, le string) string {
+	var pairs []string
+	for i, name := range f.labels {
+		pairs = append(pairs, name+`="`+escapeLabelValue(labelValues[i])+`"`)
+	}
+	if le != "" {
+		pairs = append(pairs, `le="`+le+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(v string) string {
+	return labelValueEscaper.Replace(v)
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
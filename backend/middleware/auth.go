@@ -2,11 +2,15 @@ package middleware
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 
+	"github.com/seans3/nhd/backend/apierror"
 	"github.com/seans3/nhd/backend/interfaces"
+	"github.com/seans3/nhd/backend/logging"
+	"github.com/seans3/nhd/backend/proto/gen/go"
 )
 
 type AuthClient struct {
@@ -17,56 +21,118 @@ type AuthClient struct {
 // ContextKey is a custom type for context keys to avoid collisions.
 type ContextKey string
 
-const UserIDKey ContextKey = "userID"
+const (
+	UserIDKey ContextKey = "userID"
+	// UserKey holds the authenticated user's *nhd_report.User profile.
+	UserKey ContextKey = "user"
+)
+
+// CurrentUser returns the authenticated user's profile, as loaded by
+// VerifyAuthToken, or nil if the user has not been registered.
+func CurrentUser(ctx context.Context) *nhd_report.User {
+	user, _ := ctx.Value(UserKey).(*nhd_report.User)
+	return user
+}
 
+// VerifyAuthToken authenticates the request's bearer token and loads the
+// user's profile, if there is one, so that handlers can scope their work to
+// the user's organization. Users without a profile are still let through;
+// routes that need one use RequireAdmin or RequirePermission.
 func (ac *AuthClient) VerifyAuthToken(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			http.Error(w, "Authorization header required", http.StatusUnauthorized)
+			apierror.Write(w, r, apierror.New(apierror.Unauthenticated, "Authorization header required"))
 			return
 		}
 
 		tokenParts := strings.Split(authHeader, " ")
 		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
-			http.Error(w, "Invalid Authorization header format", http.StatusUnauthorized)
+			apierror.Write(w, r, apierror.New(apierror.Unauthenticated, "Invalid Authorization header format"))
 			return
 		}
 
 		idToken := tokenParts[1]
 		token, err := ac.Firebase.VerifyIDToken(r.Context(), idToken)
 		if err != nil {
-			log.Printf("Error verifying ID token: %v\n", err)
-			http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+			logging.FromContext(r.Context()).Warn("Error verifying ID token", "error", err)
+			apierror.Write(w, r, apierror.New(apierror.Unauthenticated, "Invalid or expired token"))
 			return
 		}
 
-		// Add user ID to the context
+		// Add user ID to the context and to every log line for the request
 		ctx := context.WithValue(r.Context(), UserIDKey, token.UID)
+		ctx = logging.With(ctx, "user_id", token.UID)
+		if info := Info(ctx); info != nil {
+			info.UserID = token.UID
+		}
+
+		user, err := ac.DS.GetUserByID(ctx, token.UID)
+		if err != nil && !errors.Is(err, interfaces.ErrNotFound) {
+			apierror.Write(w, r.WithContext(ctx), apierror.Wrap(apierror.From(err).Code, err, "Could not retrieve user profile"))
+			return
+		}
+		if user != nil {
+			ctx = context.WithValue(ctx, UserKey, user)
+			ctx = logging.With(ctx, "organization_id", user.OrganizationId)
+		}
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
 func (ac *AuthClient) RequireAdmin(next http.Handler) http.Handler {
-	return ac.VerifyAuthToken(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		uid, ok := r.Context().Value(UserIDKey).(string)
-		if !ok {
-			// This should not happen if VerifyFirebaseToken runs first
-			http.Error(w, "User ID not found in context", http.StatusInternalServerError)
+	return ac.VerifyAuthToken(ac.requireUser(func(p *nhd_report.Permissions) bool { return p.GetIsAdmin() }, "Admin privileges required", next))
+}
+
+// Permission names one of the flags in a user's Permissions.
+type Permission string
+
+const (
+	CanCreateCustomers Permission = "can_create_customers"
+	CanGenerateReports Permission = "can_generate_reports"
+)
+
+// granted reports whether perms includes p.
+func (p Permission) granted(perms *nhd_report.Permissions) bool {
+	switch p {
+	case CanCreateCustomers:
+		return perms.GetCanCreateCustomers()
+	case CanGenerateReports:
+		return perms.GetCanGenerateReports()
+	}
+	return false
+}
+
+// RequirePermission serves next only if the authenticated user's profile
+// grants p. It must run after VerifyAuthToken. Users without a profile, or
+// whose profile lacks p, get 403 Forbidden. Being an admin does not imply
+// other permissions; admins need the flag like anyone else.
+func (ac *AuthClient) RequirePermission(p Permission, next http.Handler) http.Handler {
+	return ac.requireUser(p.granted, fmt.Sprintf("Permission %s required", p), next)
+}
+
+// requireUser serves next only if the authenticated user has a profile and
+// allowed returns true for its permissions. Otherwise it responds 403 with
+// denied as the message.
+func (ac *AuthClient) requireUser(allowed func(*nhd_report.Permissions) bool, denied string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(UserIDKey).(string); !ok {
+			// This should not happen if VerifyAuthToken runs first
+			apierror.Write(w, r, apierror.New(apierror.Unauthenticated, "User ID not found in context"))
 			return
 		}
 
-		user, err := ac.DS.GetUserByID(r.Context(), uid)
-		if err != nil {
-			http.Error(w, "Could not retrieve user profile", http.StatusInternalServerError)
+		user := CurrentUser(r.Context())
+		if user == nil {
+			apierror.Write(w, r, apierror.New(apierror.PermissionDenied, "No user profile found; ask an administrator to register you"))
 			return
 		}
 
-		if user.Permissions == nil || !user.Permissions.IsAdmin {
-			http.Error(w, "Admin privileges required", http.StatusForbidden)
+		if !allowed(user.Permissions) {
+			apierror.Write(w, r, apierror.New(apierror.PermissionDenied, "%s", denied))
 			return
 		}
 
 		next.ServeHTTP(w, r)
-	}))
+	})
 }
@@ -0,0 +1,207 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/seans3/nhd/backend/apierror"
+	"github.com/seans3/nhd/backend/interfaces"
+	"github.com/seans3/nhd/backend/logging"
+)
+
+const (
+	// IdempotencyKeyHeader is the request header that carries a client's
+	// idempotency key.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set to "true" on replayed responses.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	// DefaultIdempotencyTTL is how long responses are kept when
+	// Idempotency.TTL is not set.
+	DefaultIdempotencyTTL = 24 * time.Hour
+	// DefaultIdempotencyLease is how long a key is held for the request
+	// being served with it when Idempotency.Lease is not set.
+	DefaultIdempotencyLease = time.Minute
+	// maxIdempotencyKeyLength is the longest key accepted.
+	maxIdempotencyKeyLength = 255
+)
+
+// Idempotency makes POST and PUT requests sent with an Idempotency-Key header
+// safe to retry. The first request with a key is served as usual and its
+// response stored in the datastore; a retry with the same key and the same
+// request replays the stored response instead of being served again.
+type Idempotency struct {
+	DS interfaces.Datastore
+	// TTL is how long a key and its response are kept. Defaults to
+	// DefaultIdempotencyTTL.
+	TTL time.Duration
+	// Lease is how long a key is held while the first request with it is
+	// being served. If the instance serving it dies before storing the
+	// outcome, the key can be claimed again once the lease has run out, so
+	// it should be longer than the request timeout. Defaults to
+	// DefaultIdempotencyLease.
+	Lease time.Duration
+	// Now defaults to time.Now.
+	Now func() time.Time
+}
+
+// Middleware applies idempotency keys to POST and PUT requests. Keys are
+// scoped to the authenticated user, so it must run after VerifyAuthToken.
+//
+// A key reused with a different method, URL or body, or while the first
+// request with it is still being served, is rejected with 409 Conflict.
+// A key whose request never finished, because the instance serving it died,
+// is taken over once its lease has run out.
+// Only final outcomes are stored (see storableStatus); for any other
+// response the key is released, so that a request which failed for a
+// reason that may pass can be retried with the same key. If the datastore
+// cannot be reached the request is rejected with 503, since serving it could
+// duplicate its effect.
+func (id *Idempotency) Middleware(next http.Handler) http.Handler {
+	ttl := id.TTL
+	if ttl <= 0 {
+		ttl = DefaultIdempotencyTTL
+	}
+	lease := id.Lease
+	if lease <= 0 {
+		lease = DefaultIdempotencyLease
+	}
+	now := id.Now
+	if now == nil {
+		now = time.Now
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" || (r.Method != http.MethodPost && r.Method != http.MethodPut) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			apierror.Write(w, r, apierror.New(apierror.ValidationFailed, "Idempotency-Key must be at most %d characters", maxIdempotencyKeyLength))
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.ValidationFailed, err, "Could not read request body"))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		uid, _ := r.Context().Value(UserIDKey).(string)
+		created := now()
+		record := &interfaces.IdempotencyRecord{
+			Key:         uid + "|" + key,
+			Fingerprint: fingerprint(r, body),
+			CreatedAt:   created,
+			ExpiresAt:   created.Add(lease),
+		}
+		logger := logging.FromContext(r.Context()).With("idempotency_key", key)
+
+		existing, err := id.DS.ClaimIdempotencyKey(r.Context(), record)
+		if err != nil {
+			apierror.Write(w, r, apierror.Wrap(apierror.UpstreamUnavailable, err, "Could not check Idempotency-Key; try again later"))
+			return
+		}
+		if existing != nil {
+			switch {
+			case existing.Fingerprint != record.Fingerprint:
+				apierror.Write(w, r, apierror.New(apierror.Conflict, "Idempotency-Key has already been used for a different request"))
+			case !existing.Completed:
+				apierror.Write(w, r, apierror.New(apierror.Conflict, "A request with this Idempotency-Key is still being processed"))
+			default:
+				logger.Info("Replaying stored response", "status", existing.StatusCode)
+				if existing.ContentType != "" {
+					w.Header().Set("Content-Type", existing.ContentType)
+				}
+				w.Header().Set(IdempotentReplayedHeader, "true")
+				w.WriteHeader(existing.StatusCode)
+				w.Write(existing.Body)
+			}
+			return
+		}
+
+		// Store the outcome even if the client has gone away: that is when
+		// it is most likely to retry.
+		ctx := context.WithoutCancel(r.Context())
+		release := func() {
+			if err := id.DS.DeleteIdempotencyKey(ctx, record.Key); err != nil {
+				logger.Error("Could not release idempotency key", "error", err)
+			}
+		}
+		// A panicking handler must not leave the key claimed, or every retry
+		// would be told the request is still being processed.
+		defer func() {
+			if p := recover(); p != nil {
+				release()
+				panic(p)
+			}
+		}()
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		if !storableStatus(rec.status) {
+			release()
+			return
+		}
+		record.Completed = true
+		record.ExpiresAt = created.Add(ttl)
+		record.StatusCode = rec.status
+		record.ContentType = rec.Header().Get("Content-Type")
+		record.Body = rec.body.Bytes()
+		if err := id.DS.SaveIdempotencyRecord(ctx, record); err != nil {
+			logger.Error("Could not store response for idempotency key", "error", err)
+		}
+	})
+}
+
+// storableStatus reports whether a response with the given status is the
+// final outcome of its request, to be replayed to every retry: a success, or
+// a rejection of the request itself (400, 404 or 422) that a retry of the
+// same request would get again. Responses that depend on the moment, such as
+// 401, 403, 409, 429 and 5xx, are not stored.
+func storableStatus(status int) bool {
+	switch {
+	case status >= 200 && status < 300:
+		return true
+	case status == http.StatusBadRequest, status == http.StatusNotFound, status == http.StatusUnprocessableEntity:
+		return true
+	}
+	return false
+}
+
+// fingerprint identifies a request by its method, URL and body.
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder passes a response through while keeping a copy of its
+// status and body.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if !rec.wroteHeader {
+		rec.status = status
+		rec.wroteHeader = true
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	rec.wroteHeader = true
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/seans3/nhd/backend/interfaces"
+	"github.com/seans3/nhd/backend/memstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotency_ReplaysAndRejectsMismatches(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	idem := &Idempotency{DS: memstore.NewClient(), TTL: time.Hour, Now: func() time.Time { return now }}
+	calls := 0
+	handler := idem.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"call":%d,"body":%q}`, calls, body)
+	}))
+	do := func(method, uid, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/report-runs", strings.NewReader(body))
+		req = req.WithContext(context.WithValue(req.Context(), UserIDKey, uid))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := do("POST", "alice", "k1", `{"a":1}`)
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Equal(t, `{"call":1,"body":"{\"a\":1}"}`, first.Body.String())
+
+	// A retry replays the stored response without calling the handler.
+	retry := do("POST", "alice", "k1", `{"a":1}`)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, "application/json", retry.Header().Get("Content-Type"))
+	assert.Equal(t, "true", retry.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, 1, calls)
+
+	// The same key with a different body is a conflict.
+	assert.Equal(t, http.StatusConflict, do("POST", "alice", "k1", `{"a":2}`).Code)
+	assert.Equal(t, 1, calls)
+
+	// Keys are per user, and requests without a key or that are not POST
+	// or PUT are always served.
+	assert.Equal(t, http.StatusCreated, do("POST", "bob", "k1", `{"a":1}`).Code)
+	assert.Equal(t, http.StatusCreated, do("POST", "alice", "", `{"a":1}`).Code)
+	assert.Equal(t, http.StatusCreated, do("PATCH", "alice", "k1", `{"a":2}`).Code)
+	assert.Equal(t, 4, calls)
+
+	// Once the key expires it can be reused.
+	now = now.Add(time.Hour)
+	assert.Equal(t, http.StatusCreated, do("POST", "alice", "k1", `{"a":2}`).Code)
+	assert.Equal(t, 5, calls)
+
+	assert.Equal(t, http.StatusBadRequest, do("POST", "alice", strings.Repeat("k", 256), `{}`).Code)
+}
+
+func TestIdempotency_OnlyFinalOutcomesAreStored(t *testing.T) {
+	idem := &Idempotency{DS: memstore.NewClient()}
+	var status int
+	handler := idem.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	do := func(key string) int {
+		req := httptest.NewRequest("PUT", "/report-runs/run1/cost", strings.NewReader(`{}`))
+		req.Header.Set(IdempotencyKeyHeader, key)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Outcomes that may change on retry release the key.
+	for _, status = range []int{http.StatusInternalServerError, http.StatusForbidden, http.StatusConflict, http.StatusTooManyRequests} {
+		assert.Equal(t, status, do("k1"))
+	}
+	status = http.StatusOK
+	assert.Equal(t, http.StatusOK, do("k1"))
+	status = http.StatusTeapot
+	assert.Equal(t, http.StatusOK, do("k1"))
+
+	// A rejected request is rejected again without being served.
+	status = http.StatusUnprocessableEntity
+	assert.Equal(t, http.StatusUnprocessableEntity, do("k2"))
+	status = http.StatusOK
+	assert.Equal(t, http.StatusUnprocessableEntity, do("k2"))
+}
+
+func TestIdempotency_PanicReleasesKey(t *testing.T) {
+	idem := &Idempotency{DS: memstore.NewClient()}
+	panics := true
+	handler := idem.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if panics {
+			panic("boom")
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	do := func() int {
+		req := httptest.NewRequest("POST", "/report-runs", strings.NewReader(`{}`))
+		req.Header.Set(IdempotencyKeyHeader, "k1")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.PanicsWithValue(t, "boom", func() { do() })
+	panics = false
+	assert.Equal(t, http.StatusCreated, do())
+}
+
+func TestIdempotency_ExpiredClaimCanBeTakenOver(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	ds := memstore.NewClient()
+	idem := &Idempotency{DS: ds, TTL: time.Hour, Lease: time.Minute, Now: func() time.Time { return now }}
+	calls := 0
+	handler := idem.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusCreated)
+	}))
+	do := func() int {
+		req := httptest.NewRequest("POST", "/report-runs", strings.NewReader(`{}`))
+		req.Header.Set(IdempotencyKeyHeader, "k1")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Claim the key as a request would, then never finish it, as if the
+	// instance serving it had died.
+	req := httptest.NewRequest("POST", "/report-runs", strings.NewReader(`{}`))
+	existing, err := ds.ClaimIdempotencyKey(context.Background(), &interfaces.IdempotencyRecord{
+		Key:         "|k1",
+		Fingerprint: fingerprint(req, []byte(`{}`)),
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(time.Minute),
+	})
+	assert.NoError(t, err)
+	assert.Nil(t, existing)
+
+	// Within the lease the request is still taken to be in progress.
+	now = now.Add(30 * time.Second)
+	assert.Equal(t, http.StatusConflict, do())
+	assert.Equal(t, 0, calls)
+
+	// Once the lease has run out a retry takes the key over.
+	now = now.Add(30 * time.Second)
+	assert.Equal(t, http.StatusCreated, do())
+	assert.Equal(t, 1, calls)
+
+	// The completed response is kept for the full TTL, not just the lease.
+	now = now.Add(30 * time.Minute)
+	assert.Equal(t, http.StatusCreated, do())
+	assert.Equal(t, 1, calls)
+}
@@ -1,20 +1,131 @@
 package middleware
 
 import (
-	"log"
+	"context"
+	"log/slog"
 	"net/http"
+	"strings"
 	"time"
+
+	"github.com/google/uuid"
+	"github.com/seans3/nhd/backend/logging"
 )
 
-// Logging wraps an HTTP handler to log request details.
+// RequestInfo describes the request being served. Logging stores a pointer
+// to it in the request context; middleware further down the chain fills in
+// what only it knows, such as the route and the authenticated user.
+type RequestInfo struct {
+	ID      string
+	TraceID string
+	// Route is the pattern that matched the request, e.g.
+	// "/api/report-runs/{id}", or "" if no route matched.
+	Route  string
+	UserID string
+}
+
+type requestInfoKey struct{}
+
+// Info returns the RequestInfo for the request ctx belongs to, or nil if the
+// request did not pass through Logging.
+func Info(ctx context.Context) *RequestInfo {
+	info, _ := ctx.Value(requestInfoKey{}).(*RequestInfo)
+	return info
+}
+
+// statusRecorder is a wrapper around http.ResponseWriter to capture the status code.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader captures the status code before writing it.
+func (rec *statusRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+// Logging assigns each request an ID and a trace ID, stores a logger tagged
+// with both in the request context, and logs the outcome of the request once
+// it has been served. The trace ID is taken from an incoming traceparent or
+// X-Cloud-Trace-Context header when there is one. The request ID is returned
+// in the X-Request-ID response header.
 func Logging(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		log.Printf("--> %s %s", r.Method, r.RequestURI)
+		info := &RequestInfo{
+			ID:      uuid.New().String(),
+			TraceID: traceIDFromHeaders(r.Header),
+		}
+		ctx := context.WithValue(r.Context(), requestInfoKey{}, info)
+		ctx = logging.With(ctx, "request_id", info.ID)
+		ctx = logging.WithTraceID(ctx, info.TraceID)
+		w.Header().Set("X-Request-ID", info.ID)
 
 		// Serve the next handler in the chain.
-		next.ServeHTTP(w, r)
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(ctx))
 
-		log.Printf("<-- %s %s (%v)", r.Method, r.RequestURI, time.Since(start))
+		level := slog.LevelInfo
+		switch {
+		case rec.status >= 500:
+			level = slog.LevelError
+		case rec.status >= 400:
+			level = slog.LevelWarn
+		}
+		logging.FromContext(ctx).LogAttrs(ctx, level, "request completed",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.String("route", info.Route),
+			slog.Int("status", rec.status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("user_id", info.UserID),
+		)
+	})
+}
+
+// traceIDFromHeaders returns the trace ID from a W3C traceparent header
+// ("00-<trace-id>-<span-id>-<flags>") or a Cloud Trace
+// X-Cloud-Trace-Context header ("<trace-id>/<span-id>;o=1"), or a new trace
+// ID if neither holds a valid one.
+func traceIDFromHeaders(h http.Header) string {
+	if parts := strings.Split(h.Get("traceparent"), "-"); len(parts) == 4 && isTraceID(parts[1]) {
+		return parts[1]
+	}
+	if traceID, _, _ := strings.Cut(h.Get("X-Cloud-Trace-Context"), "/"); isTraceID(traceID) {
+		return strings.ToLower(traceID)
+	}
+	return logging.NewTraceID()
+}
+
+// isTraceID reports whether s is 32 hex digits and not all zeros.
+func isTraceID(s string) bool {
+	if len(s) != 32 || strings.Trim(s, "0") == "" {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// Route serves requests with mux, first recording the pattern mux matches in
+// the request's RequestInfo. prefix is prepended to the pattern's path; pass
+// the prefix that was stripped before the request reached mux. Requests that
+// match no pattern get mux's 404 or 405 as problem details.
+func Route(prefix string, mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := mux.Handler(r)
+		if pattern == "" {
+			w = &plainErrorWriter{ResponseWriter: w, r: r, convert: unmatchedRouteError(r)}
+		} else if info := Info(r.Context()); info != nil {
+			// Drop the method from patterns such as "GET /customers".
+			if _, path, ok := strings.Cut(pattern, " "); ok {
+				pattern = path
+			}
+			info.Route = prefix + pattern
+		}
+		mux.ServeHTTP(w, r)
 	})
 }
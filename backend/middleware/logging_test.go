@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraceIDFromHeaders(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   string
+	}{
+		{"traceparent", http.Header{"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}}, "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{"cloud trace", http.Header{"X-Cloud-Trace-Context": {"4BF92F3577B34DA6A3CE929D0E0E4736/1;o=1"}}, "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{"all zeros", http.Header{"Traceparent": {"00-00000000000000000000000000000000-00f067aa0ba902b7-01"}}, ""},
+		{"malformed", http.Header{"X-Cloud-Trace-Context": {"not-a-trace"}}, ""},
+		{"none", http.Header{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := traceIDFromHeaders(tt.header)
+			if tt.want != "" {
+				assert.Equal(t, tt.want, got)
+			} else {
+				// A new trace ID is generated.
+				assert.Regexp(t, "^[0-9a-f]{32}$", got)
+				assert.NotEqual(t, "00000000000000000000000000000000", got)
+			}
+		})
+	}
+}
+
+func TestLogging_RecordsRouteAndRequestID(t *testing.T) {
+	var info *RequestInfo
+	apiMux := http.NewServeMux()
+	apiMux.HandleFunc("GET /report-runs/{id}", func(w http.ResponseWriter, r *http.Request) {
+		info = Info(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux := http.NewServeMux()
+	mux.Handle("/api/", http.StripPrefix("/api", Route("/api", apiMux)))
+	handler := Logging(Route("", mux))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/api/report-runs/run1", nil))
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	if assert.NotNil(t, info) {
+		assert.Equal(t, "/api/report-runs/{id}", info.Route)
+		assert.Equal(t, info.ID, rec.Header().Get("X-Request-ID"))
+		assert.Len(t, info.TraceID, 32)
+	}
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/seans3/nhd/backend/apierror"
+)
+
+// plainErrorWriter replaces the plain-text error responses that net/http
+// writes itself, such as ServeMux's 404 and 405 and TimeoutHandler's 503,
+// with problem details, so that clients get every error in one format.
+// Responses with any other Content-Type pass through.
+type plainErrorWriter struct {
+	http.ResponseWriter
+	r *http.Request
+	// convert returns the error to write in place of a plain-text response
+	// with the given status, or nil to pass the response through.
+	convert func(status int) *apierror.Error
+
+	wroteHeader bool
+	// replaced is set once a problem has been written, after which the
+	// plain-text body is dropped.
+	replaced bool
+}
+
+func (w *plainErrorWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if contentType := w.Header().Get("Content-Type"); contentType == "" || strings.HasPrefix(contentType, "text/plain") {
+		if err := w.convert(status); err != nil {
+			w.replaced = true
+			apierror.Write(w.ResponseWriter, w.r, err)
+			return
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *plainErrorWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.replaced {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *plainErrorWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// unmatchedRouteError converts the responses ServeMux writes for requests
+// that match no route.
+func unmatchedRouteError(r *http.Request) func(status int) *apierror.Error {
+	return func(status int) *apierror.Error {
+		switch status {
+		case http.StatusNotFound:
+			return apierror.New(apierror.NotFound, "No such route")
+		case http.StatusMethodNotAllowed:
+			err := apierror.New(apierror.ValidationFailed, "Method %s is not allowed on this route", r.Method)
+			err.Status = status
+			return err
+		}
+		return nil
+	}
+}
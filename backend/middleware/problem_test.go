@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoute_UnmatchedRequestsGetProblems(t *testing.T) {
+	apiMux := http.NewServeMux()
+	apiMux.HandleFunc("GET /customers", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "handler error", http.StatusNotFound)
+	})
+	handler := Route("", apiMux)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+		code   string
+	}{
+		{"unknown path", "GET", "/nowhere", http.StatusNotFound, "NOT_FOUND"},
+		{"wrong method", "DELETE", "/customers", http.StatusMethodNotAllowed, "VALIDATION_FAILED"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+			var problem map[string]any
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+			assert.Equal(t, tt.code, problem["code"])
+			assert.EqualValues(t, tt.status, problem["status"])
+		})
+	}
+
+	// Responses from matched routes are left as the handler wrote them.
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/customers", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "handler error\n", rec.Body.String())
+}
+
+func TestTimeout_WritesProblem(t *testing.T) {
+	handler := Timeout(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}), 10*time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/customers", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+	var problem map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+	assert.Equal(t, "UPSTREAM_UNAVAILABLE", problem["code"])
+
+	// A handler's own 503 passes through.
+	handler = Timeout(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"code":"UPSTREAM_UNAVAILABLE"}`))
+	}), time.Second)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/customers", nil))
+	assert.Equal(t, `{"code":"UPSTREAM_UNAVAILABLE"}`, rec.Body.String())
+}
@@ -1,22 +1,140 @@
 package middleware
 
 import (
+	"math"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
-	"golang.org/x/time/rate"
+	"github.com/seans3/nhd/backend/apierror"
+	"github.com/seans3/nhd/backend/logging"
+	"github.com/seans3/nhd/backend/ratelimit"
 )
 
-// RateLimit creates a middleware that limits requests based on a token bucket algorithm.
-func RateLimit(rps float64, burst int) func(http.Handler) http.Handler {
-	limiter := rate.NewLimiter(rate.Limit(rps), burst)
+// Route classes used by ClassifyRoute. Each class has its own budget, so that
+// a burst of report requests does not stop a principal from listing.
+const (
+	RateLimitClassRead   = "read"
+	RateLimitClassWrite  = "write"
+	RateLimitClassReport = "report"
+)
 
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if !limiter.Allow() {
-				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
-				return
-			}
+// RateLimitClassAuth is the class of every request to a limiter that runs
+// before authentication, which keys each request by client IP.
+const RateLimitClassAuth = "auth"
+
+// RateLimiter limits how often each principal may call each class of route.
+// A principal is the authenticated user, or the client IP for requests that
+// have not been authenticated.
+type RateLimiter struct {
+	Store ratelimit.Store
+	// Limits is the budget for each route class. Requests whose class has
+	// no limit are not limited.
+	Limits map[string]ratelimit.Limit
+	// Classify returns a request's route class. Defaults to ClassifyRoute.
+	Classify func(r *http.Request) string
+	// TrustForwardedFor takes the client IP from the last X-Forwarded-For
+	// entry, which is the address the load balancer in front of the server
+	// (e.g. Cloud Run's) saw. Only enable it behind such a proxy.
+	TrustForwardedFor bool
+	// OnLimited, if set, is called with the class of every rejected request.
+	OnLimited func(class string)
+	// Now defaults to time.Now.
+	Now func() time.Time
+}
+
+// ClassifyRoute puts report creation in RateLimitClassReport, other requests
+// that read in RateLimitClassRead and everything else in
+// RateLimitClassWrite. It expects the path with any /api or /admin prefix
+// already stripped.
+func ClassifyRoute(r *http.Request) string {
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/report-runs":
+		return RateLimitClassReport
+	case r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions:
+		return RateLimitClassRead
+	default:
+		return RateLimitClassWrite
+	}
+}
+
+// Middleware charges each request to its principal's budget for the
+// request's route class, rejecting it with 429 Too Many Requests once the
+// budget is used up. Responses carry RateLimit-Limit, RateLimit-Remaining,
+// RateLimit-Reset and RateLimit-Policy headers, and rejections a Retry-After
+// header. To key by user it must run after VerifyAuthToken.
+//
+// If the store fails, the request is allowed: an outage of a shared store
+// should not take the API down with it.
+func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
+	classify := rl.Classify
+	if classify == nil {
+		classify = ClassifyRoute
+	}
+	now := rl.Now
+	if now == nil {
+		now = time.Now
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		class := classify(r)
+		limit, ok := rl.Limits[class]
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := class + "|" + rl.principal(r)
+		result, err := rl.Store.Take(r.Context(), key, limit, now())
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Rate limit store failed; allowing request", "error", err)
 			next.ServeHTTP(w, r)
-		})
+			return
+		}
+
+		h := w.Header()
+		h.Set("RateLimit-Limit", strconv.Itoa(limit.Burst))
+		h.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		h.Set("RateLimit-Reset", ceilSeconds(result.ResetAfter))
+		h.Set("RateLimit-Policy", strconv.Itoa(limit.Burst)+";w="+ceilSeconds(limit.Window()))
+		if !result.Allowed {
+			if rl.OnLimited != nil {
+				rl.OnLimited(class)
+			}
+			h.Set("Retry-After", ceilSeconds(result.RetryAfter))
+			apierror.Write(w, r, apierror.New(apierror.RateLimited, "Too many requests; retry after %s seconds", h.Get("Retry-After")))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// principal identifies who a request is charged to.
+func (rl *RateLimiter) principal(r *http.Request) string {
+	if uid, ok := r.Context().Value(UserIDKey).(string); ok && uid != "" {
+		return "uid:" + uid
 	}
+	return "ip:" + rl.clientIP(r)
+}
+
+func (rl *RateLimiter) clientIP(r *http.Request) string {
+	if rl.TrustForwardedFor {
+		if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+			entries := strings.Split(forwarded[len(forwarded)-1], ",")
+			if ip := strings.TrimSpace(entries[len(entries)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ceilSeconds formats d as a whole number of seconds, rounding up.
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
 }
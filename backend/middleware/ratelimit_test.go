@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/seans3/nhd/backend/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+type failingStore struct{}
+
+func (failingStore) Take(ctx context.Context, key string, limit ratelimit.Limit, now time.Time) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("store unavailable")
+}
+
+func TestRateLimiter_PerPrincipalAndClass(t *testing.T) {
+	var limited []string
+	rl := &RateLimiter{
+		Store: ratelimit.NewMemoryStore(),
+		Limits: map[string]ratelimit.Limit{
+			RateLimitClassRead:   {Rate: 1, Burst: 2},
+			RateLimitClassReport: {Rate: 0.1, Burst: 1},
+		},
+		OnLimited: func(class string) { limited = append(limited, class) },
+		Now:       func() time.Time { return time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC) },
+	}
+	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	do := func(method, path, uid, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = remoteAddr
+		if uid != "" {
+			req = req.WithContext(context.WithValue(req.Context(), UserIDKey, uid))
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Alice's report budget is spent after one run; she can still list, and
+	// Bob, calling from the same IP, is unaffected.
+	assert.Equal(t, http.StatusOK, do("POST", "/report-runs", "alice", "10.0.0.1:1234").Code)
+	rec := do("POST", "/report-runs", "alice", "10.0.0.1:1234")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "10", rec.Header().Get("Retry-After"))
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "1;w=10", rec.Header().Get("RateLimit-Policy"))
+
+	rec = do("GET", "/report-runs", "alice", "10.0.0.1:1234")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "1", rec.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, http.StatusOK, do("POST", "/report-runs", "bob", "10.0.0.1:1234").Code)
+
+	// Unauthenticated requests are keyed by client IP.
+	assert.Equal(t, http.StatusOK, do("GET", "/readyz", "", "10.0.0.2:1").Code)
+	assert.Equal(t, http.StatusOK, do("GET", "/readyz", "", "10.0.0.2:2").Code)
+	assert.Equal(t, http.StatusTooManyRequests, do("GET", "/readyz", "", "10.0.0.2:3").Code)
+	assert.Equal(t, http.StatusOK, do("GET", "/readyz", "", "10.0.0.3:1").Code)
+
+	// Classes without a limit are not limited.
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, http.StatusOK, do("PATCH", "/property-addresses/a", "alice", "10.0.0.1:1234").Code)
+	}
+	assert.Equal(t, []string{RateLimitClassReport, RateLimitClassRead}, limited)
+}
+
+func TestRateLimiter_ForwardedForAndStoreFailure(t *testing.T) {
+	rl := &RateLimiter{TrustForwardedFor: true}
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Add("X-Forwarded-For", "1.1.1.1, 2.2.2.2")
+	assert.Equal(t, "ip:2.2.2.2", rl.principal(req))
+	rl.TrustForwardedFor = false
+	assert.Equal(t, "ip:192.0.2.1", rl.principal(req))
+
+	rl = &RateLimiter{Store: failingStore{}, Limits: map[string]ratelimit.Limit{RateLimitClassRead: {Rate: 1, Burst: 1}}}
+	rec := httptest.NewRecorder()
+	rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestRateLimiter_BeforeAuthentication(t *testing.T) {
+	rl := &RateLimiter{
+		Store:    ratelimit.NewMemoryStore(),
+		Limits:   map[string]ratelimit.Limit{RateLimitClassAuth: {Rate: 1, Burst: 2}},
+		Classify: func(*http.Request) string { return RateLimitClassAuth },
+		Now:      func() time.Time { return time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC) },
+	}
+	verified := 0
+	// The wrapped handler stands in for VerifyAuthToken rejecting a bad token.
+	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		verified++
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	do := func(remoteAddr string) int {
+		req := httptest.NewRequest("GET", "/customers", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("Authorization", "Bearer forged")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Requests with bad tokens use up the client IP's budget, after which
+	// they are rejected without verifying the token.
+	assert.Equal(t, http.StatusUnauthorized, do("10.0.0.1:1234"))
+	assert.Equal(t, http.StatusUnauthorized, do("10.0.0.1:1234"))
+	assert.Equal(t, http.StatusTooManyRequests, do("10.0.0.1:1234"))
+	assert.Equal(t, 2, verified)
+	assert.Equal(t, http.StatusUnauthorized, do("10.0.0.2:1234"))
+}
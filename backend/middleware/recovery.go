@@ -1,9 +1,11 @@
 package middleware
 
 import (
-	"log"
 	"net/http"
 	"runtime/debug"
+
+	"github.com/seans3/nhd/backend/apierror"
+	"github.com/seans3/nhd/backend/logging"
 )
 
 // Recover is a middleware that recovers from panics, logs the panic,
@@ -12,8 +14,8 @@ func Recover(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
-				log.Printf("panic recovered: %v\n%s", err, debug.Stack())
-				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				logging.FromContext(r.Context()).Error("panic recovered", "panic", err, "stack", string(debug.Stack()))
+				apierror.Write(w, r, apierror.New(apierror.Internal, "Internal error"))
 			}
 		}()
 		next.ServeHTTP(w, r)
@@ -3,11 +3,23 @@ package middleware
 import (
 	"net/http"
 	"time"
+
+	"github.com/seans3/nhd/backend/apierror"
 )
 
 // Timeout is a middleware that sets a timeout for each request.
 // If the handler takes longer than the duration, it will send a
-// 503 Service Unavailable response and the context of the request will be cancelled.
+// 503 Service Unavailable problem and the context of the request will be cancelled.
 func Timeout(next http.Handler, duration time.Duration) http.Handler {
-	return http.TimeoutHandler(next, duration, "request timed out")
+	timeout := http.TimeoutHandler(next, duration, "request timed out")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// TimeoutHandler writes its 503 with no Content-Type, unlike the
+		// handler's own responses, which it passes on with their headers.
+		timeout.ServeHTTP(&plainErrorWriter{ResponseWriter: w, r: r, convert: func(status int) *apierror.Error {
+			if status != http.StatusServiceUnavailable {
+				return nil
+			}
+			return apierror.New(apierror.UpstreamUnavailable, "The request timed out; try again")
+		}}, r)
+	})
 }
@@ -17,9 +17,38 @@ type MockDatastoreClient struct {
 	mock.Mock
 }
 
-func (m *MockDatastoreClient) GetCustomers(ctx context.Context) ([]*nhd_report.Customer, error) {
+func (m *MockDatastoreClient) CreateOrganization(ctx context.Context, org *nhd_report.Organization) (*firestore.DocumentRef, *firestore.WriteResult, error) {
+	args := m.Called(ctx, org)
+	return args.Get(0).(*firestore.DocumentRef), args.Get(1).(*firestore.WriteResult), args.Error(2)
+}
+
+func (m *MockDatastoreClient) GetOrganization(ctx context.Context, organizationID string) (*nhd_report.Organization, error) {
+	args := m.Called(ctx, organizationID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*nhd_report.Organization), args.Error(1)
+}
+
+func (m *MockDatastoreClient) GetOrganizations(ctx context.Context) ([]*nhd_report.Organization, error) {
 	args := m.Called(ctx)
-	return args.Get(0).([]*nhd_report.Customer), args.Error(1)
+	return args.Get(0).([]*nhd_report.Organization), args.Error(1)
+}
+
+func (m *MockDatastoreClient) GetCustomers(ctx context.Context, query interfaces.CustomerQuery) (*interfaces.CustomerPage, error) {
+	args := m.Called(ctx, query)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*interfaces.CustomerPage), args.Error(1)
+}
+
+func (m *MockDatastoreClient) GetCustomer(ctx context.Context, customerID string) (*nhd_report.Customer, error) {
+	args := m.Called(ctx, customerID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*nhd_report.Customer), args.Error(1)
 }
 
 func (m *MockDatastoreClient) CreateCustomer(ctx context.Context, customer *nhd_report.Customer) (*firestore.DocumentRef, *firestore.WriteResult, error) {
@@ -27,14 +56,76 @@ func (m *MockDatastoreClient) CreateCustomer(ctx context.Context, customer *nhd_
 	return args.Get(0).(*firestore.DocumentRef), args.Get(1).(*firestore.WriteResult), args.Error(2)
 }
 
+func (m *MockDatastoreClient) UpdateCustomer(ctx context.Context, customer *nhd_report.Customer) error {
+	args := m.Called(ctx, customer)
+	return args.Error(0)
+}
+
+func (m *MockDatastoreClient) CreatePropertyAddress(ctx context.Context, address *nhd_report.PropertyAddress) (*firestore.DocumentRef, *firestore.WriteResult, error) {
+	args := m.Called(ctx, address)
+	return args.Get(0).(*firestore.DocumentRef), args.Get(1).(*firestore.WriteResult), args.Error(2)
+}
+
+func (m *MockDatastoreClient) GetPropertyAddress(ctx context.Context, propertyAddressID string) (*nhd_report.PropertyAddress, error) {
+	args := m.Called(ctx, propertyAddressID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*nhd_report.PropertyAddress), args.Error(1)
+}
+
+func (m *MockDatastoreClient) GetPropertyAddresses(ctx context.Context, organizationID string) ([]*nhd_report.PropertyAddress, error) {
+	args := m.Called(ctx, organizationID)
+	return args.Get(0).([]*nhd_report.PropertyAddress), args.Error(1)
+}
+
+func (m *MockDatastoreClient) UpdatePropertyAddress(ctx context.Context, address *nhd_report.PropertyAddress) error {
+	args := m.Called(ctx, address)
+	return args.Error(0)
+}
+
 func (m *MockDatastoreClient) CreateReportRun(ctx context.Context, reportRun *nhd_report.ReportRun) (*firestore.DocumentRef, *firestore.WriteResult, error) {
 	args := m.Called(ctx, reportRun)
 	return args.Get(0).(*firestore.DocumentRef), args.Get(1).(*firestore.WriteResult), args.Error(2)
 }
 
-func (m *MockDatastoreClient) GetReportRuns(ctx context.Context, paymentStatusFilter string) ([]*nhd_report.ReportRun, error) {
-	args := m.Called(ctx, paymentStatusFilter)
-	return args.Get(0).([]*nhd_report.ReportRun), args.Error(1)
+func (m *MockDatastoreClient) CreateReportRunForAddress(ctx context.Context, reportRun *nhd_report.ReportRun, address *nhd_report.PropertyAddress) (*firestore.DocumentRef, error) {
+	args := m.Called(ctx, reportRun, address)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*firestore.DocumentRef), args.Error(1)
+}
+
+func (m *MockDatastoreClient) GetReportRun(ctx context.Context, reportRunID string) (*nhd_report.ReportRun, error) {
+	args := m.Called(ctx, reportRunID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*nhd_report.ReportRun), args.Error(1)
+}
+
+func (m *MockDatastoreClient) GetReportRuns(ctx context.Context, query interfaces.ReportRunQuery) (*interfaces.ReportRunPage, error) {
+	args := m.Called(ctx, query)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*interfaces.ReportRunPage), args.Error(1)
+}
+
+func (m *MockDatastoreClient) AppendEmailDelivery(ctx context.Context, reportRunID string, delivery *nhd_report.ReportRun_EmailDelivery) error {
+	args := m.Called(ctx, reportRunID, delivery)
+	return args.Error(0)
+}
+
+func (m *MockDatastoreClient) UpdateReportRunStatus(ctx context.Context, reportRunID string, status nhd_report.ReportRun_Status, actor, reason string) error {
+	args := m.Called(ctx, reportRunID, status, actor, reason)
+	return args.Error(0)
+}
+
+func (m *MockDatastoreClient) CompleteReportRun(ctx context.Context, reportRunID string, results *nhd_report.ReportRun_HazardResults, actor string) error {
+	args := m.Called(ctx, reportRunID, results, actor)
+	return args.Error(0)
 }
 
 func (m *MockDatastoreClient) UpdateReportCost(ctx context.Context, reportRunID string, newCost *nhd_report.ReportRun_ReportCost) error {
@@ -42,16 +133,36 @@ func (m *MockDatastoreClient) UpdateReportCost(ctx context.Context, reportRunID
 	return args.Error(0)
 }
 
-func (m *MockDatastoreClient) RecordReportPayment(ctx context.Context, reportRunID string, payment *nhd_report.ReportRun_Payment) error {
-	args := m.Called(ctx, reportRunID, payment)
+func (m *MockDatastoreClient) AppendLedgerEntry(ctx context.Context, reportRunID string, entry *nhd_report.ReportRun_LedgerEntry) error {
+	args := m.Called(ctx, reportRunID, entry)
 	return args.Error(0)
 }
 
-func (m *MockDatastoreClient) GetPaidReportsSummary(ctx context.Context) (*interfaces.FinancialsSummary, error) {
-	args := m.Called(ctx)
+func (m *MockDatastoreClient) GetPaidReportsSummary(ctx context.Context, query interfaces.FinancialsQuery) (*interfaces.FinancialsSummary, error) {
+	args := m.Called(ctx, query)
 	return args.Get(0).(*interfaces.FinancialsSummary), args.Error(1)
 }
 
+func (m *MockDatastoreClient) CreatePriceRule(ctx context.Context, rule *nhd_report.PriceRule) (*firestore.DocumentRef, *firestore.WriteResult, error) {
+	args := m.Called(ctx, rule)
+	return args.Get(0).(*firestore.DocumentRef), args.Get(1).(*firestore.WriteResult), args.Error(2)
+}
+
+func (m *MockDatastoreClient) GetPriceRules(ctx context.Context) ([]*nhd_report.PriceRule, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*nhd_report.PriceRule), args.Error(1)
+}
+
+func (m *MockDatastoreClient) CreateExchangeRate(ctx context.Context, rate *nhd_report.ExchangeRate) (*firestore.DocumentRef, *firestore.WriteResult, error) {
+	args := m.Called(ctx, rate)
+	return args.Get(0).(*firestore.DocumentRef), args.Get(1).(*firestore.WriteResult), args.Error(2)
+}
+
+func (m *MockDatastoreClient) GetExchangeRates(ctx context.Context) ([]*nhd_report.ExchangeRate, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*nhd_report.ExchangeRate), args.Error(1)
+}
+
 func (m *MockDatastoreClient) GetUserByID(ctx context.Context, uid string) (*nhd_report.User, error) {
 	args := m.Called(ctx, uid)
 	if args.Get(0) == nil {
@@ -64,3 +175,21 @@ func (m *MockDatastoreClient) CreateUser(ctx context.Context, user *nhd_report.U
 	args := m.Called(ctx, user)
 	return args.Error(0)
 }
+
+func (m *MockDatastoreClient) ClaimIdempotencyKey(ctx context.Context, record *interfaces.IdempotencyRecord) (*interfaces.IdempotencyRecord, error) {
+	args := m.Called(ctx, record)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*interfaces.IdempotencyRecord), args.Error(1)
+}
+
+func (m *MockDatastoreClient) SaveIdempotencyRecord(ctx context.Context, record *interfaces.IdempotencyRecord) error {
+	args := m.Called(ctx, record)
+	return args.Error(0)
+}
+
+func (m *MockDatastoreClient) DeleteIdempotencyKey(ctx context.Context, key string) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
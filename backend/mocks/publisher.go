@@ -15,7 +15,20 @@ type MockPublisherClient struct {
 	mock.Mock
 }
 
-func (m *MockPublisherClient) Publish(ctx context.Context, topicID string, data []byte) (string, error) {
-	args := m.Called(ctx, topicID, data)
+func (m *MockPublisherClient) Publish(ctx context.Context, topicID string, data []byte, attributes map[string]string) (string, error) {
+	args := m.Called(ctx, topicID, data, attributes)
 	return args.String(0), args.Error(1)
 }
+
+// Statically assert that our mock satisfies the interface.
+var _ interfaces.Subscriber = (*MockSubscriberClient)(nil)
+
+// MockSubscriberClient is a mock implementation of the Subscriber interface.
+type MockSubscriberClient struct {
+	mock.Mock
+}
+
+func (m *MockSubscriberClient) Receive(ctx context.Context, subscriptionID string, maxConcurrent int, handler interfaces.MessageHandler) error {
+	args := m.Called(ctx, subscriptionID, maxConcurrent, handler)
+	return args.Error(0)
+}
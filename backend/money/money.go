@@ -0,0 +1,115 @@
+// Package money does exact arithmetic on amounts of money.
+//
+// Amounts are nhd_report.Money values, which count whole minor units of a
+// currency (cents for USD, yen for JPY), so sums are exact and never pick up
+// the rounding errors of floating point. Amounts are only rounded when they
+// are converted between currencies.
+package money
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/seans3/nhd/backend/proto/gen/go"
+)
+
+// ErrCurrencyMismatch is returned (possibly wrapped) when amounts in
+// different currencies are combined.
+var ErrCurrencyMismatch = errors.New("currencies differ")
+
+// exponents lists the ISO 4217 currencies whose minor unit is not a
+// hundredth of the major unit.
+var exponents = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0,
+	"KRW": 0, "PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0,
+	"XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+// Exponent returns the number of decimal places of currency's minor unit:
+// 2 for USD, 0 for JPY and 3 for KWD.
+func Exponent(currency string) int {
+	if exponent, ok := exponents[currency]; ok {
+		return exponent
+	}
+	return 2
+}
+
+// New returns minorUnits of currency.
+func New(currency string, minorUnits int64) *nhd_report.Money {
+	return &nhd_report.Money{Currency: currency, MinorUnits: minorUnits}
+}
+
+// Parse parses a decimal amount of currency, such as "49.99" or "-5". The
+// amount may not have more decimal places than the currency's minor unit.
+func Parse(currency, amount string) (*nhd_report.Money, error) {
+	digits, negative := strings.CutPrefix(amount, "-")
+	whole, fraction, _ := strings.Cut(digits, ".")
+	exponent := Exponent(currency)
+	if whole == "" || !isDigits(whole) || !isDigits(fraction) || (strings.Contains(digits, ".") && fraction == "") {
+		return nil, fmt.Errorf("invalid amount %q", amount)
+	}
+	if len(fraction) > exponent {
+		return nil, fmt.Errorf("amount %q has more than %d decimal places for %s", amount, exponent, currency)
+	}
+	units, err := strconv.ParseInt(whole+fraction+strings.Repeat("0", exponent-len(fraction)), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid amount %q: %w", amount, err)
+	}
+	if negative {
+		units = -units
+	}
+	return New(currency, units), nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Format returns m as a decimal with as many places as its currency's minor
+// unit, e.g. "49.99". A nil m is formatted as zero.
+func Format(m *nhd_report.Money) string {
+	units := m.GetMinorUnits()
+	sign := ""
+	if units < 0 {
+		sign = "-"
+	}
+	digits := strconv.FormatUint(absUnits(units), 10)
+	exponent := Exponent(m.GetCurrency())
+	if exponent == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+}
+
+// String returns m formatted with its currency, e.g. "49.99 USD".
+func String(m *nhd_report.Money) string {
+	return Format(m) + " " + m.GetCurrency()
+}
+
+func absUnits(units int64) uint64 {
+	if units < 0 {
+		return uint64(-units)
+	}
+	return uint64(units)
+}
+
+// Add returns a plus b. It returns an error wrapping ErrCurrencyMismatch if
+// they are in different currencies.
+func Add(a, b *nhd_report.Money) (*nhd_report.Money, error) {
+	if a.GetCurrency() != b.GetCurrency() {
+		return nil, fmt.Errorf("cannot add %s to %s: %w", String(b), String(a), ErrCurrencyMismatch)
+	}
+	return New(a.GetCurrency(), a.GetMinorUnits()+b.GetMinorUnits()), nil
+}
@@ -0,0 +1,114 @@
+package money
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/seans3/nhd/backend/proto/gen/go"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestParseAndFormat(t *testing.T) {
+	tests := []struct {
+		currency, amount string
+		units            int64
+		formatted        string
+	}{
+		{"USD", "49.99", 4999, "49.99"},
+		{"USD", "49.9", 4990, "49.90"},
+		{"USD", "5", 500, "5.00"},
+		{"USD", "-0.05", -5, "-0.05"},
+		{"JPY", "4999", 4999, "4999"},
+		{"KWD", "1.005", 1005, "1.005"},
+	}
+	for _, tt := range tests {
+		m, err := Parse(tt.currency, tt.amount)
+		if err != nil {
+			t.Fatalf("Parse(%q, %q) error: %v", tt.currency, tt.amount, err)
+		}
+		if m.MinorUnits != tt.units || m.Currency != tt.currency {
+			t.Errorf("Parse(%q, %q) = %v, want %d minor units", tt.currency, tt.amount, m, tt.units)
+		}
+		if got := Format(m); got != tt.formatted {
+			t.Errorf("Format(%v) = %q, want %q", m, got, tt.formatted)
+		}
+	}
+
+	for _, amount := range []string{"", "1.", ".5", "1.001", "1e3", "1,000", "--1"} {
+		if _, err := Parse("USD", amount); err == nil {
+			t.Errorf("Parse(USD, %q) succeeded, want an error", amount)
+		}
+	}
+	if _, err := Parse("JPY", "1.5"); err == nil {
+		t.Error("Parse(JPY, 1.5) succeeded, want an error")
+	}
+}
+
+func TestAdd(t *testing.T) {
+	sum, err := Add(New("USD", 4999), New("USD", 1))
+	if err != nil || sum.MinorUnits != 5000 {
+		t.Errorf("Add() = %v, %v; want 50.00 USD", sum, err)
+	}
+	if _, err := Add(New("USD", 1), New("CAD", 1)); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Add(USD, CAD) error = %v, want ErrCurrencyMismatch", err)
+	}
+}
+
+func TestConverter(t *testing.T) {
+	jan := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	jun := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	converter := NewConverter([]*nhd_report.ExchangeRate{
+		{BaseCurrency: "USD", QuoteCurrency: "CAD", Rate: "1.25", EffectiveFrom: timestamppb.New(jan)},
+		{BaseCurrency: "CAD", QuoteCurrency: "USD", Rate: "0.7312", EffectiveFrom: timestamppb.New(jun)},
+		{BaseCurrency: "USD", QuoteCurrency: "JPY", Rate: "150", EffectiveFrom: timestamppb.New(jan)},
+	})
+
+	tests := []struct {
+		name string
+		from *nhd_report.Money
+		to   string
+		at   time.Time
+		want int64
+	}{
+		{"same currency", New("USD", 4999), "USD", jan, 4999},
+		{"direct", New("USD", 4999), "CAD", jan, 6249}, // 62.4875 rounds up
+		{"inverse of the rate in effect", New("CAD", 10000), "USD", jan, 8000},
+		{"later rate wins", New("CAD", 10000), "USD", jun, 7312},
+		{"later inverse rate wins", New("USD", 7312), "CAD", jun, 10000},
+		{"negative rounds away from zero", New("USD", -4999), "CAD", jan, -6249},
+		{"different exponents", New("USD", 4999), "JPY", jan, 7499}, // 7498.5
+		{"back again", New("JPY", 7499), "USD", jan, 4999},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := converter.Convert(tt.from, tt.to, tt.at)
+			if err != nil {
+				t.Fatalf("Convert() error: %v", err)
+			}
+			if got.Currency != tt.to || got.MinorUnits != tt.want {
+				t.Errorf("Convert() = %s, want %d minor units of %s", String(got), tt.want, tt.to)
+			}
+		})
+	}
+
+	_, err := converter.Convert(New("USD", 100), "CAD", jan.AddDate(0, 0, -1))
+	if !errors.Is(err, ErrNoExchangeRate) {
+		t.Errorf("Convert() before any rate error = %v, want ErrNoExchangeRate", err)
+	}
+	_, err = converter.Convert(New("EUR", 100), "USD", jun)
+	if !errors.Is(err, ErrNoExchangeRate) {
+		t.Errorf("Convert(EUR) error = %v, want ErrNoExchangeRate", err)
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	for _, rate := range []string{"", "0", "0.0", "-1.2", "1/3", "1e3", "abc"} {
+		if _, err := ParseRate(rate); err == nil {
+			t.Errorf("ParseRate(%q) succeeded, want an error", rate)
+		}
+	}
+	if r, err := ParseRate("0.7312"); err != nil || r.FloatString(4) != "0.7312" {
+		t.Errorf("ParseRate(0.7312) = %v, %v", r, err)
+	}
+}
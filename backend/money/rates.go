@@ -0,0 +1,115 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"time"
+
+	"github.com/seans3/nhd/backend/proto/gen/go"
+)
+
+// ErrNoExchangeRate is returned (possibly wrapped) when an amount cannot be
+// converted because no exchange rate between its currency and the target
+// currency was in effect.
+var ErrNoExchangeRate = errors.New("no exchange rate")
+
+var decimalRate = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?$`)
+
+// ParseRate parses a positive decimal exchange rate, such as "0.7312",
+// exactly.
+func ParseRate(rate string) (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(rate)
+	if !decimalRate.MatchString(rate) || !ok {
+		return nil, fmt.Errorf("rate %q must be a decimal number, e.g. 0.7312", rate)
+	}
+	if r.Sign() <= 0 {
+		return nil, fmt.Errorf("rate %q must be positive", rate)
+	}
+	return r, nil
+}
+
+// Converter converts amounts between currencies using an effective-dated
+// history of ExchangeRates.
+type Converter struct {
+	rates []*nhd_report.ExchangeRate
+}
+
+// NewConverter returns a Converter that uses rates. Rates that ParseRate
+// rejects are ignored; the API never stores them.
+func NewConverter(rates []*nhd_report.ExchangeRate) *Converter {
+	return &Converter{rates: rates}
+}
+
+// Rate returns the number of units of to that one unit of from was worth at
+// time at: the rate between the two currencies, in either direction, with
+// the latest effective_from not after at. A rate from to back to from is
+// applied inverted. Rate returns an error wrapping ErrNoExchangeRate if no
+// rate was in effect.
+func (c *Converter) Rate(from, to string, at time.Time) (*big.Rat, error) {
+	if from == to {
+		return big.NewRat(1, 1), nil
+	}
+	var best *nhd_report.ExchangeRate
+	var rate *big.Rat
+	for _, candidate := range c.rates {
+		effective := candidate.GetEffectiveFrom().AsTime()
+		if effective.After(at) || (best != nil && !effective.After(best.GetEffectiveFrom().AsTime())) {
+			continue
+		}
+		r, err := ParseRate(candidate.Rate)
+		if err != nil {
+			continue
+		}
+		switch {
+		case candidate.BaseCurrency == from && candidate.QuoteCurrency == to:
+		case candidate.BaseCurrency == to && candidate.QuoteCurrency == from:
+			r.Inv(r)
+		default:
+			continue
+		}
+		best, rate = candidate, r
+	}
+	if best == nil {
+		return nil, fmt.Errorf("converting %s to %s on %s: %w", from, to, at.Format(time.DateOnly), ErrNoExchangeRate)
+	}
+	return rate, nil
+}
+
+// Convert returns m in currency to, at the rate in effect at time at,
+// rounded to the nearest minor unit of to (halves away from zero).
+func (c *Converter) Convert(m *nhd_report.Money, to string, at time.Time) (*nhd_report.Money, error) {
+	if m.GetCurrency() == to {
+		return New(to, m.GetMinorUnits()), nil
+	}
+	rate, err := c.Rate(m.GetCurrency(), to, at)
+	if err != nil {
+		return nil, err
+	}
+	// Scale from the minor units of one currency to those of the other.
+	value := new(big.Rat).Mul(new(big.Rat).SetInt64(m.GetMinorUnits()), rate)
+	value.Mul(value, pow10(Exponent(to)))
+	value.Quo(value, pow10(Exponent(m.GetCurrency())))
+	units := roundHalfAwayFromZero(value)
+	if !units.IsInt64() {
+		return nil, fmt.Errorf("converting %s to %s: amount out of range", String(m), to)
+	}
+	return New(to, units.Int64()), nil
+}
+
+func pow10(exponent int) *big.Rat {
+	return new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exponent)), nil))
+}
+
+func roundHalfAwayFromZero(r *big.Rat) *big.Int {
+	num := new(big.Int).Abs(r.Num())
+	den := r.Denom()
+	// floor((2*num + den) / (2*den))
+	num.Mul(num, big.NewInt(2)).Add(num, den)
+	rounded := num.Quo(num, new(big.Int).Mul(den, big.NewInt(2)))
+	if r.Sign() < 0 {
+		rounded.Neg(rounded)
+	}
+	return rounded
+}
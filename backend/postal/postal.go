@@ -0,0 +1,139 @@
+// Package postal normalizes US postal addresses so that different spellings
+// of the same property ("123 Main Street, Apt. 4" vs "123 MAIN ST APT 4")
+// compare equal.
+package postal
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/seans3/nhd/backend/proto/gen/go"
+)
+
+// DefaultState is assumed when an address omits its state. The service only
+// produces California disclosures.
+const DefaultState = "CA"
+
+// streetAbbreviations maps common USPS street suffixes, directionals and unit
+// designators to their standard abbreviations.
+var streetAbbreviations = map[string]string{
+	"ALLEY":      "ALY",
+	"AVENUE":     "AVE",
+	"BOULEVARD":  "BLVD",
+	"CIRCLE":     "CIR",
+	"COURT":      "CT",
+	"DRIVE":      "DR",
+	"EXPRESSWAY": "EXPY",
+	"FREEWAY":    "FWY",
+	"HIGHWAY":    "HWY",
+	"LANE":       "LN",
+	"PARKWAY":    "PKWY",
+	"PLACE":      "PL",
+	"ROAD":       "RD",
+	"SQUARE":     "SQ",
+	"STREET":     "ST",
+	"TERRACE":    "TER",
+	"TRAIL":      "TRL",
+	"WAY":        "WAY",
+	"NORTH":      "N",
+	"SOUTH":      "S",
+	"EAST":       "E",
+	"WEST":       "W",
+	"NORTHEAST":  "NE",
+	"NORTHWEST":  "NW",
+	"SOUTHEAST":  "SE",
+	"SOUTHWEST":  "SW",
+	"APARTMENT":  "APT",
+	"BUILDING":   "BLDG",
+	"FLOOR":      "FL",
+	"SUITE":      "STE",
+	"#":          "UNIT",
+}
+
+// Normalize returns a copy of the address details in canonical USPS-style
+// form: upper case, without punctuation, with standard abbreviations and a
+// five digit ZIP code. A missing state defaults to DefaultState.
+func Normalize(d *nhd_report.PropertyAddress_AddressDetails) *nhd_report.PropertyAddress_AddressDetails {
+	normalized := &nhd_report.PropertyAddress_AddressDetails{
+		StreetAddress:   normalizeStreet(d.GetStreetAddress()),
+		StreetAddress_2: normalizeStreet(d.GetStreetAddress_2()),
+		City:            normalizeWords(d.GetCity()),
+		State:           normalizeWords(d.GetState()),
+		ZipCode:         digits(d.GetZipCode()),
+		ZipPlus_4:       digits(d.GetZipPlus_4()),
+	}
+	if normalized.State == "" || normalized.State == "CALIFORNIA" {
+		normalized.State = DefaultState
+	}
+	// Accept "95814-1234" in the zip_code field.
+	if len(normalized.ZipCode) == 9 && normalized.ZipPlus_4 == "" {
+		normalized.ZipPlus_4 = normalized.ZipCode[5:]
+	}
+	if len(normalized.ZipCode) > 5 {
+		normalized.ZipCode = normalized.ZipCode[:5]
+	}
+	return normalized
+}
+
+// Key returns the matching key for the address details. Two addresses that
+// refer to the same property produce the same key. The ZIP+4 extension is not
+// part of the key because it is frequently omitted.
+func Key(d *nhd_report.PropertyAddress_AddressDetails) string {
+	n := Normalize(d)
+	return strings.Join([]string{n.StreetAddress, n.StreetAddress_2, n.City, n.State, n.ZipCode}, "|")
+}
+
+// Format returns the address details as a single display line, e.g.
+// "123 Main St, Apt 4, Sacramento, CA 95814-1234".
+func Format(d *nhd_report.PropertyAddress_AddressDetails) string {
+	var parts []string
+	for _, s := range []string{d.GetStreetAddress(), d.GetStreetAddress_2(), d.GetCity()} {
+		if s = strings.TrimSpace(s); s != "" {
+			parts = append(parts, s)
+		}
+	}
+	stateZip := strings.TrimSpace(d.GetState() + " " + d.GetZipCode())
+	if d.GetZipPlus_4() != "" {
+		stateZip += "-" + d.GetZipPlus_4()
+	}
+	if stateZip != "" {
+		parts = append(parts, stateZip)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func normalizeStreet(s string) string {
+	s = strings.ReplaceAll(s, "#", " # ")
+	words := strings.Fields(normalizeWords(s))
+	for i, w := range words {
+		if abbr, ok := streetAbbreviations[w]; ok {
+			words[i] = abbr
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// normalizeWords upper-cases s, drops punctuation other than '#' and '-', and
+// collapses runs of whitespace.
+func normalizeWords(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '#', r == '-':
+			return unicode.ToUpper(r)
+		case unicode.IsSpace(r), r == ',':
+			return ' '
+		default:
+			return -1
+		}
+	}, s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func digits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
@@ -0,0 +1,39 @@
+package postal
+
+import (
+	"testing"
+
+	"github.com/seans3/nhd/backend/proto/gen/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKey_EquivalentSpellingsMatch(t *testing.T) {
+	a := &nhd_report.PropertyAddress_AddressDetails{
+		StreetAddress:   "123 North Main Street",
+		StreetAddress_2: "Apt. 4",
+		City:            "Sacramento",
+		ZipCode:         "95814-1234",
+	}
+	b := &nhd_report.PropertyAddress_AddressDetails{
+		StreetAddress:   "  123 n main st ",
+		StreetAddress_2: "APARTMENT 4",
+		City:            "SACRAMENTO",
+		State:           "California",
+		ZipCode:         "95814",
+	}
+	assert.Equal(t, Key(a), Key(b))
+	assert.Equal(t, "123 N MAIN ST|APT 4|SACRAMENTO|CA|95814", Key(a))
+}
+
+func TestKey_DifferentUnitsDoNotMatch(t *testing.T) {
+	a := &nhd_report.PropertyAddress_AddressDetails{StreetAddress: "500 Oak Ave", StreetAddress_2: "#1", City: "Davis", ZipCode: "95616"}
+	b := &nhd_report.PropertyAddress_AddressDetails{StreetAddress: "500 Oak Ave", StreetAddress_2: "#2", City: "Davis", ZipCode: "95616"}
+	assert.NotEqual(t, Key(a), Key(b))
+	assert.Equal(t, "UNIT 1", Normalize(a).StreetAddress_2)
+}
+
+func TestNormalize_SplitsZipPlus4(t *testing.T) {
+	n := Normalize(&nhd_report.PropertyAddress_AddressDetails{ZipCode: "95814-1234"})
+	assert.Equal(t, "95814", n.ZipCode)
+	assert.Equal(t, "1234", n.ZipPlus_4)
+}
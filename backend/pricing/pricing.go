@@ -0,0 +1,159 @@
+// Package pricing decides what a new report run costs.
+//
+// Prices come from effective-dated PriceRules stored in the Datastore. A rule
+// applies to every report (DEFAULT), to every customer of one company
+// (COMPANY), or to a single customer (CUSTOMER). The most specific rule in
+// effect wins; when no rule applies the engine falls back to its built-in
+// default price.
+package pricing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/seans3/nhd/backend/interfaces"
+	"github.com/seans3/nhd/backend/money"
+	"github.com/seans3/nhd/backend/proto/gen/go"
+	"github.com/seans3/nhd/backend/validation"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// DefaultAmount, in minor units of DefaultCurrency, is charged when no
+// PriceRule applies.
+const (
+	DefaultAmount   = 4900
+	DefaultCurrency = "USD"
+)
+
+// DefaultRuleCacheTTL is how long an Engine reuses the price rules it loaded
+// when Engine.RuleCacheTTL is not set.
+const DefaultRuleCacheTTL = time.Minute
+
+// Engine quotes report prices from the rules in a Datastore.
+//
+// The rules are loaded once and reused for RuleCacheTTL, so quoting does not
+// read every rule on each new report run. Invalidate drops them after a rule
+// is created through this Engine's process; other processes see a new rule
+// once their copy expires.
+type Engine struct {
+	DS interfaces.Datastore
+	// Fallback is used when no rule applies. If nil, DefaultAmount and
+	// DefaultCurrency are used.
+	Fallback *nhd_report.ReportRun_ReportCost
+	// RuleCacheTTL is how long loaded rules are reused. Defaults to
+	// DefaultRuleCacheTTL.
+	RuleCacheTTL time.Duration
+
+	mu       sync.Mutex
+	rules    []*nhd_report.PriceRule
+	loadedAt time.Time
+}
+
+// NewEngine returns an Engine backed by ds with the built-in default price.
+func NewEngine(ds interfaces.Datastore) *Engine {
+	return &Engine{DS: ds}
+}
+
+// Quote returns the cost of a report for customer at time at. customer may be
+// nil, in which case only DEFAULT rules are considered. The returned cost has
+// PriceRuleId set to the rule that produced it, or empty for the fallback.
+func (e *Engine) Quote(ctx context.Context, customer *nhd_report.Customer, at time.Time) (*nhd_report.ReportRun_ReportCost, error) {
+	rules, err := e.loadRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading price rules: %w", err)
+	}
+	if rule := Select(rules, customer, at); rule != nil {
+		return &nhd_report.ReportRun_ReportCost{
+			Amount:      money.New(rule.Amount.GetCurrency(), rule.Amount.GetMinorUnits()),
+			SetAt:       timestamppb.New(at),
+			PriceRuleId: rule.PriceRuleId,
+		}, nil
+	}
+	cost := &nhd_report.ReportRun_ReportCost{Amount: money.New(DefaultCurrency, DefaultAmount)}
+	if e.Fallback != nil {
+		cost.Amount = money.New(e.Fallback.Amount.GetCurrency(), e.Fallback.Amount.GetMinorUnits())
+	}
+	cost.SetAt = timestamppb.New(at)
+	return cost, nil
+}
+
+// Invalidate drops the cached rules, so the next quote reloads them.
+func (e *Engine) Invalidate() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.rules = nil
+}
+
+// loadRules returns the cached rules, reloading them if they have expired.
+func (e *Engine) loadRules(ctx context.Context) ([]*nhd_report.PriceRule, error) {
+	ttl := e.RuleCacheTTL
+	if ttl <= 0 {
+		ttl = DefaultRuleCacheTTL
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.rules != nil && time.Since(e.loadedAt) < ttl {
+		return e.rules, nil
+	}
+	rules, err := e.DS.GetPriceRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if rules == nil {
+		rules = []*nhd_report.PriceRule{}
+	}
+	e.rules, e.loadedAt = rules, time.Now()
+	return rules, nil
+}
+
+// Select returns the rule that prices a report for customer at time at, or
+// nil if none applies. CUSTOMER rules take precedence over COMPANY rules,
+// which take precedence over DEFAULT rules. Within a scope the rule with the
+// latest effective_from not after at wins.
+func Select(rules []*nhd_report.PriceRule, customer *nhd_report.Customer, at time.Time) *nhd_report.PriceRule {
+	var best *nhd_report.PriceRule
+	for _, rule := range rules {
+		if !appliesTo(rule, customer) || rule.GetEffectiveFrom().AsTime().After(at) {
+			continue
+		}
+		if best == nil || rule.Scope > best.Scope ||
+			(rule.Scope == best.Scope && rule.GetEffectiveFrom().AsTime().After(best.GetEffectiveFrom().AsTime())) {
+			best = rule
+		}
+	}
+	return best
+}
+
+func appliesTo(rule *nhd_report.PriceRule, customer *nhd_report.Customer) bool {
+	switch rule.Scope {
+	case nhd_report.PriceRule_DEFAULT:
+		return true
+	case nhd_report.PriceRule_COMPANY:
+		return customer.GetCompanyName() != "" && strings.EqualFold(rule.ScopeValue, customer.GetCompanyName())
+	case nhd_report.PriceRule_CUSTOMER:
+		return customer.GetCustomerId() != "" && rule.ScopeValue == customer.GetCustomerId()
+	}
+	return false
+}
+
+// Validate checks that rule is well formed before it is stored. The error
+// is a ValidationFailed apierror.Error listing every invalid field.
+func Validate(rule *nhd_report.PriceRule) error {
+	var v validation.Validator
+	switch rule.Scope {
+	case nhd_report.PriceRule_DEFAULT:
+		v.Check(rule.ScopeValue == "", "scope_value", "must be empty for DEFAULT rules")
+	case nhd_report.PriceRule_COMPANY, nhd_report.PriceRule_CUSTOMER:
+		v.Check(rule.ScopeValue != "", "scope_value", "is required for "+rule.Scope.String()+" rules")
+	default:
+		v.Add("scope", "must be DEFAULT, COMPANY or CUSTOMER")
+	}
+	v.Money("amount", rule.Amount)
+	if rule.Amount != nil {
+		v.NonNegative("amount.minor_units", rule.Amount.MinorUnits)
+	}
+	return v.Err()
+}
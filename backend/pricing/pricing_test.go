@@ -0,0 +1,99 @@
+package pricing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/seans3/nhd/backend/apierror"
+	"github.com/seans3/nhd/backend/memstore"
+	"github.com/seans3/nhd/backend/mocks"
+	"github.com/seans3/nhd/backend/money"
+	"github.com/seans3/nhd/backend/proto/gen/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestSelect(t *testing.T) {
+	jan := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	jun := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	rules := []*nhd_report.PriceRule{
+		{PriceRuleId: "default-jan", Scope: nhd_report.PriceRule_DEFAULT, Amount: money.New("USD", 5000), EffectiveFrom: timestamppb.New(jan)},
+		{PriceRuleId: "default-jun", Scope: nhd_report.PriceRule_DEFAULT, Amount: money.New("USD", 6000), EffectiveFrom: timestamppb.New(jun)},
+		{PriceRuleId: "acme-jan", Scope: nhd_report.PriceRule_COMPANY, ScopeValue: "Acme", Amount: money.New("USD", 4000), EffectiveFrom: timestamppb.New(jan)},
+		{PriceRuleId: "cust-jun", Scope: nhd_report.PriceRule_CUSTOMER, ScopeValue: "c1", Amount: money.New("USD", 3000), EffectiveFrom: timestamppb.New(jun)},
+	}
+	acmeCustomer := &nhd_report.Customer{CustomerId: "c1", CompanyName: "ACME"}
+
+	tests := []struct {
+		name     string
+		customer *nhd_report.Customer
+		at       time.Time
+		want     string
+	}{
+		{"no customer before any rule", nil, jan.AddDate(0, 0, -1), ""},
+		{"no customer", nil, jan.AddDate(0, 1, 0), "default-jan"},
+		{"latest default wins", nil, jun, "default-jun"},
+		{"company beats default", acmeCustomer, jan.AddDate(0, 1, 0), "acme-jan"},
+		{"customer beats company", acmeCustomer, jun.AddDate(0, 1, 0), "cust-jun"},
+		{"other customer", &nhd_report.Customer{CustomerId: "c2"}, jun, "default-jun"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Select(rules, tt.customer, tt.at).GetPriceRuleId())
+		})
+	}
+}
+
+func TestQuoteFallsBackToDefault(t *testing.T) {
+	engine := NewEngine(memstore.NewClient())
+	cost, err := engine.Quote(context.Background(), nil, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, int64(DefaultAmount), cost.Amount.GetMinorUnits())
+	assert.Equal(t, DefaultCurrency, cost.Amount.GetCurrency())
+	assert.Empty(t, cost.PriceRuleId)
+}
+
+func TestQuoteCachesRules(t *testing.T) {
+	ctx := context.Background()
+	jan := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	mockDS := new(mocks.MockDatastoreClient)
+	mockDS.On("GetPriceRules", mock.Anything).Return([]*nhd_report.PriceRule{
+		{PriceRuleId: "default-jan", Scope: nhd_report.PriceRule_DEFAULT, Amount: money.New("USD", 5000), EffectiveFrom: timestamppb.New(jan)},
+	}, nil).Twice()
+	engine := NewEngine(mockDS)
+
+	for range 3 {
+		cost, err := engine.Quote(ctx, nil, jan.AddDate(0, 1, 0))
+		require.NoError(t, err)
+		assert.Equal(t, "default-jan", cost.PriceRuleId)
+	}
+	mockDS.AssertNumberOfCalls(t, "GetPriceRules", 1)
+
+	// Invalidate forces the next quote to reload the rules.
+	engine.Invalidate()
+	_, err := engine.Quote(ctx, nil, jan.AddDate(0, 1, 0))
+	require.NoError(t, err)
+	mockDS.AssertNumberOfCalls(t, "GetPriceRules", 2)
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(&nhd_report.PriceRule{Scope: nhd_report.PriceRule_COMPANY, ScopeValue: "Acme", Amount: money.New("USD", 4000)}))
+
+	err := Validate(&nhd_report.PriceRule{Scope: nhd_report.PriceRule_CUSTOMER, Amount: &nhd_report.Money{Currency: "usd", MinorUnits: -1}})
+	apiErr := apierror.From(err)
+	assert.Equal(t, apierror.ValidationFailed, apiErr.Code)
+	assert.Equal(t, []apierror.FieldError{
+		{Field: "scope_value", Message: "is required for CUSTOMER rules"},
+		{Field: "amount.currency", Message: "must be an ISO 4217 currency code, e.g. USD"},
+		{Field: "amount.minor_units", Message: "must not be negative"},
+	}, apiErr.Fields)
+
+	apiErr = apierror.From(Validate(&nhd_report.PriceRule{Scope: nhd_report.PriceRule_DEFAULT, ScopeValue: "Acme"}))
+	assert.Equal(t, []apierror.FieldError{
+		{Field: "scope_value", Message: "must be empty for DEFAULT rules"},
+		{Field: "amount", Message: "is required"},
+	}, apiErr.Fields)
+}
@@ -30,6 +30,7 @@ const (
 	ReportRun_PROCESSING         ReportRun_Status = 2
 	ReportRun_COMPLETED          ReportRun_Status = 3
 	ReportRun_FAILED             ReportRun_Status = 4
+	ReportRun_CANCELLED          ReportRun_Status = 5
 )
 
 // Enum value maps for ReportRun_Status.
@@ -40,6 +41,7 @@ var (
 		2: "PROCESSING",
 		3: "COMPLETED",
 		4: "FAILED",
+		5: "CANCELLED",
 	}
 	ReportRun_Status_value = map[string]int32{
 		"STATUS_UNSPECIFIED": 0,
@@ -47,6 +49,7 @@ var (
 		"PROCESSING":         2,
 		"COMPLETED":          3,
 		"FAILED":             4,
+		"CANCELLED":          5,
 	}
 )
 
@@ -74,7 +77,7 @@ func (x ReportRun_Status) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use ReportRun_Status.Descriptor instead.
 func (ReportRun_Status) EnumDescriptor() ([]byte, []int) {
-	return file_proto_nhd_proto_rawDescGZIP(), []int{4, 0}
+	return file_proto_nhd_proto_rawDescGZIP(), []int{6, 0}
 }
 
 type ReportRun_EmailDelivery_DeliveryStatus int32
@@ -83,6 +86,9 @@ const (
 	ReportRun_EmailDelivery_STATUS_UNSPECIFIED ReportRun_EmailDelivery_DeliveryStatus = 0
 	ReportRun_EmailDelivery_SENT               ReportRun_EmailDelivery_DeliveryStatus = 1
 	ReportRun_EmailDelivery_FAILED             ReportRun_EmailDelivery_DeliveryStatus = 2
+	// The send timed out after the message was handed to the relay, so it
+	// may or may not have been delivered.
+	ReportRun_EmailDelivery_UNKNOWN ReportRun_EmailDelivery_DeliveryStatus = 3
 )
 
 // Enum value maps for ReportRun_EmailDelivery_DeliveryStatus.
@@ -91,11 +97,13 @@ var (
 		0: "STATUS_UNSPECIFIED",
 		1: "SENT",
 		2: "FAILED",
+		3: "UNKNOWN",
 	}
 	ReportRun_EmailDelivery_DeliveryStatus_value = map[string]int32{
 		"STATUS_UNSPECIFIED": 0,
 		"SENT":               1,
 		"FAILED":             2,
+		"UNKNOWN":            3,
 	}
 )
 
@@ -123,16 +131,18 @@ func (x ReportRun_EmailDelivery_DeliveryStatus) Number() protoreflect.EnumNumber
 
 // Deprecated: Use ReportRun_EmailDelivery_DeliveryStatus.Descriptor instead.
 func (ReportRun_EmailDelivery_DeliveryStatus) EnumDescriptor() ([]byte, []int) {
-	return file_proto_nhd_proto_rawDescGZIP(), []int{4, 1, 0}
+	return file_proto_nhd_proto_rawDescGZIP(), []int{6, 2, 0}
 }
 
 type ReportRun_Payment_PaymentStatus int32
 
 const (
 	ReportRun_Payment_PAYMENT_STATUS_UNSPECIFIED ReportRun_Payment_PaymentStatus = 0
-	ReportRun_Payment_OUTSTANDING                ReportRun_Payment_PaymentStatus = 1
-	ReportRun_Payment_PAID                       ReportRun_Payment_PaymentStatus = 2
-	ReportRun_Payment_REFUNDED                   ReportRun_Payment_PaymentStatus = 3
+	ReportRun_Payment_OUTSTANDING                ReportRun_Payment_PaymentStatus = 1 // balance_due is positive.
+	ReportRun_Payment_PAID                       ReportRun_Payment_PaymentStatus = 2 // Settled, with nothing refunded.
+	ReportRun_Payment_REFUNDED                   ReportRun_Payment_PaymentStatus = 3 // Everything paid has been refunded.
+	ReportRun_Payment_VOID                       ReportRun_Payment_PaymentStatus = 4 // Nothing is owed or paid, e.g. because the run was cancelled.
+	ReportRun_Payment_PARTIALLY_REFUNDED         ReportRun_Payment_PaymentStatus = 5 // Settled, and part of what was paid has been refunded.
 )
 
 // Enum value maps for ReportRun_Payment_PaymentStatus.
@@ -142,12 +152,16 @@ var (
 		1: "OUTSTANDING",
 		2: "PAID",
 		3: "REFUNDED",
+		4: "VOID",
+		5: "PARTIALLY_REFUNDED",
 	}
 	ReportRun_Payment_PaymentStatus_value = map[string]int32{
 		"PAYMENT_STATUS_UNSPECIFIED": 0,
 		"OUTSTANDING":                1,
 		"PAID":                       2,
 		"REFUNDED":                   3,
+		"VOID":                       4,
+		"PARTIALLY_REFUNDED":         5,
 	}
 )
 
@@ -175,7 +189,241 @@ func (x ReportRun_Payment_PaymentStatus) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use ReportRun_Payment_PaymentStatus.Descriptor instead.
 func (ReportRun_Payment_PaymentStatus) EnumDescriptor() ([]byte, []int) {
-	return file_proto_nhd_proto_rawDescGZIP(), []int{4, 3, 0}
+	return file_proto_nhd_proto_rawDescGZIP(), []int{6, 4, 0}
+}
+
+type ReportRun_LedgerEntry_Type int32
+
+const (
+	ReportRun_LedgerEntry_TYPE_UNSPECIFIED ReportRun_LedgerEntry_Type = 0
+	ReportRun_LedgerEntry_CHARGE           ReportRun_LedgerEntry_Type = 1 // The report's initial cost.
+	ReportRun_LedgerEntry_PAYMENT          ReportRun_LedgerEntry_Type = 2
+	ReportRun_LedgerEntry_REFUND           ReportRun_LedgerEntry_Type = 3 // Returns part or all of the payments.
+	ReportRun_LedgerEntry_ADJUSTMENT       ReportRun_LedgerEntry_Type = 4 // Changes what is owed, e.g. when the cost changes.
+)
+
+// Enum value maps for ReportRun_LedgerEntry_Type.
+var (
+	ReportRun_LedgerEntry_Type_name = map[int32]string{
+		0: "TYPE_UNSPECIFIED",
+		1: "CHARGE",
+		2: "PAYMENT",
+		3: "REFUND",
+		4: "ADJUSTMENT",
+	}
+	ReportRun_LedgerEntry_Type_value = map[string]int32{
+		"TYPE_UNSPECIFIED": 0,
+		"CHARGE":           1,
+		"PAYMENT":          2,
+		"REFUND":           3,
+		"ADJUSTMENT":       4,
+	}
+)
+
+func (x ReportRun_LedgerEntry_Type) Enum() *ReportRun_LedgerEntry_Type {
+	p := new(ReportRun_LedgerEntry_Type)
+	*p = x
+	return p
+}
+
+func (x ReportRun_LedgerEntry_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ReportRun_LedgerEntry_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_nhd_proto_enumTypes[3].Descriptor()
+}
+
+func (ReportRun_LedgerEntry_Type) Type() protoreflect.EnumType {
+	return &file_proto_nhd_proto_enumTypes[3]
+}
+
+func (x ReportRun_LedgerEntry_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ReportRun_LedgerEntry_Type.Descriptor instead.
+func (ReportRun_LedgerEntry_Type) EnumDescriptor() ([]byte, []int) {
+	return file_proto_nhd_proto_rawDescGZIP(), []int{6, 5, 0}
+}
+
+type PriceRule_Scope int32
+
+const (
+	PriceRule_SCOPE_UNSPECIFIED PriceRule_Scope = 0
+	PriceRule_DEFAULT           PriceRule_Scope = 1
+	PriceRule_COMPANY           PriceRule_Scope = 2
+	PriceRule_CUSTOMER          PriceRule_Scope = 3
+)
+
+// Enum value maps for PriceRule_Scope.
+var (
+	PriceRule_Scope_name = map[int32]string{
+		0: "SCOPE_UNSPECIFIED",
+		1: "DEFAULT",
+		2: "COMPANY",
+		3: "CUSTOMER",
+	}
+	PriceRule_Scope_value = map[string]int32{
+		"SCOPE_UNSPECIFIED": 0,
+		"DEFAULT":           1,
+		"COMPANY":           2,
+		"CUSTOMER":          3,
+	}
+)
+
+func (x PriceRule_Scope) Enum() *PriceRule_Scope {
+	p := new(PriceRule_Scope)
+	*p = x
+	return p
+}
+
+func (x PriceRule_Scope) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (PriceRule_Scope) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_nhd_proto_enumTypes[4].Descriptor()
+}
+
+func (PriceRule_Scope) Type() protoreflect.EnumType {
+	return &file_proto_nhd_proto_enumTypes[4]
+}
+
+func (x PriceRule_Scope) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use PriceRule_Scope.Descriptor instead.
+func (PriceRule_Scope) EnumDescriptor() ([]byte, []int) {
+	return file_proto_nhd_proto_rawDescGZIP(), []int{7, 0}
+}
+
+// ========== Money ==========
+// Money is an exact amount of money: a whole number of the currency's minor
+// units, e.g. 4999 for USD 49.99 or 4999 for JPY 4,999.
+type Money struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Currency      string                 `protobuf:"bytes,1,opt,name=currency,proto3" json:"currency,omitempty"` // ISO 4217 code, e.g. "USD".
+	MinorUnits    int64                  `protobuf:"varint,2,opt,name=minor_units,json=minorUnits,proto3" json:"minor_units,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Money) Reset() {
+	*x = Money{}
+	mi := &file_proto_nhd_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Money) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Money) ProtoMessage() {}
+
+func (x *Money) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_nhd_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Money.ProtoReflect.Descriptor instead.
+func (*Money) Descriptor() ([]byte, []int) {
+	return file_proto_nhd_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Money) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *Money) GetMinorUnits() int64 {
+	if x != nil {
+		return x.MinorUnits
+	}
+	return 0
+}
+
+// ========== Organization ==========
+// An Organization is a tenant, such as a brokerage. Users, customers and
+// report runs each belong to one organization, and users only see the
+// customers and report runs of their own.
+type Organization struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	OrganizationId  string                 `protobuf:"bytes,1,opt,name=organization_id,json=organizationId,proto3" json:"organization_id,omitempty"`
+	Name            string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	CreatedAt       *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	CreatedByUserId string                 `protobuf:"bytes,4,opt,name=created_by_user_id,json=createdByUserId,proto3" json:"created_by_user_id,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *Organization) Reset() {
+	*x = Organization{}
+	mi := &file_proto_nhd_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Organization) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Organization) ProtoMessage() {}
+
+func (x *Organization) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_nhd_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Organization.ProtoReflect.Descriptor instead.
+func (*Organization) Descriptor() ([]byte, []int) {
+	return file_proto_nhd_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Organization) GetOrganizationId() string {
+	if x != nil {
+		return x.OrganizationId
+	}
+	return ""
+}
+
+func (x *Organization) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Organization) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Organization) GetCreatedByUserId() string {
+	if x != nil {
+		return x.CreatedByUserId
+	}
+	return ""
 }
 
 // ========== User ==========
@@ -190,7 +438,7 @@ type Permissions struct {
 
 func (x *Permissions) Reset() {
 	*x = Permissions{}
-	mi := &file_proto_nhd_proto_msgTypes[0]
+	mi := &file_proto_nhd_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -202,7 +450,7 @@ func (x *Permissions) String() string {
 func (*Permissions) ProtoMessage() {}
 
 func (x *Permissions) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_nhd_proto_msgTypes[0]
+	mi := &file_proto_nhd_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -215,7 +463,7 @@ func (x *Permissions) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Permissions.ProtoReflect.Descriptor instead.
 func (*Permissions) Descriptor() ([]byte, []int) {
-	return file_proto_nhd_proto_rawDescGZIP(), []int{0}
+	return file_proto_nhd_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *Permissions) GetCanCreateCustomers() bool {
@@ -240,19 +488,20 @@ func (x *Permissions) GetIsAdmin() bool {
 }
 
 type User struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"` // Firebase Auth UID
-	FullName      string                 `protobuf:"bytes,2,opt,name=full_name,json=fullName,proto3" json:"full_name,omitempty"`
-	Email         string                 `protobuf:"bytes,3,opt,name=email,proto3" json:"email,omitempty"`
-	Permissions   *Permissions           `protobuf:"bytes,4,opt,name=permissions,proto3" json:"permissions,omitempty"`
-	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	UserId         string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"` // Firebase Auth UID
+	FullName       string                 `protobuf:"bytes,2,opt,name=full_name,json=fullName,proto3" json:"full_name,omitempty"`
+	Email          string                 `protobuf:"bytes,3,opt,name=email,proto3" json:"email,omitempty"`
+	Permissions    *Permissions           `protobuf:"bytes,4,opt,name=permissions,proto3" json:"permissions,omitempty"`
+	CreatedAt      *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	OrganizationId string                 `protobuf:"bytes,6,opt,name=organization_id,json=organizationId,proto3" json:"organization_id,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *User) Reset() {
 	*x = User{}
-	mi := &file_proto_nhd_proto_msgTypes[1]
+	mi := &file_proto_nhd_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -264,7 +513,7 @@ func (x *User) String() string {
 func (*User) ProtoMessage() {}
 
 func (x *User) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_nhd_proto_msgTypes[1]
+	mi := &file_proto_nhd_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -277,7 +526,7 @@ func (x *User) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use User.ProtoReflect.Descriptor instead.
 func (*User) Descriptor() ([]byte, []int) {
-	return file_proto_nhd_proto_rawDescGZIP(), []int{1}
+	return file_proto_nhd_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *User) GetUserId() string {
@@ -315,6 +564,13 @@ func (x *User) GetCreatedAt() *timestamppb.Timestamp {
 	return nil
 }
 
+func (x *User) GetOrganizationId() string {
+	if x != nil {
+		return x.OrganizationId
+	}
+	return ""
+}
+
 // ========== Customer ==========
 type Customer struct {
 	state           protoimpl.MessageState `protogen:"open.v1"`
@@ -324,13 +580,25 @@ type Customer struct {
 	CompanyName     string                 `protobuf:"bytes,4,opt,name=company_name,json=companyName,proto3" json:"company_name,omitempty"`
 	CreatedAt       *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
 	CreatedByUserId string                 `protobuf:"bytes,6,opt,name=created_by_user_id,json=createdByUserId,proto3" json:"created_by_user_id,omitempty"`
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+	// Set from the creating user's organization.
+	OrganizationId string `protobuf:"bytes,7,opt,name=organization_id,json=organizationId,proto3" json:"organization_id,omitempty"`
+	// Time of the most recent write to this customer.
+	UpdatedAt *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	// Archived customers are hidden from listings and cannot be used for new
+	// report runs, but keep their existing report runs.
+	Archived   bool                   `protobuf:"varint,9,opt,name=archived,proto3" json:"archived,omitempty"`
+	ArchivedAt *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=archived_at,json=archivedAt,proto3" json:"archived_at,omitempty"`
+	// Lower-case prefixes of full_name, email and company_name and of the words
+	// in them, used for prefix search. Maintained by the datastore; never
+	// returned by the API.
+	SearchPrefixes []string `protobuf:"bytes,11,rep,name=search_prefixes,json=searchPrefixes,proto3" json:"search_prefixes,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *Customer) Reset() {
 	*x = Customer{}
-	mi := &file_proto_nhd_proto_msgTypes[2]
+	mi := &file_proto_nhd_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -342,7 +610,7 @@ func (x *Customer) String() string {
 func (*Customer) ProtoMessage() {}
 
 func (x *Customer) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_nhd_proto_msgTypes[2]
+	mi := &file_proto_nhd_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -355,7 +623,7 @@ func (x *Customer) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Customer.ProtoReflect.Descriptor instead.
 func (*Customer) Descriptor() ([]byte, []int) {
-	return file_proto_nhd_proto_rawDescGZIP(), []int{2}
+	return file_proto_nhd_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *Customer) GetCustomerId() string {
@@ -400,6 +668,41 @@ func (x *Customer) GetCreatedByUserId() string {
 	return ""
 }
 
+func (x *Customer) GetOrganizationId() string {
+	if x != nil {
+		return x.OrganizationId
+	}
+	return ""
+}
+
+func (x *Customer) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *Customer) GetArchived() bool {
+	if x != nil {
+		return x.Archived
+	}
+	return false
+}
+
+func (x *Customer) GetArchivedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ArchivedAt
+	}
+	return nil
+}
+
+func (x *Customer) GetSearchPrefixes() []string {
+	if x != nil {
+		return x.SearchPrefixes
+	}
+	return nil
+}
+
 // ========== Property Address ==========
 type PropertyAddress struct {
 	state             protoimpl.MessageState          `protogen:"open.v1"`
@@ -408,13 +711,19 @@ type PropertyAddress struct {
 	Coordinates       *PropertyAddress_Coordinates    `protobuf:"bytes,3,opt,name=coordinates,proto3" json:"coordinates,omitempty"`
 	PlusCode          string                          `protobuf:"bytes,4,opt,name=plus_code,json=plusCode,proto3" json:"plus_code,omitempty"`
 	GooglePlaceId     string                          `protobuf:"bytes,5,opt,name=google_place_id,json=googlePlaceId,proto3" json:"google_place_id,omitempty"`
-	unknownFields     protoimpl.UnknownFields
-	sizeCache         protoimpl.SizeCache
+	// Canonical form of address_details used to match repeat orders for the
+	// same property. Maintained by the backend; clients should not set it.
+	NormalizedKey string `protobuf:"bytes,6,opt,name=normalized_key,json=normalizedKey,proto3" json:"normalized_key,omitempty"`
+	// The organization the address belongs to. Like customers and report
+	// runs, addresses are only visible to their own organization.
+	OrganizationId string `protobuf:"bytes,7,opt,name=organization_id,json=organizationId,proto3" json:"organization_id,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *PropertyAddress) Reset() {
 	*x = PropertyAddress{}
-	mi := &file_proto_nhd_proto_msgTypes[3]
+	mi := &file_proto_nhd_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -426,7 +735,7 @@ func (x *PropertyAddress) String() string {
 func (*PropertyAddress) ProtoMessage() {}
 
 func (x *PropertyAddress) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_nhd_proto_msgTypes[3]
+	mi := &file_proto_nhd_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -439,7 +748,7 @@ func (x *PropertyAddress) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PropertyAddress.ProtoReflect.Descriptor instead.
 func (*PropertyAddress) Descriptor() ([]byte, []int) {
-	return file_proto_nhd_proto_rawDescGZIP(), []int{3}
+	return file_proto_nhd_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *PropertyAddress) GetPropertyAddressId() string {
@@ -477,6 +786,20 @@ func (x *PropertyAddress) GetGooglePlaceId() string {
 	return ""
 }
 
+func (x *PropertyAddress) GetNormalizedKey() string {
+	if x != nil {
+		return x.NormalizedKey
+	}
+	return ""
+}
+
+func (x *PropertyAddress) GetOrganizationId() string {
+	if x != nil {
+		return x.OrganizationId
+	}
+	return ""
+}
+
 // ========== Report Run ==========
 type ReportRun struct {
 	state       protoimpl.MessageState `protogen:"open.v1"`
@@ -485,9 +808,13 @@ type ReportRun struct {
 	// The ID of the internal user who created the report run on behalf of a customer.
 	// This field is optional. If it's not set, it implies the customer
 	// (identified by customer_id) created the report for themselves.
-	CreatedByUserId       string                     `protobuf:"bytes,3,opt,name=created_by_user_id,json=createdByUserId,proto3" json:"created_by_user_id,omitempty"`
-	PropertyAddressId     string                     `protobuf:"bytes,4,opt,name=property_address_id,json=propertyAddressId,proto3" json:"property_address_id,omitempty"`
-	CreatedAt             *timestamppb.Timestamp     `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	CreatedByUserId   string                 `protobuf:"bytes,3,opt,name=created_by_user_id,json=createdByUserId,proto3" json:"created_by_user_id,omitempty"`
+	PropertyAddressId string                 `protobuf:"bytes,4,opt,name=property_address_id,json=propertyAddressId,proto3" json:"property_address_id,omitempty"`
+	CreatedAt         *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	// Changed only through the lifecycle PENDING -> PROCESSING -> COMPLETED or
+	// FAILED, or PENDING -> CANCELLED. A FAILED run may be retried, which
+	// returns it to PENDING; a retry that cannot be queued returns it to
+	// FAILED. Each change is appended to status_history.
 	Status                ReportRun_Status           `protobuf:"varint,6,opt,name=status,proto3,enum=nhdreport.ReportRun_Status" json:"status,omitempty"`
 	Results               *ReportRun_HazardResults   `protobuf:"bytes,7,opt,name=results,proto3" json:"results,omitempty"`
 	TemplateReference     string                     `protobuf:"bytes,8,opt,name=template_reference,json=templateReference,proto3" json:"template_reference,omitempty"`
@@ -496,13 +823,28 @@ type ReportRun struct {
 	DisableAutomaticEmail bool                       `protobuf:"varint,11,opt,name=disable_automatic_email,json=disableAutomaticEmail,proto3" json:"disable_automatic_email,omitempty"`
 	CostHistory           []*ReportRun_ReportCost    `protobuf:"bytes,12,rep,name=cost_history,json=costHistory,proto3" json:"cost_history,omitempty"` // Complete, auditable history of cost changes.
 	PaymentDetails        *ReportRun_Payment         `protobuf:"bytes,13,opt,name=payment_details,json=paymentDetails,proto3" json:"payment_details,omitempty"`
-	unknownFields         protoimpl.UnknownFields
-	sizeCache             protoimpl.SizeCache
+	// Every charge, payment, refund and adjustment, oldest first. Entries are
+	// only ever appended; payment_details is computed from them.
+	Ledger []*ReportRun_LedgerEntry `protobuf:"bytes,19,rep,name=ledger,proto3" json:"ledger,omitempty"`
+	// Time of the most recent write to this report run. Used for the
+	// Last-Modified header and for sorting.
+	UpdatedAt *timestamppb.Timestamp `protobuf:"bytes,14,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	// Why the run FAILED, e.g. "property address has no coordinates".
+	FailureReason string `protobuf:"bytes,15,opt,name=failure_reason,json=failureReason,proto3" json:"failure_reason,omitempty"`
+	// Set from the creating user's organization.
+	OrganizationId string `protobuf:"bytes,16,opt,name=organization_id,json=organizationId,proto3" json:"organization_id,omitempty"`
+	// Every change of status, oldest first.
+	StatusHistory []*ReportRun_StatusChange `protobuf:"bytes,17,rep,name=status_history,json=statusHistory,proto3" json:"status_history,omitempty"`
+	// How many times the run has been queued: 1 when it is created, plus one
+	// for each retry.
+	Attempt       int32 `protobuf:"varint,18,opt,name=attempt,proto3" json:"attempt,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ReportRun) Reset() {
 	*x = ReportRun{}
-	mi := &file_proto_nhd_proto_msgTypes[4]
+	mi := &file_proto_nhd_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -514,7 +856,7 @@ func (x *ReportRun) String() string {
 func (*ReportRun) ProtoMessage() {}
 
 func (x *ReportRun) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_nhd_proto_msgTypes[4]
+	mi := &file_proto_nhd_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -527,7 +869,7 @@ func (x *ReportRun) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ReportRun.ProtoReflect.Descriptor instead.
 func (*ReportRun) Descriptor() ([]byte, []int) {
-	return file_proto_nhd_proto_rawDescGZIP(), []int{4}
+	return file_proto_nhd_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *ReportRun) GetReportRunId() string {
@@ -621,51 +963,290 @@ func (x *ReportRun) GetPaymentDetails() *ReportRun_Payment {
 	return nil
 }
 
-type PropertyAddress_AddressDetails struct {
-	state           protoimpl.MessageState `protogen:"open.v1"`
-	StreetAddress   string                 `protobuf:"bytes,1,opt,name=street_address,json=streetAddress,proto3" json:"street_address,omitempty"`
-	StreetAddress_2 string                 `protobuf:"bytes,2,opt,name=street_address_2,json=streetAddress2,proto3" json:"street_address_2,omitempty"`
-	City            string                 `protobuf:"bytes,3,opt,name=city,proto3" json:"city,omitempty"`
-	State           string                 `protobuf:"bytes,4,opt,name=state,proto3" json:"state,omitempty"`
-	ZipCode         string                 `protobuf:"bytes,5,opt,name=zip_code,json=zipCode,proto3" json:"zip_code,omitempty"`
-	ZipPlus_4       string                 `protobuf:"bytes,6,opt,name=zip_plus_4,json=zipPlus4,proto3" json:"zip_plus_4,omitempty"`
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
-}
-
-func (x *PropertyAddress_AddressDetails) Reset() {
-	*x = PropertyAddress_AddressDetails{}
-	mi := &file_proto_nhd_proto_msgTypes[5]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
-}
-
-func (x *PropertyAddress_AddressDetails) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *ReportRun) GetLedger() []*ReportRun_LedgerEntry {
+	if x != nil {
+		return x.Ledger
+	}
+	return nil
 }
 
-func (*PropertyAddress_AddressDetails) ProtoMessage() {}
-
-func (x *PropertyAddress_AddressDetails) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_nhd_proto_msgTypes[5]
+func (x *ReportRun) GetUpdatedAt() *timestamppb.Timestamp {
 	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
+		return x.UpdatedAt
 	}
-	return mi.MessageOf(x)
+	return nil
 }
 
-// Deprecated: Use PropertyAddress_AddressDetails.ProtoReflect.Descriptor instead.
-func (*PropertyAddress_AddressDetails) Descriptor() ([]byte, []int) {
-	return file_proto_nhd_proto_rawDescGZIP(), []int{3, 0}
+func (x *ReportRun) GetFailureReason() string {
+	if x != nil {
+		return x.FailureReason
+	}
+	return ""
 }
 
-func (x *PropertyAddress_AddressDetails) GetStreetAddress() string {
+func (x *ReportRun) GetOrganizationId() string {
 	if x != nil {
-		return x.StreetAddress
+		return x.OrganizationId
+	}
+	return ""
+}
+
+func (x *ReportRun) GetStatusHistory() []*ReportRun_StatusChange {
+	if x != nil {
+		return x.StatusHistory
+	}
+	return nil
+}
+
+func (x *ReportRun) GetAttempt() int32 {
+	if x != nil {
+		return x.Attempt
+	}
+	return 0
+}
+
+// ========== Pricing ==========
+// A PriceRule sets the price of a report from effective_from onwards. Rules
+// are never edited; a price change is recorded by adding a new rule, so the
+// collection is a complete, effective-dated history.
+type PriceRule struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	PriceRuleId string                 `protobuf:"bytes,1,opt,name=price_rule_id,json=priceRuleId,proto3" json:"price_rule_id,omitempty"`
+	Scope       PriceRule_Scope        `protobuf:"varint,2,opt,name=scope,proto3,enum=nhdreport.PriceRule_Scope" json:"scope,omitempty"`
+	// The company_name (COMPANY) or customer_id (CUSTOMER) the rule applies to.
+	// Empty for DEFAULT rules.
+	ScopeValue      string                 `protobuf:"bytes,3,opt,name=scope_value,json=scopeValue,proto3" json:"scope_value,omitempty"`
+	Amount          *Money                 `protobuf:"bytes,9,opt,name=amount,proto3" json:"amount,omitempty"`
+	EffectiveFrom   *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=effective_from,json=effectiveFrom,proto3" json:"effective_from,omitempty"`
+	CreatedAt       *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	CreatedByUserId string                 `protobuf:"bytes,8,opt,name=created_by_user_id,json=createdByUserId,proto3" json:"created_by_user_id,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *PriceRule) Reset() {
+	*x = PriceRule{}
+	mi := &file_proto_nhd_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PriceRule) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PriceRule) ProtoMessage() {}
+
+func (x *PriceRule) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_nhd_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PriceRule.ProtoReflect.Descriptor instead.
+func (*PriceRule) Descriptor() ([]byte, []int) {
+	return file_proto_nhd_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *PriceRule) GetPriceRuleId() string {
+	if x != nil {
+		return x.PriceRuleId
+	}
+	return ""
+}
+
+func (x *PriceRule) GetScope() PriceRule_Scope {
+	if x != nil {
+		return x.Scope
+	}
+	return PriceRule_SCOPE_UNSPECIFIED
+}
+
+func (x *PriceRule) GetScopeValue() string {
+	if x != nil {
+		return x.ScopeValue
+	}
+	return ""
+}
+
+func (x *PriceRule) GetAmount() *Money {
+	if x != nil {
+		return x.Amount
+	}
+	return nil
+}
+
+func (x *PriceRule) GetEffectiveFrom() *timestamppb.Timestamp {
+	if x != nil {
+		return x.EffectiveFrom
+	}
+	return nil
+}
+
+func (x *PriceRule) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *PriceRule) GetCreatedByUserId() string {
+	if x != nil {
+		return x.CreatedByUserId
+	}
+	return ""
+}
+
+// ========== ExchangeRate ==========
+// An ExchangeRate converts base_currency to quote_currency from
+// effective_from onwards: one unit of base_currency is worth rate units of
+// quote_currency. It also converts quote_currency back to base_currency at
+// the inverse rate. Rates are never edited; a new rate is recorded by adding
+// a new one, so the collection is a complete, effective-dated history.
+type ExchangeRate struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	ExchangeRateId string                 `protobuf:"bytes,1,opt,name=exchange_rate_id,json=exchangeRateId,proto3" json:"exchange_rate_id,omitempty"`
+	BaseCurrency   string                 `protobuf:"bytes,2,opt,name=base_currency,json=baseCurrency,proto3" json:"base_currency,omitempty"`
+	QuoteCurrency  string                 `protobuf:"bytes,3,opt,name=quote_currency,json=quoteCurrency,proto3" json:"quote_currency,omitempty"`
+	// A positive decimal, e.g. "0.7312". It is a string so that it is exact.
+	Rate            string                 `protobuf:"bytes,4,opt,name=rate,proto3" json:"rate,omitempty"`
+	EffectiveFrom   *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=effective_from,json=effectiveFrom,proto3" json:"effective_from,omitempty"`
+	CreatedAt       *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	CreatedByUserId string                 `protobuf:"bytes,7,opt,name=created_by_user_id,json=createdByUserId,proto3" json:"created_by_user_id,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ExchangeRate) Reset() {
+	*x = ExchangeRate{}
+	mi := &file_proto_nhd_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExchangeRate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExchangeRate) ProtoMessage() {}
+
+func (x *ExchangeRate) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_nhd_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExchangeRate.ProtoReflect.Descriptor instead.
+func (*ExchangeRate) Descriptor() ([]byte, []int) {
+	return file_proto_nhd_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ExchangeRate) GetExchangeRateId() string {
+	if x != nil {
+		return x.ExchangeRateId
+	}
+	return ""
+}
+
+func (x *ExchangeRate) GetBaseCurrency() string {
+	if x != nil {
+		return x.BaseCurrency
+	}
+	return ""
+}
+
+func (x *ExchangeRate) GetQuoteCurrency() string {
+	if x != nil {
+		return x.QuoteCurrency
+	}
+	return ""
+}
+
+func (x *ExchangeRate) GetRate() string {
+	if x != nil {
+		return x.Rate
+	}
+	return ""
+}
+
+func (x *ExchangeRate) GetEffectiveFrom() *timestamppb.Timestamp {
+	if x != nil {
+		return x.EffectiveFrom
+	}
+	return nil
+}
+
+func (x *ExchangeRate) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *ExchangeRate) GetCreatedByUserId() string {
+	if x != nil {
+		return x.CreatedByUserId
+	}
+	return ""
+}
+
+type PropertyAddress_AddressDetails struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	StreetAddress   string                 `protobuf:"bytes,1,opt,name=street_address,json=streetAddress,proto3" json:"street_address,omitempty"`
+	StreetAddress_2 string                 `protobuf:"bytes,2,opt,name=street_address_2,json=streetAddress2,proto3" json:"street_address_2,omitempty"`
+	City            string                 `protobuf:"bytes,3,opt,name=city,proto3" json:"city,omitempty"`
+	State           string                 `protobuf:"bytes,4,opt,name=state,proto3" json:"state,omitempty"`
+	ZipCode         string                 `protobuf:"bytes,5,opt,name=zip_code,json=zipCode,proto3" json:"zip_code,omitempty"`
+	ZipPlus_4       string                 `protobuf:"bytes,6,opt,name=zip_plus_4,json=zipPlus4,proto3" json:"zip_plus_4,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *PropertyAddress_AddressDetails) Reset() {
+	*x = PropertyAddress_AddressDetails{}
+	mi := &file_proto_nhd_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PropertyAddress_AddressDetails) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PropertyAddress_AddressDetails) ProtoMessage() {}
+
+func (x *PropertyAddress_AddressDetails) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_nhd_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PropertyAddress_AddressDetails.ProtoReflect.Descriptor instead.
+func (*PropertyAddress_AddressDetails) Descriptor() ([]byte, []int) {
+	return file_proto_nhd_proto_rawDescGZIP(), []int{5, 0}
+}
+
+func (x *PropertyAddress_AddressDetails) GetStreetAddress() string {
+	if x != nil {
+		return x.StreetAddress
 	}
 	return ""
 }
@@ -715,7 +1296,7 @@ type PropertyAddress_Coordinates struct {
 
 func (x *PropertyAddress_Coordinates) Reset() {
 	*x = PropertyAddress_Coordinates{}
-	mi := &file_proto_nhd_proto_msgTypes[6]
+	mi := &file_proto_nhd_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -727,7 +1308,7 @@ func (x *PropertyAddress_Coordinates) String() string {
 func (*PropertyAddress_Coordinates) ProtoMessage() {}
 
 func (x *PropertyAddress_Coordinates) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_nhd_proto_msgTypes[6]
+	mi := &file_proto_nhd_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -740,7 +1321,7 @@ func (x *PropertyAddress_Coordinates) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PropertyAddress_Coordinates.ProtoReflect.Descriptor instead.
 func (*PropertyAddress_Coordinates) Descriptor() ([]byte, []int) {
-	return file_proto_nhd_proto_rawDescGZIP(), []int{3, 1}
+	return file_proto_nhd_proto_rawDescGZIP(), []int{5, 1}
 }
 
 func (x *PropertyAddress_Coordinates) GetLatitude() float64 {
@@ -765,13 +1346,17 @@ type ReportRun_HazardResults struct {
 	InWildlandFireArea               bool                   `protobuf:"varint,4,opt,name=in_wildland_fire_area,json=inWildlandFireArea,proto3" json:"in_wildland_fire_area,omitempty"`
 	InEarthquakeFaultZone            bool                   `protobuf:"varint,5,opt,name=in_earthquake_fault_zone,json=inEarthquakeFaultZone,proto3" json:"in_earthquake_fault_zone,omitempty"`
 	InSeismicHazardZone              bool                   `protobuf:"varint,6,opt,name=in_seismic_hazard_zone,json=inSeismicHazardZone,proto3" json:"in_seismic_hazard_zone,omitempty"`
-	unknownFields                    protoimpl.UnknownFields
-	sizeCache                        protoimpl.SizeCache
+	// Evidence for the results above, one entry per hazard layer that was
+	// consulted. A hazard whose layer was not loaded has no entry, so
+	// consumers must find an entry by its hazard field, not its position.
+	Evidence      []*ReportRun_HazardEvidence `protobuf:"bytes,7,rep,name=evidence,proto3" json:"evidence,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ReportRun_HazardResults) Reset() {
 	*x = ReportRun_HazardResults{}
-	mi := &file_proto_nhd_proto_msgTypes[7]
+	mi := &file_proto_nhd_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -783,7 +1368,7 @@ func (x *ReportRun_HazardResults) String() string {
 func (*ReportRun_HazardResults) ProtoMessage() {}
 
 func (x *ReportRun_HazardResults) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_nhd_proto_msgTypes[7]
+	mi := &file_proto_nhd_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -796,7 +1381,7 @@ func (x *ReportRun_HazardResults) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ReportRun_HazardResults.ProtoReflect.Descriptor instead.
 func (*ReportRun_HazardResults) Descriptor() ([]byte, []int) {
-	return file_proto_nhd_proto_rawDescGZIP(), []int{4, 0}
+	return file_proto_nhd_proto_rawDescGZIP(), []int{6, 0}
 }
 
 func (x *ReportRun_HazardResults) GetInSpecialFloodHazardArea() bool {
@@ -841,18 +1426,135 @@ func (x *ReportRun_HazardResults) GetInSeismicHazardZone() bool {
 	return false
 }
 
+func (x *ReportRun_HazardResults) GetEvidence() []*ReportRun_HazardEvidence {
+	if x != nil {
+		return x.Evidence
+	}
+	return nil
+}
+
+// HazardEvidence records how one hazard result was reached: the layer that
+// was consulted and the features of it, if any, that contain the property.
+type ReportRun_HazardEvidence struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The result this supports: its HazardResults field name without the
+	// "in_" prefix, e.g. "very_high_fire_hazard_severity_zone".
+	Hazard             string                                     `protobuf:"bytes,1,opt,name=hazard,proto3" json:"hazard,omitempty"`
+	InZone             bool                                       `protobuf:"varint,2,opt,name=in_zone,json=inZone,proto3" json:"in_zone,omitempty"`
+	Dataset            string                                     `protobuf:"bytes,3,opt,name=dataset,proto3" json:"dataset,omitempty"` // e.g., "Fire Hazard Severity Zones"
+	Agency             string                                     `protobuf:"bytes,4,opt,name=agency,proto3" json:"agency,omitempty"`   // e.g., "CAL FIRE"
+	LayerVersion       string                                     `protobuf:"bytes,5,opt,name=layer_version,json=layerVersion,proto3" json:"layer_version,omitempty"`
+	LayerEffectiveDate *timestamppb.Timestamp                     `protobuf:"bytes,6,opt,name=layer_effective_date,json=layerEffectiveDate,proto3" json:"layer_effective_date,omitempty"`
+	MatchedFeatures    []*ReportRun_HazardEvidence_MatchedFeature `protobuf:"bytes,7,rep,name=matched_features,json=matchedFeatures,proto3" json:"matched_features,omitempty"`
+	// Distance from the property to the nearest boundary: the nearest edge
+	// of a matched feature if in_zone, otherwise the nearest edge of any
+	// feature in the layer. Unset if the layer has no features; zero means
+	// the property is on a boundary.
+	DistanceToBoundaryMeters *float64 `protobuf:"fixed64,8,opt,name=distance_to_boundary_meters,json=distanceToBoundaryMeters,proto3,oneof" json:"distance_to_boundary_meters,omitempty"`
+	unknownFields            protoimpl.UnknownFields
+	sizeCache                protoimpl.SizeCache
+}
+
+func (x *ReportRun_HazardEvidence) Reset() {
+	*x = ReportRun_HazardEvidence{}
+	mi := &file_proto_nhd_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportRun_HazardEvidence) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportRun_HazardEvidence) ProtoMessage() {}
+
+func (x *ReportRun_HazardEvidence) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_nhd_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportRun_HazardEvidence.ProtoReflect.Descriptor instead.
+func (*ReportRun_HazardEvidence) Descriptor() ([]byte, []int) {
+	return file_proto_nhd_proto_rawDescGZIP(), []int{6, 1}
+}
+
+func (x *ReportRun_HazardEvidence) GetHazard() string {
+	if x != nil {
+		return x.Hazard
+	}
+	return ""
+}
+
+func (x *ReportRun_HazardEvidence) GetInZone() bool {
+	if x != nil {
+		return x.InZone
+	}
+	return false
+}
+
+func (x *ReportRun_HazardEvidence) GetDataset() string {
+	if x != nil {
+		return x.Dataset
+	}
+	return ""
+}
+
+func (x *ReportRun_HazardEvidence) GetAgency() string {
+	if x != nil {
+		return x.Agency
+	}
+	return ""
+}
+
+func (x *ReportRun_HazardEvidence) GetLayerVersion() string {
+	if x != nil {
+		return x.LayerVersion
+	}
+	return ""
+}
+
+func (x *ReportRun_HazardEvidence) GetLayerEffectiveDate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LayerEffectiveDate
+	}
+	return nil
+}
+
+func (x *ReportRun_HazardEvidence) GetMatchedFeatures() []*ReportRun_HazardEvidence_MatchedFeature {
+	if x != nil {
+		return x.MatchedFeatures
+	}
+	return nil
+}
+
+func (x *ReportRun_HazardEvidence) GetDistanceToBoundaryMeters() float64 {
+	if x != nil && x.DistanceToBoundaryMeters != nil {
+		return *x.DistanceToBoundaryMeters
+	}
+	return 0
+}
+
 type ReportRun_EmailDelivery struct {
 	state                  protoimpl.MessageState                 `protogen:"open.v1"`
 	Status                 ReportRun_EmailDelivery_DeliveryStatus `protobuf:"varint,1,opt,name=status,proto3,enum=nhdreport.ReportRun_EmailDelivery_DeliveryStatus" json:"status,omitempty"`
 	SentAt                 *timestamppb.Timestamp                 `protobuf:"bytes,2,opt,name=sent_at,json=sentAt,proto3" json:"sent_at,omitempty"`
 	EmailTemplateReference string                                 `protobuf:"bytes,3,opt,name=email_template_reference,json=emailTemplateReference,proto3" json:"email_template_reference,omitempty"`
+	Recipient              string                                 `protobuf:"bytes,4,opt,name=recipient,proto3" json:"recipient,omitempty"`
+	FailureReason          string                                 `protobuf:"bytes,5,opt,name=failure_reason,json=failureReason,proto3" json:"failure_reason,omitempty"` // Set when status is FAILED or UNKNOWN.
 	unknownFields          protoimpl.UnknownFields
 	sizeCache              protoimpl.SizeCache
 }
 
 func (x *ReportRun_EmailDelivery) Reset() {
 	*x = ReportRun_EmailDelivery{}
-	mi := &file_proto_nhd_proto_msgTypes[8]
+	mi := &file_proto_nhd_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -864,7 +1566,7 @@ func (x *ReportRun_EmailDelivery) String() string {
 func (*ReportRun_EmailDelivery) ProtoMessage() {}
 
 func (x *ReportRun_EmailDelivery) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_nhd_proto_msgTypes[8]
+	mi := &file_proto_nhd_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -877,7 +1579,7 @@ func (x *ReportRun_EmailDelivery) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ReportRun_EmailDelivery.ProtoReflect.Descriptor instead.
 func (*ReportRun_EmailDelivery) Descriptor() ([]byte, []int) {
-	return file_proto_nhd_proto_rawDescGZIP(), []int{4, 1}
+	return file_proto_nhd_proto_rawDescGZIP(), []int{6, 2}
 }
 
 func (x *ReportRun_EmailDelivery) GetStatus() ReportRun_EmailDelivery_DeliveryStatus {
@@ -901,20 +1603,35 @@ func (x *ReportRun_EmailDelivery) GetEmailTemplateReference() string {
 	return ""
 }
 
+func (x *ReportRun_EmailDelivery) GetRecipient() string {
+	if x != nil {
+		return x.Recipient
+	}
+	return ""
+}
+
+func (x *ReportRun_EmailDelivery) GetFailureReason() string {
+	if x != nil {
+		return x.FailureReason
+	}
+	return ""
+}
+
 // Financials
 type ReportRun_ReportCost struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Amount        float64                `protobuf:"fixed64,1,opt,name=amount,proto3" json:"amount,omitempty"`
-	Currency      string                 `protobuf:"bytes,2,opt,name=currency,proto3" json:"currency,omitempty"` // e.g., "USD"
+	Amount        *Money                 `protobuf:"bytes,7,opt,name=amount,proto3" json:"amount,omitempty"`
 	SetAt         *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=set_at,json=setAt,proto3" json:"set_at,omitempty"`
 	SetByUserId   string                 `protobuf:"bytes,4,opt,name=set_by_user_id,json=setByUserId,proto3" json:"set_by_user_id,omitempty"`
+	PriceRuleId   string                 `protobuf:"bytes,5,opt,name=price_rule_id,json=priceRuleId,proto3" json:"price_rule_id,omitempty"` // The pricing rule that produced this cost, if any.
+	Reason        string                 `protobuf:"bytes,6,opt,name=reason,proto3" json:"reason,omitempty"`                                // Why the cost was set, e.g. "Report run cancelled".
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ReportRun_ReportCost) Reset() {
 	*x = ReportRun_ReportCost{}
-	mi := &file_proto_nhd_proto_msgTypes[9]
+	mi := &file_proto_nhd_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -926,7 +1643,7 @@ func (x *ReportRun_ReportCost) String() string {
 func (*ReportRun_ReportCost) ProtoMessage() {}
 
 func (x *ReportRun_ReportCost) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_nhd_proto_msgTypes[9]
+	mi := &file_proto_nhd_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -939,21 +1656,14 @@ func (x *ReportRun_ReportCost) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ReportRun_ReportCost.ProtoReflect.Descriptor instead.
 func (*ReportRun_ReportCost) Descriptor() ([]byte, []int) {
-	return file_proto_nhd_proto_rawDescGZIP(), []int{4, 2}
+	return file_proto_nhd_proto_rawDescGZIP(), []int{6, 3}
 }
 
-func (x *ReportRun_ReportCost) GetAmount() float64 {
+func (x *ReportRun_ReportCost) GetAmount() *Money {
 	if x != nil {
 		return x.Amount
 	}
-	return 0
-}
-
-func (x *ReportRun_ReportCost) GetCurrency() string {
-	if x != nil {
-		return x.Currency
-	}
-	return ""
+	return nil
 }
 
 func (x *ReportRun_ReportCost) GetSetAt() *timestamppb.Timestamp {
@@ -970,21 +1680,43 @@ func (x *ReportRun_ReportCost) GetSetByUserId() string {
 	return ""
 }
 
+func (x *ReportRun_ReportCost) GetPriceRuleId() string {
+	if x != nil {
+		return x.PriceRuleId
+	}
+	return ""
+}
+
+func (x *ReportRun_ReportCost) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+// Payment summarizes the ledger, so that runs can be filtered by what is
+// owed. The datastore recomputes it whenever an entry is appended to the
+// ledger; it is never written directly.
 type ReportRun_Payment struct {
-	state         protoimpl.MessageState          `protogen:"open.v1"`
-	Status        ReportRun_Payment_PaymentStatus `protobuf:"varint,1,opt,name=status,proto3,enum=nhdreport.ReportRun_Payment_PaymentStatus" json:"status,omitempty"`
-	AmountPaid    float64                         `protobuf:"fixed64,2,opt,name=amount_paid,json=amountPaid,proto3" json:"amount_paid,omitempty"`
-	Currency      string                          `protobuf:"bytes,3,opt,name=currency,proto3" json:"currency,omitempty"`
-	PaidAt        *timestamppb.Timestamp          `protobuf:"bytes,4,opt,name=paid_at,json=paidAt,proto3" json:"paid_at,omitempty"`
-	PaymentMethod string                          `protobuf:"bytes,5,opt,name=payment_method,json=paymentMethod,proto3" json:"payment_method,omitempty"` // e.g., "Stripe", "Manual"
-	TransactionId string                          `protobuf:"bytes,6,opt,name=transaction_id,json=transactionId,proto3" json:"transaction_id,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state          protoimpl.MessageState          `protogen:"open.v1"`
+	Status         ReportRun_Payment_PaymentStatus `protobuf:"varint,1,opt,name=status,proto3,enum=nhdreport.ReportRun_Payment_PaymentStatus" json:"status,omitempty"`
+	AmountPaid     *Money                          `protobuf:"bytes,10,opt,name=amount_paid,json=amountPaid,proto3" json:"amount_paid,omitempty"`         // Payments less refunds.
+	PaidAt         *timestamppb.Timestamp          `protobuf:"bytes,4,opt,name=paid_at,json=paidAt,proto3" json:"paid_at,omitempty"`                      // Time of the latest payment.
+	PaymentMethod  string                          `protobuf:"bytes,5,opt,name=payment_method,json=paymentMethod,proto3" json:"payment_method,omitempty"` // Of the latest payment, e.g., "Stripe", "Manual"
+	TransactionId  string                          `protobuf:"bytes,6,opt,name=transaction_id,json=transactionId,proto3" json:"transaction_id,omitempty"` // Of the latest payment.
+	AmountRefunded *Money                          `protobuf:"bytes,11,opt,name=amount_refunded,json=amountRefunded,proto3" json:"amount_refunded,omitempty"`
+	// Charges and adjustments less payments. A refund is credited against
+	// the charge as well as the payment, so it does not change the balance.
+	BalanceDue *Money `protobuf:"bytes,12,opt,name=balance_due,json=balanceDue,proto3" json:"balance_due,omitempty"`
+	// Time of the latest payment or refund.
+	LastTransactionAt *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=last_transaction_at,json=lastTransactionAt,proto3" json:"last_transaction_at,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
 }
 
 func (x *ReportRun_Payment) Reset() {
 	*x = ReportRun_Payment{}
-	mi := &file_proto_nhd_proto_msgTypes[10]
+	mi := &file_proto_nhd_proto_msgTypes[15]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -996,7 +1728,7 @@ func (x *ReportRun_Payment) String() string {
 func (*ReportRun_Payment) ProtoMessage() {}
 
 func (x *ReportRun_Payment) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_nhd_proto_msgTypes[10]
+	mi := &file_proto_nhd_proto_msgTypes[15]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1009,7 +1741,7 @@ func (x *ReportRun_Payment) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ReportRun_Payment.ProtoReflect.Descriptor instead.
 func (*ReportRun_Payment) Descriptor() ([]byte, []int) {
-	return file_proto_nhd_proto_rawDescGZIP(), []int{4, 3}
+	return file_proto_nhd_proto_rawDescGZIP(), []int{6, 4}
 }
 
 func (x *ReportRun_Payment) GetStatus() ReportRun_Payment_PaymentStatus {
@@ -1019,57 +1751,317 @@ func (x *ReportRun_Payment) GetStatus() ReportRun_Payment_PaymentStatus {
 	return ReportRun_Payment_PAYMENT_STATUS_UNSPECIFIED
 }
 
-func (x *ReportRun_Payment) GetAmountPaid() float64 {
+func (x *ReportRun_Payment) GetAmountPaid() *Money {
 	if x != nil {
 		return x.AmountPaid
 	}
-	return 0
+	return nil
 }
 
-func (x *ReportRun_Payment) GetCurrency() string {
+func (x *ReportRun_Payment) GetPaidAt() *timestamppb.Timestamp {
 	if x != nil {
-		return x.Currency
+		return x.PaidAt
+	}
+	return nil
+}
+
+func (x *ReportRun_Payment) GetPaymentMethod() string {
+	if x != nil {
+		return x.PaymentMethod
 	}
 	return ""
 }
 
-func (x *ReportRun_Payment) GetPaidAt() *timestamppb.Timestamp {
+func (x *ReportRun_Payment) GetTransactionId() string {
 	if x != nil {
-		return x.PaidAt
+		return x.TransactionId
+	}
+	return ""
+}
+
+func (x *ReportRun_Payment) GetAmountRefunded() *Money {
+	if x != nil {
+		return x.AmountRefunded
 	}
 	return nil
 }
 
-func (x *ReportRun_Payment) GetPaymentMethod() string {
+func (x *ReportRun_Payment) GetBalanceDue() *Money {
+	if x != nil {
+		return x.BalanceDue
+	}
+	return nil
+}
+
+func (x *ReportRun_Payment) GetLastTransactionAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastTransactionAt
+	}
+	return nil
+}
+
+// A LedgerEntry is one movement on the run's account.
+type ReportRun_LedgerEntry struct {
+	state   protoimpl.MessageState     `protogen:"open.v1"`
+	EntryId string                     `protobuf:"bytes,1,opt,name=entry_id,json=entryId,proto3" json:"entry_id,omitempty"`
+	Type    ReportRun_LedgerEntry_Type `protobuf:"varint,2,opt,name=type,proto3,enum=nhdreport.ReportRun_LedgerEntry_Type" json:"type,omitempty"`
+	// Positive, except that an ADJUSTMENT that reduces what is owed is
+	// negative.
+	Amount          *Money                 `protobuf:"bytes,10,opt,name=amount,proto3" json:"amount,omitempty"`
+	CreatedAt       *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	CreatedByUserId string                 `protobuf:"bytes,6,opt,name=created_by_user_id,json=createdByUserId,proto3" json:"created_by_user_id,omitempty"`
+	PaymentMethod   string                 `protobuf:"bytes,7,opt,name=payment_method,json=paymentMethod,proto3" json:"payment_method,omitempty"` // PAYMENT and REFUND entries, e.g., "Stripe"
+	TransactionId   string                 `protobuf:"bytes,8,opt,name=transaction_id,json=transactionId,proto3" json:"transaction_id,omitempty"` // PAYMENT and REFUND entries.
+	Reason          string                 `protobuf:"bytes,9,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ReportRun_LedgerEntry) Reset() {
+	*x = ReportRun_LedgerEntry{}
+	mi := &file_proto_nhd_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportRun_LedgerEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportRun_LedgerEntry) ProtoMessage() {}
+
+func (x *ReportRun_LedgerEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_nhd_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportRun_LedgerEntry.ProtoReflect.Descriptor instead.
+func (*ReportRun_LedgerEntry) Descriptor() ([]byte, []int) {
+	return file_proto_nhd_proto_rawDescGZIP(), []int{6, 5}
+}
+
+func (x *ReportRun_LedgerEntry) GetEntryId() string {
+	if x != nil {
+		return x.EntryId
+	}
+	return ""
+}
+
+func (x *ReportRun_LedgerEntry) GetType() ReportRun_LedgerEntry_Type {
+	if x != nil {
+		return x.Type
+	}
+	return ReportRun_LedgerEntry_TYPE_UNSPECIFIED
+}
+
+func (x *ReportRun_LedgerEntry) GetAmount() *Money {
+	if x != nil {
+		return x.Amount
+	}
+	return nil
+}
+
+func (x *ReportRun_LedgerEntry) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *ReportRun_LedgerEntry) GetCreatedByUserId() string {
+	if x != nil {
+		return x.CreatedByUserId
+	}
+	return ""
+}
+
+func (x *ReportRun_LedgerEntry) GetPaymentMethod() string {
 	if x != nil {
 		return x.PaymentMethod
 	}
 	return ""
 }
 
-func (x *ReportRun_Payment) GetTransactionId() string {
+func (x *ReportRun_LedgerEntry) GetTransactionId() string {
 	if x != nil {
 		return x.TransactionId
 	}
 	return ""
 }
 
+func (x *ReportRun_LedgerEntry) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+// A StatusChange records one change of status.
+type ReportRun_StatusChange struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// STATUS_UNSPECIFIED for the entry recording the run's creation.
+	FromStatus ReportRun_Status       `protobuf:"varint,1,opt,name=from_status,json=fromStatus,proto3,enum=nhdreport.ReportRun_Status" json:"from_status,omitempty"`
+	ToStatus   ReportRun_Status       `protobuf:"varint,2,opt,name=to_status,json=toStatus,proto3,enum=nhdreport.ReportRun_Status" json:"to_status,omitempty"`
+	ChangedAt  *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=changed_at,json=changedAt,proto3" json:"changed_at,omitempty"`
+	// The ID of the user who made the change, or "worker" or "reporter" for
+	// the report generators.
+	Actor         string `protobuf:"bytes,4,opt,name=actor,proto3" json:"actor,omitempty"`
+	Reason        string `protobuf:"bytes,5,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportRun_StatusChange) Reset() {
+	*x = ReportRun_StatusChange{}
+	mi := &file_proto_nhd_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportRun_StatusChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportRun_StatusChange) ProtoMessage() {}
+
+func (x *ReportRun_StatusChange) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_nhd_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportRun_StatusChange.ProtoReflect.Descriptor instead.
+func (*ReportRun_StatusChange) Descriptor() ([]byte, []int) {
+	return file_proto_nhd_proto_rawDescGZIP(), []int{6, 6}
+}
+
+func (x *ReportRun_StatusChange) GetFromStatus() ReportRun_Status {
+	if x != nil {
+		return x.FromStatus
+	}
+	return ReportRun_STATUS_UNSPECIFIED
+}
+
+func (x *ReportRun_StatusChange) GetToStatus() ReportRun_Status {
+	if x != nil {
+		return x.ToStatus
+	}
+	return ReportRun_STATUS_UNSPECIFIED
+}
+
+func (x *ReportRun_StatusChange) GetChangedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ChangedAt
+	}
+	return nil
+}
+
+func (x *ReportRun_StatusChange) GetActor() string {
+	if x != nil {
+		return x.Actor
+	}
+	return ""
+}
+
+func (x *ReportRun_StatusChange) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type ReportRun_HazardEvidence_MatchedFeature struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FeatureId     string                 `protobuf:"bytes,1,opt,name=feature_id,json=featureId,proto3" json:"feature_id,omitempty"`
+	Attributes    map[string]string      `protobuf:"bytes,2,rep,name=attributes,proto3" json:"attributes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // e.g., {"HAZ_CLASS": "Very High"}
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportRun_HazardEvidence_MatchedFeature) Reset() {
+	*x = ReportRun_HazardEvidence_MatchedFeature{}
+	mi := &file_proto_nhd_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportRun_HazardEvidence_MatchedFeature) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportRun_HazardEvidence_MatchedFeature) ProtoMessage() {}
+
+func (x *ReportRun_HazardEvidence_MatchedFeature) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_nhd_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportRun_HazardEvidence_MatchedFeature.ProtoReflect.Descriptor instead.
+func (*ReportRun_HazardEvidence_MatchedFeature) Descriptor() ([]byte, []int) {
+	return file_proto_nhd_proto_rawDescGZIP(), []int{6, 1, 0}
+}
+
+func (x *ReportRun_HazardEvidence_MatchedFeature) GetFeatureId() string {
+	if x != nil {
+		return x.FeatureId
+	}
+	return ""
+}
+
+func (x *ReportRun_HazardEvidence_MatchedFeature) GetAttributes() map[string]string {
+	if x != nil {
+		return x.Attributes
+	}
+	return nil
+}
+
 var File_proto_nhd_proto protoreflect.FileDescriptor
 
 const file_proto_nhd_proto_rawDesc = "" +
 	"\n" +
-	"\x0fproto/nhd.proto\x12\tnhdreport\x1a\x1fgoogle/protobuf/timestamp.proto\"\x8c\x01\n" +
+	"\x0fproto/nhd.proto\x12\tnhdreport\x1a\x1fgoogle/protobuf/timestamp.proto\"D\n" +
+	"\x05Money\x12\x1a\n" +
+	"\bcurrency\x18\x01 \x01(\tR\bcurrency\x12\x1f\n" +
+	"\vminor_units\x18\x02 \x01(\x03R\n" +
+	"minorUnits\"\xb3\x01\n" +
+	"\fOrganization\x12'\n" +
+	"\x0forganization_id\x18\x01 \x01(\tR\x0eorganizationId\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x129\n" +
+	"\n" +
+	"created_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x12+\n" +
+	"\x12created_by_user_id\x18\x04 \x01(\tR\x0fcreatedByUserId\"\x8c\x01\n" +
 	"\vPermissions\x120\n" +
 	"\x14can_create_customers\x18\x01 \x01(\bR\x12canCreateCustomers\x120\n" +
 	"\x14can_generate_reports\x18\x02 \x01(\bR\x12canGenerateReports\x12\x19\n" +
-	"\bis_admin\x18\x03 \x01(\bR\aisAdmin\"\xc7\x01\n" +
+	"\bis_admin\x18\x03 \x01(\bR\aisAdmin\"\xf0\x01\n" +
 	"\x04User\x12\x17\n" +
 	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x1b\n" +
 	"\tfull_name\x18\x02 \x01(\tR\bfullName\x12\x14\n" +
 	"\x05email\x18\x03 \x01(\tR\x05email\x128\n" +
 	"\vpermissions\x18\x04 \x01(\v2\x16.nhdreport.PermissionsR\vpermissions\x129\n" +
 	"\n" +
-	"created_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\"\xe9\x01\n" +
+	"created_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x12'\n" +
+	"\x0forganization_id\x18\x06 \x01(\tR\x0eorganizationId\"\xcf\x03\n" +
 	"\bCustomer\x12\x1f\n" +
 	"\vcustomer_id\x18\x01 \x01(\tR\n" +
 	"customerId\x12\x1b\n" +
@@ -1078,13 +2070,23 @@ const file_proto_nhd_proto_rawDesc = "" +
 	"\fcompany_name\x18\x04 \x01(\tR\vcompanyName\x129\n" +
 	"\n" +
 	"created_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x12+\n" +
-	"\x12created_by_user_id\x18\x06 \x01(\tR\x0fcreatedByUserId\"\xb4\x04\n" +
+	"\x12created_by_user_id\x18\x06 \x01(\tR\x0fcreatedByUserId\x12'\n" +
+	"\x0forganization_id\x18\a \x01(\tR\x0eorganizationId\x129\n" +
+	"\n" +
+	"updated_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x12\x1a\n" +
+	"\barchived\x18\t \x01(\bR\barchived\x12;\n" +
+	"\varchived_at\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"archivedAt\x12'\n" +
+	"\x0fsearch_prefixes\x18\v \x03(\tR\x0esearchPrefixes\"\x84\x05\n" +
 	"\x0fPropertyAddress\x12.\n" +
 	"\x13property_address_id\x18\x01 \x01(\tR\x11propertyAddressId\x12R\n" +
 	"\x0faddress_details\x18\x02 \x01(\v2).nhdreport.PropertyAddress.AddressDetailsR\x0eaddressDetails\x12H\n" +
 	"\vcoordinates\x18\x03 \x01(\v2&.nhdreport.PropertyAddress.CoordinatesR\vcoordinates\x12\x1b\n" +
 	"\tplus_code\x18\x04 \x01(\tR\bplusCode\x12&\n" +
-	"\x0fgoogle_place_id\x18\x05 \x01(\tR\rgooglePlaceId\x1a\xc4\x01\n" +
+	"\x0fgoogle_place_id\x18\x05 \x01(\tR\rgooglePlaceId\x12%\n" +
+	"\x0enormalized_key\x18\x06 \x01(\tR\rnormalizedKey\x12'\n" +
+	"\x0forganization_id\x18\a \x01(\tR\x0eorganizationId\x1a\xc4\x01\n" +
 	"\x0eAddressDetails\x12%\n" +
 	"\x0estreet_address\x18\x01 \x01(\tR\rstreetAddress\x12(\n" +
 	"\x10street_address_2\x18\x02 \x01(\tR\x0estreetAddress2\x12\x12\n" +
@@ -1095,7 +2097,7 @@ const file_proto_nhd_proto_rawDesc = "" +
 	"zip_plus_4\x18\x06 \x01(\tR\bzipPlus4\x1aG\n" +
 	"\vCoordinates\x12\x1a\n" +
 	"\blatitude\x18\x01 \x01(\x01R\blatitude\x12\x1c\n" +
-	"\tlongitude\x18\x02 \x01(\x01R\tlongitude\"\xb6\x0f\n" +
+	"\tlongitude\x18\x02 \x01(\x01R\tlongitude\"\xf7\x1f\n" +
 	"\tReportRun\x12\"\n" +
 	"\rreport_run_id\x18\x01 \x01(\tR\vreportRunId\x12\x1f\n" +
 	"\vcustomer_id\x18\x02 \x01(\tR\n" +
@@ -1112,42 +2114,107 @@ const file_proto_nhd_proto_rawDesc = "" +
 	" \x03(\v2\".nhdreport.ReportRun.EmailDeliveryR\x0femailDeliveries\x126\n" +
 	"\x17disable_automatic_email\x18\v \x01(\bR\x15disableAutomaticEmail\x12B\n" +
 	"\fcost_history\x18\f \x03(\v2\x1f.nhdreport.ReportRun.ReportCostR\vcostHistory\x12E\n" +
-	"\x0fpayment_details\x18\r \x01(\v2\x1c.nhdreport.ReportRun.PaymentR\x0epaymentDetails\x1a\xf7\x02\n" +
+	"\x0fpayment_details\x18\r \x01(\v2\x1c.nhdreport.ReportRun.PaymentR\x0epaymentDetails\x128\n" +
+	"\x06ledger\x18\x13 \x03(\v2 .nhdreport.ReportRun.LedgerEntryR\x06ledger\x129\n" +
+	"\n" +
+	"updated_at\x18\x0e \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x12%\n" +
+	"\x0efailure_reason\x18\x0f \x01(\tR\rfailureReason\x12'\n" +
+	"\x0forganization_id\x18\x10 \x01(\tR\x0eorganizationId\x12H\n" +
+	"\x0estatus_history\x18\x11 \x03(\v2!.nhdreport.ReportRun.StatusChangeR\rstatusHistory\x12\x18\n" +
+	"\aattempt\x18\x12 \x01(\x05R\aattempt\x1a\xb8\x03\n" +
 	"\rHazardResults\x12>\n" +
 	"\x1cin_special_flood_hazard_area\x18\x01 \x01(\bR\x18inSpecialFloodHazardArea\x123\n" +
 	"\x16in_dam_inundation_area\x18\x02 \x01(\bR\x13inDamInundationArea\x12P\n" +
 	"&in_very_high_fire_hazard_severity_zone\x18\x03 \x01(\bR inVeryHighFireHazardSeverityZone\x121\n" +
 	"\x15in_wildland_fire_area\x18\x04 \x01(\bR\x12inWildlandFireArea\x127\n" +
 	"\x18in_earthquake_fault_zone\x18\x05 \x01(\bR\x15inEarthquakeFaultZone\x123\n" +
-	"\x16in_seismic_hazard_zone\x18\x06 \x01(\bR\x13inSeismicHazardZone\x1a\x89\x02\n" +
+	"\x16in_seismic_hazard_zone\x18\x06 \x01(\bR\x13inSeismicHazardZone\x12?\n" +
+	"\bevidence\x18\a \x03(\v2#.nhdreport.ReportRun.HazardEvidenceR\bevidence\x1a\xfe\x04\n" +
+	"\x0eHazardEvidence\x12\x16\n" +
+	"\x06hazard\x18\x01 \x01(\tR\x06hazard\x12\x17\n" +
+	"\ain_zone\x18\x02 \x01(\bR\x06inZone\x12\x18\n" +
+	"\adataset\x18\x03 \x01(\tR\adataset\x12\x16\n" +
+	"\x06agency\x18\x04 \x01(\tR\x06agency\x12#\n" +
+	"\rlayer_version\x18\x05 \x01(\tR\flayerVersion\x12L\n" +
+	"\x14layer_effective_date\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\x12layerEffectiveDate\x12]\n" +
+	"\x10matched_features\x18\a \x03(\v22.nhdreport.ReportRun.HazardEvidence.MatchedFeatureR\x0fmatchedFeatures\x12B\n" +
+	"\x1bdistance_to_boundary_meters\x18\b \x01(\x01H\x00R\x18distanceToBoundaryMeters\x88\x01\x01\x1a\xd2\x01\n" +
+	"\x0eMatchedFeature\x12\x1d\n" +
+	"\n" +
+	"feature_id\x18\x01 \x01(\tR\tfeatureId\x12b\n" +
+	"\n" +
+	"attributes\x18\x02 \x03(\v2B.nhdreport.ReportRun.HazardEvidence.MatchedFeature.AttributesEntryR\n" +
+	"attributes\x1a=\n" +
+	"\x0fAttributesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01B\x1e\n" +
+	"\x1c_distance_to_boundary_meters\x1a\xdb\x02\n" +
 	"\rEmailDelivery\x12I\n" +
 	"\x06status\x18\x01 \x01(\x0e21.nhdreport.ReportRun.EmailDelivery.DeliveryStatusR\x06status\x123\n" +
 	"\asent_at\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x06sentAt\x128\n" +
-	"\x18email_template_reference\x18\x03 \x01(\tR\x16emailTemplateReference\">\n" +
+	"\x18email_template_reference\x18\x03 \x01(\tR\x16emailTemplateReference\x12\x1c\n" +
+	"\trecipient\x18\x04 \x01(\tR\trecipient\x12%\n" +
+	"\x0efailure_reason\x18\x05 \x01(\tR\rfailureReason\"K\n" +
 	"\x0eDeliveryStatus\x12\x16\n" +
 	"\x12STATUS_UNSPECIFIED\x10\x00\x12\b\n" +
 	"\x04SENT\x10\x01\x12\n" +
 	"\n" +
-	"\x06FAILED\x10\x02\x1a\x98\x01\n" +
+	"\x06FAILED\x10\x02\x12\v\n" +
+	"\aUNKNOWN\x10\x03\x1a\xe0\x01\n" +
 	"\n" +
-	"ReportCost\x12\x16\n" +
-	"\x06amount\x18\x01 \x01(\x01R\x06amount\x12\x1a\n" +
-	"\bcurrency\x18\x02 \x01(\tR\bcurrency\x121\n" +
+	"ReportCost\x12(\n" +
+	"\x06amount\x18\a \x01(\v2\x10.nhdreport.MoneyR\x06amount\x121\n" +
 	"\x06set_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x05setAt\x12#\n" +
-	"\x0eset_by_user_id\x18\x04 \x01(\tR\vsetByUserId\x1a\xe7\x02\n" +
+	"\x0eset_by_user_id\x18\x04 \x01(\tR\vsetByUserId\x12\"\n" +
+	"\rprice_rule_id\x18\x05 \x01(\tR\vpriceRuleId\x12\x16\n" +
+	"\x06reason\x18\x06 \x01(\tR\x06reasonJ\x04\b\x01\x10\x02J\x04\b\x02\x10\x03R\bcurrency\x1a\xdb\x04\n" +
 	"\aPayment\x12B\n" +
-	"\x06status\x18\x01 \x01(\x0e2*.nhdreport.ReportRun.Payment.PaymentStatusR\x06status\x12\x1f\n" +
-	"\vamount_paid\x18\x02 \x01(\x01R\n" +
-	"amountPaid\x12\x1a\n" +
-	"\bcurrency\x18\x03 \x01(\tR\bcurrency\x123\n" +
+	"\x06status\x18\x01 \x01(\x0e2*.nhdreport.ReportRun.Payment.PaymentStatusR\x06status\x121\n" +
+	"\vamount_paid\x18\n" +
+	" \x01(\v2\x10.nhdreport.MoneyR\n" +
+	"amountPaid\x123\n" +
 	"\apaid_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x06paidAt\x12%\n" +
 	"\x0epayment_method\x18\x05 \x01(\tR\rpaymentMethod\x12%\n" +
-	"\x0etransaction_id\x18\x06 \x01(\tR\rtransactionId\"X\n" +
+	"\x0etransaction_id\x18\x06 \x01(\tR\rtransactionId\x129\n" +
+	"\x0famount_refunded\x18\v \x01(\v2\x10.nhdreport.MoneyR\x0eamountRefunded\x121\n" +
+	"\vbalance_due\x18\f \x01(\v2\x10.nhdreport.MoneyR\n" +
+	"balanceDue\x12J\n" +
+	"\x13last_transaction_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\x11lastTransactionAt\"z\n" +
 	"\rPaymentStatus\x12\x1e\n" +
 	"\x1aPAYMENT_STATUS_UNSPECIFIED\x10\x00\x12\x0f\n" +
 	"\vOUTSTANDING\x10\x01\x12\b\n" +
 	"\x04PAID\x10\x02\x12\f\n" +
-	"\bREFUNDED\x10\x03\"X\n" +
+	"\bREFUNDED\x10\x03\x12\b\n" +
+	"\x04VOID\x10\x04\x12\x16\n" +
+	"\x12PARTIALLY_REFUNDED\x10\x05J\x04\b\x02\x10\x03J\x04\b\x03\x10\x04J\x04\b\a\x10\bJ\x04\b\b\x10\tR\bcurrency\x1a\xc4\x03\n" +
+	"\vLedgerEntry\x12\x19\n" +
+	"\bentry_id\x18\x01 \x01(\tR\aentryId\x129\n" +
+	"\x04type\x18\x02 \x01(\x0e2%.nhdreport.ReportRun.LedgerEntry.TypeR\x04type\x12(\n" +
+	"\x06amount\x18\n" +
+	" \x01(\v2\x10.nhdreport.MoneyR\x06amount\x129\n" +
+	"\n" +
+	"created_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x12+\n" +
+	"\x12created_by_user_id\x18\x06 \x01(\tR\x0fcreatedByUserId\x12%\n" +
+	"\x0epayment_method\x18\a \x01(\tR\rpaymentMethod\x12%\n" +
+	"\x0etransaction_id\x18\b \x01(\tR\rtransactionId\x12\x16\n" +
+	"\x06reason\x18\t \x01(\tR\x06reason\"Q\n" +
+	"\x04Type\x12\x14\n" +
+	"\x10TYPE_UNSPECIFIED\x10\x00\x12\n" +
+	"\n" +
+	"\x06CHARGE\x10\x01\x12\v\n" +
+	"\aPAYMENT\x10\x02\x12\n" +
+	"\n" +
+	"\x06REFUND\x10\x03\x12\x0e\n" +
+	"\n" +
+	"ADJUSTMENT\x10\x04J\x04\b\x03\x10\x04J\x04\b\x04\x10\x05R\bcurrency\x1a\xef\x01\n" +
+	"\fStatusChange\x12<\n" +
+	"\vfrom_status\x18\x01 \x01(\x0e2\x1b.nhdreport.ReportRun.StatusR\n" +
+	"fromStatus\x128\n" +
+	"\tto_status\x18\x02 \x01(\x0e2\x1b.nhdreport.ReportRun.StatusR\btoStatus\x129\n" +
+	"\n" +
+	"changed_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\tchangedAt\x12\x14\n" +
+	"\x05actor\x18\x04 \x01(\tR\x05actor\x12\x16\n" +
+	"\x06reason\x18\x05 \x01(\tR\x06reason\"g\n" +
 	"\x06Status\x12\x16\n" +
 	"\x12STATUS_UNSPECIFIED\x10\x00\x12\v\n" +
 	"\aPENDING\x10\x01\x12\x0e\n" +
@@ -1155,7 +2222,32 @@ const file_proto_nhd_proto_rawDesc = "" +
 	"PROCESSING\x10\x02\x12\r\n" +
 	"\tCOMPLETED\x10\x03\x12\n" +
 	"\n" +
-	"\x06FAILED\x10\x04B7Z5github.com/seans3/nhd/backend/proto/gen/go;nhd_reportb\x06proto3"
+	"\x06FAILED\x10\x04\x12\r\n" +
+	"\tCANCELLED\x10\x05\"\xb5\x03\n" +
+	"\tPriceRule\x12\"\n" +
+	"\rprice_rule_id\x18\x01 \x01(\tR\vpriceRuleId\x120\n" +
+	"\x05scope\x18\x02 \x01(\x0e2\x1a.nhdreport.PriceRule.ScopeR\x05scope\x12\x1f\n" +
+	"\vscope_value\x18\x03 \x01(\tR\n" +
+	"scopeValue\x12(\n" +
+	"\x06amount\x18\t \x01(\v2\x10.nhdreport.MoneyR\x06amount\x12A\n" +
+	"\x0eeffective_from\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\reffectiveFrom\x129\n" +
+	"\n" +
+	"created_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x12+\n" +
+	"\x12created_by_user_id\x18\b \x01(\tR\x0fcreatedByUserId\"F\n" +
+	"\x05Scope\x12\x15\n" +
+	"\x11SCOPE_UNSPECIFIED\x10\x00\x12\v\n" +
+	"\aDEFAULT\x10\x01\x12\v\n" +
+	"\aCOMPANY\x10\x02\x12\f\n" +
+	"\bCUSTOMER\x10\x03J\x04\b\x04\x10\x05J\x04\b\x05\x10\x06R\bcurrency\"\xc3\x02\n" +
+	"\fExchangeRate\x12(\n" +
+	"\x10exchange_rate_id\x18\x01 \x01(\tR\x0eexchangeRateId\x12#\n" +
+	"\rbase_currency\x18\x02 \x01(\tR\fbaseCurrency\x12%\n" +
+	"\x0equote_currency\x18\x03 \x01(\tR\rquoteCurrency\x12\x12\n" +
+	"\x04rate\x18\x04 \x01(\tR\x04rate\x12A\n" +
+	"\x0eeffective_from\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\reffectiveFrom\x129\n" +
+	"\n" +
+	"created_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x12+\n" +
+	"\x12created_by_user_id\x18\a \x01(\tR\x0fcreatedByUserIdB7Z5github.com/seans3/nhd/backend/proto/gen/go;nhd_reportb\x06proto3"
 
 var (
 	file_proto_nhd_proto_rawDescOnce sync.Once
@@ -1169,47 +2261,85 @@ func file_proto_nhd_proto_rawDescGZIP() []byte {
 	return file_proto_nhd_proto_rawDescData
 }
 
-var file_proto_nhd_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
-var file_proto_nhd_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_proto_nhd_proto_enumTypes = make([]protoimpl.EnumInfo, 5)
+var file_proto_nhd_proto_msgTypes = make([]protoimpl.MessageInfo, 20)
 var file_proto_nhd_proto_goTypes = []any{
-	(ReportRun_Status)(0),                       // 0: nhdreport.ReportRun.Status
-	(ReportRun_EmailDelivery_DeliveryStatus)(0), // 1: nhdreport.ReportRun.EmailDelivery.DeliveryStatus
-	(ReportRun_Payment_PaymentStatus)(0),        // 2: nhdreport.ReportRun.Payment.PaymentStatus
-	(*Permissions)(nil),                         // 3: nhdreport.Permissions
-	(*User)(nil),                                // 4: nhdreport.User
-	(*Customer)(nil),                            // 5: nhdreport.Customer
-	(*PropertyAddress)(nil),                     // 6: nhdreport.PropertyAddress
-	(*ReportRun)(nil),                           // 7: nhdreport.ReportRun
-	(*PropertyAddress_AddressDetails)(nil),      // 8: nhdreport.PropertyAddress.AddressDetails
-	(*PropertyAddress_Coordinates)(nil),         // 9: nhdreport.PropertyAddress.Coordinates
-	(*ReportRun_HazardResults)(nil),             // 10: nhdreport.ReportRun.HazardResults
-	(*ReportRun_EmailDelivery)(nil),             // 11: nhdreport.ReportRun.EmailDelivery
-	(*ReportRun_ReportCost)(nil),                // 12: nhdreport.ReportRun.ReportCost
-	(*ReportRun_Payment)(nil),                   // 13: nhdreport.ReportRun.Payment
-	(*timestamppb.Timestamp)(nil),               // 14: google.protobuf.Timestamp
+	(ReportRun_Status)(0),                           // 0: nhdreport.ReportRun.Status
+	(ReportRun_EmailDelivery_DeliveryStatus)(0),     // 1: nhdreport.ReportRun.EmailDelivery.DeliveryStatus
+	(ReportRun_Payment_PaymentStatus)(0),            // 2: nhdreport.ReportRun.Payment.PaymentStatus
+	(ReportRun_LedgerEntry_Type)(0),                 // 3: nhdreport.ReportRun.LedgerEntry.Type
+	(PriceRule_Scope)(0),                            // 4: nhdreport.PriceRule.Scope
+	(*Money)(nil),                                   // 5: nhdreport.Money
+	(*Organization)(nil),                            // 6: nhdreport.Organization
+	(*Permissions)(nil),                             // 7: nhdreport.Permissions
+	(*User)(nil),                                    // 8: nhdreport.User
+	(*Customer)(nil),                                // 9: nhdreport.Customer
+	(*PropertyAddress)(nil),                         // 10: nhdreport.PropertyAddress
+	(*ReportRun)(nil),                               // 11: nhdreport.ReportRun
+	(*PriceRule)(nil),                               // 12: nhdreport.PriceRule
+	(*ExchangeRate)(nil),                            // 13: nhdreport.ExchangeRate
+	(*PropertyAddress_AddressDetails)(nil),          // 14: nhdreport.PropertyAddress.AddressDetails
+	(*PropertyAddress_Coordinates)(nil),             // 15: nhdreport.PropertyAddress.Coordinates
+	(*ReportRun_HazardResults)(nil),                 // 16: nhdreport.ReportRun.HazardResults
+	(*ReportRun_HazardEvidence)(nil),                // 17: nhdreport.ReportRun.HazardEvidence
+	(*ReportRun_EmailDelivery)(nil),                 // 18: nhdreport.ReportRun.EmailDelivery
+	(*ReportRun_ReportCost)(nil),                    // 19: nhdreport.ReportRun.ReportCost
+	(*ReportRun_Payment)(nil),                       // 20: nhdreport.ReportRun.Payment
+	(*ReportRun_LedgerEntry)(nil),                   // 21: nhdreport.ReportRun.LedgerEntry
+	(*ReportRun_StatusChange)(nil),                  // 22: nhdreport.ReportRun.StatusChange
+	(*ReportRun_HazardEvidence_MatchedFeature)(nil), // 23: nhdreport.ReportRun.HazardEvidence.MatchedFeature
+	nil,                           // 24: nhdreport.ReportRun.HazardEvidence.MatchedFeature.AttributesEntry
+	(*timestamppb.Timestamp)(nil), // 25: google.protobuf.Timestamp
 }
 var file_proto_nhd_proto_depIdxs = []int32{
-	3,  // 0: nhdreport.User.permissions:type_name -> nhdreport.Permissions
-	14, // 1: nhdreport.User.created_at:type_name -> google.protobuf.Timestamp
-	14, // 2: nhdreport.Customer.created_at:type_name -> google.protobuf.Timestamp
-	8,  // 3: nhdreport.PropertyAddress.address_details:type_name -> nhdreport.PropertyAddress.AddressDetails
-	9,  // 4: nhdreport.PropertyAddress.coordinates:type_name -> nhdreport.PropertyAddress.Coordinates
-	14, // 5: nhdreport.ReportRun.created_at:type_name -> google.protobuf.Timestamp
-	0,  // 6: nhdreport.ReportRun.status:type_name -> nhdreport.ReportRun.Status
-	10, // 7: nhdreport.ReportRun.results:type_name -> nhdreport.ReportRun.HazardResults
-	11, // 8: nhdreport.ReportRun.email_deliveries:type_name -> nhdreport.ReportRun.EmailDelivery
-	12, // 9: nhdreport.ReportRun.cost_history:type_name -> nhdreport.ReportRun.ReportCost
-	13, // 10: nhdreport.ReportRun.payment_details:type_name -> nhdreport.ReportRun.Payment
-	1,  // 11: nhdreport.ReportRun.EmailDelivery.status:type_name -> nhdreport.ReportRun.EmailDelivery.DeliveryStatus
-	14, // 12: nhdreport.ReportRun.EmailDelivery.sent_at:type_name -> google.protobuf.Timestamp
-	14, // 13: nhdreport.ReportRun.ReportCost.set_at:type_name -> google.protobuf.Timestamp
-	2,  // 14: nhdreport.ReportRun.Payment.status:type_name -> nhdreport.ReportRun.Payment.PaymentStatus
-	14, // 15: nhdreport.ReportRun.Payment.paid_at:type_name -> google.protobuf.Timestamp
-	16, // [16:16] is the sub-list for method output_type
-	16, // [16:16] is the sub-list for method input_type
-	16, // [16:16] is the sub-list for extension type_name
-	16, // [16:16] is the sub-list for extension extendee
-	0,  // [0:16] is the sub-list for field type_name
+	25, // 0: nhdreport.Organization.created_at:type_name -> google.protobuf.Timestamp
+	7,  // 1: nhdreport.User.permissions:type_name -> nhdreport.Permissions
+	25, // 2: nhdreport.User.created_at:type_name -> google.protobuf.Timestamp
+	25, // 3: nhdreport.Customer.created_at:type_name -> google.protobuf.Timestamp
+	25, // 4: nhdreport.Customer.updated_at:type_name -> google.protobuf.Timestamp
+	25, // 5: nhdreport.Customer.archived_at:type_name -> google.protobuf.Timestamp
+	14, // 6: nhdreport.PropertyAddress.address_details:type_name -> nhdreport.PropertyAddress.AddressDetails
+	15, // 7: nhdreport.PropertyAddress.coordinates:type_name -> nhdreport.PropertyAddress.Coordinates
+	25, // 8: nhdreport.ReportRun.created_at:type_name -> google.protobuf.Timestamp
+	0,  // 9: nhdreport.ReportRun.status:type_name -> nhdreport.ReportRun.Status
+	16, // 10: nhdreport.ReportRun.results:type_name -> nhdreport.ReportRun.HazardResults
+	18, // 11: nhdreport.ReportRun.email_deliveries:type_name -> nhdreport.ReportRun.EmailDelivery
+	19, // 12: nhdreport.ReportRun.cost_history:type_name -> nhdreport.ReportRun.ReportCost
+	20, // 13: nhdreport.ReportRun.payment_details:type_name -> nhdreport.ReportRun.Payment
+	21, // 14: nhdreport.ReportRun.ledger:type_name -> nhdreport.ReportRun.LedgerEntry
+	25, // 15: nhdreport.ReportRun.updated_at:type_name -> google.protobuf.Timestamp
+	22, // 16: nhdreport.ReportRun.status_history:type_name -> nhdreport.ReportRun.StatusChange
+	4,  // 17: nhdreport.PriceRule.scope:type_name -> nhdreport.PriceRule.Scope
+	5,  // 18: nhdreport.PriceRule.amount:type_name -> nhdreport.Money
+	25, // 19: nhdreport.PriceRule.effective_from:type_name -> google.protobuf.Timestamp
+	25, // 20: nhdreport.PriceRule.created_at:type_name -> google.protobuf.Timestamp
+	25, // 21: nhdreport.ExchangeRate.effective_from:type_name -> google.protobuf.Timestamp
+	25, // 22: nhdreport.ExchangeRate.created_at:type_name -> google.protobuf.Timestamp
+	17, // 23: nhdreport.ReportRun.HazardResults.evidence:type_name -> nhdreport.ReportRun.HazardEvidence
+	25, // 24: nhdreport.ReportRun.HazardEvidence.layer_effective_date:type_name -> google.protobuf.Timestamp
+	23, // 25: nhdreport.ReportRun.HazardEvidence.matched_features:type_name -> nhdreport.ReportRun.HazardEvidence.MatchedFeature
+	1,  // 26: nhdreport.ReportRun.EmailDelivery.status:type_name -> nhdreport.ReportRun.EmailDelivery.DeliveryStatus
+	25, // 27: nhdreport.ReportRun.EmailDelivery.sent_at:type_name -> google.protobuf.Timestamp
+	5,  // 28: nhdreport.ReportRun.ReportCost.amount:type_name -> nhdreport.Money
+	25, // 29: nhdreport.ReportRun.ReportCost.set_at:type_name -> google.protobuf.Timestamp
+	2,  // 30: nhdreport.ReportRun.Payment.status:type_name -> nhdreport.ReportRun.Payment.PaymentStatus
+	5,  // 31: nhdreport.ReportRun.Payment.amount_paid:type_name -> nhdreport.Money
+	25, // 32: nhdreport.ReportRun.Payment.paid_at:type_name -> google.protobuf.Timestamp
+	5,  // 33: nhdreport.ReportRun.Payment.amount_refunded:type_name -> nhdreport.Money
+	5,  // 34: nhdreport.ReportRun.Payment.balance_due:type_name -> nhdreport.Money
+	25, // 35: nhdreport.ReportRun.Payment.last_transaction_at:type_name -> google.protobuf.Timestamp
+	3,  // 36: nhdreport.ReportRun.LedgerEntry.type:type_name -> nhdreport.ReportRun.LedgerEntry.Type
+	5,  // 37: nhdreport.ReportRun.LedgerEntry.amount:type_name -> nhdreport.Money
+	25, // 38: nhdreport.ReportRun.LedgerEntry.created_at:type_name -> google.protobuf.Timestamp
+	0,  // 39: nhdreport.ReportRun.StatusChange.from_status:type_name -> nhdreport.ReportRun.Status
+	0,  // 40: nhdreport.ReportRun.StatusChange.to_status:type_name -> nhdreport.ReportRun.Status
+	25, // 41: nhdreport.ReportRun.StatusChange.changed_at:type_name -> google.protobuf.Timestamp
+	24, // 42: nhdreport.ReportRun.HazardEvidence.MatchedFeature.attributes:type_name -> nhdreport.ReportRun.HazardEvidence.MatchedFeature.AttributesEntry
+	43, // [43:43] is the sub-list for method output_type
+	43, // [43:43] is the sub-list for method input_type
+	43, // [43:43] is the sub-list for extension type_name
+	43, // [43:43] is the sub-list for extension extendee
+	0,  // [0:43] is the sub-list for field type_name
 }
 
 func init() { file_proto_nhd_proto_init() }
@@ -1217,13 +2347,14 @@ func file_proto_nhd_proto_init() {
 	if File_proto_nhd_proto != nil {
 		return
 	}
+	file_proto_nhd_proto_msgTypes[12].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_nhd_proto_rawDesc), len(file_proto_nhd_proto_rawDesc)),
-			NumEnums:      3,
-			NumMessages:   11,
+			NumEnums:      5,
+			NumMessages:   20,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
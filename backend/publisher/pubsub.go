@@ -2,14 +2,17 @@ package publisher
 
 import (
 	"context"
-	"log"
+	"log/slog"
 
 	"cloud.google.com/go/pubsub"
 	"github.com/seans3/nhd/backend/interfaces"
 )
 
-// Statically assert that our client satisfies the interface.
-var _ interfaces.Publisher = (*Client)(nil)
+// Statically assert that our client satisfies the interfaces.
+var (
+	_ interfaces.Publisher  = (*Client)(nil)
+	_ interfaces.Subscriber = (*Client)(nil)
+)
 
 type Client struct {
 	*pubsub.Client
@@ -20,14 +23,33 @@ func NewClient(ctx context.Context, projectID string) (*Client, error) {
 	if err != nil {
 		return nil, err
 	}
-	log.Println("Pub/Sub client initialized")
+	slog.Info("Pub/Sub client initialized")
 	return &Client{psClient}, nil
 }
 
-func (c *Client) Publish(ctx context.Context, topicID string, data []byte) (string, error) {
+func (c *Client) Publish(ctx context.Context, topicID string, data []byte, attributes map[string]string) (string, error) {
 	topic := c.Topic(topicID)
 	result := topic.Publish(ctx, &pubsub.Message{
-		Data: data,
+		Data:       data,
+		Attributes: attributes,
 	})
 	return result.Get(ctx)
 }
+
+func (c *Client) Receive(ctx context.Context, subscriptionID string, maxConcurrent int, handler interfaces.MessageHandler) error {
+	sub := c.Subscription(subscriptionID)
+	sub.ReceiveSettings.MaxOutstandingMessages = maxConcurrent
+	return sub.Receive(ctx, func(ctx context.Context, m *pubsub.Message) {
+		msg := &interfaces.Message{
+			ID:         m.ID,
+			Data:       m.Data,
+			Attributes: m.Attributes,
+			Ack:        m.Ack,
+			Nack:       m.Nack,
+		}
+		if m.DeliveryAttempt != nil {
+			msg.DeliveryAttempt = *m.DeliveryAttempt
+		}
+		handler(ctx, msg)
+	})
+}
@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Statically assert that our store satisfies the interface.
+var _ Store = (*MemoryStore)(nil)
+
+// DefaultSweepInterval is how often a MemoryStore evicts idle buckets when
+// SweepInterval is not set.
+const DefaultSweepInterval = time.Minute
+
+// MemoryStore keeps buckets in process memory. Buckets are only shared by
+// requests served by the same process.
+//
+// A bucket that has refilled completely is indistinguishable from one that
+// was never used, so once a key has been idle that long its entry is evicted.
+type MemoryStore struct {
+	// SweepInterval is how often Take looks for idle buckets to evict.
+	// Defaults to DefaultSweepInterval.
+	SweepInterval time.Duration
+
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+type memoryBucket struct {
+	Bucket
+	// fullAt is when the bucket will have refilled completely.
+	fullAt time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*memoryBucket{}}
+}
+
+// Take implements Store.
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &memoryBucket{}
+		s.buckets[key] = b
+	}
+	var r Result
+	b.Bucket, r = limit.Take(b.Bucket, now)
+	b.fullAt = now.Add(r.ResetAfter)
+	return r, nil
+}
+
+// sweep evicts full buckets if SweepInterval has passed since the last
+// sweep. The caller must hold s.mu.
+func (s *MemoryStore) sweep(now time.Time) {
+	interval := s.SweepInterval
+	if interval <= 0 {
+		interval = DefaultSweepInterval
+	}
+	if now.Sub(s.lastSweep) < interval {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if !now.Before(b.fullAt) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// Len returns the number of buckets held.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.buckets)
+}
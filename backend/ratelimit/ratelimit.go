@@ -0,0 +1,79 @@
+// Package ratelimit implements token bucket rate limiting over a pluggable
+// Store, so that the buckets can be kept in process memory or shared between
+// server instances.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// A Limit is a token bucket budget: Burst requests at once, refilled at Rate
+// requests per second.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Window returns how long an empty bucket takes to refill completely.
+func (l Limit) Window() time.Duration {
+	if l.Rate <= 0 {
+		return 0
+	}
+	return seconds(float64(l.Burst) / l.Rate)
+}
+
+// Bucket is the state of one key's token bucket. The zero Bucket is full.
+type Bucket struct {
+	Tokens  float64
+	Updated time.Time
+}
+
+// Result is the outcome of taking a token.
+type Result struct {
+	Allowed bool
+	// Remaining is the number of whole tokens left after this request.
+	Remaining int
+	// RetryAfter is how long until the next token is available. It is zero
+	// if a token is available now.
+	RetryAfter time.Duration
+	// ResetAfter is how long until the bucket is full again.
+	ResetAfter time.Duration
+}
+
+// Take refills b for the time elapsed since it was last updated and then
+// removes one token if there is one. It returns the new bucket state, which
+// the caller must store, and the result.
+func (l Limit) Take(b Bucket, now time.Time) (Bucket, Result) {
+	burst := float64(l.Burst)
+	tokens := burst
+	if !b.Updated.IsZero() {
+		tokens = math.Min(burst, b.Tokens+now.Sub(b.Updated).Seconds()*l.Rate)
+	}
+
+	var r Result
+	if tokens >= 1 {
+		tokens--
+		r.Allowed = true
+	} else if l.Rate > 0 {
+		r.RetryAfter = seconds((1 - tokens) / l.Rate)
+	}
+	r.Remaining = int(tokens)
+	if l.Rate > 0 {
+		r.ResetAfter = seconds((burst - tokens) / l.Rate)
+	}
+	return Bucket{Tokens: tokens, Updated: now}, r
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
+
+// A Store keeps a token bucket per key. Implementations must be safe for
+// concurrent use, and Take must read, update and write a key's bucket
+// atomically.
+type Store interface {
+	// Take takes a token from the bucket for key, as Limit.Take does.
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimit_Take(t *testing.T) {
+	limit := Limit{Rate: 2, Burst: 3}
+	assert.Equal(t, 1500*time.Millisecond, limit.Window())
+	start := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	var b Bucket
+	var r Result
+	for i := 2; i >= 0; i-- {
+		b, r = limit.Take(b, start)
+		require.True(t, r.Allowed)
+		assert.Equal(t, i, r.Remaining)
+	}
+	assert.Equal(t, 1500*time.Millisecond, r.ResetAfter)
+
+	b, r = limit.Take(b, start)
+	assert.False(t, r.Allowed)
+	assert.Equal(t, 500*time.Millisecond, r.RetryAfter)
+
+	// Half a second later one token has been refilled.
+	b, r = limit.Take(b, start.Add(500*time.Millisecond))
+	assert.True(t, r.Allowed)
+	assert.Equal(t, 0, r.Remaining)
+
+	// Refilling stops at the burst size.
+	_, r = limit.Take(b, start.Add(time.Hour))
+	assert.True(t, r.Allowed)
+	assert.Equal(t, 2, r.Remaining)
+}
+
+func TestMemoryStore_KeysAreIndependentAndIdleBucketsAreEvicted(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	limit := Limit{Rate: 1, Burst: 1}
+	start := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	r, err := s.Take(ctx, "alice", limit, start)
+	require.NoError(t, err)
+	assert.True(t, r.Allowed)
+	r, _ = s.Take(ctx, "alice", limit, start)
+	assert.False(t, r.Allowed)
+	r, _ = s.Take(ctx, "bob", limit, start)
+	assert.True(t, r.Allowed)
+	assert.Equal(t, 2, s.Len())
+
+	// By the next sweep both buckets have refilled and are dropped; carol's
+	// is the only one left.
+	_, err = s.Take(ctx, "carol", limit, start.Add(DefaultSweepInterval))
+	require.NoError(t, err)
+	assert.Equal(t, 1, s.Len())
+}
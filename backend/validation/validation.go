@@ -0,0 +1,337 @@
+// Package validation checks API request bodies. A Validator collects every
+// problem with a request, so that clients can be told about all invalid
+// fields in one response rather than one at a time.
+package validation
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/seans3/nhd/backend/apierror"
+	"github.com/seans3/nhd/backend/proto/gen/go"
+)
+
+// Decode decodes the JSON request body in r into dst. Unlike json.Decode it
+// rejects fields that dst does not have and trailing data after the object.
+// Errors are ValidationFailed apierror.Errors; an empty body wraps io.EOF.
+// Every unknown field and every value of the wrong JSON type is reported,
+// not just the first.
+func Decode(r io.Reader, dst any) error {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return apierror.Wrap(apierror.ValidationFailed, err, "Invalid request body: %v", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return decodeError(err)
+	}
+	if dec.More() {
+		return apierror.New(apierror.ValidationFailed, "Request body must contain a single JSON object")
+	}
+	// encoding/json stops at the first problem, so check the whole body
+	// against dst's type first.
+	var v Validator
+	v.checkJSON("", value, reflect.TypeOf(dst))
+	if err := v.Err(); err != nil {
+		return err
+	}
+
+	dec = json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		return decodeError(err)
+	}
+	return nil
+}
+
+func decodeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, io.EOF):
+		return apierror.Wrap(apierror.ValidationFailed, err, "Request body is required")
+	case errors.As(err, &typeErr) && typeErr.Field == "":
+		return apierror.Wrap(apierror.ValidationFailed, err, "Request body must be %s", jsonType(typeErr.Type))
+	case errors.As(err, &typeErr):
+		var v Validator
+		v.Add(typeErr.Field, "must be %s", jsonType(typeErr.Type))
+		return v.Err()
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no error type for unknown fields.
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		var v Validator
+		v.Add(field, "unknown field")
+		return v.Err()
+	}
+	return apierror.Wrap(apierror.ValidationFailed, err, "Invalid request body: %v", err)
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// checkJSON records a field error for each unknown field in value, a decoded
+// JSON value, and for each value that json.Unmarshal could not store in a
+// Go value of type t. field is the path of value in the request body.
+func (v *Validator) checkJSON(field string, value any, t reflect.Type) {
+	if value == nil {
+		// null leaves any Go value unchanged.
+		return
+	}
+	for t.Kind() == reflect.Pointer {
+		if t.Implements(jsonUnmarshalerType) || t.Implements(textUnmarshalerType) {
+			// The type decodes itself; leave it to json.Unmarshal.
+			return
+		}
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(jsonUnmarshalerType) || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return
+	}
+
+	ok := true
+	switch t.Kind() {
+	case reflect.Interface:
+	case reflect.Bool:
+		_, ok = value.(bool)
+	case reflect.String:
+		_, ok = value.(string)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, isNumber := value.(json.Number)
+		_, err := strconv.ParseInt(string(n), 10, t.Bits())
+		ok = isNumber && err == nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, isNumber := value.(json.Number)
+		_, err := strconv.ParseUint(string(n), 10, t.Bits())
+		ok = isNumber && err == nil
+	case reflect.Float32, reflect.Float64:
+		_, ok = value.(json.Number)
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			// []byte is decoded from a base64 string.
+			_, ok = value.(string)
+			break
+		}
+		var elems []any
+		if elems, ok = value.([]any); ok {
+			for i, elem := range elems {
+				v.checkJSON(fmt.Sprintf("%s[%d]", field, i), elem, t.Elem())
+			}
+		}
+	case reflect.Map:
+		var members map[string]any
+		if members, ok = value.(map[string]any); ok {
+			for key, member := range members {
+				v.checkJSON(joinField(field, key), member, t.Elem())
+			}
+		}
+	case reflect.Struct:
+		var members map[string]any
+		if members, ok = value.(map[string]any); ok {
+			fields := jsonFields(t)
+			keys := make([]string, 0, len(members))
+			for key := range members {
+				keys = append(keys, key)
+			}
+			// Report fields in a stable order.
+			slices.Sort(keys)
+			for _, key := range keys {
+				if ft, found := lookupField(fields, key); found {
+					v.checkJSON(joinField(field, key), members[key], ft)
+				} else {
+					v.Add(joinField(field, key), "unknown field")
+				}
+			}
+		}
+	}
+	// A body of the wrong type is left to json.Unmarshal, which reports it
+	// without a field.
+	if !ok && field != "" {
+		v.Add(field, "must be %s", jsonType(t))
+	}
+}
+
+// jsonField is a struct field as encoding/json sees it.
+type jsonField struct {
+	name string
+	typ  reflect.Type
+}
+
+// jsonFields returns the fields that encoding/json decodes into a struct of
+// type t, including those promoted from embedded structs.
+func jsonFields(t reflect.Type) []jsonField {
+	var fields []jsonField
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				fields = append(fields, jsonFields(ft)...)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, jsonField{name: name, typ: f.Type})
+	}
+	return fields
+}
+
+// lookupField finds the field for a JSON object key, preferring an exact
+// match and otherwise matching case-insensitively, as encoding/json does.
+func lookupField(fields []jsonField, key string) (reflect.Type, bool) {
+	for _, f := range fields {
+		if f.name == key {
+			return f.typ, true
+		}
+	}
+	for _, f := range fields {
+		if strings.EqualFold(f.name, key) {
+			return f.typ, true
+		}
+	}
+	return nil, false
+}
+
+func joinField(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+// jsonType describes the JSON value that decodes into a Go value of type t,
+// e.g. "a number" for float64, for use in field error messages.
+func jsonType(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.String:
+		return "a string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "a base64-encoded string"
+		}
+		return "an array"
+	case reflect.Array:
+		return "an array"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	}
+	return "a valid value"
+}
+
+// Validator accumulates field errors. The zero value is ready to use.
+type Validator struct {
+	fields []apierror.FieldError
+}
+
+// Add records that field is invalid, with a message formatted as by
+// fmt.Sprintf.
+func (v *Validator) Add(field, format string, args ...any) {
+	v.fields = append(v.fields, apierror.FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Check records message for field unless ok.
+func (v *Validator) Check(ok bool, field, message string) {
+	if !ok {
+		v.Add(field, "%s", message)
+	}
+}
+
+// Required checks that value is not blank.
+func (v *Validator) Required(field, value string) {
+	v.Check(strings.TrimSpace(value) != "", field, "is required")
+}
+
+// Email checks that value, if set, is a bare email address such as
+// "jo@example.com".
+func (v *Validator) Email(field, value string) {
+	if value == "" {
+		return
+	}
+	addr, err := mail.ParseAddress(value)
+	ok := err == nil && addr.Address == value
+	if ok {
+		// ParseAddress accepts single-label domains such as "localhost".
+		ok = strings.Contains(value[strings.LastIndex(value, "@"):], ".")
+	}
+	v.Check(ok, field, "must be a valid email address")
+}
+
+// Currency checks that value is set to an ISO 4217 currency code.
+func (v *Validator) Currency(field, value string) {
+	if value == "" {
+		v.Add(field, "is required")
+		return
+	}
+	v.Check(IsCurrency(value), field, "must be an ISO 4217 currency code, e.g. USD")
+}
+
+// NonNegative checks that value is not negative.
+func (v *Validator) NonNegative(field string, value int64) {
+	v.Check(value >= 0, field, "must not be negative")
+}
+
+// Money checks that value is set and in an ISO 4217 currency. A problem with
+// its currency is reported for field.currency.
+func (v *Validator) Money(field string, value *nhd_report.Money) {
+	if value == nil {
+		v.Add(field, "is required")
+		return
+	}
+	v.Currency(field+".currency", value.Currency)
+}
+
+// Valid reports whether no field errors have been recorded.
+func (v *Validator) Valid() bool {
+	return len(v.fields) == 0
+}
+
+// Err returns nil if no field errors have been recorded, and otherwise a
+// ValidationFailed apierror.Error listing them all.
+func (v *Validator) Err() error {
+	if v.Valid() {
+		return nil
+	}
+	details := make([]string, len(v.fields))
+	for i, f := range v.fields {
+		details[i] = f.Field + " " + f.Message
+	}
+	return &apierror.Error{
+		Code:    apierror.ValidationFailed,
+		Message: "Invalid request: " + strings.Join(details, "; "),
+		Fields:  v.fields,
+	}
+}
@@ -0,0 +1,95 @@
+package validation
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/seans3/nhd/backend/apierror"
+	"github.com/seans3/nhd/backend/proto/gen/go"
+	"github.com/stretchr/testify/assert"
+)
+
+type testBody struct {
+	Name   string     `json:"name"`
+	Amount float64    `json:"amount"`
+	Count  int        `json:"count"`
+	Tags   []string   `json:"tags"`
+	Price  *testMoney `json:"price"`
+}
+
+type testMoney struct {
+	Currency   string `json:"currency"`
+	MinorUnits int64  `json:"minor_units"`
+}
+
+func TestDecode(t *testing.T) {
+	var body testBody
+	assert.NoError(t, Decode(strings.NewReader(`{"name":"a","amount":1}`), &body))
+	assert.Equal(t, testBody{Name: "a", Amount: 1}, body)
+	assert.NoError(t, Decode(strings.NewReader(`{"price":null,"tags":null}`), &body))
+
+	tests := []struct {
+		body   string
+		fields []apierror.FieldError
+	}{
+		{`{"name":"a","colour":"red"}`, []apierror.FieldError{{Field: "colour", Message: "unknown field"}}},
+		{`{"amount":"ten"}`, []apierror.FieldError{{Field: "amount", Message: "must be a number"}}},
+		{`{"count":1.5,"colour":"red","tags":["a",2],"price":{"currency":1,"minor_units":"5","cents":5}}`, []apierror.FieldError{
+			{Field: "colour", Message: "unknown field"},
+			{Field: "count", Message: "must be an integer"},
+			{Field: "price.cents", Message: "unknown field"},
+			{Field: "price.currency", Message: "must be a string"},
+			{Field: "price.minor_units", Message: "must be an integer"},
+			{Field: "tags[1]", Message: "must be a string"},
+		}},
+		{`[]`, nil},
+		{`{"name":"a"} {"name":"b"}`, nil},
+		{`{"name":`, nil},
+	}
+	for _, tt := range tests {
+		err := Decode(strings.NewReader(tt.body), &testBody{})
+		apiErr := apierror.From(err)
+		assert.Equal(t, apierror.ValidationFailed, apiErr.Code, tt.body)
+		assert.Equal(t, tt.fields, apiErr.Fields, tt.body)
+	}
+
+	err := Decode(strings.NewReader(""), &body)
+	assert.True(t, errors.Is(err, io.EOF))
+	assert.Equal(t, apierror.ValidationFailed, apierror.From(err).Code)
+}
+
+func TestValidator(t *testing.T) {
+	var v Validator
+	v.Required("full_name", "Jo")
+	v.Email("email", "jo@example.com")
+	v.Email("email", "")
+	v.Currency("currency", "USD")
+	v.NonNegative("amount", 0)
+	v.Money("price", &nhd_report.Money{Currency: "CAD", MinorUnits: 4999})
+	assert.NoError(t, v.Err())
+
+	v.Required("full_name", "  ")
+	v.Email("email", "Jo <jo@example.com>")
+	v.Email("email", "jo@localhost")
+	v.Currency("currency", "usd")
+	v.Currency("currency", "")
+	v.NonNegative("amount", -1)
+	v.Money("price", nil)
+	v.Money("price", &nhd_report.Money{MinorUnits: 4999})
+
+	apiErr := apierror.From(v.Err())
+	assert.Equal(t, apierror.ValidationFailed, apiErr.Code)
+	assert.Equal(t, []apierror.FieldError{
+		{Field: "full_name", Message: "is required"},
+		{Field: "email", Message: "must be a valid email address"},
+		{Field: "email", Message: "must be a valid email address"},
+		{Field: "currency", Message: "must be an ISO 4217 currency code, e.g. USD"},
+		{Field: "currency", Message: "is required"},
+		{Field: "amount", Message: "must not be negative"},
+		{Field: "price", Message: "is required"},
+		{Field: "price.currency", Message: "is required"},
+	}, apiErr.Fields)
+	assert.Contains(t, apiErr.Message, "amount must not be negative")
+}
@@ -0,0 +1,160 @@
+// Package worker generates reports for the run IDs that the API publishes to
+// the nhd-report-requests topic.
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/seans3/nhd/backend/hazard"
+	"github.com/seans3/nhd/backend/interfaces"
+	"github.com/seans3/nhd/backend/logging"
+	"github.com/seans3/nhd/backend/metrics"
+	"github.com/seans3/nhd/backend/proto/gen/go"
+)
+
+const (
+	// DefaultSubscription is the worker's subscription to the
+	// nhd-report-requests topic.
+	DefaultSubscription = "nhd-report-requests-worker"
+	// DefaultConcurrency is the number of messages processed at once when
+	// Concurrency is not set.
+	DefaultConcurrency = 4
+	// Actor identifies the worker in report run status history.
+	Actor = "worker"
+)
+
+// Worker evaluates hazards for pending report runs and records the outcome.
+type Worker struct {
+	DS      interfaces.Datastore
+	Sub     interfaces.Subscriber
+	Hazards *hazard.Index
+	// SubscriptionID defaults to DefaultSubscription.
+	SubscriptionID string
+	// Concurrency defaults to DefaultConcurrency.
+	Concurrency int
+}
+
+// Run processes messages until ctx is done.
+func (w *Worker) Run(ctx context.Context) error {
+	subscriptionID := w.SubscriptionID
+	if subscriptionID == "" {
+		subscriptionID = DefaultSubscription
+	}
+	concurrency := w.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	logging.FromContext(ctx).Info("Worker receiving", "subscription", subscriptionID, "concurrency", concurrency)
+	return w.Sub.Receive(ctx, subscriptionID, concurrency, w.HandleMessage)
+}
+
+// HandleMessage processes one report request. The message is acked only once
+// the outcome has been written to the Datastore; if any read or write fails
+// it is nacked so that it will be redelivered.
+//
+// Log lines written while processing the message carry the report run ID,
+// the message ID and the trace ID of the API request that published it.
+func (w *Worker) HandleMessage(ctx context.Context, msg *interfaces.Message) {
+	reportRunID := strings.TrimSpace(string(msg.Data))
+	ctx = logging.WithTraceID(ctx, msg.Attributes[logging.TraceIDAttribute])
+	ctx = logging.With(ctx, "report_run_id", reportRunID, "message_id", msg.ID, "delivery_attempt", msg.DeliveryAttempt)
+	metrics.ReportRunsInFlight.Inc()
+	defer metrics.ReportRunsInFlight.Dec()
+	if err := w.Process(ctx, reportRunID); err != nil {
+		logging.FromContext(ctx).Error("Failed to process report run", "error", err)
+		msg.Nack()
+		return
+	}
+	msg.Ack()
+}
+
+// Process generates the report for reportRunID. It is safe to call more than
+// once for the same run: runs that are already COMPLETED, FAILED or CANCELLED
+// are left alone, and a run left PROCESSING by an interrupted attempt is
+// processed again from the start. If the run's status changes underneath it,
+// for example because the run was cancelled, the request is dropped.
+//
+// Process returns an error only for failures that may succeed if retried.
+// Problems with the run itself, such as a property address without
+// coordinates, mark the run FAILED and return nil.
+func (w *Worker) Process(ctx context.Context, reportRunID string) error {
+	logger := logging.FromContext(ctx)
+	reportRun, err := w.DS.GetReportRun(ctx, reportRunID)
+	if errors.Is(err, interfaces.ErrNotFound) {
+		logger.Warn("Report run not found; dropping request")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	switch reportRun.Status {
+	case nhd_report.ReportRun_COMPLETED, nhd_report.ReportRun_FAILED, nhd_report.ReportRun_CANCELLED:
+		logger.Info("Report run already finished; ignoring redelivered request", "status", reportRun.Status.String())
+		return nil
+	case nhd_report.ReportRun_PROCESSING:
+		logger.Info("Report run is already PROCESSING; resuming")
+	default:
+		err := w.DS.UpdateReportRunStatus(ctx, reportRunID, nhd_report.ReportRun_PROCESSING, Actor, "")
+		if errors.Is(err, interfaces.ErrInvalidTransition) {
+			logger.Info("Report run can no longer be processed; dropping request", "error", err)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("marking report run PROCESSING: %w", err)
+		}
+	}
+
+	results, failureReason, err := w.evaluate(ctx, reportRun)
+	if err != nil {
+		return err
+	}
+	if failureReason != "" {
+		logger.Warn("Report run failed", "failure_reason", failureReason)
+		err := w.DS.UpdateReportRunStatus(ctx, reportRunID, nhd_report.ReportRun_FAILED, Actor, failureReason)
+		if errors.Is(err, interfaces.ErrInvalidTransition) {
+			logger.Info("Report run was finished by another attempt; dropping request", "error", err)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		metrics.ReportRunsProcessed.Inc("failed")
+		return nil
+	}
+	err = w.DS.CompleteReportRun(ctx, reportRunID, results, Actor)
+	if errors.Is(err, interfaces.ErrInvalidTransition) {
+		logger.Info("Report run was finished by another attempt; dropping request", "error", err)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("storing results: %w", err)
+	}
+	metrics.ReportRunsProcessed.Inc("completed")
+	logger.Info("Report run completed", slog.Any("results", results))
+	return nil
+}
+
+// evaluate looks up the run's property and evaluates its hazards. A non-empty
+// failure reason means the run cannot be completed.
+func (w *Worker) evaluate(ctx context.Context, reportRun *nhd_report.ReportRun) (results *nhd_report.ReportRun_HazardResults, failureReason string, err error) {
+	if reportRun.PropertyAddressId == "" {
+		return nil, "report run has no property_address_id", nil
+	}
+	address, err := w.DS.GetPropertyAddress(ctx, reportRun.PropertyAddressId)
+	if errors.Is(err, interfaces.ErrNotFound) {
+		return nil, fmt.Sprintf("property address %s not found", reportRun.PropertyAddressId), nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	coordinates := address.GetCoordinates()
+	if coordinates == nil {
+		return nil, fmt.Sprintf("property address %s has no coordinates", reportRun.PropertyAddressId), nil
+	}
+	return w.Hazards.Evaluate(coordinates.Latitude, coordinates.Longitude), "", nil
+}
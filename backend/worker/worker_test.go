@@ -0,0 +1,171 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/seans3/nhd/backend/hazard"
+	"github.com/seans3/nhd/backend/interfaces"
+	"github.com/seans3/nhd/backend/logging"
+	"github.com/seans3/nhd/backend/memstore"
+	"github.com/seans3/nhd/backend/mocks"
+	"github.com/seans3/nhd/backend/proto/gen/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMessage records whether the worker acked or nacked it.
+type fakeMessage struct {
+	acked, nacked bool
+}
+
+func (f *fakeMessage) message(data string) *interfaces.Message {
+	return &interfaces.Message{
+		ID:   "msg1",
+		Data: []byte(data),
+		Ack:  func() { f.acked = true },
+		Nack: func() { f.nacked = true },
+	}
+}
+
+func newTestWorker(t *testing.T, ds interfaces.Datastore) *Worker {
+	hazards, err := hazard.Load("../../reporter/data")
+	require.NoError(t, err)
+	return &Worker{DS: ds, Hazards: hazards}
+}
+
+func createRun(t *testing.T, ds *memstore.Client, coordinates *nhd_report.PropertyAddress_Coordinates) string {
+	ctx := context.Background()
+	addrRef, _, err := ds.CreatePropertyAddress(ctx, &nhd_report.PropertyAddress{Coordinates: coordinates})
+	require.NoError(t, err)
+	runRef, _, err := ds.CreateReportRun(ctx, &nhd_report.ReportRun{PropertyAddressId: addrRef.ID, Status: nhd_report.ReportRun_PENDING})
+	require.NoError(t, err)
+	return runRef.ID
+}
+
+func TestHandleMessage_CompletesRun(t *testing.T) {
+	ds := memstore.NewClient()
+	w := newTestWorker(t, ds)
+	runID := createRun(t, ds, &nhd_report.PropertyAddress_Coordinates{Latitude: 37.785, Longitude: -122.435})
+
+	var f fakeMessage
+	w.HandleMessage(context.Background(), f.message(runID))
+	assert.True(t, f.acked)
+	assert.False(t, f.nacked)
+
+	run, err := ds.GetReportRun(context.Background(), runID)
+	require.NoError(t, err)
+	assert.Equal(t, nhd_report.ReportRun_COMPLETED, run.Status)
+	assert.True(t, run.Results.InSpecialFloodHazardArea)
+	assert.True(t, run.Results.InVeryHighFireHazardSeverityZone)
+	assert.False(t, run.Results.InSeismicHazardZone)
+	assert.Empty(t, run.FailureReason)
+	require.Len(t, run.StatusHistory, 2)
+	assert.Equal(t, nhd_report.ReportRun_PROCESSING, run.StatusHistory[0].ToStatus)
+	assert.Equal(t, nhd_report.ReportRun_COMPLETED, run.StatusHistory[1].ToStatus)
+	assert.Equal(t, Actor, run.StatusHistory[1].Actor)
+
+	// A redelivery of the same message is acked without touching the run.
+	updatedAt := run.UpdatedAt.AsTime()
+	var redelivered fakeMessage
+	w.HandleMessage(context.Background(), redelivered.message(runID))
+	assert.True(t, redelivered.acked)
+	run, err = ds.GetReportRun(context.Background(), runID)
+	require.NoError(t, err)
+	assert.Equal(t, updatedAt, run.UpdatedAt.AsTime())
+}
+
+func TestHandleMessage_FailsRunWithoutCoordinates(t *testing.T) {
+	ds := memstore.NewClient()
+	w := newTestWorker(t, ds)
+	runID := createRun(t, ds, nil)
+
+	var f fakeMessage
+	w.HandleMessage(context.Background(), f.message(runID))
+	assert.True(t, f.acked)
+
+	run, err := ds.GetReportRun(context.Background(), runID)
+	require.NoError(t, err)
+	assert.Equal(t, nhd_report.ReportRun_FAILED, run.Status)
+	assert.Contains(t, run.FailureReason, "has no coordinates")
+	require.NotEmpty(t, run.StatusHistory)
+	last := run.StatusHistory[len(run.StatusHistory)-1]
+	assert.Equal(t, nhd_report.ReportRun_PROCESSING, last.FromStatus)
+	assert.Equal(t, nhd_report.ReportRun_FAILED, last.ToStatus)
+	assert.Equal(t, run.FailureReason, last.Reason)
+}
+
+func TestHandleMessage_SkipsCancelledRun(t *testing.T) {
+	ds := memstore.NewClient()
+	w := newTestWorker(t, ds)
+	runID := createRun(t, ds, &nhd_report.PropertyAddress_Coordinates{Latitude: 37.785, Longitude: -122.435})
+	require.NoError(t, ds.UpdateReportRunStatus(context.Background(), runID, nhd_report.ReportRun_CANCELLED, "user1", "Ordered by mistake"))
+
+	var f fakeMessage
+	w.HandleMessage(context.Background(), f.message(runID))
+	assert.True(t, f.acked)
+
+	run, err := ds.GetReportRun(context.Background(), runID)
+	require.NoError(t, err)
+	assert.Equal(t, nhd_report.ReportRun_CANCELLED, run.Status)
+	assert.Nil(t, run.Results)
+}
+
+func TestHandleMessage_LogsCarryTraceIDAndRunID(t *testing.T) {
+	ds := memstore.NewClient()
+	w := newTestWorker(t, ds)
+	runID := createRun(t, ds, nil)
+
+	var buf bytes.Buffer
+	ctx := logging.NewContext(context.Background(), logging.New(&buf, slog.LevelInfo))
+	var f fakeMessage
+	msg := f.message(runID)
+	msg.Attributes = map[string]string{logging.TraceIDAttribute: "4bf92f3577b34da6a3ce929d0e0e4736"}
+	w.HandleMessage(ctx, msg)
+	require.True(t, f.acked)
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, "Report run failed", line["message"])
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", line["trace_id"])
+	assert.Equal(t, runID, line["report_run_id"])
+	assert.Equal(t, "msg1", line["message_id"])
+}
+
+func TestHandleMessage_UnknownRunIsAcked(t *testing.T) {
+	w := newTestWorker(t, memstore.NewClient())
+
+	var f fakeMessage
+	w.HandleMessage(context.Background(), f.message("no-such-run"))
+	assert.True(t, f.acked)
+}
+
+func TestHandleMessage_NacksWhenWriteFails(t *testing.T) {
+	mockDS := new(mocks.MockDatastoreClient)
+	w := newTestWorker(t, mockDS)
+
+	mockDS.On("GetReportRun", mock.Anything, "run1").Return(&nhd_report.ReportRun{ReportRunId: "run1", PropertyAddressId: "addr1", Status: nhd_report.ReportRun_PENDING}, nil)
+	mockDS.On("UpdateReportRunStatus", mock.Anything, "run1", nhd_report.ReportRun_PROCESSING, Actor, "").Return(nil)
+	mockDS.On("GetPropertyAddress", mock.Anything, "addr1").Return(&nhd_report.PropertyAddress{Coordinates: &nhd_report.PropertyAddress_Coordinates{Latitude: 37.77, Longitude: -122.50}}, nil)
+	mockDS.On("CompleteReportRun", mock.Anything, "run1", mock.Anything, Actor).Return(errors.New("deadline exceeded"))
+
+	var f fakeMessage
+	w.HandleMessage(context.Background(), f.message("run1"))
+	assert.False(t, f.acked)
+	assert.True(t, f.nacked)
+	mockDS.AssertExpectations(t)
+}
+
+func TestRun_UsesDefaults(t *testing.T) {
+	mockSub := new(mocks.MockSubscriberClient)
+	w := &Worker{Sub: mockSub}
+
+	mockSub.On("Receive", mock.Anything, DefaultSubscription, DefaultConcurrency, mock.Anything).Return(nil)
+	assert.NoError(t, w.Run(context.Background()))
+	mockSub.AssertExpectations(t)
+}
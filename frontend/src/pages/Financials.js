@@ -1,6 +1,22 @@
 import React, { useState, useEffect } from 'react';
 import { getFinancialsSummary } from '../services/api';
 
+/**
+ * Formats a Money value, {currency, minor_units}, in its currency, e.g.
+ * "$49.99" for {currency: "USD", minor_units: 4999}. A zero minor_units is
+ * omitted from the JSON, so a missing value counts as zero.
+ * @param {Object} money - The amount to format.
+ * @returns {string} The formatted amount.
+ */
+export const formatMoney = (money) => {
+  if (!money || !money.currency) {
+    return '';
+  }
+  const format = new Intl.NumberFormat(undefined, { style: 'currency', currency: money.currency });
+  const digits = format.resolvedOptions().maximumFractionDigits;
+  return format.format(Number(money.minor_units || 0) / 10 ** digits);
+};
+
 function Financials() {
   const [summary, setSummary] = useState(null);
   const [loading, setLoading] = useState(true);
@@ -35,10 +51,32 @@ function Financials() {
       <div style={{ marginBottom: '20px', padding: '10px', border: '1px solid #ccc' }}>
         <h2>Total Revenue</h2>
         <p style={{ fontSize: '24px', fontWeight: 'bold' }}>
-          ${summary.total_revenue.toFixed(2)}
+          {formatMoney(summary.total_revenue)}
         </p>
       </div>
 
+      <h2>By Currency</h2>
+      <table style={{ width: '100%', borderCollapse: 'collapse', marginBottom: '20px' }}>
+        <thead>
+          <tr style={{ borderBottom: '1px solid #ccc' }}>
+            <th style={{ textAlign: 'left', padding: '8px' }}>Currency</th>
+            <th style={{ textAlign: 'left', padding: '8px' }}>Revenue</th>
+            <th style={{ textAlign: 'left', padding: '8px' }}>Refunds</th>
+            <th style={{ textAlign: 'left', padding: '8px' }}>Net Revenue</th>
+          </tr>
+        </thead>
+        <tbody>
+          {(summary.totals || []).map((total) => (
+            <tr key={total.currency} style={{ borderBottom: '1px solid #eee' }}>
+              <td style={{ padding: '8px' }}>{total.currency}</td>
+              <td style={{ padding: '8px' }}>{formatMoney(total.revenue)}</td>
+              <td style={{ padding: '8px' }}>{formatMoney(total.refunds)}</td>
+              <td style={{ padding: '8px' }}>{formatMoney(total.net_revenue)}</td>
+            </tr>
+          ))}
+        </tbody>
+      </table>
+
       <h2>Paid Reports</h2>
       <table style={{ width: '100%', borderCollapse: 'collapse' }}>
         <thead>
@@ -50,11 +88,11 @@ function Financials() {
           </tr>
         </thead>
         <tbody>
-          {summary.paid_reports.map((report, index) => (
+          {(summary.paid_reports || []).map((report, index) => (
             <tr key={index} style={{ borderBottom: '1px solid #eee' }}>
               <td style={{ padding: '8px' }}>{report.customer_name}</td>
               <td style={{ padding: '8px' }}>{report.property_address}</td>
-              <td style={{ padding: '8px' }}>${report.amount_paid.toFixed(2)}</td>
+              <td style={{ padding: '8px' }}>{formatMoney(report.amount_paid)}</td>
               <td style={{ padding: '8px' }}>{report.paid_at}</td>
             </tr>
           ))}
@@ -1,7 +1,7 @@
 const API_BASE_URL = 'http://localhost:8080';
 
 /**
- * Fetches a list of all report runs from the backend.
+ * Fetches the first page of report runs from the backend.
  * @returns {Promise<Array>} A promise that resolves to an array of report runs.
  */
 export const getReportRuns = async () => {
@@ -10,7 +10,8 @@ export const getReportRuns = async () => {
     if (!response.ok) {
       throw new Error(`HTTP error! status: ${response.status}`);
     }
-    return await response.json();
+    const data = await response.json();
+    return data.report_runs || [];
   } catch (error) {
     console.error('Error fetching report runs:', error);
     return []; // Return empty array on error
@@ -39,13 +40,16 @@ export const createCustomer = async (customerData) => {
 /**
  * Creates a new report run.
  * @param {Object} reportData - The report run data to create.
+ * @param {string} [idempotencyKey] - Sent as the Idempotency-Key header. Pass the same key when
+ *   retrying a request so that the backend does not create a second run.
  * @returns {Promise<Object>} A promise that resolves to the newly created report run data.
  */
-export const createReportRun = async (reportData) => {
+export const createReportRun = async (reportData, idempotencyKey = crypto.randomUUID()) => {
   const response = await fetch(`${API_BASE_URL}/report-runs`, {
     method: 'POST',
     headers: {
       'Content-Type': 'application/json',
+      'Idempotency-Key': idempotencyKey,
     },
     body: JSON.stringify(reportData),
   });
@@ -58,11 +62,13 @@ export const createReportRun = async (reportData) => {
 
 /**
  * Fetches the financials summary from the backend.
+ * @param {Object} [params] Optional from, to (YYYY-MM-DD) and group_by (day, month or customer).
  * @returns {Promise<Object>} A promise that resolves to the financial summary data.
  */
-export const getFinancialsSummary = async () => {
+export const getFinancialsSummary = async (params = {}) => {
   try {
-    const response = await fetch(`${API_BASE_URL}/financials/summary`);
+    const query = new URLSearchParams(params).toString();
+    const response = await fetch(`${API_BASE_URL}/financials/summary${query ? `?${query}` : ''}`);
     if (!response.ok) {
       throw new Error(`HTTP error! status: ${response.status}`);
     }
@@ -7,7 +7,7 @@ describe('API Service', () => {
 
   test('getReportRuns fetches and returns data', async () => {
     const mockData = [{ report_run_id: 'run1' }];
-    fetch.mockResponseOnce(JSON.stringify(mockData));
+    fetch.mockResponseOnce(JSON.stringify({ report_runs: mockData, next_page_token: 'abc' }));
 
     const data = await getReportRuns();
 
@@ -1,7 +1,10 @@
 import base64
+import datetime
+import json
 import os
 import sys
 import geopandas
+import pandas
 from shapely.geometry import Point
 
 # Add the proto directory to the Python path
@@ -13,6 +16,71 @@ from nhd_pb2 import ReportRun
 PROJECT_ID = os.environ.get("GOOGLE_CLOUD_PROJECT")
 db = firestore.Client()
 
+# Identifies this reporter in a report run's status_history.
+ACTOR = 'reporter'
+
+# The report run lifecycle, as enforced by the Go backend
+# (backend/interfaces/status.go). COMPLETED and CANCELLED are final; a FAILED
+# run may be retried through the API, and a PENDING run fails without being
+# processed if it cannot be queued.
+ALLOWED_TRANSITIONS = {
+    ReportRun.PENDING: {ReportRun.PROCESSING, ReportRun.CANCELLED, ReportRun.FAILED},
+    ReportRun.PROCESSING: {ReportRun.COMPLETED, ReportRun.FAILED},
+    ReportRun.FAILED: {ReportRun.PENDING},
+}
+
+# The Go backend stores report runs and property addresses by encoding the
+# generated proto structs, so documents are keyed by the Go field names
+# (StatusHistory, not status_history). This reporter reads and writes the same
+# keys.
+
+class InvalidTransition(Exception):
+    """Raised when the lifecycle does not allow a status change."""
+
+@firestore.transactional
+def _change_status(transaction, report_run_ref, to_status, reason, extra_updates):
+    snapshot = report_run_ref.get(transaction=transaction)
+    if not snapshot.exists:
+        raise InvalidTransition(f"report run {report_run_ref.id} not found")
+    from_status = (snapshot.to_dict() or {}).get('Status') or ReportRun.PENDING
+    if to_status not in ALLOWED_TRANSITIONS.get(from_status, set()):
+        raise InvalidTransition(
+            f"report run {report_run_ref.id} cannot change from "
+            f"{ReportRun.Status.Name(from_status)} to {ReportRun.Status.Name(to_status)}")
+    change = {
+        'FromStatus': from_status,
+        'ToStatus': to_status,
+        # Server timestamps cannot be used inside arrays.
+        'ChangedAt': datetime.datetime.now(datetime.timezone.utc),
+        'Actor': ACTOR,
+        'Reason': reason,
+    }
+    updates = {
+        'Status': to_status,
+        'FailureReason': reason if to_status == ReportRun.FAILED else '',
+        'StatusHistory': firestore.ArrayUnion([change]),
+        'UpdatedAt': firestore.SERVER_TIMESTAMP,
+    }
+    updates.update(extra_updates)
+    transaction.update(report_run_ref, updates)
+
+def change_status(report_run_ref, to_status, reason='', extra_updates=None):
+    """Moves a report run to to_status and records the change in its status_history.
+
+    Returns False, without changing the run, if the lifecycle does not allow the
+    change, e.g. because the run was cancelled or another attempt finished it.
+    """
+    try:
+        _change_status(db.transaction(), report_run_ref, to_status, reason, extra_updates or {})
+    except InvalidTransition as e:
+        print(f"Skipping status change: {e}")
+        return False
+    return True
+
+def fail(report_run_ref, reason):
+    print(f"Error: {reason}")
+    change_status(report_run_ref, ReportRun.FAILED, reason)
+
 # ====================================================================================
 # LOAD HAZARD ZONE DATA
 # In a production environment, this data would be read from a more robust and
@@ -20,14 +88,27 @@ db = firestore.Client()
 # For this implementation, we load the mock GeoJSON files on startup.
 # ====================================================================================
 def load_zone_data(filename):
-    """Loads a GeoJSON file from the data directory."""
+    """Loads a GeoJSON file from the data directory.
+
+    For hazard evidence, the file's "metadata" member, which describes the
+    dataset it came from, is kept in the frame's attrs, and each feature's
+    GeoJSON id (or its position in the file) in a feature_id column.
+    """
     path = os.path.join(os.path.dirname(__file__), 'data', filename)
-    return geopandas.read_file(path)
+    with open(path) as f:
+        collection = json.load(f)
+    zones = geopandas.GeoDataFrame.from_features(collection['features'], crs='EPSG:4326')
+    zones['feature_id'] = [str(feature.get('id', i)) for i, feature in enumerate(collection['features'])]
+    zones.attrs['metadata'] = collection.get('metadata', {})
+    return zones
 
 try:
     flood_zones = load_zone_data('mock_flood_hazard_zones.geojson')
     dam_zones = load_zone_data('mock_dam_inundation_areas.geojson')
     fire_zones = load_zone_data('mock_fire_hazard_zones.geojson')
+    # Only Very High severity features count, as in the Go worker.
+    if 'HAZ_CLASS' in fire_zones:
+        fire_zones = fire_zones[fire_zones['HAZ_CLASS'].fillna('Very High').str.lower() == 'very high']
     wildland_zones = load_zone_data('mock_wildland_fire_areas.geojson')
     fault_zones = load_zone_data('mock_earthquake_fault_zones.geojson')
     seismic_zones = load_zone_data('mock_seismic_hazard_zones.geojson')
@@ -37,6 +118,47 @@ except Exception as e:
     # In a real Cloud Function, you might want to handle this more gracefully.
     # For this example, we'll let it fail on startup if data is missing.
 
+# Distances to zone boundaries are measured in California Albers, whose units
+# are meters.
+DISTANCE_CRS = 'EPSG:3310'
+
+def hazard_evidence(hazard, zones, location):
+    """Describes how the result for one hazard was reached.
+
+    Mirrors ReportRun.HazardEvidence: the layer's source, the features that
+    contain location (a longitude/latitude Point), and the distance to the
+    nearest edge of a matched feature or, if none match, of any feature.
+    """
+    metadata = zones.attrs.get('metadata', {})
+    matched = zones[zones.geometry.covers(location)]
+    evidence = {
+        'Hazard': hazard,
+        'InZone': len(matched) > 0,
+        'Dataset': metadata.get('dataset', ''),
+        'Agency': metadata.get('agency', ''),
+        'LayerVersion': metadata.get('version', ''),
+        'MatchedFeatures': [
+            {
+                'FeatureId': feature['feature_id'],
+                'Attributes': {
+                    name: str(value)
+                    for name, value in feature.drop(['geometry', 'feature_id']).items()
+                    if pandas.notna(value)
+                },
+            }
+            for _, feature in matched.iterrows()
+        ],
+    }
+    if metadata.get('effective_date'):
+        evidence['LayerEffectiveDate'] = datetime.datetime.fromisoformat(
+            metadata['effective_date']).replace(tzinfo=datetime.timezone.utc)
+    nearest = matched if len(matched) else zones
+    if len(nearest):
+        point = geopandas.GeoSeries([location], crs='EPSG:4326').to_crs(DISTANCE_CRS).iloc[0]
+        distance = nearest.to_crs(DISTANCE_CRS).boundary.distance(point).min()
+        evidence['DistanceToBoundaryMeters'] = round(float(distance), 1)
+    return evidence
+
 def handle_report_request(event, context):
     """Triggered from a message on a Cloud Pub/Sub topic."""
     report_run_id = base64.b64decode(event['data']).decode('utf-8')
@@ -49,47 +171,54 @@ def handle_report_request(event, context):
         print(f"Error: ReportRun document {report_run_id} not found.")
         return
 
+    # Move the run to PROCESSING before doing any work, so that a cancelled or
+    # already finished run is left alone. A run left PROCESSING by an
+    # interrupted attempt is processed again.
     report_run_data = report_run_doc.to_dict()
-    property_address_id = report_run_data.get('property_address_id')
+    if report_run_data.get('Status') != ReportRun.PROCESSING:
+        if not change_status(report_run_ref, ReportRun.PROCESSING):
+            return
+
+    property_address_id = report_run_data.get('PropertyAddressId')
 
     if not property_address_id:
-        print(f"Error: property_address_id not found in ReportRun {report_run_id}")
-        report_run_ref.update({'status': ReportRun.FAILED, 'results': {'error': 'Missing property_address_id'}})
+        fail(report_run_ref, 'report run has no property_address_id')
         return
 
     property_address_ref = db.collection('property_addresses').document(property_address_id)
     property_address_doc = property_address_ref.get()
 
     if not property_address_doc.exists:
-        print(f"Error: PropertyAddress document {property_address_id} not found.")
-        report_run_ref.update({'status': ReportRun.FAILED, 'results': {'error': 'PropertyAddress not found'}})
+        fail(report_run_ref, f'property address {property_address_id} not found')
         return
 
     property_address_data = property_address_doc.to_dict()
-    coordinates = property_address_data.get('coordinates')
+    coordinates = property_address_data.get('Coordinates')
 
-    if not coordinates or 'latitude' not in coordinates or 'longitude' not in coordinates:
-        print(f"Error: Coordinates not found in PropertyAddress {property_address_id}")
-        report_run_ref.update({'status': ReportRun.FAILED, 'results': {'error': 'Coordinates not found'}})
+    if not coordinates or 'Latitude' not in coordinates or 'Longitude' not in coordinates:
+        fail(report_run_ref, f'property address {property_address_id} has no coordinates')
         return
 
     # --- Perform Full Point-in-Polygon (PIP) Analysis ---
-    property_location = Point(coordinates['longitude'], coordinates['latitude'])
+    property_location = Point(coordinates['Longitude'], coordinates['Latitude'])
     
+    # Points on a zone's boundary are inside it.
+    evidence = [
+        hazard_evidence('special_flood_hazard_area', flood_zones, property_location),
+        hazard_evidence('dam_inundation_area', dam_zones, property_location),
+        hazard_evidence('very_high_fire_hazard_severity_zone', fire_zones, property_location),
+        hazard_evidence('wildland_fire_area', wildland_zones, property_location),
+        hazard_evidence('earthquake_fault_zone', fault_zones, property_location),
+        hazard_evidence('seismic_hazard_zone', seismic_zones, property_location),
+    ]
+    # e.g. InSpecialFloodHazardArea for special_flood_hazard_area.
     hazard_results = {
-        'in_special_flood_hazard_area': any(flood_zones.geometry.contains(property_location)),
-        'in_dam_inundation_area': any(dam_zones.geometry.contains(property_location)),
-        'in_very_high_fire_hazard_severity_zone': any(fire_zones.geometry.contains(property_location)),
-        'in_wildland_fire_area': any(wildland_zones.geometry.contains(property_location)),
-        'in_earthquake_fault_zone': any(fault_zones.geometry.contains(property_location)),
-        'in_seismic_hazard_zone': any(seismic_zones.geometry.contains(property_location)),
+        'In' + ''.join(word.title() for word in e['Hazard'].split('_')): e['InZone']
+        for e in evidence
     }
+    hazard_results['Evidence'] = evidence
 
     # Update the Firestore document with the complete results
-    report_run_ref.update({
-        'status': ReportRun.COMPLETED,
-        'results': hazard_results
-    })
-
-    print(f"Report run {report_run_id} completed with results: {hazard_results}")
+    if change_status(report_run_ref, ReportRun.COMPLETED, extra_updates={'Results': hazard_results}):
+        print(f"Report run {report_run_id} completed with results: {hazard_results}")
 
@@ -25,7 +25,7 @@ _sym_db = _symbol_database.Default()
 from google.protobuf import timestamp_pb2 as google_dot_protobuf_dot_timestamp__pb2
 
 
-DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\tnhd.proto\x12\tnhdreport\x1a\x1fgoogle/protobuf/timestamp.proto\"[\n\x0bPermissions\x12\x1c\n\x14\x63\x61n_create_customers\x18\x01 \x01(\x08\x12\x1c\n\x14\x63\x61n_generate_reports\x18\x02 \x01(\x08\x12\x10\n\x08is_admin\x18\x03 \x01(\x08\"\x96\x01\n\x04User\x12\x0f\n\x07user_id\x18\x01 \x01(\t\x12\x11\n\tfull_name\x18\x02 \x01(\t\x12\r\n\x05\x65mail\x18\x03 \x01(\t\x12+\n\x0bpermissions\x18\x04 \x01(\x0b\x32\x16.nhdreport.Permissions\x12.\n\ncreated_at\x18\x05 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\"\xa3\x01\n\x08\x43ustomer\x12\x13\n\x0b\x63ustomer_id\x18\x01 \x01(\t\x12\x11\n\tfull_name\x18\x02 \x01(\t\x12\r\n\x05\x65mail\x18\x03 \x01(\t\x12\x14\n\x0c\x63ompany_name\x18\x04 \x01(\t\x12.\n\ncreated_at\x18\x05 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\x12\x1a\n\x12\x63reated_by_user_id\x18\x06 \x01(\t\"\x97\x03\n\x0fPropertyAddress\x12\x1b\n\x13property_address_id\x18\x01 \x01(\t\x12\x42\n\x0f\x61\x64\x64ress_details\x18\x02 \x01(\x0b\x32).nhdreport.PropertyAddress.AddressDetails\x12;\n\x0b\x63oordinates\x18\x03 \x01(\x0b\x32&.nhdreport.PropertyAddress.Coordinates\x12\x11\n\tplus_code\x18\x04 \x01(\t\x12\x17\n\x0fgoogle_place_id\x18\x05 \x01(\t\x1a\x85\x01\n\x0e\x41\x64\x64ressDetails\x12\x16\n\x0estreet_address\x18\x01 \x01(\t\x12\x18\n\x10street_address_2\x18\x02 \x01(\t\x12\x0c\n\x04\x63ity\x18\x03 \x01(\t\x12\r\n\x05state\x18\x04 \x01(\t\x12\x10\n\x08zip_code\x18\x05 \x01(\t\x12\x12\n\nzip_plus_4\x18\x06 \x01(\t\x1a\x32\n\x0b\x43oordinates\x12\x10\n\x08latitude\x18\x01 \x01(\x01\x12\x11\n\tlongitude\x18\x02 \x01(\x01\"\xcc\x0b\n\tReportRun\x12\x15\n\rreport_run_id\x18\x01 \x01(\t\x12\x13\n\x0b\x63ustomer_id\x18\x02 \x01(\t\x12\x1a\n\x12\x63reated_by_user_id\x18\x03 \x01(\t\x12\x1b\n\x13property_address_id\x18\x04 \x01(\t\x12.\n\ncreated_at\x18\x05 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\x12+\n\x06status\x18\x06 \x01(\x0e\x32\x1b.nhdreport.ReportRun.Status\x12\x33\n\x07results\x18\x07 \x01(\x0b\x32\".nhdreport.ReportRun.HazardResults\x12\x1a\n\x12template_reference\x18\x08 \x01(\t\x12\x1e\n\x16\x66inal_pdf_storage_path\x18\t \x01(\t\x12<\n\x10\x65mail_deliveries\x18\n \x03(\x0b\x32\".nhdreport.ReportRun.EmailDelivery\x12\x1f\n\x17\x64isable_automatic_email\x18\x0b \x01(\x08\x12\x35\n\x0c\x63ost_history\x18\x0c \x03(\x0b\x32\x1f.nhdreport.ReportRun.ReportCost\x12\x35\n\x0fpayment_details\x18\r \x01(\x0b\x32\x1c.nhdreport.ReportRun.Payment\x1a\xe6\x01\n\rHazardResults\x12$\n\x1cin_special_flood_hazard_area\x18\x01 \x01(\x08\x12\x1e\n\x16in_dam_inundation_area\x18\x02 \x01(\x08\x12.\n&in_very_high_fire_hazard_severity_zone\x18\x03 \x01(\x08\x12\x1d\n\x15in_wildland_fire_area\x18\x04 \x01(\x08\x12 \n\x18in_earthquake_fault_zone\x18\x05 \x01(\x08\x12\x1e\n\x16in_seismic_hazard_zone\x18\x06 \x01(\x08\x1a\xe1\x01\n\rEmailDelivery\x12\x41\n\x06status\x18\x01 \x01(\x0e\x32\x31.nhdreport.ReportRun.EmailDelivery.DeliveryStatus\x12+\n\x07sent_at\x18\x02 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\x12 \n\x18\x65mail_template_reference\x18\x03 \x01(\t\">\n\x0e\x44\x65liveryStatus\x12\x16\n\x12STATUS_UNSPECIFIED\x10\x00\x12\x08\n\x04SENT\x10\x01\x12\n\n\x06\x46\x41ILED\x10\x02\x1ar\n\nReportCost\x12\x0e\n\x06\x61mount\x18\x01 \x01(\x01\x12\x10\n\x08\x63urrency\x18\x02 \x01(\t\x12*\n\x06set_at\x18\x03 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\x12\x16\n\x0eset_by_user_id\x18\x04 \x01(\t\x1a\xa3\x02\n\x07Payment\x12:\n\x06status\x18\x01 \x01(\x0e\x32*.nhdreport.ReportRun.Payment.PaymentStatus\x12\x13\n\x0b\x61mount_paid\x18\x02 \x01(\x01\x12\x10\n\x08\x63urrency\x18\x03 \x01(\t\x12+\n\x07paid_at\x18\x04 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\x12\x16\n\x0epayment_method\x18\x05 \x01(\t\x12\x16\n\x0etransaction_id\x18\x06 \x01(\t\"X\n\rPaymentStatus\x12\x1e\n\x1aPAYMENT_STATUS_UNSPECIFIED\x10\x00\x12\x0f\n\x0bOUTSTANDING\x10\x01\x12\x08\n\x04PAID\x10\x02\x12\x0c\n\x08REFUNDED\x10\x03\"X\n\x06Status\x12\x16\n\x12STATUS_UNSPECIFIED\x10\x00\x12\x0b\n\x07PENDING\x10\x01\x12\x0e\n\nPROCESSING\x10\x02\x12\r\n\tCOMPLETED\x10\x03\x12\n\n\x06\x46\x41ILED\x10\x04\x42\x37Z5github.com/seans3/nhd/backend/proto/gen/go;nhd_reportb\x06proto3')
+DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\tnhd.proto\x12\tnhdreport\x1a\x1fgoogle/protobuf/timestamp.proto\".\n\x05Money\x12\x10\n\x08\x63urrency\x18\x01 \x01(\t\x12\x13\n\x0bminor_units\x18\x02 \x01(\x03\"\x81\x01\n\x0cOrganization\x12\x17\n\x0forganization_id\x18\x01 \x01(\t\x12\x0c\n\x04name\x18\x02 \x01(\t\x12.\n\ncreated_at\x18\x03 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\x12\x1a\n\x12\x63reated_by_user_id\x18\x04 \x01(\t\"[\n\x0bPermissions\x12\x1c\n\x14\x63\x61n_create_customers\x18\x01 \x01(\x08\x12\x1c\n\x14\x63\x61n_generate_reports\x18\x02 \x01(\x08\x12\x10\n\x08is_admin\x18\x03 \x01(\x08\"\xaf\x01\n\x04User\x12\x0f\n\x07user_id\x18\x01 \x01(\t\x12\x11\n\tfull_name\x18\x02 \x01(\t\x12\r\n\x05\x65mail\x18\x03 \x01(\t\x12+\n\x0bpermissions\x18\x04 \x01(\x0b\x32\x16.nhdreport.Permissions\x12.\n\ncreated_at\x18\x05 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\x12\x17\n\x0forganization_id\x18\x06 \x01(\t\"\xc8\x02\n\x08\x43ustomer\x12\x13\n\x0b\x63ustomer_id\x18\x01 \x01(\t\x12\x11\n\tfull_name\x18\x02 \x01(\t\x12\r\n\x05\x65mail\x18\x03 \x01(\t\x12\x14\n\x0c\x63ompany_name\x18\x04 \x01(\t\x12.\n\ncreated_at\x18\x05 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\x12\x1a\n\x12\x63reated_by_user_id\x18\x06 \x01(\t\x12\x17\n\x0forganization_id\x18\x07 \x01(\t\x12.\n\nupdated_at\x18\x08 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\x12\x10\n\x08\x61rchived\x18\t \x01(\x08\x12/\n\x0b\x61rchived_at\x18\n \x01(\x0b\x32\x1a.google.protobuf.Timestamp\x12\x17\n\x0fsearch_prefixes\x18\x0b \x03(\t\"\xc8\x03\n\x0fPropertyAddress\x12\x1b\n\x13property_address_id\x18\x01 \x01(\t\x12\x42\n\x0f\x61\x64\x64ress_details\x18\x02 \x01(\x0b\x32).nhdreport.PropertyAddress.AddressDetails\x12;\n\x0b\x63oordinates\x18\x03 \x01(\x0b\x32&.nhdreport.PropertyAddress.Coordinates\x12\x11\n\tplus_code\x18\x04 \x01(\t\x12\x17\n\x0fgoogle_place_id\x18\x05 \x01(\t\x12\x16\n\x0enormalized_key\x18\x06 \x01(\t\x12\x17\n\x0forganization_id\x18\x07 \x01(\t\x1a\x85\x01\n\x0e\x41\x64\x64ressDetails\x12\x16\n\x0estreet_address\x18\x01 \x01(\t\x12\x18\n\x10street_address_2\x18\x02 \x01(\t\x12\x0c\n\x04\x63ity\x18\x03 \x01(\t\x12\r\n\x05state\x18\x04 \x01(\t\x12\x10\n\x08zip_code\x18\x05 \x01(\t\x12\x12\n\nzip_plus_4\x18\x06 \x01(\t\x1a\x32\n\x0b\x43oordinates\x12\x10\n\x08latitude\x18\x01 \x01(\x01\x12\x11\n\tlongitude\x18\x02 \x01(\x01\"\xd6\x18\n\tReportRun\x12\x15\n\rreport_run_id\x18\x01 \x01(\t\x12\x13\n\x0b\x63ustomer_id\x18\x02 \x01(\t\x12\x1a\n\x12\x63reated_by_user_id\x18\x03 \x01(\t\x12\x1b\n\x13property_address_id\x18\x04 \x01(\t\x12.\n\ncreated_at\x18\x05 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\x12+\n\x06status\x18\x06 \x01(\x0e\x32\x1b.nhdreport.ReportRun.Status\x12\x33\n\x07results\x18\x07 \x01(\x0b\x32\".nhdreport.ReportRun.HazardResults\x12\x1a\n\x12template_reference\x18\x08 \x01(\t\x12\x1e\n\x16\x66inal_pdf_storage_path\x18\t \x01(\t\x12<\n\x10\x65mail_deliveries\x18\n \x03(\x0b\x32\".nhdreport.ReportRun.EmailDelivery\x12\x1f\n\x17\x64isable_automatic_email\x18\x0b \x01(\x08\x12\x35\n\x0c\x63ost_history\x18\x0c \x03(\x0b\x32\x1f.nhdreport.ReportRun.ReportCost\x12\x35\n\x0fpayment_details\x18\r \x01(\x0b\x32\x1c.nhdreport.ReportRun.Payment\x12\x30\n\x06ledger\x18\x13 \x03(\x0b\x32 .nhdreport.ReportRun.LedgerEntry\x12.\n\nupdated_at\x18\x0e \x01(\x0b\x32\x1a.google.protobuf.Timestamp\x12\x16\n\x0e\x66\x61ilure_reason\x18\x0f \x01(\t\x12\x17\n\x0forganization_id\x18\x10 \x01(\t\x12\x39\n\x0estatus_history\x18\x11 \x03(\x0b\x32!.nhdreport.ReportRun.StatusChange\x12\x0f\n\x07\x61ttempt\x18\x12 \x01(\x05\x1a\x9d\x02\n\rHazardResults\x12$\n\x1cin_special_flood_hazard_area\x18\x01 \x01(\x08\x12\x1e\n\x16in_dam_inundation_area\x18\x02 \x01(\x08\x12.\n&in_very_high_fire_hazard_severity_zone\x18\x03 \x01(\x08\x12\x1d\n\x15in_wildland_fire_area\x18\x04 \x01(\x08\x12 \n\x18in_earthquake_fault_zone\x18\x05 \x01(\x08\x12\x1e\n\x16in_seismic_hazard_zone\x18\x06 \x01(\x08\x12\x35\n\x08\x65vidence\x18\x07 \x03(\x0b\x32#.nhdreport.ReportRun.HazardEvidence\x1a\xed\x03\n\x0eHazardEvidence\x12\x0e\n\x06hazard\x18\x01 \x01(\t\x12\x0f\n\x07in_zone\x18\x02 \x01(\x08\x12\x0f\n\x07\x64\x61taset\x18\x03 \x01(\t\x12\x0e\n\x06\x61gency\x18\x04 \x01(\t\x12\x15\n\rlayer_version\x18\x05 \x01(\t\x12\x38\n\x14layer_effective_date\x18\x06 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\x12L\n\x10matched_features\x18\x07 \x03(\x0b\x32\x32.nhdreport.ReportRun.HazardEvidence.MatchedFeature\x12(\n\x1b\x64istance_to_boundary_meters\x18\x08 \x01(\x01H\x00\x88\x01\x01\x1a\xaf\x01\n\x0eMatchedFeature\x12\x12\n\nfeature_id\x18\x01 \x01(\t\x12V\n\nattributes\x18\x02 \x03(\x0b\x32\x42.nhdreport.ReportRun.HazardEvidence.MatchedFeature.AttributesEntry\x1a\x31\n\x0f\x41ttributesEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\t:\x02\x38\x01\x42\x1e\n\x1c_distance_to_boundary_meters\x1a\x99\x02\n\rEmailDelivery\x12\x41\n\x06status\x18\x01 \x01(\x0e\x32\x31.nhdreport.ReportRun.EmailDelivery.DeliveryStatus\x12+\n\x07sent_at\x18\x02 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\x12 \n\x18\x65mail_template_reference\x18\x03 \x01(\t\x12\x11\n\trecipient\x18\x04 \x01(\t\x12\x16\n\x0e\x66\x61ilure_reason\x18\x05 \x01(\t\"K\n\x0e\x44\x65liveryStatus\x12\x16\n\x12STATUS_UNSPECIFIED\x10\x00\x12\x08\n\x04SENT\x10\x01\x12\n\n\x06\x46\x41ILED\x10\x02\x12\x0b\n\x07UNKNOWN\x10\x03\x1a\xaf\x01\n\nReportCost\x12 \n\x06\x61mount\x18\x07 \x01(\x0b\x32\x10.nhdreport.Money\x12*\n\x06set_at\x18\x03 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\x12\x16\n\x0eset_by_user_id\x18\x04 \x01(\t\x12\x15\n\rprice_rule_id\x18\x05 \x01(\t\x12\x0e\n\x06reason\x18\x06 \x01(\tJ\x04\x08\x01\x10\x02J\x04\x08\x02\x10\x03R\x08\x63urrency\x1a\xf2\x03\n\x07Payment\x12:\n\x06status\x18\x01 \x01(\x0e\x32*.nhdreport.ReportRun.Payment.PaymentStatus\x12%\n\x0b\x61mount_paid\x18\n \x01(\x0b\x32\x10.nhdreport.Money\x12+\n\x07paid_at\x18\x04 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\x12\x16\n\x0epayment_method\x18\x05 \x01(\t\x12\x16\n\x0etransaction_id\x18\x06 \x01(\t\x12)\n\x0f\x61mount_refunded\x18\x0b \x01(\x0b\x32\x10.nhdreport.Money\x12%\n\x0b\x62\x61lance_due\x18\x0c \x01(\x0b\x32\x10.nhdreport.Money\x12\x37\n\x13last_transaction_at\x18\t \x01(\x0b\x32\x1a.google.protobuf.Timestamp\"z\n\rPaymentStatus\x12\x1e\n\x1aPAYMENT_STATUS_UNSPECIFIED\x10\x00\x12\x0f\n\x0bOUTSTANDING\x10\x01\x12\x08\n\x04PAID\x10\x02\x12\x0c\n\x08REFUNDED\x10\x03\x12\x08\n\x04VOID\x10\x04\x12\x16\n\x12PARTIALLY_REFUNDED\x10\x05J\x04\x08\x02\x10\x03J\x04\x08\x03\x10\x04J\x04\x08\x07\x10\x08J\x04\x08\x08\x10\tR\x08\x63urrency\x1a\xeb\x02\n\x0bLedgerEntry\x12\x10\n\x08\x65ntry_id\x18\x01 \x01(\t\x12\x33\n\x04type\x18\x02 \x01(\x0e\x32%.nhdreport.ReportRun.LedgerEntry.Type\x12 \n\x06\x61mount\x18\n \x01(\x0b\x32\x10.nhdreport.Money\x12.\n\ncreated_at\x18\x05 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\x12\x1a\n\x12\x63reated_by_user_id\x18\x06 \x01(\t\x12\x16\n\x0epayment_method\x18\x07 \x01(\t\x12\x16\n\x0etransaction_id\x18\x08 \x01(\t\x12\x0e\n\x06reason\x18\t \x01(\t\"Q\n\x04Type\x12\x14\n\x10TYPE_UNSPECIFIED\x10\x00\x12\n\n\x06\x43HARGE\x10\x01\x12\x0b\n\x07PAYMENT\x10\x02\x12\n\n\x06REFUND\x10\x03\x12\x0e\n\nADJUSTMENT\x10\x04J\x04\x08\x03\x10\x04J\x04\x08\x04\x10\x05R\x08\x63urrency\x1a\xbf\x01\n\x0cStatusChange\x12\x30\n\x0b\x66rom_status\x18\x01 \x01(\x0e\x32\x1b.nhdreport.ReportRun.Status\x12.\n\tto_status\x18\x02 \x01(\x0e\x32\x1b.nhdreport.ReportRun.Status\x12.\n\nchanged_at\x18\x03 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\x12\r\n\x05\x61\x63tor\x18\x04 \x01(\t\x12\x0e\n\x06reason\x18\x05 \x01(\t\"g\n\x06Status\x12\x16\n\x12STATUS_UNSPECIFIED\x10\x00\x12\x0b\n\x07PENDING\x10\x01\x12\x0e\n\nPROCESSING\x10\x02\x12\r\n\tCOMPLETED\x10\x03\x12\n\n\x06\x46\x41ILED\x10\x04\x12\r\n\tCANCELLED\x10\x05\"\xe2\x02\n\tPriceRule\x12\x15\n\rprice_rule_id\x18\x01 \x01(\t\x12)\n\x05scope\x18\x02 \x01(\x0e\x32\x1a.nhdreport.PriceRule.Scope\x12\x13\n\x0bscope_value\x18\x03 \x01(\t\x12 \n\x06\x61mount\x18\t \x01(\x0b\x32\x10.nhdreport.Money\x12\x32\n\x0e\x65\x66\x66\x65\x63tive_from\x18\x06 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\x12.\n\ncreated_at\x18\x07 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\x12\x1a\n\x12\x63reated_by_user_id\x18\x08 \x01(\t\"F\n\x05Scope\x12\x15\n\x11SCOPE_UNSPECIFIED\x10\x00\x12\x0b\n\x07\x44\x45\x46\x41ULT\x10\x01\x12\x0b\n\x07\x43OMPANY\x10\x02\x12\x0c\n\x08\x43USTOMER\x10\x03J\x04\x08\x04\x10\x05J\x04\x08\x05\x10\x06R\x08\x63urrency\"\xe5\x01\n\x0c\x45xchangeRate\x12\x18\n\x10\x65xchange_rate_id\x18\x01 \x01(\t\x12\x15\n\rbase_currency\x18\x02 \x01(\t\x12\x16\n\x0equote_currency\x18\x03 \x01(\t\x12\x0c\n\x04rate\x18\x04 \x01(\t\x12\x32\n\x0e\x65\x66\x66\x65\x63tive_from\x18\x05 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\x12.\n\ncreated_at\x18\x06 \x01(\x0b\x32\x1a.google.protobuf.Timestamp\x12\x1a\n\x12\x63reated_by_user_id\x18\x07 \x01(\tB7Z5github.com/seans3/nhd/backend/proto/gen/go;nhd_reportb\x06proto3')
 
 _globals = globals()
 _builder.BuildMessageAndEnumDescriptors(DESCRIPTOR, _globals)
@@ -33,32 +33,56 @@ _builder.BuildTopDescriptorsAndMessages(DESCRIPTOR, 'nhd_pb2', _globals)
 if not _descriptor._USE_C_DESCRIPTORS:
   _globals['DESCRIPTOR']._loaded_options = None
   _globals['DESCRIPTOR']._serialized_options = b'Z5github.com/seans3/nhd/backend/proto/gen/go;nhd_report'
-  _globals['_PERMISSIONS']._serialized_start=57
-  _globals['_PERMISSIONS']._serialized_end=148
-  _globals['_USER']._serialized_start=151
-  _globals['_USER']._serialized_end=301
-  _globals['_CUSTOMER']._serialized_start=304
-  _globals['_CUSTOMER']._serialized_end=467
-  _globals['_PROPERTYADDRESS']._serialized_start=470
-  _globals['_PROPERTYADDRESS']._serialized_end=877
-  _globals['_PROPERTYADDRESS_ADDRESSDETAILS']._serialized_start=692
-  _globals['_PROPERTYADDRESS_ADDRESSDETAILS']._serialized_end=825
-  _globals['_PROPERTYADDRESS_COORDINATES']._serialized_start=827
-  _globals['_PROPERTYADDRESS_COORDINATES']._serialized_end=877
-  _globals['_REPORTRUN']._serialized_start=880
-  _globals['_REPORTRUN']._serialized_end=2364
-  _globals['_REPORTRUN_HAZARDRESULTS']._serialized_start=1406
-  _globals['_REPORTRUN_HAZARDRESULTS']._serialized_end=1636
-  _globals['_REPORTRUN_EMAILDELIVERY']._serialized_start=1639
-  _globals['_REPORTRUN_EMAILDELIVERY']._serialized_end=1864
-  _globals['_REPORTRUN_EMAILDELIVERY_DELIVERYSTATUS']._serialized_start=1802
-  _globals['_REPORTRUN_EMAILDELIVERY_DELIVERYSTATUS']._serialized_end=1864
-  _globals['_REPORTRUN_REPORTCOST']._serialized_start=1866
-  _globals['_REPORTRUN_REPORTCOST']._serialized_end=1980
-  _globals['_REPORTRUN_PAYMENT']._serialized_start=1983
-  _globals['_REPORTRUN_PAYMENT']._serialized_end=2274
-  _globals['_REPORTRUN_PAYMENT_PAYMENTSTATUS']._serialized_start=2186
-  _globals['_REPORTRUN_PAYMENT_PAYMENTSTATUS']._serialized_end=2274
-  _globals['_REPORTRUN_STATUS']._serialized_start=2276
-  _globals['_REPORTRUN_STATUS']._serialized_end=2364
+  _globals['_REPORTRUN_HAZARDEVIDENCE_MATCHEDFEATURE_ATTRIBUTESENTRY']._loaded_options = None
+  _globals['_REPORTRUN_HAZARDEVIDENCE_MATCHEDFEATURE_ATTRIBUTESENTRY']._serialized_options = b'8\001'
+  _globals['_MONEY']._serialized_start=57
+  _globals['_MONEY']._serialized_end=103
+  _globals['_ORGANIZATION']._serialized_start=106
+  _globals['_ORGANIZATION']._serialized_end=235
+  _globals['_PERMISSIONS']._serialized_start=237
+  _globals['_PERMISSIONS']._serialized_end=328
+  _globals['_USER']._serialized_start=331
+  _globals['_USER']._serialized_end=506
+  _globals['_CUSTOMER']._serialized_start=509
+  _globals['_CUSTOMER']._serialized_end=837
+  _globals['_PROPERTYADDRESS']._serialized_start=840
+  _globals['_PROPERTYADDRESS']._serialized_end=1296
+  _globals['_PROPERTYADDRESS_ADDRESSDETAILS']._serialized_start=1111
+  _globals['_PROPERTYADDRESS_ADDRESSDETAILS']._serialized_end=1244
+  _globals['_PROPERTYADDRESS_COORDINATES']._serialized_start=1246
+  _globals['_PROPERTYADDRESS_COORDINATES']._serialized_end=1296
+  _globals['_REPORTRUN']._serialized_start=1299
+  _globals['_REPORTRUN']._serialized_end=4457
+  _globals['_REPORTRUN_HAZARDRESULTS']._serialized_start=2048
+  _globals['_REPORTRUN_HAZARDRESULTS']._serialized_end=2333
+  _globals['_REPORTRUN_HAZARDEVIDENCE']._serialized_start=2336
+  _globals['_REPORTRUN_HAZARDEVIDENCE']._serialized_end=2829
+  _globals['_REPORTRUN_HAZARDEVIDENCE_MATCHEDFEATURE']._serialized_start=2622
+  _globals['_REPORTRUN_HAZARDEVIDENCE_MATCHEDFEATURE']._serialized_end=2797
+  _globals['_REPORTRUN_HAZARDEVIDENCE_MATCHEDFEATURE_ATTRIBUTESENTRY']._serialized_start=2748
+  _globals['_REPORTRUN_HAZARDEVIDENCE_MATCHEDFEATURE_ATTRIBUTESENTRY']._serialized_end=2797
+  _globals['_REPORTRUN_EMAILDELIVERY']._serialized_start=2832
+  _globals['_REPORTRUN_EMAILDELIVERY']._serialized_end=3113
+  _globals['_REPORTRUN_EMAILDELIVERY_DELIVERYSTATUS']._serialized_start=3038
+  _globals['_REPORTRUN_EMAILDELIVERY_DELIVERYSTATUS']._serialized_end=3113
+  _globals['_REPORTRUN_REPORTCOST']._serialized_start=3116
+  _globals['_REPORTRUN_REPORTCOST']._serialized_end=3291
+  _globals['_REPORTRUN_PAYMENT']._serialized_start=3294
+  _globals['_REPORTRUN_PAYMENT']._serialized_end=3792
+  _globals['_REPORTRUN_PAYMENT_PAYMENTSTATUS']._serialized_start=3636
+  _globals['_REPORTRUN_PAYMENT_PAYMENTSTATUS']._serialized_end=3758
+  _globals['_REPORTRUN_LEDGERENTRY']._serialized_start=3795
+  _globals['_REPORTRUN_LEDGERENTRY']._serialized_end=4158
+  _globals['_REPORTRUN_LEDGERENTRY_TYPE']._serialized_start=4055
+  _globals['_REPORTRUN_LEDGERENTRY_TYPE']._serialized_end=4136
+  _globals['_REPORTRUN_STATUSCHANGE']._serialized_start=4161
+  _globals['_REPORTRUN_STATUSCHANGE']._serialized_end=4352
+  _globals['_REPORTRUN_STATUS']._serialized_start=4354
+  _globals['_REPORTRUN_STATUS']._serialized_end=4457
+  _globals['_PRICERULE']._serialized_start=4460
+  _globals['_PRICERULE']._serialized_end=4814
+  _globals['_PRICERULE_SCOPE']._serialized_start=4722
+  _globals['_PRICERULE_SCOPE']._serialized_end=4792
+  _globals['_EXCHANGERATE']._serialized_start=4817
+  _globals['_EXCHANGERATE']._serialized_end=5046
 # @@protoc_insertion_point(module_scope)